@@ -1,31 +1,71 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
+	"arabica"
+	"arabica/internal/admin"
 	"arabica/internal/atproto"
+	"arabica/internal/bff"
+	"arabica/internal/config"
 	"arabica/internal/database/boltstore"
 	"arabica/internal/feed"
 	"arabica/internal/handlers"
+	"arabica/internal/health"
+	"arabica/internal/labels"
+	"arabica/internal/lifecycle"
+	"arabica/internal/metrics"
+	"arabica/internal/middleware"
 	"arabica/internal/routing"
+	"arabica/internal/static"
+	"arabica/internal/tracing"
 
+	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 func main() {
+	// Operator commands share the binary: arabica admin <command>
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		os.Exit(admin.Main(os.Args[2:]))
+	}
+
+	// Load configuration from defaults, config file, environment and flags
+	cfg, opts, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "arabica: invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+	if len(opts.Args) > 0 {
+		fmt.Fprintf(os.Stderr, "arabica: unexpected argument %q (did you mean arabica admin?)\n", opts.Args[0])
+		os.Exit(2)
+	}
+	if opts.PrintConfig {
+		if err := cfg.Write(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "arabica: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Configure zerolog
-	// Set log level from environment (default: info)
-	logLevel := os.Getenv("LOG_LEVEL")
-	switch logLevel {
+	switch cfg.Log.Level {
 	case "debug":
 		zerolog.SetGlobalLevel(zerolog.DebugLevel)
-	case "info", "":
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	case "warn":
 		zerolog.SetGlobalLevel(zerolog.WarnLevel)
 	case "error":
@@ -34,65 +74,117 @@ func main() {
 		zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	}
 
+	// Keep the latest errors in memory for the admin dashboard
+	recentErrors := admin.NewRecentErrors(50)
+
 	// Use pretty console logging in development, JSON in production
-	if os.Getenv("LOG_FORMAT") == "json" {
+	if cfg.Log.Format == "json" {
 		// Production: JSON logs
-		log.Logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
+		log.Logger = zerolog.New(zerolog.MultiLevelWriter(os.Stdout, recentErrors)).With().Timestamp().Logger()
 	} else {
 		// Development: pretty console logs
-		log.Logger = log.Output(zerolog.ConsoleWriter{
+		log.Logger = log.Output(zerolog.MultiLevelWriter(zerolog.ConsoleWriter{
 			Out:        os.Stdout,
 			TimeFormat: time.RFC3339,
-		})
+		}, recentErrors))
 	}
 
 	log.Info().Msg("Starting Arabica Coffee Tracker")
+	logEffectiveConfig(cfg, opts.ConfigPath)
 
-	// Get port from env or use default
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "18910"
+	// Deferred cleanup only runs if main returns, so failures from here on set
+	// exitCode and return instead of calling log.Fatal. This runs last, after
+	// every other defer.
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	// Background goroutines, stopped in reverse order after the server drains
+	tasks := lifecycle.NewManager()
+
+	// Configure tracing from OTEL_* environment variables (disabled by default)
+	shutdownTracing, traceExporter, err := tracing.Setup(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to configure tracing")
+		exitCode = 1
+		return
 	}
+	defer shutdownTracing(context.Background())
+	log.Info().Str("exporter", traceExporter).Msg("Tracing configured")
+
+	port := strconv.Itoa(cfg.Server.Port)
 
-	// Get public root URL for reverse proxy deployments
+	// Public root URL for reverse proxy deployments
 	// This allows the server to be accessed via a different URL than it's running on
 	// e.g., SERVER_PUBLIC_URL=https://arabica.example.com when behind a reverse proxy
-	publicURL := os.Getenv("SERVER_PUBLIC_URL")
+	publicURL := cfg.Server.PublicURL
 
-	// Initialize BoltDB store for persistent sessions and feed registry
-	dbPath := os.Getenv("ARABICA_DB_PATH")
-	if dbPath == "" {
-		// Default to XDG data directory or home directory for development
-		// This avoids issues when running from read-only locations (e.g., nix run)
-		dataDir := os.Getenv("XDG_DATA_HOME")
-		if dataDir == "" {
-			home, err := os.UserHomeDir()
-			if err != nil {
-				log.Fatal().Err(err).Msg("Failed to get home directory")
-			}
-			dataDir = filepath.Join(home, ".local", "share")
-		}
-		dbPath = filepath.Join(dataDir, "arabica", "arabica.db")
+	// OAuth sessions hold refresh tokens and DPoP keys, so encrypt them at
+	// rest when a session key is configured
+	currentKey, oldKeys, err := cfg.Database.SessionKeys()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load session keys")
+		exitCode = 1
+		return
+	}
+	sessionKeys, err := boltstore.NewKeyring(currentKey, oldKeys)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load session keys")
+		exitCode = 1
+		return
 	}
 
+	// Initialize BoltDB store for persistent sessions and feed registry
+	dbPath := cfg.Database.Path
 	store, err := boltstore.Open(boltstore.Options{
-		Path: dbPath,
+		Path:        dbPath,
+		SessionKeys: sessionKeys,
 	})
 	if err != nil {
-		log.Fatal().Err(err).Str("path", dbPath).Msg("Failed to open database")
+		log.Error().Err(err).Str("path", dbPath).Msg("Failed to open database")
+		exitCode = 1
+		return
 	}
 	defer store.Close()
 
 	log.Info().Str("path", dbPath).Msg("Database opened")
 
+	if sessionKeys == nil {
+		log.Warn().Msg("No session key configured, OAuth sessions are stored unencrypted")
+	} else {
+		// Sessions saved before the key was configured are encrypted in place
+		result, err := store.SessionStore().EncryptSessions(context.Background())
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to encrypt stored sessions")
+			exitCode = 1
+			return
+		}
+		log.Info().
+			Str("key_id", sessionKeys.KeyID()).
+			Int("old_keys", len(oldKeys)).
+			Int("encrypted", result.Encrypted).
+			Msg("OAuth sessions encrypted at rest")
+	}
+
+	// Export BoltDB stats on /metrics
+	if err := metrics.Register(metrics.NewBoltCollector(store.Stats)); err != nil {
+		log.Error().Err(err).Msg("Failed to register database metrics")
+		exitCode = 1
+		return
+	}
+
 	// Get specialized stores
 	sessionStore := store.SessionStore()
 	feedStore := store.FeedStore()
+	preferencesStore := store.PreferencesStore()
 
 	// Initialize OAuth manager with persistent session store
 	// For local development, localhost URLs trigger special localhost mode in indigo
-	clientID := os.Getenv("OAUTH_CLIENT_ID")
-	redirectURI := os.Getenv("OAUTH_REDIRECT_URI")
+	clientID := cfg.OAuth.ClientID
+	redirectURI := cfg.OAuth.RedirectURI
 
 	if clientID == "" && redirectURI == "" {
 		// Use public URL if set, otherwise localhost defaults for development
@@ -111,13 +203,70 @@ func main() {
 
 	oauthManager, err := atproto.NewOAuthManager(clientID, redirectURI, sessionStore)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to initialize OAuth")
+		log.Error().Err(err).Msg("Failed to initialize OAuth")
+		exitCode = 1
+		return
+	}
+
+	// A signing key makes this a confidential client, which PDSes give
+	// longer sessions
+	if cfg.OAuth.SigningKeyFile != "" {
+		current, err := atproto.LoadClientKey(cfg.OAuth.SigningKeyFile)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to load OAuth signing key")
+			exitCode = 1
+			return
+		}
+		var old []*atproto.ClientKey
+		for _, path := range cfg.OAuth.OldSigningKeyFiles {
+			key, err := atproto.LoadClientKey(path)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to load old OAuth signing key")
+				exitCode = 1
+				return
+			}
+			old = append(old, key)
+		}
+		if err := oauthManager.SetClientKeys(current, old...); err != nil {
+			log.Error().Err(err).Msg("Failed to configure confidential OAuth client")
+			exitCode = 1
+			return
+		}
+		log.Info().
+			Str("key_id", current.ID).
+			Int("old_keys", len(old)).
+			Msg("Using confidential OAuth client")
 	}
 
 	// Initialize feed registry with persistent store
 	// This loads existing registered DIDs from the database
 	feedRegistry := feed.NewPersistentRegistry(feedStore)
-	feedService := feed.NewService(feedRegistry)
+	feedConfig := feed.Config{
+		CacheTTL:   cfg.Feed.CacheTTL,
+		Moderation: store.ModerationStore(),
+	}
+
+	// Labels from labeler services hide or blur feed items
+	var labelService *labels.Service
+	if len(cfg.Labels.Labelers) > 0 {
+		clients := make([]*labels.Client, 0, len(cfg.Labels.Labelers))
+		for _, l := range cfg.Labels.Labelers {
+			clients = append(clients, labels.NewClient(l.DID, l.URL))
+		}
+		labelService = labels.NewService(clients...)
+		feedConfig.Labels = labelService
+		log.Info().Int("labelers", len(clients)).Msg("Labeler integration enabled")
+	}
+
+	feedService := feed.NewService(feedRegistry, feedConfig)
+
+	// Admin operations shared by the dashboard and the admin socket
+	adminOps := &admin.Local{
+		Store:    store,
+		Registry: feedRegistry,
+		Feed:     feedService,
+		Errors:   recentErrors,
+	}
 
 	log.Info().
 		Int("registered_users", feedRegistry.Count()).
@@ -129,6 +278,13 @@ func main() {
 		feedRegistry.Register(did)
 	})
 
+	// Record when each session was last used for the sessions page
+	oauthManager.SetOnSessionUse(func(did syntax.DID, sessionID string) {
+		if err := sessionStore.TouchSession(context.Background(), did, sessionID); err != nil {
+			log.Warn().Err(err).Str("did", did.String()).Msg("Failed to record session use")
+		}
+	})
+
 	if clientID == "" {
 		log.Info().
 			Str("mode", "localhost development").
@@ -147,14 +303,43 @@ func main() {
 	log.Info().Msg("ATProto client initialized")
 
 	// Initialize session cache for in-memory caching of user data
-	sessionCache := atproto.NewSessionCache()
-	stopCacheCleanup := sessionCache.StartCleanupRoutine(10 * time.Minute)
-	defer stopCacheCleanup()
+	sessionCache := atproto.NewSessionCacheWithTTL(cfg.Cache.TTL)
+	tasks.Add("session cache cleanup", sessionCache.StartCleanupRoutine(cfg.Cache.CleanupInterval))
 	log.Info().Msg("Session cache initialized with background cleanup")
 
-	// Determine if we should use secure cookies (default: false for development)
-	// Set SECURE_COOKIES=true in production with HTTPS
-	secureCookies := os.Getenv("SECURE_COOKIES") == "true"
+	rateLimits := middleware.NewRateLimitConfig(
+		cfg.RateLimits.Auth,
+		cfg.RateLimits.API,
+		cfg.RateLimits.Global,
+		cfg.RateLimits.Window,
+	)
+	tasks.Add("rate limiter cleanup", rateLimits.Stop)
+
+	// Secure cookies default to off for development; enable them in production with HTTPS
+	secureCookies := cfg.Server.SecureCookies
+
+	// Templates and static assets are embedded in the binary. Dev mode
+	// serves them from the working tree instead and re-parses templates on
+	// every render, so edits show up without a rebuild.
+	devMode := cfg.Server.Dev
+	templateFS, staticFS := arabica.Templates(), arabica.Static()
+	if devMode {
+		templateFS, staticFS = os.DirFS("templates"), os.DirFS("web/static")
+	}
+
+	assets, err := static.New(staticFS, devMode)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load static assets")
+		exitCode = 1
+		return
+	}
+	bff.SetStaticURL(assets.URL)
+	if err := bff.LoadTemplates(templateFS, devMode); err != nil {
+		log.Error().Err(err).Msg("Failed to parse templates")
+		exitCode = 1
+		return
+	}
+	log.Info().Bool("dev_mode", devMode).Msg("Templates and static assets loaded")
 
 	// Initialize handlers with all dependencies via constructor injection
 	h := handlers.NewHandler(
@@ -163,18 +348,100 @@ func main() {
 		sessionCache,
 		feedService,
 		feedRegistry,
+		preferencesStore,
+		store.ModerationStore(),
+		sessionStore,
+		adminOps,
 		handlers.Config{
 			SecureCookies: secureCookies,
+			AdminDIDs:     cfg.Admin.DIDs,
+			LabelsEnabled: labelService != nil,
 		},
 	)
 
+	// Readiness checks for /readyz. Upstream reachability is informational and
+	// opt-in, since probes would otherwise reach out to third parties constantly.
+	healthChecks := []health.Check{
+		{Name: "database", Critical: true, Run: func(context.Context) error { return store.Ping() }},
+		{Name: "templates", Critical: true, Run: func(context.Context) error { return bff.CheckTemplates() }},
+	}
+	if cfg.Health.CheckUpstream {
+		probeClient := &http.Client{Timeout: 5 * time.Second}
+		healthChecks = append(healthChecks,
+			health.Check{Name: "plc", Run: health.Cached(time.Minute, health.HTTPCheck(probeClient, atproto.PLCDirectoryURL+"/_health"))},
+			health.Check{Name: "appview", Run: health.Cached(time.Minute, health.HTTPCheck(probeClient, atproto.PublicAPIBaseURL+"/xrpc/_health"))},
+		)
+	}
+
 	// Setup router with middleware
 	handler := routing.SetupRouter(routing.Config{
 		Handlers:     h,
 		OAuthManager: oauthManager,
+		Assets:       assets,
+		Health:       health.NewChecker(healthChecks...),
+		RateLimits:   rateLimits,
 		Logger:       log.Logger,
 	})
 
+	// Serve admin commands on a local socket so they can run alongside the
+	// server, which holds the database lock
+	adminListener, err := admin.Listen(cfg.Admin.Socket)
+	if err != nil {
+		log.Warn().Err(err).Str("socket", cfg.Admin.Socket).Msg("Admin socket unavailable, admin commands need the server stopped")
+	} else {
+		adminServer := &http.Server{Handler: admin.Handler(adminOps)}
+		go adminServer.Serve(adminListener)
+		tasks.Add("admin socket", func() { adminServer.Shutdown(context.Background()) })
+		log.Info().Str("socket", cfg.Admin.Socket).Msg("Admin socket listening")
+	}
+
+	// Serve metrics on their own listener, which shouldn't be reachable from
+	// outside, rather than next to the public routes
+	if cfg.Metrics.Listen != "" {
+		metricsListener, err := net.Listen("tcp", cfg.Metrics.Listen)
+		if err != nil {
+			log.Error().Err(err).Str("address", cfg.Metrics.Listen).Msg("Failed to listen for metrics")
+			exitCode = 1
+			return
+		}
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /metrics", metrics.Handler())
+		metricsServer := &http.Server{Handler: metricsMux, ReadHeaderTimeout: 10 * time.Second}
+		go metricsServer.Serve(metricsListener)
+		tasks.Add("metrics listener", func() { metricsServer.Shutdown(context.Background()) })
+		log.Info().Str("address", cfg.Metrics.Listen).Msg("Metrics listening")
+	}
+
+	// Keep the public feed warm in the background
+	tasks.Go("feed refresh", func(ctx context.Context) {
+		// Load labels first so the first feed build is already filtered
+		if labelService != nil {
+			if err := labelService.Refresh(ctx, feedRegistry.List()); err != nil {
+				log.Warn().Err(err).Msg("Failed to load labels at startup")
+			}
+		}
+		feedService.RefreshLoop(ctx, cfg.Feed.RefreshInterval)
+	})
+	sessionTTL := cfg.Sweeper.SessionTTL
+	if oauthManager.IsConfidential() {
+		sessionTTL = cfg.Sweeper.ConfidentialSessionTTL
+	}
+	tasks.Go("session sweeper", func(ctx context.Context) {
+		sessionStore.SweepLoop(ctx, boltstore.SweepOptions{
+			Interval:       cfg.Sweeper.Interval,
+			AuthRequestTTL: cfg.Sweeper.AuthRequestTTL,
+			SessionTTL:     sessionTTL,
+			OnSweep: func(start time.Time, result boltstore.SweepResult, err error) {
+				metrics.ObserveSweep(start, result.AuthRequests, result.Sessions, err)
+			},
+		})
+	})
+	if labelService != nil {
+		tasks.Go("label refresh", func(ctx context.Context) {
+			labelService.RefreshLoop(ctx, cfg.Labels.RefreshInterval, feedRegistry.List)
+		})
+	}
+
 	// Start HTTP server
 	log.Info().
 		Str("address", "0.0.0.0:"+port).
@@ -183,7 +450,78 @@ func main() {
 		Str("database", dbPath).
 		Msg("Starting HTTP server")
 
-	if err := http.ListenAndServe("0.0.0.0:"+port, handler); err != nil {
-		log.Fatal().Err(err).Msg("Server failed to start")
+	srv := &http.Server{
+		Addr:              "0.0.0.0:" + port,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	// Wait for SIGINT/SIGTERM or for the server to fail
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	select {
+	case err := <-serverErr:
+		log.Error().Err(err).Msg("Server failed")
+		exitCode = 1
+	case <-ctx.Done():
+		// Restore default handling so a second signal kills the process outright
+		stopSignals()
+		log.Info().Dur("timeout", cfg.Server.ShutdownTimeout).Msg("Shutting down, draining in-flight requests")
+	}
+
+	// Stop accepting connections and let in-flight requests (including PDS
+	// writes) finish, then stop background tasks. The database and tracer are
+	// closed by the deferred calls above once this returns.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("HTTP server did not drain in time")
+		exitCode = 1
+	}
+	if err := tasks.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Background tasks did not stop in time")
+		exitCode = 1
 	}
+
+	log.Info().Msg("Shutdown complete")
+}
+
+// logEffectiveConfig logs the settings the server is running with, which
+// makes it clear which layer won when a value is set in several places
+func logEffectiveConfig(cfg *config.Config, path string) {
+	if path == "" {
+		path = "none"
+	}
+	log.Info().
+		Str("config_file", path).
+		Int("port", cfg.Server.Port).
+		Str("public_url", cfg.Server.PublicURL).
+		Dur("shutdown_timeout", cfg.Server.ShutdownTimeout).
+		Str("log_level", cfg.Log.Level).
+		Str("log_format", cfg.Log.Format).
+		Dur("cache_ttl", cfg.Cache.TTL).
+		Dur("cache_cleanup_interval", cfg.Cache.CleanupInterval).
+		Dur("feed_cache_ttl", cfg.Feed.CacheTTL).
+		Dur("feed_refresh_interval", cfg.Feed.RefreshInterval).
+		Dur("rate_limit_window", cfg.RateLimits.Window).
+		Int("rate_limit_auth", cfg.RateLimits.Auth).
+		Int("rate_limit_api", cfg.RateLimits.API).
+		Int("rate_limit_global", cfg.RateLimits.Global).
+		Bool("readyz_upstream", cfg.Health.CheckUpstream).
+		Str("metrics_listen", cfg.Metrics.Listen).
+		Int("admins", len(cfg.Admin.DIDs)).
+		Int("labelers", len(cfg.Labels.Labelers)).
+		Dur("labels_refresh_interval", cfg.Labels.RefreshInterval).
+		Dur("sweep_interval", cfg.Sweeper.Interval).
+		Dur("auth_request_ttl", cfg.Sweeper.AuthRequestTTL).
+		Dur("session_ttl", cfg.Sweeper.SessionTTL).
+		Dur("confidential_session_ttl", cfg.Sweeper.ConfidentialSessionTTL).
+		Msg("Configuration loaded")
 }
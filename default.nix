@@ -4,7 +4,7 @@ buildGoModule rec {
   pname = "arabica";
   version = "0.1.0";
   src = ./.;
-  vendorHash = "sha256-hQDxCw2UqYIglHDXctFm6bvjhFI7ykuxU8RbkcEEpBI=";
+  vendorHash = "sha256-qf7AffNmku4oYEMHoml98dCxfSY9LcFd4PlVA7hhM9c=";
 
   nativeBuildInputs = [ tailwindcss ];
 
@@ -22,7 +22,6 @@ buildGoModule rec {
     wrapperScript = ''
       #!/bin/sh
       SCRIPT_DIR="$(dirname "$(readlink -f "$0")")"
-      SHARE_DIR="$SCRIPT_DIR/../share/arabica"
 
       # Set default database path if not specified
       # Uses XDG_DATA_HOME or falls back to ~/.local/share
@@ -32,16 +31,12 @@ buildGoModule rec {
           export ARABICA_DB_PATH="$DATA_DIR/arabica.db"
       fi
 
-      cd "$SHARE_DIR"
       exec "$SCRIPT_DIR/arabica-unwrapped" "$@"
     '';
   in ''
         mkdir -p $out/bin
-        mkdir -p $out/share/arabica
 
-        # Copy static files and templates
-        cp -r web $out/share/arabica/
-        cp -r templates $out/share/arabica/
+        # Templates and static files are embedded in the binary
         cp arabica $out/bin/arabica-unwrapped
         cat > $out/bin/arabica <<'WRAPPER'
     ${wrapperScript}
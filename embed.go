@@ -0,0 +1,28 @@
+// Package arabica embeds the HTML templates and static web assets so the
+// server binary can run without the source tree next to it.
+package arabica
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+//go:embed web/static
+var staticFS embed.FS
+
+// Templates returns the embedded templates directory
+func Templates() fs.FS {
+	// fs.Sub only fails on an invalid path, and this one is fixed
+	sub, _ := fs.Sub(templatesFS, "templates")
+	return sub
+}
+
+// Static returns the embedded web/static directory
+func Static() fs.FS {
+	// fs.Sub only fails on an invalid path, and this one is fixed
+	sub, _ := fs.Sub(staticFS, "web/static")
+	return sub
+}
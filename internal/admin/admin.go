@@ -0,0 +1,352 @@
+// Package admin implements the operator commands behind `arabica admin`.
+//
+// BoltDB holds an exclusive lock on the database file while the server runs,
+// so the CLI can't open it alongside the server. Instead the server listens on
+// a unix socket next to the database and runs the commands itself, which also
+// keeps its in-memory feed registry and cache in sync. When the server is
+// stopped the CLI opens the database directly.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"arabica/internal/database/boltstore"
+	"arabica/internal/feed"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// ErrNotFound is returned when the DID to act on has no feed entry or sessions
+	ErrNotFound = errors.New("not found")
+
+	// ErrServerNotRunning is returned by commands that need the running server,
+	// such as those touching the in-memory feed cache
+	ErrServerNotRunning = errors.New("server is not running")
+)
+
+// SessionInfo describes a stored OAuth session without its tokens or keys
+type SessionInfo struct {
+	DID           string   `json:"did"`
+	SessionID     string   `json:"session_id"`
+	HostURL       string   `json:"host_url"`
+	AuthServerURL string   `json:"authserver_url"`
+	Scopes        []string `json:"scopes"`
+}
+
+// BucketStats is the number of keys in one bucket
+type BucketStats struct {
+	Name string `json:"name"`
+	Keys int    `json:"keys"`
+}
+
+// DBStats summarizes the database file
+type DBStats struct {
+	Path      string        `json:"path"`
+	SizeBytes int64         `json:"size_bytes"`
+	Buckets   []BucketStats `json:"buckets"`
+	FreePages int           `json:"free_pages"`
+	OpenTxN   int           `json:"open_read_tx"`
+}
+
+// FeedCacheItem is one entry of the cached public feed
+type FeedCacheItem struct {
+	Author     string    `json:"author"`
+	AuthorDID  string    `json:"author_did"`
+	URI        string    `json:"uri"`
+	Labels     []string  `json:"labels,omitempty"`
+	RecordType string    `json:"record_type"`
+	Action     string    `json:"action"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// FeedCache describes the server's public feed cache
+type FeedCache struct {
+	Items     []FeedCacheItem `json:"items"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+// Moderation lists the DIDs banned from the feed and the hidden records
+type Moderation struct {
+	Bans   []boltstore.ModerationEntry `json:"bans"`
+	Hidden []boltstore.ModerationEntry `json:"hidden"`
+}
+
+// Operations are the commands available to operators. Local runs them
+// in-process; Client forwards them to a running server.
+type Operations interface {
+	ListFeedUsers(ctx context.Context) ([]boltstore.FeedUser, error)
+	RemoveFeedUser(ctx context.Context, did string) error
+	ListSessions(ctx context.Context) ([]SessionInfo, error)
+	RevokeSessions(ctx context.Context, did string) (int, error)
+	RekeySessions(ctx context.Context) (*boltstore.EncryptResult, error)
+	DBStats(ctx context.Context) (*DBStats, error)
+	Backup(ctx context.Context, w io.Writer) (int64, error)
+	WarmFeed(ctx context.Context) (*FeedCache, error)
+	InspectFeed(ctx context.Context) (*FeedCache, error)
+	ListModeration(ctx context.Context) (*Moderation, error)
+	BanDID(ctx context.Context, did, reason, by string) error
+	UnbanDID(ctx context.Context, did string) error
+	HideRecord(ctx context.Context, uri, reason, by string) error
+	UnhideRecord(ctx context.Context, uri string) error
+}
+
+// Local runs operations against an open store. Registry and Feed are set
+// inside the server so changes reach its in-memory state; the CLI leaves them
+// nil when it opens a stopped server's database. Errors, when set, collects
+// the server's recent error logs for the dashboard.
+type Local struct {
+	Store    *boltstore.Store
+	Registry *feed.Registry
+	Feed     *feed.Service
+	Errors   *RecentErrors
+}
+
+// ListFeedUsers returns registered feed users, oldest first
+func (l *Local) ListFeedUsers(ctx context.Context) ([]boltstore.FeedUser, error) {
+	users := l.Store.FeedStore().ListWithMetadata()
+	slices.SortFunc(users, func(a, b boltstore.FeedUser) int {
+		return a.RegisteredAt.Compare(b.RegisteredAt)
+	})
+	return users, nil
+}
+
+// RemoveFeedUser drops a DID from the community feed. The user is registered
+// again the next time they log in.
+func (l *Local) RemoveFeedUser(ctx context.Context, did string) error {
+	feedStore := l.Store.FeedStore()
+	if !feedStore.IsRegistered(did) {
+		return ErrNotFound
+	}
+
+	if l.Registry != nil {
+		l.Registry.Unregister(did)
+	} else if err := feedStore.Unregister(did); err != nil {
+		return err
+	}
+
+	if l.Feed != nil {
+		l.Feed.InvalidateCache()
+	}
+	return nil
+}
+
+// ListSessions returns stored OAuth sessions sorted by DID
+func (l *Local) ListSessions(ctx context.Context) ([]SessionInfo, error) {
+	sessions, err := l.Store.SessionStore().ListSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		infos = append(infos, SessionInfo{
+			DID:           sess.AccountDID.String(),
+			SessionID:     sess.SessionID,
+			HostURL:       sess.HostURL,
+			AuthServerURL: sess.AuthServerURL,
+			Scopes:        sess.Scopes,
+		})
+	}
+	slices.SortFunc(infos, func(a, b SessionInfo) int {
+		if c := strings.Compare(a.DID, b.DID); c != 0 {
+			return c
+		}
+		return strings.Compare(a.SessionID, b.SessionID)
+	})
+	return infos, nil
+}
+
+// RevokeSessions deletes every session for did, logging it out on all
+// devices, and returns how many were removed
+func (l *Local) RevokeSessions(ctx context.Context, did string) (int, error) {
+	parsed, err := syntax.ParseDID(did)
+	if err != nil {
+		return 0, err
+	}
+
+	sessionStore := l.Store.SessionStore()
+	sessions, err := sessionStore.ListSessions(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, sess := range sessions {
+		if sess.AccountDID == parsed {
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, ErrNotFound
+	}
+
+	if err := sessionStore.DeleteAllSessionsForDID(ctx, parsed); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// RekeySessions encrypts every stored session with the current session key,
+// so old keys can be removed from the configuration
+func (l *Local) RekeySessions(ctx context.Context) (*boltstore.EncryptResult, error) {
+	result, err := l.Store.SessionStore().RekeySessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DBStats reports the file size and key count of each bucket
+func (l *Local) DBStats(ctx context.Context) (*DBStats, error) {
+	db := l.Store.DB()
+	stats := &DBStats{Path: db.Path()}
+
+	info, err := os.Stat(db.Path())
+	if err != nil {
+		return nil, err
+	}
+	stats.SizeBytes = info.Size()
+
+	err = db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			stats.Buckets = append(stats.Buckets, BucketStats{Name: string(name), Keys: b.Stats().KeyN})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dbStats := db.Stats()
+	stats.FreePages = dbStats.FreePageN
+	stats.OpenTxN = dbStats.OpenTxN
+	return stats, nil
+}
+
+// Backup writes a consistent copy of the database to w
+func (l *Local) Backup(ctx context.Context, w io.Writer) (int64, error) {
+	return l.Store.Backup(w)
+}
+
+// WarmFeed rebuilds the public feed cache now
+func (l *Local) WarmFeed(ctx context.Context) (*FeedCache, error) {
+	if l.Feed == nil {
+		return nil, ErrServerNotRunning
+	}
+	if _, err := l.Feed.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return l.InspectFeed(ctx)
+}
+
+// InspectFeed returns the cached public feed without refreshing it
+func (l *Local) InspectFeed(ctx context.Context) (*FeedCache, error) {
+	if l.Feed == nil {
+		return nil, ErrServerNotRunning
+	}
+
+	items, expiresAt := l.Feed.CachedItems()
+	cache := &FeedCache{Items: make([]FeedCacheItem, 0, len(items)), ExpiresAt: expiresAt}
+	for _, item := range items {
+		var author, authorDID string
+		if item.Author != nil {
+			author = item.Author.Handle
+			authorDID = item.Author.DID
+		}
+		cache.Items = append(cache.Items, FeedCacheItem{
+			Author:     author,
+			AuthorDID:  authorDID,
+			URI:        item.URI,
+			Labels:     item.Labels,
+			RecordType: item.RecordType,
+			Action:     item.Action,
+			Timestamp:  item.Timestamp,
+		})
+	}
+	return cache, nil
+}
+
+// ListModeration returns the current bans and hidden records
+func (l *Local) ListModeration(ctx context.Context) (*Moderation, error) {
+	moderation := l.Store.ModerationStore()
+	bans, err := moderation.ListBans()
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := moderation.ListHidden()
+	if err != nil {
+		return nil, err
+	}
+	return &Moderation{Bans: bans, Hidden: hidden}, nil
+}
+
+// BanDID keeps a DID out of the community feed and hides its profile. by is
+// recorded as the acting admin.
+func (l *Local) BanDID(ctx context.Context, did, reason, by string) error {
+	if _, err := syntax.ParseDID(did); err != nil {
+		return err
+	}
+	if err := l.Store.ModerationStore().BanDID(did, reason, by); err != nil {
+		return err
+	}
+	l.invalidateFeed()
+	return nil
+}
+
+// UnbanDID lifts a ban
+func (l *Local) UnbanDID(ctx context.Context, did string) error {
+	moderation := l.Store.ModerationStore()
+	if !moderation.IsBanned(did) {
+		return ErrNotFound
+	}
+	if err := moderation.UnbanDID(did); err != nil {
+		return err
+	}
+	l.invalidateFeed()
+	return nil
+}
+
+// HideRecord keeps the record at uri out of the feed and profile pages
+func (l *Local) HideRecord(ctx context.Context, uri, reason, by string) error {
+	parsed, err := syntax.ParseATURI(uri)
+	if err != nil {
+		return err
+	}
+	if parsed.RecordKey() == "" {
+		return fmt.Errorf("%s does not name a record", uri)
+	}
+	if err := l.Store.ModerationStore().HideRecord(uri, reason, by); err != nil {
+		return err
+	}
+	l.invalidateFeed()
+	return nil
+}
+
+// UnhideRecord makes a hidden record visible again
+func (l *Local) UnhideRecord(ctx context.Context, uri string) error {
+	moderation := l.Store.ModerationStore()
+	if !moderation.IsHidden(uri) {
+		return ErrNotFound
+	}
+	if err := moderation.UnhideRecord(uri); err != nil {
+		return err
+	}
+	l.invalidateFeed()
+	return nil
+}
+
+// invalidateFeed makes moderation changes show up on the next feed request
+// rather than after the cache expires
+func (l *Local) invalidateFeed() {
+	if l.Feed != nil {
+		l.Feed.InvalidateCache()
+	}
+}
@@ -0,0 +1,330 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"arabica/internal/database/boltstore"
+
+	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/rs/zerolog"
+)
+
+// newStore opens a database seeded with two feed users and three sessions
+func newStore(t *testing.T) (*boltstore.Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "arabica.db")
+	store, err := boltstore.Open(boltstore.Options{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	for _, did := range []string{"did:plc:alice", "did:plc:bob"} {
+		if err := store.FeedStore().Register(did); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	for _, sess := range []oauth.ClientSessionData{
+		{AccountDID: "did:plc:alice", SessionID: "a1", AccessToken: "secret"},
+		{AccountDID: "did:plc:alice", SessionID: "a2", AccessToken: "secret"},
+		{AccountDID: "did:plc:bob", SessionID: "b1", AccessToken: "secret"},
+	} {
+		if err := store.SessionStore().SaveSession(ctx, sess); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store, path
+}
+
+// serve runs the admin handler for ops on a socket in a temp dir
+func serve(t *testing.T, ops Operations) string {
+	t.Helper()
+	// Socket paths are limited to ~100 bytes, which t.TempDir can exceed
+	dir, err := os.MkdirTemp("", "arabica-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "admin.sock")
+	ln, err := Listen(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: Handler(ops)}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	return socket
+}
+
+func TestListenPermissions(t *testing.T) {
+	socket := serve(t, &Local{})
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("socket permissions = %o, want 600", perm)
+	}
+}
+
+// backends runs fn against both the in-process and socket implementations
+func backends(t *testing.T, fn func(t *testing.T, ops Operations)) {
+	t.Run("local", func(t *testing.T) {
+		store, _ := newStore(t)
+		fn(t, &Local{Store: store})
+	})
+	t.Run("socket", func(t *testing.T) {
+		store, _ := newStore(t)
+		client, err := Dial(serve(t, &Local{Store: store}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fn(t, client)
+	})
+}
+
+func TestFeedUsers(t *testing.T) {
+	backends(t, func(t *testing.T, ops Operations) {
+		ctx := context.Background()
+
+		if err := ops.RemoveFeedUser(ctx, "did:plc:alice"); err != nil {
+			t.Fatalf("RemoveFeedUser() error = %v", err)
+		}
+		if err := ops.RemoveFeedUser(ctx, "did:plc:alice"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("removing twice: error = %v, want ErrNotFound", err)
+		}
+
+		users, err := ops.ListFeedUsers(ctx)
+		if err != nil {
+			t.Fatalf("ListFeedUsers() error = %v", err)
+		}
+		if len(users) != 1 || users[0].DID != "did:plc:bob" {
+			t.Errorf("ListFeedUsers() = %+v, want only did:plc:bob", users)
+		}
+	})
+}
+
+func TestSessions(t *testing.T) {
+	backends(t, func(t *testing.T, ops Operations) {
+		ctx := context.Background()
+
+		count, err := ops.RevokeSessions(ctx, "did:plc:alice")
+		if err != nil || count != 2 {
+			t.Errorf("RevokeSessions() = %d, %v, want 2, nil", count, err)
+		}
+		if _, err := ops.RevokeSessions(ctx, "did:plc:alice"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("revoking twice: error = %v, want ErrNotFound", err)
+		}
+
+		sessions, err := ops.ListSessions(ctx)
+		if err != nil {
+			t.Fatalf("ListSessions() error = %v", err)
+		}
+		if len(sessions) != 1 || sessions[0].SessionID != "b1" {
+			t.Errorf("ListSessions() = %+v, want only b1", sessions)
+		}
+	})
+}
+
+func TestBackup(t *testing.T) {
+	backends(t, func(t *testing.T, ops Operations) {
+		var buf bytes.Buffer
+		n, err := ops.Backup(context.Background(), &buf)
+		if err != nil {
+			t.Fatalf("Backup() error = %v", err)
+		}
+		if n == 0 || int64(buf.Len()) != n {
+			t.Fatalf("Backup() = %d bytes, wrote %d", n, buf.Len())
+		}
+
+		// The copy must be a usable database with the same contents
+		path := filepath.Join(t.TempDir(), "backup.db")
+		if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+			t.Fatal(err)
+		}
+		restored, err := boltstore.Open(boltstore.Options{Path: path})
+		if err != nil {
+			t.Fatalf("opening backup: %v", err)
+		}
+		defer restored.Close()
+		if got := restored.FeedStore().Count(); got != 2 {
+			t.Errorf("backup has %d feed users, want 2", got)
+		}
+	})
+}
+
+func TestModeration(t *testing.T) {
+	backends(t, func(t *testing.T, ops Operations) {
+		ctx := context.Background()
+		uri := "at://did:plc:alice/social.arabica.alpha.brew/3kabc"
+
+		if err := ops.BanDID(ctx, "did:plc:bob", "spam", "did:plc:admin"); err != nil {
+			t.Fatalf("BanDID() error = %v", err)
+		}
+		if err := ops.BanDID(ctx, "bob.example.com", "", ""); err == nil {
+			t.Error("BanDID() with a handle succeeded, want error")
+		}
+		if err := ops.HideRecord(ctx, uri, "", "did:plc:admin"); err != nil {
+			t.Fatalf("HideRecord() error = %v", err)
+		}
+		if err := ops.HideRecord(ctx, "at://did:plc:alice", "", ""); err == nil {
+			t.Error("HideRecord() without a record key succeeded, want error")
+		}
+
+		moderation, err := ops.ListModeration(ctx)
+		if err != nil {
+			t.Fatalf("ListModeration() error = %v", err)
+		}
+		if len(moderation.Bans) != 1 || moderation.Bans[0].Subject != "did:plc:bob" || moderation.Bans[0].Reason != "spam" {
+			t.Errorf("Bans = %+v, want did:plc:bob for spam", moderation.Bans)
+		}
+		if len(moderation.Hidden) != 1 || moderation.Hidden[0].Subject != uri {
+			t.Errorf("Hidden = %+v, want %s", moderation.Hidden, uri)
+		}
+
+		if err := ops.UnhideRecord(ctx, uri); err != nil {
+			t.Errorf("UnhideRecord() error = %v", err)
+		}
+		if err := ops.UnhideRecord(ctx, uri); !errors.Is(err, ErrNotFound) {
+			t.Errorf("unhiding twice: error = %v, want ErrNotFound", err)
+		}
+		if err := ops.UnbanDID(ctx, "did:plc:bob"); err != nil {
+			t.Errorf("UnbanDID() error = %v", err)
+		}
+		if err := ops.UnbanDID(ctx, "did:plc:bob"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("unbanning twice: error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestRecentErrors(t *testing.T) {
+	recent := NewRecentErrors(2)
+	logger := zerolog.New(zerolog.MultiLevelWriter(io.Discard, recent)).With().Timestamp().Logger()
+
+	logger.Info().Msg("ignored")
+	logger.Warn().Msg("ignored too")
+	logger.Error().Err(errors.New("first")).Msg("one")
+	logger.Error().Str("did", "did:plc:bob").Msg("two")
+	logger.Error().Msg("three")
+
+	entries := recent.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Entries() returned %d entries, want 2", len(entries))
+	}
+	if entries[0].Message != "three" || entries[1].Message != "two" {
+		t.Errorf("Entries() = %q, %q, want three, two", entries[0].Message, entries[1].Message)
+	}
+	if entries[1].Fields != "did=did:plc:bob" {
+		t.Errorf("Fields = %q, want did=did:plc:bob", entries[1].Fields)
+	}
+	if entries[0].Time.IsZero() || entries[0].Level != "error" {
+		t.Errorf("entry = %+v, want time and error level", entries[0])
+	}
+}
+
+func TestFeedCacheNeedsServer(t *testing.T) {
+	store, _ := newStore(t)
+	ops := &Local{Store: store}
+
+	if _, err := ops.WarmFeed(context.Background()); !errors.Is(err, ErrServerNotRunning) {
+		t.Errorf("WarmFeed() error = %v, want ErrServerNotRunning", err)
+	}
+	if _, err := ops.InspectFeed(context.Background()); !errors.Is(err, ErrServerNotRunning) {
+		t.Errorf("InspectFeed() error = %v, want ErrServerNotRunning", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	store, path := newStore(t)
+	store.Close()
+	socket := filepath.Join(t.TempDir(), "missing.sock")
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantOutput string
+	}{
+		{"list sessions for one DID", []string{"sessions", "list", "did:plc:bob"}, 0, "did:plc:bob  b1"},
+		{"stats", []string{"db", "stats"}, 0, "feed_registry              2"},
+		{"unknown user", []string{"feed", "remove", "did:plc:carol"}, 1, "did:plc:carol is not in the feed"},
+		{"feed cache offline", []string{"feed", "inspect"}, 1, "server is not running"},
+		{"missing argument", []string{"sessions", "revoke"}, 2, "Usage:"},
+		{"unknown command", []string{"feed", "purge"}, 2, "Usage:"},
+		{"ban", []string{"moderation", "ban", "did:plc:bob", "posting", "spam"}, 0, "Banned did:plc:bob"},
+		{"list moderation", []string{"moderation", "list"}, 0, "posting spam"},
+		{"unhide unknown", []string{"moderation", "unhide", "at://did:plc:bob/x/y"}, 1, "is not hidden"},
+		{"compact", []string{"db", "compact"}, 0, "Compacted"},
+		{"rekey without a key", []string{"sessions", "rekey"}, 1, "no session key is configured"},
+		{"keygen", []string{"sessions", "keygen"}, 0, "="},
+		{"oauth keygen", []string{"oauth", "keygen"}, 0, "z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ARABICA_CONFIG", "")
+			t.Setenv("ARABICA_ADMIN_SOCKET", socket)
+			args := append([]string{"-db", path}, tt.args...)
+
+			var out bytes.Buffer
+			code := run(context.Background(), args, &out, &out)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d\n%s", code, tt.wantCode, out.String())
+			}
+			if !strings.Contains(out.String(), tt.wantOutput) {
+				t.Errorf("output does not contain %q:\n%s", tt.wantOutput, out.String())
+			}
+		})
+	}
+}
+
+func TestRekeySessions(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	store, path := newStore(t)
+	store.Close()
+
+	// Configuring the first key encrypts the plaintext sessions
+	keys, err := boltstore.NewKeyring(oldKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err = boltstore.Open(boltstore.Options{Path: path, SessionKeys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := store.SessionStore().EncryptSessions(context.Background())
+	store.Close()
+	if err != nil || result.Encrypted != 3 {
+		t.Fatalf("EncryptSessions() = %+v, %v, want 3 encrypted", result, err)
+	}
+
+	t.Setenv("ARABICA_CONFIG", "")
+	t.Setenv("ARABICA_ADMIN_SOCKET", filepath.Join(t.TempDir(), "missing.sock"))
+	t.Setenv("ARABICA_SESSION_KEY", base64.StdEncoding.EncodeToString(newKey))
+	t.Setenv("ARABICA_OLD_SESSION_KEYS", base64.StdEncoding.EncodeToString(oldKey))
+
+	var out bytes.Buffer
+	if code := run(context.Background(), []string{"-db", path, "sessions", "rekey"}, &out, &out); code != 0 ||
+		!strings.Contains(out.String(), "rewrapped 3 entries") {
+		t.Fatalf("rekey exit code = %d, output:\n%s", code, out.String())
+	}
+
+	// Sessions are readable with only the new key afterwards
+	t.Setenv("ARABICA_OLD_SESSION_KEYS", "")
+	out.Reset()
+	if code := run(context.Background(), []string{"-db", path, "sessions", "list", "did:plc:bob"}, &out, &out); code != 0 ||
+		!strings.Contains(out.String(), "did:plc:bob  b1") {
+		t.Errorf("list exit code = %d, output:\n%s", code, out.String())
+	}
+}
@@ -0,0 +1,360 @@
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"arabica/internal/atproto"
+	"arabica/internal/config"
+	"arabica/internal/database/boltstore"
+)
+
+const usage = `Usage: arabica admin [flags] <command>
+
+Commands:
+  feed users              list users in the community feed
+  feed remove DID         remove a user from the community feed
+  feed warm               rebuild the public feed cache (server must be running)
+  feed inspect            show the cached public feed (server must be running)
+  sessions list [DID]     list OAuth sessions, optionally only for DID
+  sessions revoke DID     delete every session for DID, logging it out everywhere
+  sessions rekey          re-encrypt stored sessions with the current session key
+  sessions keygen         print a new random session key
+  oauth keygen            print a new OAuth client signing key
+  moderation list         list banned DIDs and hidden records
+  moderation ban DID [REASON]
+                          keep DID out of the community feed and hide its profile
+  moderation unban DID    lift a ban
+  moderation hide URI [REASON]
+                          hide the record at an AT-URI from the feed and profiles
+  moderation unhide URI   make a hidden record visible again
+  db stats                show database size and bucket key counts
+  db backup FILE          write a consistent copy of the database to FILE (- for stdout)
+  db compact              rewrite the database to reclaim space (server must be stopped)
+
+Commands run through the server's admin socket when it is running and open the
+database directly otherwise. Flags and configuration are the same as for the
+server, so -config or -db select the instance to operate on.
+`
+
+// openTimeout is how long to wait for the database lock when the server isn't
+// answering on its socket
+const openTimeout = time.Second
+
+// Main runs the admin subcommand with the arguments following "admin" and
+// returns the process exit code
+func Main(args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return run(ctx, args, os.Stdout, os.Stderr)
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	cfg, opts, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprint(stderr, usage)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "arabica admin: %v\n", err)
+		return 2
+	}
+
+	cmd := strings.Join(firstN(opts.Args, 2), " ")
+	rest := opts.Args[min(len(opts.Args), 2):]
+
+	if err := dispatch(ctx, cfg, cmd, rest, stdout); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(stderr, usage)
+			return 2
+		}
+		fmt.Fprintf(stderr, "arabica admin: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// errUsage reports an unknown command or wrong number of arguments
+var errUsage = errors.New("usage")
+
+func dispatch(ctx context.Context, cfg *config.Config, cmd string, args []string, out io.Writer) error {
+	// Minimum and maximum number of arguments; a reason may span several
+	nargs := map[string][2]int{
+		"feed users": {0, 0}, "feed remove": {1, 1}, "feed warm": {0, 0}, "feed inspect": {0, 0},
+		"sessions list": {0, 1}, "sessions revoke": {1, 1}, "sessions rekey": {0, 0}, "sessions keygen": {0, 0},
+		"oauth keygen": {0, 0}, "moderation list": {0, 0}, "moderation ban": {1, -1}, "moderation unban": {1, 1},
+		"moderation hide": {1, -1}, "moderation unhide": {1, 1},
+		"db stats": {0, 0}, "db backup": {1, 1}, "db compact": {0, 0},
+	}
+	want, ok := nargs[cmd]
+	if !ok || len(args) < want[0] || (want[1] >= 0 && len(args) > want[1]) {
+		return errUsage
+	}
+
+	// Compacting replaces the file, so it must never race the server
+	if cmd == "db compact" {
+		return compact(cfg, out)
+	}
+	if cmd == "sessions keygen" {
+		return keygen(out)
+	}
+	if cmd == "oauth keygen" {
+		key, err := atproto.GenerateClientKey()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, key)
+		return nil
+	}
+
+	ops, closeOps, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeOps()
+
+	switch cmd {
+	case "feed users":
+		users, err := ops.ListFeedUsers(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "DID\tREGISTERED")
+		for _, u := range users {
+			fmt.Fprintf(tw, "%s\t%s\n", u.DID, formatTime(u.RegisteredAt))
+		}
+		return tw.Flush()
+
+	case "feed remove":
+		if err := ops.RemoveFeedUser(ctx, args[0]); err != nil {
+			return notFound(err, "%s is not in the feed", args[0])
+		}
+		fmt.Fprintf(out, "Removed %s from the feed\n", args[0])
+		return nil
+
+	case "feed warm", "feed inspect":
+		var cache *FeedCache
+		if cmd == "feed warm" {
+			cache, err = ops.WarmFeed(ctx)
+		} else {
+			cache, err = ops.InspectFeed(ctx)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%d items, expires %s\n", len(cache.Items), formatTime(cache.ExpiresAt))
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "AUTHOR\tTYPE\tACTION\tTIME")
+		for _, item := range cache.Items {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", item.Author, item.RecordType, item.Action, formatTime(item.Timestamp))
+		}
+		return tw.Flush()
+
+	case "sessions list":
+		sessions, err := ops.ListSessions(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "DID\tSESSION\tPDS\tSCOPES")
+		for _, s := range sessions {
+			if len(args) == 1 && s.DID != args[0] {
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.DID, s.SessionID, s.HostURL, strings.Join(s.Scopes, " "))
+		}
+		return tw.Flush()
+
+	case "sessions revoke":
+		count, err := ops.RevokeSessions(ctx, args[0])
+		if err != nil {
+			return notFound(err, "no sessions for %s", args[0])
+		}
+		fmt.Fprintf(out, "Revoked %d session(s) for %s\n", count, args[0])
+		return nil
+
+	case "sessions rekey":
+		result, err := ops.RekeySessions(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Encrypted %d and rewrapped %d entries with key %s\n", result.Encrypted, result.Rewrapped, result.KeyID)
+		if result.Unreadable > 0 {
+			return fmt.Errorf("%d entries use a key that isn't configured; keep the old keys or revoke those sessions", result.Unreadable)
+		}
+		return nil
+
+	case "moderation list":
+		moderation, err := ops.ListModeration(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "KIND\tSUBJECT\tREASON\tBY\tSINCE")
+		for _, e := range moderation.Bans {
+			fmt.Fprintf(tw, "ban\t%s\t%s\t%s\t%s\n", e.Subject, e.Reason, e.CreatedBy, formatTime(e.CreatedAt))
+		}
+		for _, e := range moderation.Hidden {
+			fmt.Fprintf(tw, "hidden\t%s\t%s\t%s\t%s\n", e.Subject, e.Reason, e.CreatedBy, formatTime(e.CreatedAt))
+		}
+		return tw.Flush()
+
+	case "moderation ban":
+		if err := ops.BanDID(ctx, args[0], strings.Join(args[1:], " "), "cli"); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Banned %s\n", args[0])
+		return nil
+
+	case "moderation unban":
+		if err := ops.UnbanDID(ctx, args[0]); err != nil {
+			return notFound(err, "%s is not banned", args[0])
+		}
+		fmt.Fprintf(out, "Unbanned %s\n", args[0])
+		return nil
+
+	case "moderation hide":
+		if err := ops.HideRecord(ctx, args[0], strings.Join(args[1:], " "), "cli"); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Hid %s\n", args[0])
+		return nil
+
+	case "moderation unhide":
+		if err := ops.UnhideRecord(ctx, args[0]); err != nil {
+			return notFound(err, "%s is not hidden", args[0])
+		}
+		fmt.Fprintf(out, "Unhid %s\n", args[0])
+		return nil
+
+	case "db stats":
+		stats, err := ops.DBStats(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Path:       %s\nSize:       %d bytes\nFree pages: %d\nOpen reads: %d\n\n",
+			stats.Path, stats.SizeBytes, stats.FreePages, stats.OpenTxN)
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "BUCKET\tKEYS")
+		for _, b := range stats.Buckets {
+			fmt.Fprintf(tw, "%s\t%d\n", b.Name, b.Keys)
+		}
+		return tw.Flush()
+
+	case "db backup":
+		return backup(ctx, ops, args[0], out)
+	}
+	return errUsage
+}
+
+// connect prefers the running server's socket and falls back to opening the
+// database, which only succeeds when no server holds its lock
+func connect(cfg *config.Config) (Operations, func(), error) {
+	if client, err := Dial(cfg.Admin.Socket); err == nil {
+		return client, func() {}, nil
+	}
+
+	// Opening creates a missing file, which would hide a wrong -db path
+	if _, err := os.Stat(cfg.Database.Path); err != nil {
+		return nil, nil, fmt.Errorf("server not running and no database at %s", cfg.Database.Path)
+	}
+	current, old, err := cfg.Database.SessionKeys()
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := boltstore.NewKeyring(current, old)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := boltstore.Open(boltstore.Options{Path: cfg.Database.Path, Timeout: openTimeout, SessionKeys: keys})
+	if err != nil {
+		return nil, nil, fmt.Errorf("server not answering on %s and the database is locked: %w", cfg.Admin.Socket, err)
+	}
+	return &Local{Store: store}, func() { store.Close() }, nil
+}
+
+// backup writes the snapshot to a temporary file first so an interrupted
+// backup never leaves a truncated file under the requested name
+func backup(ctx context.Context, ops Operations, path string, out io.Writer) error {
+	if path == "-" {
+		_, err := ops.Backup(ctx, out)
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := ops.Backup(ctx, tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Wrote %d bytes to %s\n", n, path)
+	return nil
+}
+
+func compact(cfg *config.Config, out io.Writer) error {
+	if _, err := Dial(cfg.Admin.Socket); err == nil {
+		return errors.New("stop the server before compacting the database")
+	}
+
+	before, after, err := boltstore.Compact(cfg.Database.Path, openTimeout)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Compacted %s: %d -> %d bytes\n", cfg.Database.Path, before, after)
+	return nil
+}
+
+// keygen prints a random session key in the format the configuration expects
+func keygen(out io.Writer) error {
+	key := make([]byte, boltstore.SessionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, base64.StdEncoding.EncodeToString(key))
+	return nil
+}
+
+// notFound replaces ErrNotFound with a message naming what was missing
+func notFound(err error, format string, args ...any) error {
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf(format, args...)
+	}
+	return err
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+func firstN(s []string, n int) []string {
+	return s[:min(len(s), n)]
+}
@@ -0,0 +1,213 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"arabica/internal/database/boltstore"
+)
+
+// Client runs operations on a server through its admin socket
+type Client struct {
+	http *http.Client
+}
+
+// Dial connects to the admin socket at path. It returns ErrServerNotRunning
+// when nothing is listening there.
+func Dial(path string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrServerNotRunning, err)
+	}
+	conn.Close()
+
+	dialer := &net.Dialer{}
+	return &Client{http: &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", path)
+			},
+		},
+	}}, nil
+}
+
+// do sends a request to the server and returns the response if it succeeded
+func (c *Client) do(ctx context.Context, method, path string) (*http.Response, error) {
+	// The host is ignored; every request goes to the socket
+	req, err := http.NewRequestWithContext(ctx, method, "http://arabica"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(req)
+}
+
+// send performs req and returns the response if it succeeded
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var body errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+		body.Error = resp.Status
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	return nil, errors.New(body.Error)
+}
+
+// post sends a JSON body and decodes the JSON response into result
+func (c *Client) post(ctx context.Context, path string, body, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://arabica"+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// call sends a request and decodes the JSON response into result
+func (c *Client) call(ctx context.Context, method, path string, result any) error {
+	resp, err := c.do(ctx, method, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// ListFeedUsers returns registered feed users, oldest first
+func (c *Client) ListFeedUsers(ctx context.Context) ([]boltstore.FeedUser, error) {
+	var users []boltstore.FeedUser
+	err := c.call(ctx, http.MethodGet, "/feed/users", &users)
+	return users, err
+}
+
+// RemoveFeedUser drops a DID from the community feed
+func (c *Client) RemoveFeedUser(ctx context.Context, did string) error {
+	return c.call(ctx, http.MethodDelete, "/feed/users/"+url.PathEscape(did), &struct{}{})
+}
+
+// ListSessions returns stored OAuth sessions sorted by DID
+func (c *Client) ListSessions(ctx context.Context) ([]SessionInfo, error) {
+	var sessions []SessionInfo
+	err := c.call(ctx, http.MethodGet, "/sessions", &sessions)
+	return sessions, err
+}
+
+// RevokeSessions deletes every session for did and returns how many were removed
+func (c *Client) RevokeSessions(ctx context.Context, did string) (int, error) {
+	var result revokeResult
+	err := c.call(ctx, http.MethodDelete, "/sessions/"+url.PathEscape(did), &result)
+	return result.Revoked, err
+}
+
+// RekeySessions encrypts every stored session with the server's current
+// session key
+func (c *Client) RekeySessions(ctx context.Context) (*boltstore.EncryptResult, error) {
+	var result boltstore.EncryptResult
+	if err := c.call(ctx, http.MethodPost, "/sessions/rekey", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DBStats reports the file size and key count of each bucket
+func (c *Client) DBStats(ctx context.Context) (*DBStats, error) {
+	var stats DBStats
+	if err := c.call(ctx, http.MethodGet, "/db/stats", &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Backup streams a consistent copy of the database to w
+func (c *Client) Backup(ctx context.Context, w io.Writer) (int64, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/db/backup")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, err
+	}
+	// Trailers are only populated once the body has been read to the end
+	want, err := strconv.ParseInt(resp.Trailer.Get(backupLengthTrailer), 10, 64)
+	if err != nil || want != n {
+		return n, fmt.Errorf("backup was cut short after %d bytes", n)
+	}
+	return n, nil
+}
+
+// WarmFeed rebuilds the public feed cache now
+func (c *Client) WarmFeed(ctx context.Context) (*FeedCache, error) {
+	var cache FeedCache
+	if err := c.call(ctx, http.MethodPost, "/feed/warm", &cache); err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// InspectFeed returns the cached public feed without refreshing it
+func (c *Client) InspectFeed(ctx context.Context) (*FeedCache, error) {
+	var cache FeedCache
+	if err := c.call(ctx, http.MethodGet, "/feed/cache", &cache); err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// ListModeration returns the current bans and hidden records
+func (c *Client) ListModeration(ctx context.Context) (*Moderation, error) {
+	var moderation Moderation
+	if err := c.call(ctx, http.MethodGet, "/moderation", &moderation); err != nil {
+		return nil, err
+	}
+	return &moderation, nil
+}
+
+// BanDID keeps a DID out of the community feed and hides its profile
+func (c *Client) BanDID(ctx context.Context, did, reason, by string) error {
+	return c.post(ctx, "/moderation/bans", moderationRequest{Subject: did, Reason: reason, By: by}, &struct{}{})
+}
+
+// UnbanDID lifts a ban
+func (c *Client) UnbanDID(ctx context.Context, did string) error {
+	return c.call(ctx, http.MethodDelete, "/moderation/bans/"+url.PathEscape(did), &struct{}{})
+}
+
+// HideRecord keeps the record at uri out of the feed and profile pages
+func (c *Client) HideRecord(ctx context.Context, uri, reason, by string) error {
+	return c.post(ctx, "/moderation/hidden", moderationRequest{Subject: uri, Reason: reason, By: by}, &struct{}{})
+}
+
+// UnhideRecord makes a hidden record visible again
+func (c *Client) UnhideRecord(ctx context.Context, uri string) error {
+	return c.call(ctx, http.MethodDelete, "/moderation/hidden/"+url.PathEscape(uri), &struct{}{})
+}
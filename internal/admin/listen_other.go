@@ -0,0 +1,10 @@
+//go:build !unix
+
+package admin
+
+import "net"
+
+// listenUnix creates the socket; Listen restricts its permissions afterwards
+func listenUnix(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package admin
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenUnix creates the socket without group or other permissions, so no
+// other user can connect in the moment before Listen's chmod. The umask is
+// process-wide; Listen runs during startup, before the server creates files
+// from other goroutines.
+func listenUnix(path string) (net.Listener, error) {
+	old := syscall.Umask(0o177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,108 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// LogEntry is an error logged by the server
+type LogEntry struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+	Error   string    `json:"error,omitempty"`
+	// Fields holds the remaining structured fields as key=value pairs
+	Fields string `json:"fields,omitempty"`
+}
+
+// RecentErrors keeps the last few error-level log entries for the dashboard.
+// It is a zerolog.LevelWriter meant to sit next to the normal log output in a
+// zerolog.MultiLevelWriter, and only ever holds entries in memory.
+type RecentErrors struct {
+	mu      sync.Mutex
+	entries []LogEntry
+	next    int
+	full    bool
+}
+
+// NewRecentErrors creates a buffer holding the last size errors
+func NewRecentErrors(size int) *RecentErrors {
+	return &RecentErrors{entries: make([]LogEntry, size)}
+}
+
+// Write ignores entries without a level
+func (r *RecentErrors) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// WriteLevel records entries at error level and above
+func (r *RecentErrors) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < zerolog.ErrorLevel || level == zerolog.NoLevel || len(r.entries) == 0 {
+		return len(p), nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(p, &fields); err != nil {
+		// Never fail the log call over the dashboard's copy
+		return len(p), nil
+	}
+
+	entry := LogEntry{Level: level.String()}
+	if ts, ok := fields[zerolog.TimestampFieldName].(string); ok {
+		entry.Time, _ = time.Parse(zerolog.TimeFieldFormat, ts)
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Message, _ = fields[zerolog.MessageFieldName].(string)
+	entry.Error, _ = fields[zerolog.ErrorFieldName].(string)
+	for _, key := range []string{zerolog.TimestampFieldName, zerolog.LevelFieldName, zerolog.MessageFieldName, zerolog.ErrorFieldName} {
+		delete(fields, key)
+	}
+	entry.Fields = formatFields(fields)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+	return len(p), nil
+}
+
+// Entries returns the recorded errors, most recent first
+func (r *RecentErrors) Entries() []LogEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.entries)
+	}
+	entries := make([]LogEntry, 0, count)
+	for i := 1; i <= count; i++ {
+		entries = append(entries, r.entries[(r.next-i+len(r.entries))%len(r.entries)])
+	}
+	return entries
+}
+
+func formatFields(fields map[string]any) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, fields[k]))
+	}
+	return strings.Join(pairs, " ")
+}
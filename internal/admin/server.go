@@ -0,0 +1,153 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Handler serves ops over HTTP for the admin socket. It has no
+// authentication; access is controlled by the socket's file permissions.
+func Handler(ops Operations) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /feed/users", func(w http.ResponseWriter, r *http.Request) {
+		users, err := ops.ListFeedUsers(r.Context())
+		writeResult(w, r, users, err)
+	})
+	mux.HandleFunc("DELETE /feed/users/{did}", func(w http.ResponseWriter, r *http.Request) {
+		err := ops.RemoveFeedUser(r.Context(), r.PathValue("did"))
+		writeResult(w, r, struct{}{}, err)
+	})
+	mux.HandleFunc("POST /feed/warm", func(w http.ResponseWriter, r *http.Request) {
+		cache, err := ops.WarmFeed(r.Context())
+		writeResult(w, r, cache, err)
+	})
+	mux.HandleFunc("GET /feed/cache", func(w http.ResponseWriter, r *http.Request) {
+		cache, err := ops.InspectFeed(r.Context())
+		writeResult(w, r, cache, err)
+	})
+	mux.HandleFunc("GET /sessions", func(w http.ResponseWriter, r *http.Request) {
+		sessions, err := ops.ListSessions(r.Context())
+		writeResult(w, r, sessions, err)
+	})
+	mux.HandleFunc("DELETE /sessions/{did}", func(w http.ResponseWriter, r *http.Request) {
+		count, err := ops.RevokeSessions(r.Context(), r.PathValue("did"))
+		writeResult(w, r, revokeResult{Revoked: count}, err)
+	})
+	mux.HandleFunc("POST /sessions/rekey", func(w http.ResponseWriter, r *http.Request) {
+		result, err := ops.RekeySessions(r.Context())
+		writeResult(w, r, result, err)
+	})
+	mux.HandleFunc("GET /db/stats", func(w http.ResponseWriter, r *http.Request) {
+		stats, err := ops.DBStats(r.Context())
+		writeResult(w, r, stats, err)
+	})
+	mux.HandleFunc("GET /moderation", func(w http.ResponseWriter, r *http.Request) {
+		moderation, err := ops.ListModeration(r.Context())
+		writeResult(w, r, moderation, err)
+	})
+	mux.HandleFunc("POST /moderation/bans", func(w http.ResponseWriter, r *http.Request) {
+		var req moderationRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err == nil {
+			err = ops.BanDID(r.Context(), req.Subject, req.Reason, req.By)
+		}
+		writeResult(w, r, struct{}{}, err)
+	})
+	mux.HandleFunc("DELETE /moderation/bans/{did}", func(w http.ResponseWriter, r *http.Request) {
+		err := ops.UnbanDID(r.Context(), r.PathValue("did"))
+		writeResult(w, r, struct{}{}, err)
+	})
+	mux.HandleFunc("POST /moderation/hidden", func(w http.ResponseWriter, r *http.Request) {
+		var req moderationRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err == nil {
+			err = ops.HideRecord(r.Context(), req.Subject, req.Reason, req.By)
+		}
+		writeResult(w, r, struct{}{}, err)
+	})
+	mux.HandleFunc("DELETE /moderation/hidden/{uri...}", func(w http.ResponseWriter, r *http.Request) {
+		err := ops.UnhideRecord(r.Context(), r.PathValue("uri"))
+		writeResult(w, r, struct{}{}, err)
+	})
+	mux.HandleFunc("GET /db/backup", func(w http.ResponseWriter, r *http.Request) {
+		// Errors after the first write can only be reported by cutting the
+		// stream short; the client checks the length against the trailer.
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Trailer", backupLengthTrailer)
+		n, err := ops.Backup(r.Context(), w)
+		if err != nil {
+			log.Error().Err(err).Msg("admin: backup failed")
+			return
+		}
+		w.Header().Set(backupLengthTrailer, fmt.Sprint(n))
+	})
+
+	return mux
+}
+
+// backupLengthTrailer carries the backup size so the client can detect a
+// truncated stream
+const backupLengthTrailer = "X-Backup-Length"
+
+// revokeResult is the body returned after revoking sessions
+type revokeResult struct {
+	Revoked int `json:"revoked"`
+}
+
+// moderationRequest is the body for banning a DID or hiding a record
+type moderationRequest struct {
+	Subject string `json:"subject"`
+	Reason  string `json:"reason,omitempty"`
+	By      string `json:"by,omitempty"`
+}
+
+// errorResponse is the body returned for failed commands
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeResult(w http.ResponseWriter, r *http.Request, result any, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrNotFound) {
+			code = http.StatusNotFound
+		}
+		log.Warn().Err(err).Str("method", r.Method).Str("path", r.URL.Path).Msg("admin: command failed")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// Listen opens the admin socket at path, readable and writable only by the
+// server's user. A socket left behind by a crashed server is replaced, but a
+// live one is not, so two servers can't share a data directory unnoticed.
+func Listen(path string) (net.Listener, error) {
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("admin socket %s is in use by another process", path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("removing stale admin socket: %w", err)
+	}
+
+	ln, err := listenUnix(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
@@ -0,0 +1,65 @@
+package atproto
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// MaxAccounts is how many accounts can be signed in at once in one browser
+const MaxAccounts = 5
+
+// AccountsCookie holds the accounts signed in besides the active one, whose
+// session is in the account_did and session_id cookies
+const AccountsCookie = "accounts"
+
+// Account is a signed-in account the user can switch to
+type Account struct {
+	DID       syntax.DID `json:"did"`
+	SessionID string     `json:"sid"`
+	// Handle is only for display, and may be out of date
+	Handle string `json:"handle,omitempty"`
+}
+
+// EncodeAccounts returns the accounts cookie value for accounts
+func EncodeAccounts(accounts []Account) string {
+	if len(accounts) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(accounts)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeAccounts reads an accounts cookie value. Malformed values give no
+// accounts, and at most MaxAccounts-1 are returned.
+func DecodeAccounts(value string) []Account {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil
+	}
+	var decoded []Account
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil
+	}
+
+	var accounts []Account
+	for _, account := range decoded {
+		if _, err := syntax.ParseDID(account.DID.String()); err != nil || account.SessionID == "" {
+			continue
+		}
+		if len(accounts) == MaxAccounts-1 {
+			break
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts
+}
+
+// GetAccountsFromContext returns the accounts the user can switch to, as listed
+// in the accounts cookie. Their sessions may have been revoked since.
+func GetAccountsFromContext(ctx context.Context) []Account {
+	accounts, _ := ctx.Value(contextKeyAccounts).([]Account)
+	return accounts
+}
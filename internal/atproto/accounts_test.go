@@ -0,0 +1,48 @@
+package atproto
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+func TestAccountsCookie(t *testing.T) {
+	accounts := []Account{
+		{DID: "did:plc:alice", SessionID: "session-a", Handle: "alice.example.com"},
+		{DID: "did:web:cafe.example.com", SessionID: "session-b"},
+	}
+	if got := DecodeAccounts(EncodeAccounts(accounts)); !slices.Equal(got, accounts) {
+		t.Errorf("DecodeAccounts(EncodeAccounts()) = %v, want %v", got, accounts)
+	}
+	if EncodeAccounts(nil) != "" {
+		t.Error("EncodeAccounts(nil) should be empty")
+	}
+
+	var many []Account
+	for i := range MaxAccounts + 2 {
+		many = append(many, Account{DID: syntax.DID(fmt.Sprintf("did:plc:user%d", i)), SessionID: "session"})
+	}
+	if got := DecodeAccounts(EncodeAccounts(many)); len(got) != MaxAccounts-1 {
+		t.Errorf("decoded %d accounts, want at most %d", len(got), MaxAccounts-1)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"empty", ""},
+		{"not base64", "not base64!"},
+		{"not json", "bm90IGpzb24"},
+		{"invalid did", EncodeAccounts([]Account{{DID: "alice", SessionID: "session"}})},
+		{"no session", EncodeAccounts([]Account{{DID: "did:plc:alice"}})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DecodeAccounts(tt.value); len(got) != 0 {
+				t.Errorf("DecodeAccounts() = %v, want none", got)
+			}
+		})
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"arabica/internal/models"
 )
 
-// CacheTTL is how long cached data remains valid
+// CacheTTL is the default for how long cached data remains valid
 // Set to 2 minutes to balance multi-device sync with PDS request load
 const CacheTTL = 2 * time.Minute
 
@@ -19,15 +19,22 @@ type UserCache struct {
 	Grinders  []*models.Grinder
 	Brewers   []*models.Brewer
 	Brews     []*models.Brew
+	Settings  *models.Settings
+	Graph     *models.Graph
 	Timestamp time.Time
 }
 
-// IsValid returns true if the cache is still valid
+// IsValid returns true if the cache is still valid under the default CacheTTL
 func (c *UserCache) IsValid() bool {
+	return c.IsValidFor(CacheTTL)
+}
+
+// IsValidFor returns true if the cache is younger than ttl
+func (c *UserCache) IsValidFor(ttl time.Duration) bool {
 	if c == nil {
 		return false
 	}
-	return time.Since(c.Timestamp) < CacheTTL
+	return time.Since(c.Timestamp) < ttl
 }
 
 // clone creates a shallow copy of the UserCache for safe modification
@@ -41,6 +48,8 @@ func (c *UserCache) clone() *UserCache {
 		Grinders:  c.Grinders,
 		Brewers:   c.Brewers,
 		Brews:     c.Brews,
+		Settings:  c.Settings,
+		Graph:     c.Graph,
 		Timestamp: c.Timestamp,
 	}
 }
@@ -51,16 +60,28 @@ func (c *UserCache) clone() *UserCache {
 type SessionCache struct {
 	mu     sync.RWMutex
 	caches map[string]*UserCache // keyed by session ID
+	ttl    time.Duration
 }
 
-// NewSessionCache creates a new session cache instance.
+// NewSessionCache creates a new session cache instance using the default CacheTTL.
 // Prefer this over global state for better testability and dependency injection.
 func NewSessionCache() *SessionCache {
+	return NewSessionCacheWithTTL(CacheTTL)
+}
+
+// NewSessionCacheWithTTL creates a session cache whose entries stay valid for ttl
+func NewSessionCacheWithTTL(ttl time.Duration) *SessionCache {
 	return &SessionCache{
 		caches: make(map[string]*UserCache),
+		ttl:    ttl,
 	}
 }
 
+// TTL returns how long entries in this cache remain valid
+func (sc *SessionCache) TTL() time.Duration {
+	return sc.ttl
+}
+
 // Get retrieves a user's cache by session ID.
 // The returned UserCache is safe to read without holding a lock.
 func (sc *SessionCache) Get(sessionID string) *UserCache {
@@ -133,6 +154,26 @@ func (sc *SessionCache) SetBrews(sessionID string, brews []*models.Brew) {
 	sc.caches[sessionID] = newCache
 }
 
+// SetSettings updates just the settings in the cache using copy-on-write
+func (sc *SessionCache) SetSettings(sessionID string, settings *models.Settings) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	newCache := sc.caches[sessionID].clone()
+	newCache.Settings = settings
+	newCache.Timestamp = time.Now()
+	sc.caches[sessionID] = newCache
+}
+
+// SetGraph updates just the mute and block lists in the cache using copy-on-write
+func (sc *SessionCache) SetGraph(sessionID string, graph *models.Graph) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	newCache := sc.caches[sessionID].clone()
+	newCache.Graph = graph
+	newCache.Timestamp = time.Now()
+	sc.caches[sessionID] = newCache
+}
+
 // InvalidateBeans marks that beans need to be refreshed using copy-on-write
 func (sc *SessionCache) InvalidateBeans(sessionID string) {
 	sc.mu.Lock()
@@ -198,31 +239,36 @@ func (sc *SessionCache) Cleanup() {
 
 	now := time.Now()
 	for sessionID, cache := range sc.caches {
-		if now.Sub(cache.Timestamp) > CacheTTL*2 {
+		if now.Sub(cache.Timestamp) > sc.ttl*2 {
 			delete(sc.caches, sessionID)
 		}
 	}
 }
 
 // StartCleanupRoutine starts a background goroutine that periodically cleans up
-// expired cache entries. Returns a stop function to gracefully shut down.
+// expired cache entries. Returns a stop function to gracefully shut down, which
+// blocks until the goroutine has exited so no cleanup runs after it returns.
 func (sc *SessionCache) StartCleanupRoutine(interval time.Duration) (stop func()) {
 	ticker := time.NewTicker(interval)
 	done := make(chan struct{})
+	stopped := make(chan struct{})
 
 	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
 				sc.Cleanup()
 			case <-done:
-				ticker.Stop()
 				return
 			}
 		}
 	}()
 
+	var once sync.Once
 	return func() {
-		close(done)
+		once.Do(func() { close(done) })
+		<-stopped
 	}
 }
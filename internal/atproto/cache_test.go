@@ -138,6 +138,23 @@ func TestNewSessionCache(t *testing.T) {
 	assert.Empty(t, cache.caches)
 }
 
+func TestNewSessionCacheWithTTL(t *testing.T) {
+	cache := NewSessionCacheWithTTL(10 * time.Second)
+	assert.Equal(t, 10*time.Second, cache.TTL())
+	assert.Equal(t, CacheTTL, NewSessionCache().TTL())
+
+	entry := &UserCache{Timestamp: time.Now().Add(-30 * time.Second)}
+	assert.True(t, entry.IsValid(), "within the default TTL")
+	assert.False(t, entry.IsValidFor(cache.TTL()), "older than the configured TTL")
+
+	// Cleanup drops entries older than twice the configured TTL
+	cache.Set("old", entry)
+	cache.Set("fresh", &UserCache{Timestamp: time.Now()})
+	cache.Cleanup()
+	assert.Nil(t, cache.Get("old"))
+	assert.NotNil(t, cache.Get("fresh"))
+}
+
 func TestSessionCache_GetSetInvalidate(t *testing.T) {
 	cache := NewSessionCache()
 	sessionID := "session123"
@@ -251,6 +268,16 @@ func TestSessionCache_SetCollections(t *testing.T) {
 		assert.Len(t, result.Brews, 1)
 		assert.Equal(t, "brew2", result.Brews[0].RKey)
 	})
+
+	t.Run("SetSettings updates only settings", func(t *testing.T) {
+		cache.SetSettings(sessionID, &models.Settings{PrivateMode: true})
+		result := cache.Get(sessionID)
+		require.NotNil(t, result)
+
+		require.NotNil(t, result.Settings)
+		assert.True(t, result.Settings.PrivateMode)
+		assert.Len(t, result.Brews, 1)
+	})
 }
 
 func TestSessionCache_InvalidateCollections(t *testing.T) {
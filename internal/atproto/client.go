@@ -2,12 +2,18 @@ package atproto
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"arabica/internal/metrics"
+	"arabica/internal/tracing"
+
 	"github.com/bluesky-social/indigo/atproto/atclient"
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Client wraps the atproto API client for making authenticated requests to a PDS
@@ -15,6 +21,16 @@ type Client struct {
 	oauth *OAuthManager
 }
 
+// IsRecordNotFound reports whether err means the requested record does not exist,
+// from either an authenticated or a public request
+func IsRecordNotFound(err error) bool {
+	if errors.Is(err, ErrRecordNotFound) {
+		return true
+	}
+	var apiErr *atclient.APIError
+	return errors.As(err, &apiErr) && apiErr.Name == "RecordNotFound"
+}
+
 // NewClient creates a new atproto client
 func NewClient(oauth *OAuthManager) *Client {
 	return &Client{
@@ -26,7 +42,9 @@ func NewClient(oauth *OAuthManager) *Client {
 // This properly handles DPOP token signing and refresh
 func (c *Client) getAuthenticatedAPIClient(ctx context.Context, did syntax.DID, sessionID string) (*atclient.APIClient, error) {
 	// Resume the OAuth session - this returns a ClientSession that handles DPOP
-	session, err := c.oauth.app.ResumeSession(ctx, did, sessionID)
+	ctx, span := tracing.Start(ctx, "oauth.ResumeSession")
+	session, err := c.oauth.resumeSession(ctx, did, sessionID)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resume session: %w", err)
 	}
@@ -38,6 +56,18 @@ func (c *Client) getAuthenticatedAPIClient(ctx context.Context, did syntax.DID,
 	return apiClient, nil
 }
 
+// startXRPCSpan starts a client span for an XRPC call against the user's PDS
+func startXRPCSpan(ctx context.Context, method string, did syntax.DID, collection string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "xrpc "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("rpc.method", method),
+			attribute.String("atproto.did", did.String()),
+			attribute.String("atproto.collection", collection),
+		),
+	)
+}
+
 // CreateRecordInput contains parameters for creating a record
 type CreateRecordInput struct {
 	Collection string
@@ -54,6 +84,8 @@ type CreateRecordOutput struct {
 // CreateRecord creates a new record in the user's repository
 func (c *Client) CreateRecord(ctx context.Context, did syntax.DID, sessionID string, input *CreateRecordInput) (*CreateRecordOutput, error) {
 	start := time.Now()
+	ctx, span := startXRPCSpan(ctx, "com.atproto.repo.createRecord", did, input.Collection)
+	defer span.End()
 
 	apiClient, err := c.getAuthenticatedAPIClient(ctx, did, sessionID)
 	if err != nil {
@@ -78,6 +110,8 @@ func (c *Client) CreateRecord(ctx context.Context, did syntax.DID, sessionID str
 	}
 
 	err = apiClient.Post(ctx, "com.atproto.repo.createRecord", body, &result)
+	tracing.RecordError(span, err)
+	metrics.ObservePDSRequest("createRecord", input.Collection, start, err != nil)
 
 	duration := time.Since(start)
 
@@ -123,6 +157,8 @@ type GetRecordOutput struct {
 // GetRecord retrieves a single record by its rkey
 func (c *Client) GetRecord(ctx context.Context, did syntax.DID, sessionID string, input *GetRecordInput) (*GetRecordOutput, error) {
 	start := time.Now()
+	ctx, span := startXRPCSpan(ctx, "com.atproto.repo.getRecord", did, input.Collection)
+	defer span.End()
 
 	apiClient, err := c.getAuthenticatedAPIClient(ctx, did, sessionID)
 	if err != nil {
@@ -144,6 +180,11 @@ func (c *Client) GetRecord(ctx context.Context, did syntax.DID, sessionID string
 	}
 
 	err = apiClient.Get(ctx, "com.atproto.repo.getRecord", params, &result)
+	failed := err != nil && !IsRecordNotFound(err) // A missing record is an answer, not a failure
+	if failed {
+		tracing.RecordError(span, err)
+	}
+	metrics.ObservePDSRequest("getRecord", input.Collection, start, failed)
 
 	duration := time.Since(start)
 
@@ -199,6 +240,8 @@ type Record struct {
 // ListRecords retrieves a list of records from a collection
 func (c *Client) ListRecords(ctx context.Context, did syntax.DID, sessionID string, input *ListRecordsInput) (*ListRecordsOutput, error) {
 	start := time.Now()
+	ctx, span := startXRPCSpan(ctx, "com.atproto.repo.listRecords", did, input.Collection)
+	defer span.End()
 
 	apiClient, err := c.getAuthenticatedAPIClient(ctx, did, sessionID)
 	if err != nil {
@@ -229,6 +272,8 @@ func (c *Client) ListRecords(ctx context.Context, did syntax.DID, sessionID stri
 	}
 
 	err = apiClient.Get(ctx, "com.atproto.repo.listRecords", params, &result)
+	tracing.RecordError(span, err)
+	metrics.ObservePDSRequest("listRecords", input.Collection, start, err != nil)
 
 	duration := time.Since(start)
 	recordCount := len(result.Records)
@@ -277,8 +322,13 @@ func (c *Client) ListRecords(ctx context.Context, did syntax.DID, sessionID stri
 
 // ListAllRecords retrieves all records from a collection, handling pagination automatically
 // This is useful when you need to fetch the complete collection without worrying about pagination
-func (c *Client) ListAllRecords(ctx context.Context, did syntax.DID, sessionID string, collection string) (*ListRecordsOutput, error) {
+func (c *Client) ListAllRecords(ctx context.Context, did syntax.DID, sessionID string, collection string) (_ *ListRecordsOutput, err error) {
 	start := time.Now()
+	ctx, span := tracing.Start(ctx, "Client.ListAllRecords", trace.WithAttributes(
+		attribute.String("atproto.did", did.String()),
+		attribute.String("atproto.collection", collection),
+	))
+	defer func() { tracing.End(span, err) }()
 	var allRecords []Record
 	var cursor *string
 	pageCount := 0
@@ -343,6 +393,8 @@ type PutRecordInput struct {
 // PutRecord updates an existing record in the user's repository
 func (c *Client) PutRecord(ctx context.Context, did syntax.DID, sessionID string, input *PutRecordInput) error {
 	start := time.Now()
+	ctx, span := startXRPCSpan(ctx, "com.atproto.repo.putRecord", did, input.Collection)
+	defer span.End()
 
 	apiClient, err := c.getAuthenticatedAPIClient(ctx, did, sessionID)
 	if err != nil {
@@ -364,6 +416,8 @@ func (c *Client) PutRecord(ctx context.Context, did syntax.DID, sessionID string
 	}
 
 	err = apiClient.Post(ctx, "com.atproto.repo.putRecord", body, &result)
+	tracing.RecordError(span, err)
+	metrics.ObservePDSRequest("putRecord", input.Collection, start, err != nil)
 
 	duration := time.Since(start)
 
@@ -401,6 +455,8 @@ type DeleteRecordInput struct {
 // DeleteRecord deletes a record from the user's repository
 func (c *Client) DeleteRecord(ctx context.Context, did syntax.DID, sessionID string, input *DeleteRecordInput) error {
 	start := time.Now()
+	ctx, span := startXRPCSpan(ctx, "com.atproto.repo.deleteRecord", did, input.Collection)
+	defer span.End()
 
 	apiClient, err := c.getAuthenticatedAPIClient(ctx, did, sessionID)
 	if err != nil {
@@ -418,6 +474,8 @@ func (c *Client) DeleteRecord(ctx context.Context, did syntax.DID, sessionID str
 	var result struct{}
 
 	err = apiClient.Post(ctx, "com.atproto.repo.deleteRecord", body, &result)
+	tracing.RecordError(span, err)
+	metrics.ObservePDSRequest("deleteRecord", input.Collection, start, err != nil)
 
 	duration := time.Since(start)
 
@@ -0,0 +1,136 @@
+package atproto
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/atcrypto"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// JWKSPath is where the public keys of a confidential client are served
+const JWKSPath = "/oauth/jwks.json"
+
+// ClientKey is a key a confidential OAuth client signs client assertions
+// with. Only ES256 (P-256) keys are allowed by atproto OAuth.
+type ClientKey struct {
+	// ID is derived from the public key, so it stays the same across restarts
+	ID  string
+	key *atcrypto.PrivateKeyP256
+}
+
+// ClientKeyStore remembers which client key each session was created with.
+// Auth servers bind sessions to that key, so refreshes must keep using it
+// after the current key is rotated.
+type ClientKeyStore interface {
+	SetSessionClientKey(ctx context.Context, did syntax.DID, sessionID, keyID string) error
+	GetSessionClientKey(ctx context.Context, did syntax.DID, sessionID string) (string, error)
+}
+
+// ParseClientKey reads a P-256 private key in multibase form, as printed by
+// GenerateClientKey, or as a PEM "EC PRIVATE KEY" or "PRIVATE KEY" block
+func ParseClientKey(data []byte) (*ClientKey, error) {
+	text := strings.TrimSpace(string(data))
+
+	var key *atcrypto.PrivateKeyP256
+	if block, _ := pem.Decode([]byte(text)); block != nil {
+		k, err := parsePEMKey(block)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	} else {
+		k, err := atcrypto.ParsePrivateMultibase(text)
+		if err != nil {
+			return nil, fmt.Errorf("parsing client key: %w", err)
+		}
+		p256, ok := k.(*atcrypto.PrivateKeyP256)
+		if !ok {
+			return nil, errors.New("client key must be a P-256 (ES256) key")
+		}
+		key = p256
+	}
+
+	pub, err := key.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(pub.Bytes())
+	return &ClientKey{ID: hex.EncodeToString(sum[:8]), key: key}, nil
+}
+
+func parsePEMKey(block *pem.Block) (*atcrypto.PrivateKeyP256, error) {
+	var ecKey *ecdsa.PrivateKey
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		k, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing client key: %w", err)
+		}
+		ecKey = k
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing client key: %w", err)
+		}
+		var ok bool
+		if ecKey, ok = k.(*ecdsa.PrivateKey); !ok {
+			return nil, errors.New("client key must be a P-256 (ES256) key")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in client key", block.Type)
+	}
+	if ecKey.Curve.Params().Name != "P-256" {
+		return nil, errors.New("client key must be a P-256 (ES256) key")
+	}
+
+	d := make([]byte, 32)
+	ecKey.D.FillBytes(d)
+	return atcrypto.ParsePrivateBytesP256(d)
+}
+
+// LoadClientKey reads a client key from a file
+func LoadClientKey(path string) (*ClientKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading client key: %w", err)
+	}
+	key, err := ParseClientKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// GenerateClientKey returns a new P-256 private key in multibase form
+func GenerateClientKey() (string, error) {
+	key, err := atcrypto.GeneratePrivateKeyP256()
+	if err != nil {
+		return "", err
+	}
+	return key.Multibase(), nil
+}
+
+// jwk returns the public half of the key for the client's JWKS
+func (k *ClientKey) jwk() (*atcrypto.JWK, error) {
+	pub, err := k.key.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	jwk, err := pub.JWK()
+	if err != nil {
+		return nil, err
+	}
+	id := k.ID
+	jwk.KeyID = &id
+	jwk.Use = "sig"
+	return jwk, nil
+}
@@ -0,0 +1,193 @@
+package atproto
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/atcrypto"
+	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+func TestParseClientKey(t *testing.T) {
+	multibase, err := GenerateClientKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec1, _ := x509.MarshalECPrivateKey(ecKey)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	p384DER, _ := x509.MarshalECPrivateKey(p384)
+	k256, _ := atcrypto.GeneratePrivateKeyK256()
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"multibase", multibase + "\n", false},
+		{"PEM EC", string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1})), false},
+		{"PEM PKCS8", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})), false},
+		{"P-384", string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: p384DER})), true},
+		{"K-256", k256.Multibase(), true},
+		{"garbage", "not a key", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParseClientKey([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseClientKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(key.ID) != 16 {
+				t.Errorf("key ID = %q, want 16 hex characters", key.ID)
+			}
+		})
+	}
+
+	// The same key gets the same ID in either encoding
+	fromSEC1, _ := ParseClientKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}))
+	fromPKCS8, _ := ParseClientKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}))
+	if fromSEC1.ID != fromPKCS8.ID {
+		t.Errorf("key IDs differ: %s and %s", fromSEC1.ID, fromPKCS8.ID)
+	}
+}
+
+// keyMemStore adds client key tracking to the in-memory OAuth store
+type keyMemStore struct {
+	*oauth.MemStore
+	keys map[string]string
+}
+
+func (s *keyMemStore) SetSessionClientKey(ctx context.Context, did syntax.DID, sessionID, keyID string) error {
+	s.keys[did.String()+":"+sessionID] = keyID
+	return nil
+}
+
+func (s *keyMemStore) GetSessionClientKey(ctx context.Context, did syntax.DID, sessionID string) (string, error) {
+	return s.keys[did.String()+":"+sessionID], nil
+}
+
+// newTestClientKey generates a client signing key
+func newTestClientKey(t *testing.T) *ClientKey {
+	t.Helper()
+	text, err := GenerateClientKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseClientKey([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSetClientKeys(t *testing.T) {
+	current, old := newTestClientKey(t), newTestClientKey(t)
+
+	localhost, _ := NewOAuthManager("", "http://127.0.0.1:18910/oauth/callback", &keyMemStore{MemStore: oauth.NewMemStore()})
+	if err := localhost.SetClientKeys(current); err == nil {
+		t.Error("SetClientKeys() on a localhost client succeeded, want error")
+	}
+
+	m, err := NewOAuthManager("https://arabica.example.com/client-metadata.json", "https://arabica.example.com/oauth/callback",
+		&keyMemStore{MemStore: oauth.NewMemStore(), keys: map[string]string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.IsConfidential() {
+		t.Fatal("client is confidential before SetClientKeys")
+	}
+	if err := m.SetClientKeys(current, old); err != nil {
+		t.Fatalf("SetClientKeys() error = %v", err)
+	}
+
+	metadata := m.ClientMetadata()
+	if metadata.TokenEndpointAuthMethod != "private_key_jwt" {
+		t.Errorf("token_endpoint_auth_method = %q, want private_key_jwt", metadata.TokenEndpointAuthMethod)
+	}
+	if metadata.JWKSURI == nil || *metadata.JWKSURI != "https://arabica.example.com/oauth/jwks.json" {
+		t.Errorf("jwks_uri = %v, want https://arabica.example.com/oauth/jwks.json", metadata.JWKSURI)
+	}
+
+	jwks, err := m.JWKS()
+	if err != nil {
+		t.Fatalf("JWKS() error = %v", err)
+	}
+	if len(jwks.Keys) != 2 || *jwks.Keys[0].KeyID != current.ID || *jwks.Keys[1].KeyID != old.ID {
+		t.Errorf("JWKS() = %+v, want the current key then the old one", jwks.Keys)
+	}
+	if jwks.Keys[0].Curve != "P-256" || jwks.Keys[0].Use != "sig" {
+		t.Errorf("JWK = %+v, want a P-256 signing key", jwks.Keys[0])
+	}
+}
+
+func TestResumeSessionClientKey(t *testing.T) {
+	ctx := context.Background()
+	store := &keyMemStore{MemStore: oauth.NewMemStore(), keys: map[string]string{}}
+	dpopKey, err := atcrypto.GeneratePrivateKeyP256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	did := syntax.DID("did:plc:alice")
+	saveSession := func(sessionID, keyID string) {
+		data := oauth.ClientSessionData{AccountDID: did, SessionID: sessionID, DPoPPrivateKeyMultibase: dpopKey.Multibase()}
+		if err := store.SaveSession(ctx, data); err != nil {
+			t.Fatal(err)
+		}
+		if keyID != "" {
+			if err := store.SetSessionClientKey(ctx, did, sessionID, keyID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Sessions created before and after the client became confidential with key A
+	keyA, keyB := newTestClientKey(t), newTestClientKey(t)
+	saveSession("public", "")
+	saveSession("a", keyA.ID)
+
+	// Rotated to key B, keeping A
+	m, err := NewOAuthManager("https://arabica.example.com/client-metadata.json", "https://arabica.example.com/oauth/callback", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetClientKeys(keyB, keyA); err != nil {
+		t.Fatal(err)
+	}
+	saveSession("b", keyB.ID)
+	saveSession("removed", newTestClientKey(t).ID)
+
+	tests := []struct {
+		sessionID string
+		want      *oauth.ClientConfig
+	}{
+		{"public", m.publicConfig},
+		{"a", m.keyConfigs[keyA.ID]},
+		{"b", m.keyConfigs[keyB.ID]},
+		{"removed", m.app.Config},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sessionID, func(t *testing.T) {
+			session, err := m.resumeSession(ctx, did, tt.sessionID)
+			if err != nil {
+				t.Fatalf("resumeSession() error = %v", err)
+			}
+			if session.Config != tt.want {
+				t.Errorf("resumeSession() config = %+v, want %+v", session.Config, tt.want)
+			}
+		})
+	}
+	if m.keyConfigs[keyA.ID] == m.keyConfigs[keyB.ID] || m.publicConfig.IsConfidential() {
+		t.Error("client configs aren't kept apart per key")
+	}
+}
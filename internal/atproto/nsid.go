@@ -19,6 +19,23 @@ const (
 	NSIDGrinder = NSIDBase + ".grinder"
 	NSIDRoaster = NSIDBase + ".roaster"
 
+	// NSIDSettings is a singleton collection; each user has at most one record, keyed SettingsRKey
+	NSIDSettings = NSIDBase + ".settings"
+
+	// SettingsRKey is the record key of the settings record
+	SettingsRKey = "self"
+
+	// NSIDGraph is a singleton collection holding the accounts a user muted
+	// or blocked, keyed GraphRKey
+	NSIDGraph = NSIDBase + ".graph"
+
+	// GraphRKey is the record key of the graph record
+	GraphRKey = "self"
+
+	// NSIDBlueskyBlock is the collection of Bluesky blocks, read to import
+	// them into the graph record
+	NSIDBlueskyBlock = "app.bsky.graph.block"
+
 	// MaxRKeyLength is the maximum allowed length for a record key
 	MaxRKeyLength = 512
 )
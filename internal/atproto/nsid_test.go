@@ -20,6 +20,7 @@ func TestNSIDConstants(t *testing.T) {
 		{"NSIDBean", NSIDBean, "social.arabica.alpha.bean"},
 		{"NSIDBrew", NSIDBrew, "social.arabica.alpha.brew"},
 		{"NSIDBrewer", NSIDBrewer, "social.arabica.alpha.brewer"},
+		{"NSIDGraph", NSIDGraph, "social.arabica.alpha.graph"},
 		{"NSIDGrinder", NSIDGrinder, "social.arabica.alpha.grinder"},
 		{"NSIDRoaster", NSIDRoaster, "social.arabica.alpha.roaster"},
 	}
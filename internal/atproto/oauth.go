@@ -7,23 +7,24 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/bluesky-social/indigo/atproto/atcrypto"
 	"github.com/bluesky-social/indigo/atproto/auth/oauth"
 	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/rs/zerolog/log"
 )
 
-var scopes = []string{
-	"atproto",
-	"repo:" + NSIDBean,
-	"repo:" + NSIDBrew,
-	"repo:" + NSIDBrewer,
-	"repo:" + NSIDGrinder,
-	"repo:" + NSIDRoaster,
-}
-
 // OAuthManager wraps indigo's OAuth client for managing user authentication
 type OAuthManager struct {
 	app           *oauth.ClientApp
 	onAuthSuccess func(did string) // Callback when user authenticates successfully
+	onSessionUse  func(did syntax.DID, sessionID string)
+
+	// Set for confidential clients by SetClientKeys
+	clientKeys   []*ClientKey
+	keyConfigs   map[string]*oauth.ClientConfig // per key ID, for refreshing sessions
+	publicConfig *oauth.ClientConfig            // for sessions created as a public client
+	keyStore     ClientKeyStore
+	jwksURI      string
 }
 
 // NewOAuthManager creates a new OAuth manager with the given configuration.
@@ -54,6 +55,89 @@ func NewOAuthManager(clientID, redirectURI string, store oauth.ClientAuthStore)
 	}, nil
 }
 
+// SetClientKeys makes the client confidential, signing client assertions with
+// current. Sessions created with one of old keep refreshing with it, and the
+// old keys stay in the JWKS, so rotating the key doesn't log anyone out.
+// Sessions created while the client was public keep refreshing without a key.
+// Must be called before serving requests; the session store passed to
+// NewOAuthManager has to implement ClientKeyStore.
+func (m *OAuthManager) SetClientKeys(current *ClientKey, old ...*ClientKey) error {
+	config := m.app.Config
+	if strings.HasPrefix(config.ClientID, "http://localhost") {
+		return fmt.Errorf("a localhost OAuth client can't be confidential; set a public URL or client ID")
+	}
+	keyStore, ok := m.app.Store.(ClientKeyStore)
+	if !ok {
+		return fmt.Errorf("session store %T can't record client keys", m.app.Store)
+	}
+	clientID, err := url.Parse(config.ClientID)
+	if err != nil {
+		return fmt.Errorf("invalid client ID: %w", err)
+	}
+
+	public := *config
+	m.publicConfig = &public
+	m.keyConfigs = make(map[string]*oauth.ClientConfig)
+	for _, key := range append([]*ClientKey{current}, old...) {
+		keyConfig := public
+		if err := keyConfig.SetClientSecret(key.key, key.ID); err != nil {
+			return err
+		}
+		m.keyConfigs[key.ID] = &keyConfig
+	}
+	if err := config.SetClientSecret(current.key, current.ID); err != nil {
+		return err
+	}
+
+	m.clientKeys = append([]*ClientKey{current}, old...)
+	m.keyStore = keyStore
+	m.jwksURI = (&url.URL{Scheme: clientID.Scheme, Host: clientID.Host, Path: JWKSPath}).String()
+	return nil
+}
+
+// IsConfidential reports whether the client authenticates with a signing key
+func (m *OAuthManager) IsConfidential() bool {
+	return m.app.Config.IsConfidential()
+}
+
+// JWKS returns the public keys of a confidential client, current key first
+func (m *OAuthManager) JWKS() (oauth.JWKS, error) {
+	jwks := oauth.JWKS{Keys: []atcrypto.JWK{}}
+	for _, key := range m.clientKeys {
+		jwk, err := key.jwk()
+		if err != nil {
+			return jwks, err
+		}
+		jwks.Keys = append(jwks.Keys, *jwk)
+	}
+	return jwks, nil
+}
+
+// resumeSession loads a session for making requests, refreshing its tokens
+// with the client key it was created with
+func (m *OAuthManager) resumeSession(ctx context.Context, did syntax.DID, sessionID string) (*oauth.ClientSession, error) {
+	session, err := m.app.ResumeSession(ctx, did, sessionID)
+	if err != nil || m.keyStore == nil {
+		return session, err
+	}
+
+	keyID, err := m.keyStore.GetSessionClientKey(ctx, did, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	switch config, ok := m.keyConfigs[keyID]; {
+	case keyID == "":
+		session.Config = m.publicConfig
+	case ok:
+		session.Config = config
+	default:
+		// The key was removed from the configuration; the current key is the
+		// best remaining guess, though the auth server will likely refuse it
+		log.Warn().Str("did", did.String()).Str("key_id", keyID).Msg("Session was created with a client key that is no longer configured")
+	}
+	return session, nil
+}
+
 // InitiateLogin starts the OAuth flow for a user
 // Returns the authorization URL to redirect the user to
 func (m *OAuthManager) InitiateLogin(ctx context.Context, handle string) (authURL string, err error) {
@@ -71,6 +155,8 @@ type SessionData struct {
 	AccountDID syntax.DID
 	SessionID  string
 	Scopes     []string
+	// Handle is the account's handle, or empty if it has no valid handle
+	Handle string
 }
 
 // HandleCallback processes the OAuth callback after user authorization
@@ -82,10 +168,26 @@ func (m *OAuthManager) HandleCallback(ctx context.Context, params url.Values) (*
 		return nil, fmt.Errorf("failed to process OAuth callback: %w", err)
 	}
 
+	// Without a recorded key the session would be refreshed as a public client
+	if m.keyStore != nil {
+		err := m.keyStore.SetSessionClientKey(ctx, sessData.AccountDID, sessData.SessionID, *m.app.Config.KeyID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record session client key: %w", err)
+		}
+	}
+
+	// The callback has just resolved the DID, so the directory answers from
+	// its cache
+	var handle string
+	if ident, err := m.app.Dir.LookupDID(ctx, sessData.AccountDID); err == nil && !ident.Handle.IsInvalidHandle() {
+		handle = ident.Handle.String()
+	}
+
 	return &SessionData{
 		AccountDID: sessData.AccountDID,
 		SessionID:  sessData.SessionID,
 		Scopes:     sessData.Scopes,
+		Handle:     handle,
 	}, nil
 }
 
@@ -112,7 +214,11 @@ func (m *OAuthManager) DeleteSession(ctx context.Context, did syntax.DID, sessio
 // ClientMetadata returns the OAuth client metadata document
 // This should be served at the client_id URL
 func (m *OAuthManager) ClientMetadata() oauth.ClientMetadata {
-	return m.app.Config.ClientMetadata()
+	metadata := m.app.Config.ClientMetadata()
+	if m.IsConfidential() {
+		metadata.JWKSURI = &m.jwksURI
+	}
+	return metadata
 }
 
 // SetOnAuthSuccess sets a callback that is called when a user authenticates successfully
@@ -121,6 +227,12 @@ func (m *OAuthManager) SetOnAuthSuccess(fn func(did string)) {
 	m.onAuthSuccess = fn
 }
 
+// SetOnSessionUse sets a callback that is called for every request made with a
+// valid session, e.g. to record when the session was last used
+func (m *OAuthManager) SetOnSessionUse(fn func(did syntax.DID, sessionID string)) {
+	m.onSessionUse = fn
+}
+
 // AuthMiddleware adds authentication context to HTTP requests
 func (m *OAuthManager) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -154,6 +266,9 @@ func (m *OAuthManager) AuthMiddleware(next http.Handler) http.Handler {
 		if m.onAuthSuccess != nil {
 			m.onAuthSuccess(did.String())
 		}
+		if m.onSessionUse != nil {
+			m.onSessionUse(did, sessionCookie.Value)
+		}
 
 		// Note: Token refresh is handled automatically by the SDK when making authenticated requests
 
@@ -161,11 +276,29 @@ func (m *OAuthManager) AuthMiddleware(next http.Handler) http.Handler {
 		ctx := context.WithValue(r.Context(), contextKeyUserDID, did.String())
 		ctx = context.WithValue(ctx, contextKeySessionID, sessionCookie.Value)
 		ctx = context.WithValue(ctx, contextKeySessionData, sessData)
+		ctx = context.WithValue(ctx, contextKeyAccounts, signedInAccounts(r, did))
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// signedInAccounts returns the accounts in the accounts cookie other than the
+// active one. Their sessions aren't looked up on every request; switching to
+// an account checks its session is still there.
+func signedInAccounts(r *http.Request, active syntax.DID) []Account {
+	cookie, err := r.Cookie(AccountsCookie)
+	if err != nil {
+		return nil
+	}
+	var accounts []Account
+	for _, account := range DecodeAccounts(cookie.Value) {
+		if account.DID != active {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts
+}
+
 // Context keys for storing auth info
 type contextKey string
 
@@ -173,6 +306,7 @@ const (
 	contextKeyUserDID     contextKey = "userDID"
 	contextKeySessionID   contextKey = "sessionID"
 	contextKeySessionData contextKey = "sessionData"
+	contextKeyAccounts    contextKey = "accounts"
 )
 
 // GetAuthenticatedDID retrieves the authenticated user's DID from the request context
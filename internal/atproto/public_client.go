@@ -11,6 +11,12 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"arabica/internal/metrics"
+	"arabica/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -23,6 +29,9 @@ const (
 // ErrSSRFBlocked is returned when a potential SSRF attack is blocked
 var ErrSSRFBlocked = errors.New("request blocked: potential SSRF detected")
 
+// ErrRecordNotFound is returned when a PDS reports that a record does not exist
+var ErrRecordNotFound = errors.New("record not found")
+
 // isPrivateIP checks if an IP address is in a private/internal range
 func isPrivateIP(ip net.IP) bool {
 	if ip == nil {
@@ -108,7 +117,7 @@ func NewPublicClient() *PublicClient {
 }
 
 // GetPDSEndpoint resolves a DID to find the user's PDS endpoint
-func (c *PublicClient) GetPDSEndpoint(ctx context.Context, did string) (string, error) {
+func (c *PublicClient) GetPDSEndpoint(ctx context.Context, did string) (_ string, err error) {
 	// Check cache first
 	c.pdsCacheMu.RLock()
 	if pds, ok := c.pdsCache[did]; ok {
@@ -117,6 +126,10 @@ func (c *PublicClient) GetPDSEndpoint(ctx context.Context, did string) (string,
 	}
 	c.pdsCacheMu.RUnlock()
 
+	ctx, span := tracing.Start(ctx, "PublicClient.GetPDSEndpoint",
+		trace.WithAttributes(attribute.String("atproto.did", did)))
+	defer func() { tracing.End(span, err) }()
+
 	// Resolve DID document from PLC directory
 	var pdsEndpoint string
 
@@ -192,6 +205,14 @@ func (c *PublicClient) GetPDSEndpoint(ctx context.Context, did string) (string,
 	return pdsEndpoint, nil
 }
 
+// startPublicXRPCSpan starts a client span for an unauthenticated XRPC call
+func startPublicXRPCSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "xrpc "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attrs, attribute.String("rpc.method", method))...),
+	)
+}
+
 // Profile represents a user's public profile
 type Profile struct {
 	DID         string  `json:"did"`
@@ -214,7 +235,10 @@ type PublicRecordEntry struct {
 }
 
 // GetProfile fetches a user's public profile by DID or handle
-func (c *PublicClient) GetProfile(ctx context.Context, actor string) (*Profile, error) {
+func (c *PublicClient) GetProfile(ctx context.Context, actor string) (_ *Profile, err error) {
+	ctx, span := startPublicXRPCSpan(ctx, "app.bsky.actor.getProfile", attribute.String("atproto.actor", actor))
+	defer func() { tracing.End(span, err) }()
+
 	reqURL := fmt.Sprintf("%s/xrpc/app.bsky.actor.getProfile?actor=%s",
 		c.baseURL, url.QueryEscape(actor))
 
@@ -244,7 +268,17 @@ func (c *PublicClient) GetProfile(ctx context.Context, actor string) (*Profile,
 // ListRecords fetches public records from a user's repository
 // Records are returned in reverse chronological order (newest first)
 // This queries the user's PDS directly to support custom collections
-func (c *PublicClient) ListRecords(ctx context.Context, did, collection string, limit int) (*PublicListRecordsOutput, error) {
+func (c *PublicClient) ListRecords(ctx context.Context, did, collection string, limit int) (_ *PublicListRecordsOutput, err error) {
+	start := time.Now()
+	ctx, span := startPublicXRPCSpan(ctx, "com.atproto.repo.listRecords",
+		attribute.String("atproto.did", did),
+		attribute.String("atproto.collection", collection),
+	)
+	defer func() {
+		tracing.End(span, err)
+		metrics.ObservePDSRequest("listRecords", collection, start, err != nil)
+	}()
+
 	// Resolve the user's PDS endpoint
 	pdsEndpoint, err := c.GetPDSEndpoint(ctx, did)
 	if err != nil {
@@ -278,7 +312,10 @@ func (c *PublicClient) ListRecords(ctx context.Context, did, collection string,
 }
 
 // ResolveHandle resolves an AT Protocol handle to a DID
-func (c *PublicClient) ResolveHandle(ctx context.Context, handle string) (string, error) {
+func (c *PublicClient) ResolveHandle(ctx context.Context, handle string) (_ string, err error) {
+	ctx, span := startPublicXRPCSpan(ctx, "com.atproto.identity.resolveHandle", attribute.String("atproto.handle", handle))
+	defer func() { tracing.End(span, err) }()
+
 	reqURL := fmt.Sprintf("%s/xrpc/com.atproto.identity.resolveHandle?handle=%s",
 		c.baseURL, url.QueryEscape(handle))
 
@@ -312,7 +349,21 @@ func (c *PublicClient) ResolveHandle(ctx context.Context, handle string) (string
 }
 
 // GetRecord fetches a single public record from the user's PDS
-func (c *PublicClient) GetRecord(ctx context.Context, did, collection, rkey string) (*PublicRecordEntry, error) {
+func (c *PublicClient) GetRecord(ctx context.Context, did, collection, rkey string) (_ *PublicRecordEntry, err error) {
+	start := time.Now()
+	ctx, span := startPublicXRPCSpan(ctx, "com.atproto.repo.getRecord",
+		attribute.String("atproto.did", did),
+		attribute.String("atproto.collection", collection),
+	)
+	defer func() {
+		failed := err != nil && !errors.Is(err, ErrRecordNotFound)
+		if failed {
+			tracing.RecordError(span, err)
+		}
+		span.End()
+		metrics.ObservePDSRequest("getRecord", collection, start, failed)
+	}()
+
 	// Resolve the user's PDS endpoint
 	pdsEndpoint, err := c.GetPDSEndpoint(ctx, did)
 	if err != nil {
@@ -334,6 +385,13 @@ func (c *PublicClient) GetRecord(ctx context.Context, did, collection, rkey stri
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		// PDSes answer a missing record with 400 and an XRPC error body
+		var xrpcErr struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&xrpcErr) == nil && xrpcErr.Error == "RecordNotFound" {
+			return nil, ErrRecordNotFound
+		}
 		return nil, fmt.Errorf("get record request failed with status %d", resp.StatusCode)
 	}
 
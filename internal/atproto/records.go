@@ -2,6 +2,7 @@ package atproto
 
 import (
 	"fmt"
+	"math"
 	"time"
 
 	"arabica/internal/models"
@@ -11,6 +12,17 @@ import (
 
 // ========== Brew Conversions ==========
 
+// Values of the brew temperatureUnit field
+const (
+	TemperatureUnitCelsius    = "celsius"
+	TemperatureUnitFahrenheit = "fahrenheit"
+)
+
+// legacyFahrenheitThreshold is the temperature above which a brew written
+// before temperatureUnit existed is assumed to be in °F. Water can't be
+// hotter than 100°C at normal pressure.
+const legacyFahrenheitThreshold = 100.0
+
 // BrewToRecord converts a models.Brew to an atproto record map
 // Note: References (beanRef, grinderRef, brewerRef) must be AT-URIs
 func BrewToRecord(brew *models.Brew, beanURI, grinderURI, brewerURI string) (map[string]interface{}, error) {
@@ -30,7 +42,8 @@ func BrewToRecord(brew *models.Brew, beanURI, grinderURI, brewerURI string) (map
 	}
 	if brew.Temperature > 0 {
 		// Convert float to tenths (93.5 -> 935)
-		record["temperature"] = int(brew.Temperature * 10)
+		record["temperature"] = int(math.Round(brew.Temperature * 10))
+		record["temperatureUnit"] = TemperatureUnitCelsius
 	}
 	if brew.WaterAmount > 0 {
 		record["waterAmount"] = brew.WaterAmount
@@ -56,6 +69,13 @@ func BrewToRecord(brew *models.Brew, beanURI, grinderURI, brewerURI string) (map
 	if brew.Rating > 0 {
 		record["rating"] = brew.Rating
 	}
+	if brew.TDS > 0 {
+		// Convert percent to hundredths (1.35 -> 135)
+		record["tds"] = int(math.Round(brew.TDS * 100))
+	}
+	if brew.BeverageWeight > 0 {
+		record["beverageWeight"] = brew.BeverageWeight
+	}
 
 	// Convert pours to embedded array
 	if len(brew.Pours) > 0 {
@@ -112,6 +132,12 @@ func RecordToBrew(record map[string]interface{}, atURI string) (*models.Brew, er
 	if temp, ok := record["temperature"].(float64); ok {
 		// Convert from tenths to float (935 -> 93.5)
 		brew.Temperature = temp / 10.0
+
+		// Normalize to °C. Older records have no unit and may hold °F.
+		unit, _ := record["temperatureUnit"].(string)
+		if unit == TemperatureUnitFahrenheit || (unit == "" && brew.Temperature > legacyFahrenheitThreshold) {
+			brew.Temperature = math.Round(models.FahrenheitToCelsius(brew.Temperature)*10) / 10
+		}
 	}
 	if waterAmount, ok := record["waterAmount"].(float64); ok {
 		brew.WaterAmount = int(waterAmount)
@@ -131,6 +157,13 @@ func RecordToBrew(record map[string]interface{}, atURI string) (*models.Brew, er
 	if rating, ok := record["rating"].(float64); ok {
 		brew.Rating = int(rating)
 	}
+	if tds, ok := record["tds"].(float64); ok {
+		// Convert from hundredths to percent (135 -> 1.35)
+		brew.TDS = tds / 100.0
+	}
+	if beverageWeight, ok := record["beverageWeight"].(float64); ok {
+		brew.BeverageWeight = int(beverageWeight)
+	}
 
 	// Convert pours from embedded array
 	if poursRaw, ok := record["pours"].([]interface{}); ok {
@@ -316,6 +349,27 @@ func GrinderToRecord(grinder *models.Grinder) (map[string]interface{}, error) {
 	if grinder.Notes != "" {
 		record["notes"] = grinder.Notes
 	}
+	if grinder.Scale != nil {
+		// Scale values are stored in tenths (3.5 -> 35)
+		record["settingScale"] = map[string]interface{}{
+			"min":  toTenths(grinder.Scale.Min),
+			"max":  toTenths(grinder.Scale.Max),
+			"step": toTenths(grinder.Scale.Step),
+			"unit": grinder.Scale.Unit,
+		}
+	}
+
+	// Convert calibration points to embedded array
+	if len(grinder.Calibration) > 0 {
+		points := make([]map[string]interface{}, len(grinder.Calibration))
+		for i, point := range grinder.Calibration {
+			points[i] = map[string]interface{}{
+				"setting": toTenths(point.Setting),
+				"microns": point.Microns,
+			}
+		}
+		record["calibration"] = points
+	}
 
 	return record, nil
 }
@@ -361,10 +415,53 @@ func RecordToGrinder(record map[string]interface{}, atURI string) (*models.Grind
 	if notes, ok := record["notes"].(string); ok {
 		grinder.Notes = notes
 	}
+	if scaleMap, ok := record["settingScale"].(map[string]interface{}); ok {
+		scale := &models.GrindScale{}
+		if min, ok := scaleMap["min"].(float64); ok {
+			scale.Min = min / 10.0
+		}
+		if max, ok := scaleMap["max"].(float64); ok {
+			scale.Max = max / 10.0
+		}
+		if step, ok := scaleMap["step"].(float64); ok {
+			scale.Step = step / 10.0
+		}
+		if unit, ok := scaleMap["unit"].(string); ok {
+			scale.Unit = unit
+		}
+		// Ignore malformed scales rather than failing the whole record
+		if scale.Validate() == nil {
+			grinder.Scale = scale
+		}
+	}
+
+	// Convert calibration points from embedded array
+	if pointsRaw, ok := record["calibration"].([]interface{}); ok {
+		for _, pointRaw := range pointsRaw {
+			pointMap, ok := pointRaw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			setting, okSetting := pointMap["setting"].(float64)
+			microns, okMicrons := pointMap["microns"].(float64)
+			if !okSetting || !okMicrons {
+				continue
+			}
+			grinder.Calibration = append(grinder.Calibration, models.CalibrationPoint{
+				Setting: setting / 10.0,
+				Microns: int(microns),
+			})
+		}
+	}
 
 	return grinder, nil
 }
 
+// toTenths converts a decimal value to the integer tenths used in records (93.5 -> 935)
+func toTenths(v float64) int {
+	return int(math.Round(v * 10))
+}
+
 // ========== Brewer Conversions ==========
 
 // BrewerToRecord converts a models.Brewer to an atproto record map
@@ -427,3 +524,98 @@ func RecordToBrewer(record map[string]interface{}, atURI string) (*models.Brewer
 
 	return brewer, nil
 }
+
+// ========== Settings Conversions ==========
+
+// SettingsToRecord converts models.Settings to an atproto record map
+func SettingsToRecord(settings *models.Settings) (map[string]interface{}, error) {
+	record := map[string]interface{}{
+		"$type":       NSIDSettings,
+		"privateMode": settings.PrivateMode,
+		"devMode":     settings.DevMode,
+		"updatedAt":   settings.UpdatedAt.Format(time.RFC3339),
+	}
+
+	if settings.ViewMode != "" {
+		record["viewMode"] = settings.ViewMode
+	}
+
+	return record, nil
+}
+
+// RecordToSettings converts an atproto record map to models.Settings.
+// Missing fields keep their defaults, so older records stay readable.
+func RecordToSettings(record map[string]interface{}) (*models.Settings, error) {
+	settings := models.DefaultSettings()
+
+	if privateMode, ok := record["privateMode"].(bool); ok {
+		settings.PrivateMode = privateMode
+	}
+	if devMode, ok := record["devMode"].(bool); ok {
+		settings.DevMode = devMode
+	}
+	if viewMode, ok := record["viewMode"].(string); ok && viewMode != "" {
+		settings.ViewMode = viewMode
+	}
+	if updatedAtStr, ok := record["updatedAt"].(string); ok {
+		updatedAt, err := time.Parse(time.RFC3339, updatedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid updatedAt format: %w", err)
+		}
+		settings.UpdatedAt = updatedAt
+	}
+
+	return settings, nil
+}
+
+// ========== Graph Conversions ==========
+
+// GraphToRecord converts models.Graph to an atproto record map
+func GraphToRecord(graph *models.Graph) (map[string]interface{}, error) {
+	record := map[string]interface{}{
+		"$type":     NSIDGraph,
+		"mutes":     nonNilStrings(graph.Mutes),
+		"blocks":    nonNilStrings(graph.Blocks),
+		"updatedAt": graph.UpdatedAt.Format(time.RFC3339),
+	}
+
+	return record, nil
+}
+
+// nonNilStrings keeps empty lists as [] rather than null in records
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// RecordToGraph converts an atproto record map to models.Graph.
+// Entries that aren't strings are skipped.
+func RecordToGraph(record map[string]interface{}) (*models.Graph, error) {
+	graph := &models.Graph{
+		Mutes:  recordStrings(record["mutes"]),
+		Blocks: recordStrings(record["blocks"]),
+	}
+	if updatedAtStr, ok := record["updatedAt"].(string); ok {
+		updatedAt, err := time.Parse(time.RFC3339, updatedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid updatedAt format: %w", err)
+		}
+		graph.UpdatedAt = updatedAt
+	}
+
+	return graph, nil
+}
+
+// recordStrings reads a JSON array of strings from a decoded record field
+func recordStrings(v interface{}) []string {
+	items, _ := v.([]interface{})
+	var out []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
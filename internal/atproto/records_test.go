@@ -1,10 +1,16 @@
 package atproto
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
 	"arabica/internal/models"
+
+	"github.com/bluesky-social/indigo/atproto/atclient"
 )
 
 func TestBrewToRecord(t *testing.T) {
@@ -12,14 +18,16 @@ func TestBrewToRecord(t *testing.T) {
 
 	t.Run("full brew with all fields", func(t *testing.T) {
 		brew := &models.Brew{
-			Method:       "V60",
-			Temperature:  93.5,
-			WaterAmount:  300,
-			TimeSeconds:  180,
-			GrindSize:    "Medium",
-			TastingNotes: "Fruity and bright",
-			Rating:       8,
-			CreatedAt:    createdAt,
+			Method:         "V60",
+			Temperature:    93.5,
+			WaterAmount:    300,
+			TimeSeconds:    180,
+			GrindSize:      "Medium",
+			TastingNotes:   "Fruity and bright",
+			Rating:         8,
+			CreatedAt:      createdAt,
+			TDS:            1.35,
+			BeverageWeight: 260,
 			Pours: []*models.Pour{
 				{WaterAmount: 50, TimeSeconds: 30},
 				{WaterAmount: 100, TimeSeconds: 60},
@@ -54,6 +62,9 @@ func TestBrewToRecord(t *testing.T) {
 		if record["temperature"] != 935 {
 			t.Errorf("temperature = %v, want %v", record["temperature"], 935)
 		}
+		if record["temperatureUnit"] != TemperatureUnitCelsius {
+			t.Errorf("temperatureUnit = %v, want %v", record["temperatureUnit"], TemperatureUnitCelsius)
+		}
 		if record["waterAmount"] != 300 {
 			t.Errorf("waterAmount = %v, want %v", record["waterAmount"], 300)
 		}
@@ -75,6 +86,13 @@ func TestBrewToRecord(t *testing.T) {
 		if record["rating"] != 8 {
 			t.Errorf("rating = %v, want %v", record["rating"], 8)
 		}
+		// TDS should be converted to hundredths (1.35 -> 135)
+		if record["tds"] != 135 {
+			t.Errorf("tds = %v, want %v", record["tds"], 135)
+		}
+		if record["beverageWeight"] != 260 {
+			t.Errorf("beverageWeight = %v, want %v", record["beverageWeight"], 260)
+		}
 
 		// Check pours
 		pours, ok := record["pours"].([]map[string]interface{})
@@ -117,6 +135,9 @@ func TestBrewToRecord(t *testing.T) {
 		if _, ok := record["pours"]; ok {
 			t.Error("pours should be omitted when empty")
 		}
+		if _, ok := record["tds"]; ok {
+			t.Error("tds should be omitted when zero")
+		}
 	})
 
 	t.Run("error without beanURI", func(t *testing.T) {
@@ -134,18 +155,20 @@ func TestBrewToRecord(t *testing.T) {
 func TestRecordToBrew(t *testing.T) {
 	t.Run("full record", func(t *testing.T) {
 		record := map[string]interface{}{
-			"$type":        NSIDBrew,
-			"beanRef":      "at://did:plc:test/social.arabica.alpha.bean/bean123",
-			"createdAt":    "2025-01-10T12:00:00Z",
-			"method":       "V60",
-			"temperature":  float64(935), // tenths
-			"waterAmount":  float64(300),
-			"timeSeconds":  float64(180),
-			"grindSize":    "Medium",
-			"grinderRef":   "at://did:plc:test/social.arabica.alpha.grinder/grinder123",
-			"brewerRef":    "at://did:plc:test/social.arabica.alpha.brewer/brewer123",
-			"tastingNotes": "Fruity",
-			"rating":       float64(8),
+			"$type":          NSIDBrew,
+			"beanRef":        "at://did:plc:test/social.arabica.alpha.bean/bean123",
+			"createdAt":      "2025-01-10T12:00:00Z",
+			"method":         "V60",
+			"temperature":    float64(935), // tenths
+			"waterAmount":    float64(300),
+			"timeSeconds":    float64(180),
+			"grindSize":      "Medium",
+			"grinderRef":     "at://did:plc:test/social.arabica.alpha.grinder/grinder123",
+			"brewerRef":      "at://did:plc:test/social.arabica.alpha.brewer/brewer123",
+			"tastingNotes":   "Fruity",
+			"rating":         float64(8),
+			"tds":            float64(128),
+			"beverageWeight": float64(250),
 			"pours": []interface{}{
 				map[string]interface{}{"waterAmount": float64(50), "timeSeconds": float64(30)},
 				map[string]interface{}{"waterAmount": float64(100), "timeSeconds": float64(60)},
@@ -183,6 +206,13 @@ func TestRecordToBrew(t *testing.T) {
 		if brew.Rating != 8 {
 			t.Errorf("Rating = %v, want %v", brew.Rating, 8)
 		}
+		// TDS should be converted from hundredths (128 -> 1.28)
+		if brew.TDS != 1.28 {
+			t.Errorf("TDS = %v, want %v", brew.TDS, 1.28)
+		}
+		if brew.BeverageWeight != 250 {
+			t.Errorf("BeverageWeight = %v, want %v", brew.BeverageWeight, 250)
+		}
 
 		if len(brew.Pours) != 2 {
 			t.Fatalf("len(Pours) = %v, want %v", len(brew.Pours), 2)
@@ -233,6 +263,41 @@ func TestRecordToBrew(t *testing.T) {
 	})
 }
 
+func TestRecordToBrewTemperatureUnit(t *testing.T) {
+	tests := []struct {
+		name string
+		temp float64
+		unit string
+		want float64
+	}{
+		{"celsius", 935, TemperatureUnitCelsius, 93.5},
+		{"fahrenheit", 2003, TemperatureUnitFahrenheit, 93.5},
+		{"legacy celsius", 935, "", 93.5},
+		{"legacy fahrenheit", 2000, "", 93.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := map[string]interface{}{
+				"beanRef":     "at://did:plc:test/social.arabica.alpha.bean/bean123",
+				"createdAt":   "2025-01-10T12:00:00Z",
+				"temperature": tt.temp,
+			}
+			if tt.unit != "" {
+				record["temperatureUnit"] = tt.unit
+			}
+
+			brew, err := RecordToBrew(record, "at://did:plc:test/social.arabica.alpha.brew/brew123")
+			if err != nil {
+				t.Fatalf("RecordToBrew() error = %v", err)
+			}
+			if brew.Temperature != tt.want {
+				t.Errorf("Temperature = %v, want %v", brew.Temperature, tt.want)
+			}
+		})
+	}
+}
+
 func TestBeanToRecord(t *testing.T) {
 	createdAt := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
 
@@ -451,6 +516,38 @@ func TestGrinderToRecord(t *testing.T) {
 			t.Errorf("notes = %v, want %v", record["notes"], "Great for travel")
 		}
 	})
+
+	t.Run("scale and calibration in tenths", func(t *testing.T) {
+		grinder := &models.Grinder{
+			Name:      "1Zpresso JX",
+			CreatedAt: createdAt,
+			Scale:     &models.GrindScale{Min: 0, Max: 40, Step: 0.5, Unit: models.GrindUnitClicks},
+			Calibration: []models.CalibrationPoint{
+				{Setting: 10, Microns: 400},
+				{Setting: 22.5, Microns: 800},
+			},
+		}
+
+		record, err := GrinderToRecord(grinder)
+		if err != nil {
+			t.Fatalf("GrinderToRecord() error = %v", err)
+		}
+
+		scale, ok := record["settingScale"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("settingScale missing from record")
+		}
+		if scale["max"] != 400 || scale["step"] != 5 || scale["unit"] != "clicks" {
+			t.Errorf("settingScale = %v, want max 400, step 5, unit clicks", scale)
+		}
+		points, ok := record["calibration"].([]map[string]interface{})
+		if !ok || len(points) != 2 {
+			t.Fatalf("calibration = %v, want 2 points", record["calibration"])
+		}
+		if points[1]["setting"] != 225 || points[1]["microns"] != 800 {
+			t.Errorf("calibration[1] = %v, want setting 225, microns 800", points[1])
+		}
+	})
 }
 
 func TestRecordToGrinder(t *testing.T) {
@@ -485,9 +582,128 @@ func TestRecordToGrinder(t *testing.T) {
 		if grinder.Notes != "Great for travel" {
 			t.Errorf("Notes = %v, want %v", grinder.Notes, "Great for travel")
 		}
+		if grinder.Scale != nil || grinder.Calibration != nil {
+			t.Errorf("Scale/Calibration should be empty for records without them")
+		}
+	})
+
+	t.Run("scale and calibration", func(t *testing.T) {
+		record := map[string]interface{}{
+			"$type":     NSIDGrinder,
+			"name":      "1Zpresso JX",
+			"createdAt": "2025-01-10T12:00:00Z",
+			"settingScale": map[string]interface{}{
+				"min":  float64(0),
+				"max":  float64(400),
+				"step": float64(5),
+				"unit": "clicks",
+			},
+			"calibration": []interface{}{
+				map[string]interface{}{"setting": float64(100), "microns": float64(400)},
+				map[string]interface{}{"setting": float64(225), "microns": float64(800)},
+				"garbage",
+			},
+		}
+
+		grinder, err := RecordToGrinder(record, "")
+		if err != nil {
+			t.Fatalf("RecordToGrinder() error = %v", err)
+		}
+
+		want := models.GrindScale{Min: 0, Max: 40, Step: 0.5, Unit: "clicks"}
+		if grinder.Scale == nil || *grinder.Scale != want {
+			t.Errorf("Scale = %+v, want %+v", grinder.Scale, want)
+		}
+		if len(grinder.Calibration) != 2 {
+			t.Fatalf("Calibration length = %d, want 2", len(grinder.Calibration))
+		}
+		if grinder.Calibration[1].Setting != 22.5 || grinder.Calibration[1].Microns != 800 {
+			t.Errorf("Calibration[1] = %+v, want {22.5 800}", grinder.Calibration[1])
+		}
+	})
+
+	t.Run("invalid scale is dropped", func(t *testing.T) {
+		record := map[string]interface{}{
+			"name":         "Broken",
+			"createdAt":    "2025-01-10T12:00:00Z",
+			"settingScale": map[string]interface{}{"min": float64(10), "max": float64(5), "step": float64(1), "unit": "clicks"},
+		}
+
+		grinder, err := RecordToGrinder(record, "")
+		if err != nil {
+			t.Fatalf("RecordToGrinder() error = %v", err)
+		}
+		if grinder.Scale != nil {
+			t.Errorf("Scale = %+v, want nil", grinder.Scale)
+		}
 	})
 }
 
+// TestGrinderScalePrecision checks that every scale accepted by validation
+// survives a save through the PDS, which stores settings in whole tenths
+func TestGrinderScalePrecision(t *testing.T) {
+	tests := []struct {
+		name    string
+		scale   models.GrindScale
+		setting float64
+		wantErr error
+	}{
+		{"half step", models.GrindScale{Min: 0, Max: 40, Step: 0.5, Unit: models.GrindUnitClicks}, 22.5, nil},
+		{"tenth step", models.GrindScale{Min: 0.5, Max: 9.9, Step: 0.1, Unit: models.GrindUnitNumbers}, 3.3, nil},
+		{"quarter step", models.GrindScale{Min: 0, Max: 40, Step: 0.25, Unit: models.GrindUnitClicks}, 20, models.ErrGrindPrecision},
+		{"hundredth step", models.GrindScale{Min: 0, Max: 4, Step: 0.01, Unit: models.GrindUnitRotations}, 2, models.ErrGrindPrecision},
+		{"hundredths setting", models.GrindScale{Min: 0, Max: 40, Step: 0.5, Unit: models.GrindUnitClicks}, 12.25, models.ErrGrindPrecision},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scale := tt.scale
+			req := models.CreateGrinderRequest{
+				Name:        "1Zpresso JX",
+				GrinderType: "Hand",
+				Scale:       &scale,
+				Calibration: []models.CalibrationPoint{{Setting: tt.setting, Microns: 600}},
+			}
+			if err := req.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			record, err := GrinderToRecord(&models.Grinder{
+				Name:        req.Name,
+				CreatedAt:   time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC),
+				Scale:       req.Scale,
+				Calibration: req.Calibration,
+			})
+			if err != nil {
+				t.Fatalf("GrinderToRecord() error = %v", err)
+			}
+			// Records come back from the PDS as JSON
+			data, err := json.Marshal(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded map[string]interface{}
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+
+			restored, err := RecordToGrinder(decoded, "at://did:plc:test/social.arabica.alpha.grinder/grinder123")
+			if err != nil {
+				t.Fatalf("RecordToGrinder() error = %v", err)
+			}
+			if restored.Scale == nil || *restored.Scale != tt.scale {
+				t.Errorf("Scale = %+v, want %+v", restored.Scale, tt.scale)
+			}
+			if len(restored.Calibration) != 1 || restored.Calibration[0].Setting != tt.setting {
+				t.Errorf("Calibration = %+v, want setting %v", restored.Calibration, tt.setting)
+			}
+		})
+	}
+}
+
 func TestBrewerToRecord(t *testing.T) {
 	createdAt := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
 
@@ -718,3 +934,107 @@ func TestTemperatureConversion(t *testing.T) {
 		})
 	}
 }
+
+func TestSettingsRoundTrip(t *testing.T) {
+	updatedAt := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+	original := &models.Settings{
+		PrivateMode: true,
+		DevMode:     true,
+		ViewMode:    models.ViewModePosts,
+		UpdatedAt:   updatedAt,
+	}
+
+	record, err := SettingsToRecord(original)
+	if err != nil {
+		t.Fatalf("SettingsToRecord() error = %v", err)
+	}
+	if record["$type"] != NSIDSettings {
+		t.Errorf("$type = %v, want %v", record["$type"], NSIDSettings)
+	}
+
+	restored, err := RecordToSettings(record)
+	if err != nil {
+		t.Fatalf("RecordToSettings() error = %v", err)
+	}
+	if *restored != *original {
+		t.Errorf("RecordToSettings() = %+v, want %+v", restored, original)
+	}
+}
+
+func TestRecordToSettingsDefaults(t *testing.T) {
+	settings, err := RecordToSettings(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("RecordToSettings() error = %v", err)
+	}
+	if *settings != *models.DefaultSettings() {
+		t.Errorf("RecordToSettings(empty) = %+v, want defaults", settings)
+	}
+
+	if _, err := RecordToSettings(map[string]interface{}{"updatedAt": "yesterday"}); err == nil {
+		t.Error("RecordToSettings() should reject an invalid updatedAt")
+	}
+}
+
+func TestGraphRoundTrip(t *testing.T) {
+	original := &models.Graph{
+		Mutes:     []string{"did:plc:alice"},
+		Blocks:    []string{"did:plc:bob", "did:web:example.com"},
+		UpdatedAt: time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC),
+	}
+
+	record, err := GraphToRecord(original)
+	if err != nil {
+		t.Fatalf("GraphToRecord() error = %v", err)
+	}
+	if record["$type"] != NSIDGraph {
+		t.Errorf("$type = %v, want %v", record["$type"], NSIDGraph)
+	}
+
+	// Records come back from the PDS as decoded JSON
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	restored, err := RecordToGraph(decoded)
+	if err != nil {
+		t.Fatalf("RecordToGraph() error = %v", err)
+	}
+	if !reflect.DeepEqual(restored, original) {
+		t.Errorf("RecordToGraph() = %+v, want %+v", restored, original)
+	}
+
+	empty, err := GraphToRecord(&models.Graph{})
+	if err != nil {
+		t.Fatalf("GraphToRecord() error = %v", err)
+	}
+	if data, _ := json.Marshal(empty["mutes"]); string(data) != "[]" {
+		t.Errorf("empty mutes encoded as %s, want []", data)
+	}
+}
+
+func TestIsRecordNotFound(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"public client sentinel", fmt.Errorf("fetching settings: %w", ErrRecordNotFound), true},
+		{"api error", fmt.Errorf("failed to get record: %w", &atclient.APIError{StatusCode: 400, Name: "RecordNotFound"}), true},
+		{"other api error", &atclient.APIError{StatusCode: 400, Name: "InvalidRequest"}, false},
+		{"other error", errors.New("connection refused"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRecordNotFound(tt.err); got != tt.want {
+				t.Errorf("IsRecordNotFound() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package atproto
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// scopes are the permissions requested at login: write access to each Arabica
+// collection and nothing else. Repo scopes can't use partial wildcards like
+// social.arabica.alpha.*, so every collection is listed; adding a collection
+// here makes existing sessions ask for a new login (see MissingScopes).
+// Arabica stores no blobs, so no blob scope is needed. Reading other
+// collections, such as Bluesky blocks, goes through public endpoints.
+var scopes = []string{
+	"atproto",
+	"repo:" + NSIDBean,
+	"repo:" + NSIDBrew,
+	"repo:" + NSIDBrewer,
+	"repo:" + NSIDGraph,
+	"repo:" + NSIDGrinder,
+	"repo:" + NSIDRoaster,
+	"repo:" + NSIDSettings,
+}
+
+// Scopes returns the OAuth scopes Arabica requests, in order
+func Scopes() []string {
+	return slices.Clone(scopes)
+}
+
+// MissingScopes returns the requested scopes a session granted doesn't cover,
+// e.g. because collections were added since the user logged in. Such sessions
+// work until they touch a new collection, so users should log in again.
+func MissingScopes(granted []string) []string {
+	var missing []string
+	for _, want := range scopes {
+		if !slices.ContainsFunc(granted, func(g string) bool { return scopeCovers(g, want) }) {
+			missing = append(missing, want)
+		}
+	}
+	return missing
+}
+
+// scopeCovers reports whether the granted scope allows everything the wanted
+// one does. Only the forms Arabica requests are compared in detail.
+func scopeCovers(granted, want string) bool {
+	if granted == want {
+		return true
+	}
+	collection, ok := strings.CutPrefix(want, "repo:")
+	if !ok {
+		return false
+	}
+	// The transitional scope predates granular permissions and allows writing
+	// to any collection
+	if granted == "transition:generic" {
+		return true
+	}
+	collections, allActions, ok := parseRepoScope(granted)
+	return ok && allActions && (slices.Contains(collections, collection) || slices.Contains(collections, "*"))
+}
+
+// parseRepoScope reads a repo scope in either the "repo:<collection>" or the
+// "repo?collection=..." form, reporting whether it allows every action
+func parseRepoScope(scope string) (collections []string, allActions bool, ok bool) {
+	rest, ok := strings.CutPrefix(scope, "repo")
+	if !ok || rest == "" {
+		return nil, false, false
+	}
+	var query string
+	switch rest[0] {
+	case ':':
+		var collection string
+		collection, query, _ = strings.Cut(rest[1:], "?")
+		collections = []string{collection}
+	case '?':
+		query = rest[1:]
+	default:
+		return nil, false, false
+	}
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, false, false
+	}
+	collections = append(collections, params["collection"]...)
+	actions := params["action"]
+	allActions = len(actions) == 0 ||
+		(slices.Contains(actions, "create") && slices.Contains(actions, "update") && slices.Contains(actions, "delete"))
+	return collections, allActions, len(collections) > 0
+}
@@ -0,0 +1,42 @@
+package atproto
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMissingScopes(t *testing.T) {
+	beforeSettings := []string{
+		"atproto",
+		"repo:" + NSIDBean,
+		"repo:" + NSIDBrew,
+		"repo:" + NSIDBrewer,
+		"repo:" + NSIDGraph,
+		"repo:" + NSIDGrinder,
+		"repo:" + NSIDRoaster,
+	}
+
+	tests := []struct {
+		name    string
+		granted []string
+		want    []string
+	}{
+		{"all requested", Scopes(), nil},
+		{"collection added since login", beforeSettings, []string{"repo:" + NSIDSettings}},
+		{"transitional", []string{"atproto", "transition:generic"}, nil},
+		{"repo wildcard", []string{"atproto", "repo:*"}, nil},
+		{"query form", append(beforeSettings, "repo?collection="+NSIDSettings), nil},
+		{"create only", append(beforeSettings, "repo:"+NSIDSettings+"?action=create"), []string{"repo:" + NSIDSettings}},
+		{"all actions", append(beforeSettings, "repo:"+NSIDSettings+"?action=create&action=update&action=delete"), nil},
+		{"no atproto", Scopes()[1:], []string{"atproto"}},
+		{"nothing", nil, Scopes()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MissingScopes(tt.granted); !slices.Equal(got, tt.want) {
+				t.Errorf("MissingScopes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
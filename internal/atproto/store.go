@@ -3,13 +3,18 @@ package atproto
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"arabica/internal/database"
+	"arabica/internal/metrics"
 	"arabica/internal/models"
+	"arabica/internal/tracing"
 
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // AtprotoStore implements the database.Store interface using atproto records.
@@ -33,9 +38,19 @@ func NewAtprotoStore(client *Client, did syntax.DID, sessionID string, cache *Se
 	}
 }
 
+// recordCacheLookup counts a session cache read and notes the result on the
+// current span, so traces show which store calls skipped the PDS
+func recordCacheLookup(ctx context.Context, collection string, hit bool) {
+	metrics.ObserveCacheLookup(collection, hit)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("cache.hit", hit))
+}
+
 // ========== Brew Operations ==========
 
 func (s *AtprotoStore) CreateBrew(ctx context.Context, brew *models.CreateBrewRequest, userID int) (*models.Brew, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.CreateBrew")
+	defer span.End()
+
 	// Build AT-URI references from rkeys
 	if brew.BeanRKey == "" {
 		return nil, fmt.Errorf("bean_rkey is required")
@@ -65,6 +80,9 @@ func (s *AtprotoStore) CreateBrew(ctx context.Context, brew *models.CreateBrewRe
 		TastingNotes: brew.TastingNotes,
 		Rating:       brew.Rating,
 		CreatedAt:    time.Now(),
+
+		TDS:            brew.TDS,
+		BeverageWeight: brew.BeverageWeight,
 	}
 
 	// Convert pours
@@ -117,6 +135,9 @@ func (s *AtprotoStore) CreateBrew(ctx context.Context, brew *models.CreateBrewRe
 }
 
 func (s *AtprotoStore) GetBrewByRKey(ctx context.Context, rkey string) (*models.Brew, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.GetBrewByRKey")
+	defer span.End()
+
 	output, err := s.client.GetRecord(ctx, s.did, s.sessionID, &GetRecordInput{
 		Collection: NSIDBrew,
 		RKey:       rkey,
@@ -168,11 +189,16 @@ func (s *AtprotoStore) GetBrewByRKey(ctx context.Context, rkey string) (*models.
 }
 
 func (s *AtprotoStore) ListBrews(ctx context.Context, userID int) ([]*models.Brew, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.ListBrews")
+	defer span.End()
+
 	// Check cache first
 	userCache := s.cache.Get(s.sessionID)
-	if userCache != nil && userCache.Brews != nil && userCache.IsValid() {
+	if userCache != nil && userCache.Brews != nil && userCache.IsValidFor(s.cache.TTL()) {
+		recordCacheLookup(ctx, "brews", true)
 		return userCache.Brews, nil
 	}
+	recordCacheLookup(ctx, "brews", false)
 
 	// Use ListAllRecords to handle pagination automatically
 	output, err := s.client.ListAllRecords(ctx, s.did, s.sessionID, NSIDBrew)
@@ -268,6 +294,9 @@ func (s *AtprotoStore) ListBrews(ctx context.Context, userID int) ([]*models.Bre
 }
 
 func (s *AtprotoStore) UpdateBrewByRKey(ctx context.Context, rkey string, brew *models.CreateBrewRequest) error {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.UpdateBrewByRKey")
+	defer span.End()
+
 	// Build AT-URI references from rkeys
 	if brew.BeanRKey == "" {
 		return fmt.Errorf("bean_rkey is required")
@@ -303,6 +332,9 @@ func (s *AtprotoStore) UpdateBrewByRKey(ctx context.Context, rkey string, brew *
 		TastingNotes: brew.TastingNotes,
 		Rating:       brew.Rating,
 		CreatedAt:    existing.CreatedAt, // Preserve original creation time
+
+		TDS:            brew.TDS,
+		BeverageWeight: brew.BeverageWeight,
 	}
 
 	// Convert pours
@@ -339,6 +371,9 @@ func (s *AtprotoStore) UpdateBrewByRKey(ctx context.Context, rkey string, brew *
 }
 
 func (s *AtprotoStore) DeleteBrewByRKey(ctx context.Context, rkey string) error {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.DeleteBrewByRKey")
+	defer span.End()
+
 	err := s.client.DeleteRecord(ctx, s.did, s.sessionID, &DeleteRecordInput{
 		Collection: NSIDBrew,
 		RKey:       rkey,
@@ -356,6 +391,9 @@ func (s *AtprotoStore) DeleteBrewByRKey(ctx context.Context, rkey string) error
 // ========== Bean Operations ==========
 
 func (s *AtprotoStore) CreateBean(ctx context.Context, bean *models.CreateBeanRequest) (*models.Bean, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.CreateBean")
+	defer span.End()
+
 	var roasterURI string
 	if bean.RoasterRKey != "" {
 		roasterURI = BuildATURI(s.did.String(), NSIDRoaster, bean.RoasterRKey)
@@ -400,6 +438,9 @@ func (s *AtprotoStore) CreateBean(ctx context.Context, bean *models.CreateBeanRe
 }
 
 func (s *AtprotoStore) GetBeanByRKey(ctx context.Context, rkey string) (*models.Bean, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.GetBeanByRKey")
+	defer span.End()
+
 	output, err := s.client.GetRecord(ctx, s.did, s.sessionID, &GetRecordInput{
 		Collection: NSIDBean,
 		RKey:       rkey,
@@ -435,11 +476,16 @@ func (s *AtprotoStore) GetBeanByRKey(ctx context.Context, rkey string) (*models.
 }
 
 func (s *AtprotoStore) ListBeans(ctx context.Context) ([]*models.Bean, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.ListBeans")
+	defer span.End()
+
 	// Check cache first
 	userCache := s.cache.Get(s.sessionID)
-	if userCache != nil && userCache.Beans != nil && userCache.IsValid() {
+	if userCache != nil && userCache.Beans != nil && userCache.IsValidFor(s.cache.TTL()) {
+		recordCacheLookup(ctx, "beans", true)
 		return userCache.Beans, nil
 	}
+	recordCacheLookup(ctx, "beans", false)
 
 	// Use ListAllRecords to handle pagination automatically
 	output, err := s.client.ListAllRecords(ctx, s.did, s.sessionID, NSIDBean)
@@ -496,6 +542,9 @@ func LinkBeansToRoasters(beans []*models.Bean, roasters []*models.Roaster) {
 }
 
 func (s *AtprotoStore) UpdateBeanByRKey(ctx context.Context, rkey string, bean *models.UpdateBeanRequest) error {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.UpdateBeanByRKey")
+	defer span.End()
+
 	// Get existing to preserve createdAt
 	existing, err := s.GetBeanByRKey(ctx, rkey)
 	if err != nil {
@@ -538,6 +587,9 @@ func (s *AtprotoStore) UpdateBeanByRKey(ctx context.Context, rkey string, bean *
 }
 
 func (s *AtprotoStore) DeleteBeanByRKey(ctx context.Context, rkey string) error {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.DeleteBeanByRKey")
+	defer span.End()
+
 	err := s.client.DeleteRecord(ctx, s.did, s.sessionID, &DeleteRecordInput{
 		Collection: NSIDBean,
 		RKey:       rkey,
@@ -555,6 +607,9 @@ func (s *AtprotoStore) DeleteBeanByRKey(ctx context.Context, rkey string) error
 // ========== Roaster Operations ==========
 
 func (s *AtprotoStore) CreateRoaster(ctx context.Context, roaster *models.CreateRoasterRequest) (*models.Roaster, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.CreateRoaster")
+	defer span.End()
+
 	roasterModel := &models.Roaster{
 		Name:      roaster.Name,
 		Location:  roaster.Location,
@@ -591,6 +646,9 @@ func (s *AtprotoStore) CreateRoaster(ctx context.Context, roaster *models.Create
 }
 
 func (s *AtprotoStore) GetRoasterByRKey(ctx context.Context, rkey string) (*models.Roaster, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.GetRoasterByRKey")
+	defer span.End()
+
 	output, err := s.client.GetRecord(ctx, s.did, s.sessionID, &GetRecordInput{
 		Collection: NSIDRoaster,
 		RKey:       rkey,
@@ -611,11 +669,16 @@ func (s *AtprotoStore) GetRoasterByRKey(ctx context.Context, rkey string) (*mode
 }
 
 func (s *AtprotoStore) ListRoasters(ctx context.Context) ([]*models.Roaster, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.ListRoasters")
+	defer span.End()
+
 	// Check cache first
 	userCache := s.cache.Get(s.sessionID)
-	if userCache != nil && userCache.Roasters != nil && userCache.IsValid() {
+	if userCache != nil && userCache.Roasters != nil && userCache.IsValidFor(s.cache.TTL()) {
+		recordCacheLookup(ctx, "roasters", true)
 		return userCache.Roasters, nil
 	}
+	recordCacheLookup(ctx, "roasters", false)
 
 	// Use ListAllRecords to handle pagination automatically
 	output, err := s.client.ListAllRecords(ctx, s.did, s.sessionID, NSIDRoaster)
@@ -647,6 +710,9 @@ func (s *AtprotoStore) ListRoasters(ctx context.Context) ([]*models.Roaster, err
 }
 
 func (s *AtprotoStore) UpdateRoasterByRKey(ctx context.Context, rkey string, roaster *models.UpdateRoasterRequest) error {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.UpdateRoasterByRKey")
+	defer span.End()
+
 	// Get existing to preserve createdAt
 	existing, err := s.GetRoasterByRKey(ctx, rkey)
 	if err != nil {
@@ -681,6 +747,9 @@ func (s *AtprotoStore) UpdateRoasterByRKey(ctx context.Context, rkey string, roa
 }
 
 func (s *AtprotoStore) DeleteRoasterByRKey(ctx context.Context, rkey string) error {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.DeleteRoasterByRKey")
+	defer span.End()
+
 	err := s.client.DeleteRecord(ctx, s.did, s.sessionID, &DeleteRecordInput{
 		Collection: NSIDRoaster,
 		RKey:       rkey,
@@ -698,12 +767,17 @@ func (s *AtprotoStore) DeleteRoasterByRKey(ctx context.Context, rkey string) err
 // ========== Grinder Operations ==========
 
 func (s *AtprotoStore) CreateGrinder(ctx context.Context, grinder *models.CreateGrinderRequest) (*models.Grinder, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.CreateGrinder")
+	defer span.End()
+
 	grinderModel := &models.Grinder{
 		Name:        grinder.Name,
 		GrinderType: grinder.GrinderType,
 		BurrType:    grinder.BurrType,
 		Notes:       grinder.Notes,
 		CreatedAt:   time.Now(),
+		Scale:       grinder.Scale,
+		Calibration: grinder.Calibration,
 	}
 
 	record, err := GrinderToRecord(grinderModel)
@@ -735,6 +809,9 @@ func (s *AtprotoStore) CreateGrinder(ctx context.Context, grinder *models.Create
 }
 
 func (s *AtprotoStore) GetGrinderByRKey(ctx context.Context, rkey string) (*models.Grinder, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.GetGrinderByRKey")
+	defer span.End()
+
 	output, err := s.client.GetRecord(ctx, s.did, s.sessionID, &GetRecordInput{
 		Collection: NSIDGrinder,
 		RKey:       rkey,
@@ -755,11 +832,16 @@ func (s *AtprotoStore) GetGrinderByRKey(ctx context.Context, rkey string) (*mode
 }
 
 func (s *AtprotoStore) ListGrinders(ctx context.Context) ([]*models.Grinder, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.ListGrinders")
+	defer span.End()
+
 	// Check cache first
 	userCache := s.cache.Get(s.sessionID)
-	if userCache != nil && userCache.Grinders != nil && userCache.IsValid() {
+	if userCache != nil && userCache.Grinders != nil && userCache.IsValidFor(s.cache.TTL()) {
+		recordCacheLookup(ctx, "grinders", true)
 		return userCache.Grinders, nil
 	}
+	recordCacheLookup(ctx, "grinders", false)
 
 	// Use ListAllRecords to handle pagination automatically
 	output, err := s.client.ListAllRecords(ctx, s.did, s.sessionID, NSIDGrinder)
@@ -791,6 +873,9 @@ func (s *AtprotoStore) ListGrinders(ctx context.Context) ([]*models.Grinder, err
 }
 
 func (s *AtprotoStore) UpdateGrinderByRKey(ctx context.Context, rkey string, grinder *models.UpdateGrinderRequest) error {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.UpdateGrinderByRKey")
+	defer span.End()
+
 	// Get existing to preserve createdAt
 	existing, err := s.GetGrinderByRKey(ctx, rkey)
 	if err != nil {
@@ -803,6 +888,8 @@ func (s *AtprotoStore) UpdateGrinderByRKey(ctx context.Context, rkey string, gri
 		BurrType:    grinder.BurrType,
 		Notes:       grinder.Notes,
 		CreatedAt:   existing.CreatedAt,
+		Scale:       grinder.Scale,
+		Calibration: grinder.Calibration,
 	}
 
 	record, err := GrinderToRecord(grinderModel)
@@ -826,6 +913,9 @@ func (s *AtprotoStore) UpdateGrinderByRKey(ctx context.Context, rkey string, gri
 }
 
 func (s *AtprotoStore) DeleteGrinderByRKey(ctx context.Context, rkey string) error {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.DeleteGrinderByRKey")
+	defer span.End()
+
 	err := s.client.DeleteRecord(ctx, s.did, s.sessionID, &DeleteRecordInput{
 		Collection: NSIDGrinder,
 		RKey:       rkey,
@@ -843,6 +933,9 @@ func (s *AtprotoStore) DeleteGrinderByRKey(ctx context.Context, rkey string) err
 // ========== Brewer Operations ==========
 
 func (s *AtprotoStore) CreateBrewer(ctx context.Context, brewer *models.CreateBrewerRequest) (*models.Brewer, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.CreateBrewer")
+	defer span.End()
+
 	brewerModel := &models.Brewer{
 		Name:        brewer.Name,
 		BrewerType:  brewer.BrewerType,
@@ -879,6 +972,9 @@ func (s *AtprotoStore) CreateBrewer(ctx context.Context, brewer *models.CreateBr
 }
 
 func (s *AtprotoStore) GetBrewerByRKey(ctx context.Context, rkey string) (*models.Brewer, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.GetBrewerByRKey")
+	defer span.End()
+
 	output, err := s.client.GetRecord(ctx, s.did, s.sessionID, &GetRecordInput{
 		Collection: NSIDBrewer,
 		RKey:       rkey,
@@ -899,11 +995,16 @@ func (s *AtprotoStore) GetBrewerByRKey(ctx context.Context, rkey string) (*model
 }
 
 func (s *AtprotoStore) ListBrewers(ctx context.Context) ([]*models.Brewer, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.ListBrewers")
+	defer span.End()
+
 	// Check cache first
 	userCache := s.cache.Get(s.sessionID)
-	if userCache != nil && userCache.Brewers != nil && userCache.IsValid() {
+	if userCache != nil && userCache.Brewers != nil && userCache.IsValidFor(s.cache.TTL()) {
+		recordCacheLookup(ctx, "brewers", true)
 		return userCache.Brewers, nil
 	}
+	recordCacheLookup(ctx, "brewers", false)
 
 	// Use ListAllRecords to handle pagination automatically
 	output, err := s.client.ListAllRecords(ctx, s.did, s.sessionID, NSIDBrewer)
@@ -935,6 +1036,9 @@ func (s *AtprotoStore) ListBrewers(ctx context.Context) ([]*models.Brewer, error
 }
 
 func (s *AtprotoStore) UpdateBrewerByRKey(ctx context.Context, rkey string, brewer *models.UpdateBrewerRequest) error {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.UpdateBrewerByRKey")
+	defer span.End()
+
 	// Get existing to preserve createdAt
 	existing, err := s.GetBrewerByRKey(ctx, rkey)
 	if err != nil {
@@ -969,6 +1073,9 @@ func (s *AtprotoStore) UpdateBrewerByRKey(ctx context.Context, rkey string, brew
 }
 
 func (s *AtprotoStore) DeleteBrewerByRKey(ctx context.Context, rkey string) error {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.DeleteBrewerByRKey")
+	defer span.End()
+
 	err := s.client.DeleteRecord(ctx, s.did, s.sessionID, &DeleteRecordInput{
 		Collection: NSIDBrewer,
 		RKey:       rkey,
@@ -983,6 +1090,161 @@ func (s *AtprotoStore) DeleteBrewerByRKey(ctx context.Context, rkey string) erro
 	return nil
 }
 
+// GetSettings returns the user's settings record, or the defaults if they
+// have never saved one
+func (s *AtprotoStore) GetSettings(ctx context.Context) (*models.Settings, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.GetSettings")
+	defer span.End()
+
+	// Check cache first
+	userCache := s.cache.Get(s.sessionID)
+	if userCache != nil && userCache.Settings != nil && userCache.IsValidFor(s.cache.TTL()) {
+		recordCacheLookup(ctx, "settings", true)
+		return userCache.Settings, nil
+	}
+	recordCacheLookup(ctx, "settings", false)
+
+	output, err := s.client.GetRecord(ctx, s.did, s.sessionID, &GetRecordInput{
+		Collection: NSIDSettings,
+		RKey:       SettingsRKey,
+	})
+
+	var settings *models.Settings
+	switch {
+	case IsRecordNotFound(err):
+		settings = models.DefaultSettings()
+	case err != nil:
+		return nil, fmt.Errorf("failed to get settings record: %w", err)
+	default:
+		settings, err = RecordToSettings(output.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert settings record: %w", err)
+		}
+	}
+
+	s.cache.SetSettings(s.sessionID, settings)
+
+	return settings, nil
+}
+
+// UpdateSettings writes the user's settings record, creating it if needed
+func (s *AtprotoStore) UpdateSettings(ctx context.Context, settings *models.Settings) error {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.UpdateSettings")
+	defer span.End()
+
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	updated := *settings
+	updated.UpdatedAt = time.Now()
+
+	record, err := SettingsToRecord(&updated)
+	if err != nil {
+		return fmt.Errorf("failed to convert settings to record: %w", err)
+	}
+
+	err = s.client.PutRecord(ctx, s.did, s.sessionID, &PutRecordInput{
+		Collection: NSIDSettings,
+		RKey:       SettingsRKey,
+		Record:     record,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update settings record: %w", err)
+	}
+
+	s.cache.SetSettings(s.sessionID, &updated)
+
+	return nil
+}
+
+// GetGraph returns the accounts the user muted or blocked. Users who have
+// never saved the lists get empty ones.
+func (s *AtprotoStore) GetGraph(ctx context.Context) (*models.Graph, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.GetGraph")
+	defer span.End()
+
+	// Check cache first
+	userCache := s.cache.Get(s.sessionID)
+	if userCache != nil && userCache.Graph != nil && userCache.IsValidFor(s.cache.TTL()) {
+		recordCacheLookup(ctx, "graph", true)
+		return userCache.Graph, nil
+	}
+	recordCacheLookup(ctx, "graph", false)
+
+	output, err := s.client.GetRecord(ctx, s.did, s.sessionID, &GetRecordInput{
+		Collection: NSIDGraph,
+		RKey:       GraphRKey,
+	})
+
+	var graph *models.Graph
+	switch {
+	case IsRecordNotFound(err):
+		graph = &models.Graph{}
+	case err != nil:
+		return nil, fmt.Errorf("failed to get graph record: %w", err)
+	default:
+		graph, err = RecordToGraph(output.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert graph record: %w", err)
+		}
+	}
+
+	s.cache.SetGraph(s.sessionID, graph)
+
+	return graph, nil
+}
+
+// UpdateGraph writes the user's mute and block lists, creating the record if needed
+func (s *AtprotoStore) UpdateGraph(ctx context.Context, graph *models.Graph) error {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.UpdateGraph")
+	defer span.End()
+
+	if err := graph.Validate(); err != nil {
+		return err
+	}
+
+	updated := *graph
+	updated.UpdatedAt = time.Now()
+
+	record, err := GraphToRecord(&updated)
+	if err != nil {
+		return fmt.Errorf("failed to convert graph to record: %w", err)
+	}
+
+	err = s.client.PutRecord(ctx, s.did, s.sessionID, &PutRecordInput{
+		Collection: NSIDGraph,
+		RKey:       GraphRKey,
+		Record:     record,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update graph record: %w", err)
+	}
+
+	s.cache.SetGraph(s.sessionID, &updated)
+
+	return nil
+}
+
+// ListBlueskyBlocks returns the DIDs of the accounts the user blocked on Bluesky
+func (s *AtprotoStore) ListBlueskyBlocks(ctx context.Context) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "AtprotoStore.ListBlueskyBlocks")
+	defer span.End()
+
+	output, err := s.client.ListAllRecords(ctx, s.did, s.sessionID, NSIDBlueskyBlock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Bluesky blocks: %w", err)
+	}
+
+	dids := make([]string, 0, len(output.Records))
+	for _, record := range output.Records {
+		if subject, ok := record.Value["subject"].(string); ok && strings.HasPrefix(subject, "did:") {
+			dids = append(dids, subject)
+		}
+	}
+	return dids, nil
+}
+
 func (s *AtprotoStore) Close() error {
 	// No persistent connection to close for atproto
 	return nil
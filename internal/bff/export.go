@@ -0,0 +1,58 @@
+package bff
+
+import (
+	"encoding/json"
+
+	"arabica/internal/models"
+)
+
+// BrewExport is the brew export file. Measurements are converted to the
+// units listed in Units so the file matches what the user sees in the app.
+type BrewExport struct {
+	Units models.UnitPreferences `json:"units"`
+	Brews []*ExportedBrew        `json:"brews"`
+}
+
+// ExportedBrew is a brew with its measurements in the export's units
+type ExportedBrew struct {
+	*models.Brew
+	Temperature    json.Number     `json:"temperature"`
+	WaterAmount    json.Number     `json:"water_amount"`
+	CoffeeAmount   json.Number     `json:"coffee_amount"`
+	BeverageWeight json.Number     `json:"beverage_weight"`
+	Pours          []*ExportedPour `json:"pours,omitempty"`
+}
+
+// ExportedPour is a pour with its water in the export's volume unit
+type ExportedPour struct {
+	*models.Pour
+	WaterAmount json.Number `json:"water_amount"`
+}
+
+// NewBrewExport converts brews to the given units for export
+func NewBrewExport(brews []*models.Brew, units models.UnitPreferences) *BrewExport {
+	units = units.Normalize()
+	export := &BrewExport{
+		Units: units,
+		Brews: make([]*ExportedBrew, len(brews)),
+	}
+
+	for i, brew := range brews {
+		exported := &ExportedBrew{
+			Brew:           brew,
+			Temperature:    json.Number(units.TemperatureInput(brew.Temperature)),
+			WaterAmount:    json.Number(units.VolumeInput(brew.WaterAmount)),
+			CoffeeAmount:   json.Number(units.WeightInput(brew.CoffeeAmount)),
+			BeverageWeight: json.Number(units.WeightInput(brew.BeverageWeight)),
+		}
+		for _, pour := range brew.Pours {
+			exported.Pours = append(exported.Pours, &ExportedPour{
+				Pour:        pour,
+				WaterAmount: json.Number(units.VolumeInput(pour.WaterAmount)),
+			})
+		}
+		export.Brews[i] = exported
+	}
+
+	return export
+}
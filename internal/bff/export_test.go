@@ -0,0 +1,73 @@
+package bff
+
+import (
+	"encoding/json"
+	"testing"
+
+	"arabica/internal/models"
+)
+
+func TestNewBrewExport(t *testing.T) {
+	brews := []*models.Brew{
+		{
+			RKey:         "abc",
+			Temperature:  93.5,
+			CoffeeAmount: 18,
+			WaterAmount:  300,
+			Pours:        []*models.Pour{{PourNumber: 1, WaterAmount: 50, TimeSeconds: 30}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		units models.UnitPreferences
+		want  map[string]any
+	}{
+		{
+			name:  "zero value exports metric",
+			units: models.UnitPreferences{},
+			want:  map[string]any{"temperature": 93.5, "coffee_amount": 18.0, "water_amount": 300.0, "pour": 50.0, "unit": "C"},
+		},
+		{
+			name:  "imperial",
+			units: models.ImperialUnits(),
+			want:  map[string]any{"temperature": 200.3, "coffee_amount": 0.63, "water_amount": 10.14, "pour": 1.69, "unit": "F"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(NewBrewExport(brews, tt.units))
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+
+			var got struct {
+				Units struct {
+					Temperature string `json:"temperature"`
+				} `json:"units"`
+				Brews []map[string]any `json:"brews"`
+			}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+
+			if got.Units.Temperature != tt.want["unit"] {
+				t.Errorf("units.temperature = %q, want %q", got.Units.Temperature, tt.want["unit"])
+			}
+			brew := got.Brews[0]
+			for _, field := range []string{"temperature", "coffee_amount", "water_amount"} {
+				if brew[field] != tt.want[field] {
+					t.Errorf("%s = %v, want %v", field, brew[field], tt.want[field])
+				}
+			}
+			pour := brew["pours"].([]any)[0].(map[string]any)
+			if pour["water_amount"] != tt.want["pour"] {
+				t.Errorf("pour water_amount = %v, want %v", pour["water_amount"], tt.want["pour"])
+			}
+			if brew["rkey"] != "abc" {
+				t.Errorf("rkey = %v, want abc", brew["rkey"])
+			}
+		})
+	}
+}
@@ -5,26 +5,19 @@ package bff
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"arabica/internal/models"
 )
 
-// FormatTemp formats a temperature value with unit detection.
-// Returns "N/A" if temp is 0, otherwise determines C/F based on >100 threshold.
+// FormatTemp formats a canonical °C temperature value.
+// Returns "N/A" if temp is 0. Use models.UnitPreferences.FormatTemperature
+// to show a temperature in the viewer's preferred unit.
 func FormatTemp(temp float64) string {
-	if temp == 0 {
-		return "N/A"
-	}
-
-	// REFACTOR: This probably isn't the best way to deal with units
-	unit := 'C'
-	if temp > 100 {
-		unit = 'F'
-	}
-
-	return fmt.Sprintf("%.1f°%c", temp, unit)
+	return models.MetricUnits().FormatTemperature(temp)
 }
 
 // FormatTempValue formats a temperature for use in input fields (numeric value only).
@@ -58,6 +51,33 @@ func FormatRating(rating int) string {
 	return fmt.Sprintf("%d/10", rating)
 }
 
+// FormatRatio formats a water-to-coffee ratio as "1:X" (e.g., "1:16.7").
+// Returns "N/A" if ratio is 0.
+func FormatRatio(ratio float64) string {
+	if ratio == 0 {
+		return "N/A"
+	}
+	return "1:" + strconv.FormatFloat(math.Round(ratio*10)/10, 'f', -1, 64)
+}
+
+// FormatTDS formats a total dissolved solids reading (e.g., "1.35%").
+// Returns "N/A" if tds is 0.
+func FormatTDS(tds float64) string {
+	if tds == 0 {
+		return "N/A"
+	}
+	return fmt.Sprintf("%.2f%%", tds)
+}
+
+// FormatExtraction formats an extraction yield percentage (e.g., "20.3%").
+// Returns "N/A" if yield is 0.
+func FormatExtraction(yield float64) string {
+	if yield == 0 {
+		return "N/A"
+	}
+	return fmt.Sprintf("%.1f%%", yield)
+}
+
 // FormatID converts an int to string.
 func FormatID(id int) string {
 	return fmt.Sprintf("%d", id)
@@ -78,20 +98,21 @@ func FormatRoasterID(id *int) string {
 }
 
 // PoursToJSON serializes a slice of pours to JSON for use in JavaScript.
-func PoursToJSON(pours []*models.Pour) string {
+// Water amounts are converted to the preferred volume unit for the form.
+func PoursToJSON(pours []*models.Pour, units models.UnitPreferences) string {
 	if len(pours) == 0 {
 		return "[]"
 	}
 
 	type pourData struct {
-		Water int `json:"water"`
-		Time  int `json:"time"`
+		Water json.Number `json:"water"`
+		Time  int         `json:"time"`
 	}
 
 	data := make([]pourData, len(pours))
 	for i, p := range pours {
 		data[i] = pourData{
-			Water: p.WaterAmount,
+			Water: json.Number(units.VolumeInput(p.WaterAmount)),
 			Time:  p.TimeSeconds,
 		}
 	}
@@ -104,6 +125,45 @@ func PoursToJSON(pours []*models.Pour) string {
 	return string(jsonBytes)
 }
 
+// GrinderSettingsJSON serializes a grinder's scale and calibration as an object
+// literal for the manage page editor. Returns "{}" if the grinder has neither.
+func GrinderSettingsJSON(grinder *models.Grinder) string {
+	data := struct {
+		Scale       *models.GrindScale        `json:"scale,omitempty"`
+		Calibration []models.CalibrationPoint `json:"calibration,omitempty"`
+	}{
+		Scale:       grinder.Scale,
+		Calibration: grinder.Calibration,
+	}
+
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		return "{}"
+	}
+
+	return string(jsonBytes)
+}
+
+// maxGrindOptions caps how many settings are suggested for a single grinder.
+const maxGrindOptions = 200
+
+// GrindOptions lists every setting on a grind scale for use in a datalist.
+// Returns nil if the scale is missing or has too many steps to be useful.
+func GrindOptions(scale *models.GrindScale) []string {
+	if scale == nil || scale.Step <= 0 {
+		return nil
+	}
+	count := int(math.Round((scale.Max-scale.Min)/scale.Step)) + 1
+	if count <= 0 || count > maxGrindOptions {
+		return nil
+	}
+	options := make([]string, count)
+	for i := range options {
+		options[i] = models.FormatGrindSetting(scale.Min + float64(i)*scale.Step)
+	}
+	return options
+}
+
 // Ptr returns a pointer to the given value.
 func Ptr[T any](v T) *T {
 	return &v
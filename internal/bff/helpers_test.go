@@ -16,10 +16,8 @@ func TestFormatTemp(t *testing.T) {
 		{"celsius range", 93.5, "93.5°C"},
 		{"celsius whole number", 90.0, "90.0°C"},
 		{"celsius at 100", 100.0, "100.0°C"},
-		{"fahrenheit range", 200.0, "200.0°F"},
-		{"fahrenheit at 212", 212.0, "212.0°F"},
 		{"low temp celsius", 20.5, "20.5°C"},
-		{"just over 100 is fahrenheit", 100.1, "100.1°F"},
+		{"over 100 is still celsius", 100.1, "100.1°C"},
 	}
 
 	for _, tt := range tests {
@@ -101,6 +99,70 @@ func TestFormatRating(t *testing.T) {
 	}
 }
 
+func TestFormatRatio(t *testing.T) {
+	tests := []struct {
+		name     string
+		ratio    float64
+		expected string
+	}{
+		{"zero returns N/A", 0, "N/A"},
+		{"whole ratio", 16, "1:16"},
+		{"rounded to one decimal", 250.0 / 15.0, "1:16.7"},
+		{"espresso", 2, "1:2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatRatio(tt.ratio)
+			if got != tt.expected {
+				t.Errorf("FormatRatio(%v) = %q, want %q", tt.ratio, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatTDS(t *testing.T) {
+	tests := []struct {
+		name     string
+		tds      float64
+		expected string
+	}{
+		{"zero returns N/A", 0, "N/A"},
+		{"filter", 1.35, "1.35%"},
+		{"espresso", 9.5, "9.50%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatTDS(tt.tds)
+			if got != tt.expected {
+				t.Errorf("FormatTDS(%v) = %q, want %q", tt.tds, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatExtraction(t *testing.T) {
+	tests := []struct {
+		name     string
+		yield    float64
+		expected string
+	}{
+		{"zero returns N/A", 0, "N/A"},
+		{"rounded to one decimal", 20.26, "20.3%"},
+		{"whole number", 19, "19.0%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatExtraction(tt.yield)
+			if got != tt.expected {
+				t.Errorf("FormatExtraction(%v) = %q, want %q", tt.yield, got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestFormatID(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -172,6 +234,7 @@ func TestPoursToJSON(t *testing.T) {
 	tests := []struct {
 		name     string
 		pours    []*models.Pour
+		units    models.UnitPreferences
 		expected string
 	}{
 		{
@@ -207,11 +270,19 @@ func TestPoursToJSON(t *testing.T) {
 			},
 			expected: `[{"water":0,"time":0}]`,
 		},
+		{
+			name: "fluid ounces",
+			pours: []*models.Pour{
+				{WaterAmount: 50, TimeSeconds: 30},
+			},
+			units:    models.ImperialUnits(),
+			expected: `[{"water":1.69,"time":30}]`,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := PoursToJSON(tt.pours)
+			got := PoursToJSON(tt.pours, tt.units)
 			if got != tt.expected {
 				t.Errorf("PoursToJSON() = %q, want %q", got, tt.expected)
 			}
@@ -219,6 +290,49 @@ func TestPoursToJSON(t *testing.T) {
 	}
 }
 
+func TestGrindOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		scale    *models.GrindScale
+		expected []string
+	}{
+		{
+			name:     "nil scale",
+			scale:    nil,
+			expected: nil,
+		},
+		{
+			name:     "whole steps",
+			scale:    &models.GrindScale{Min: 0, Max: 3, Step: 1, Unit: models.GrindUnitClicks},
+			expected: []string{"0", "1", "2", "3"},
+		},
+		{
+			name:     "half steps",
+			scale:    &models.GrindScale{Min: 1, Max: 2.5, Step: 0.5, Unit: models.GrindUnitNumbers},
+			expected: []string{"1", "1.5", "2", "2.5"},
+		},
+		{
+			name:     "too many steps",
+			scale:    &models.GrindScale{Min: 0, Max: 1000, Step: 1, Unit: models.GrindUnitMicrons},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GrindOptions(tt.scale)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("GrindOptions() = %v, want %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("GrindOptions()[%d] = %q, want %q", i, got[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
 func TestPtr(t *testing.T) {
 	t.Run("int", func(t *testing.T) {
 		p := Ptr(42)
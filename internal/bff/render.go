@@ -1,21 +1,28 @@
 package bff
 
 import (
+	"context"
 	"html/template"
+	"io"
 	"net/http"
-	"os"
+	"slices"
 	"sync"
+	"time"
 
+	"arabica/internal/admin"
 	"arabica/internal/atproto"
 	"arabica/internal/feed"
 	"arabica/internal/models"
+	"arabica/internal/search"
+	"arabica/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
 	templateFuncs template.FuncMap
 	funcsOnce     sync.Once
-	templateDir   string
-	templateDirMu sync.Once
 )
 
 // getTemplateFuncs returns the function map used by all templates
@@ -25,6 +32,9 @@ func getTemplateFuncs() template.FuncMap {
 			"formatTemp":       FormatTemp,
 			"formatTime":       FormatTime,
 			"formatRating":     FormatRating,
+			"formatRatio":      FormatRatio,
+			"formatTDS":        FormatTDS,
+			"formatExtraction": FormatExtraction,
 			"formatID":         FormatID,
 			"formatInt":        FormatInt,
 			"formatRoasterID":  FormatRoasterID,
@@ -38,75 +48,25 @@ func getTemplateFuncs() template.FuncMap {
 			"safeAvatarURL":    SafeAvatarURL,
 			"safeWebsiteURL":   SafeWebsiteURL,
 			"escapeJS":         EscapeJS,
+			"formatGrind":      models.FormatGrindSetting,
+			"grindOptions":     GrindOptions,
+			"grinderSettings":  GrinderSettingsJSON,
+			"static":           StaticURL,
 		}
 	})
 	return templateFuncs
 }
 
-// getTemplateDir finds the template directory
-func getTemplateDir() string {
-	templateDirMu.Do(func() {
-		dirs := []string{
-			"templates",
-			"../../templates",
-			"../../../templates",
-		}
-		for _, dir := range dirs {
-			if _, err := os.Stat(dir); err == nil {
-				templateDir = dir
-				return
-			}
-		}
-		templateDir = "templates" // fallback
-	})
-	return templateDir
-}
-
-// parsePageTemplate parses a complete page template with layout and partials
-func parsePageTemplate(pageName string) (*template.Template, error) {
-	dir := getTemplateDir()
-	t := template.New("").Funcs(getTemplateFuncs())
-
-	// Parse layout first
-	t, err := t.ParseFiles(dir + "/layout.tmpl")
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse all partials
-	t, err = t.ParseGlob(dir + "/partials/*.tmpl")
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse the specific page template
-	t, err = t.ParseFiles(dir + "/" + pageName)
-	if err != nil {
-		return nil, err
-	}
-
-	return t, nil
-}
-
-// parsePartialTemplate parses just the partials (for partial-only renders)
-func parsePartialTemplate() (*template.Template, error) {
-	dir := getTemplateDir()
-	t := template.New("").Funcs(getTemplateFuncs())
-
-	// Parse all partials
-	t, err := t.ParseGlob(dir + "/partials/*.tmpl")
-	if err != nil {
-		return nil, err
-	}
-
-	return t, nil
-}
-
 // UserProfile contains user profile data for header display
 type UserProfile struct {
 	Handle      string
 	DisplayName string
 	Avatar      string
+	// NeedsPermissions is set when the session was granted fewer scopes than
+	// Arabica now asks for, to prompt the user to log in again
+	NeedsPermissions bool
+	// Accounts are the other accounts signed in in this browser
+	Accounts []atproto.Account
 }
 
 // PageData contains data for rendering pages
@@ -122,6 +82,18 @@ type PageData struct {
 	IsAuthenticated bool
 	UserDID         string
 	UserProfile     *UserProfile
+
+	// Units the viewer sees brew measurements in
+	Units models.UnitPreferences
+
+	// DevMode shows DIDs and other protocol details
+	DevMode bool
+
+	// ViewMode selects post cards or the legacy table for brews and the feed
+	ViewMode string
+
+	// Encoded brew list query, carried from the page URL into the HTMX request
+	BrewQuery string
 }
 
 // BrewData wraps a brew with pre-serialized JSON for pours
@@ -138,13 +110,182 @@ type BrewListData struct {
 	RatingFormatted string
 }
 
+// GrindHintsData contains grind size hints for the grinder selected in the brew form
+type GrindHintsData struct {
+	Grinder     *models.Grinder
+	Error       string
+	Microns     int // Estimated particle size, 0 when unknown
+	Equivalents []GrindEquivalent
+}
+
+// GrindEquivalent is the closest setting on another calibrated grinder
+type GrindEquivalent struct {
+	Grinder *models.Grinder
+	Setting string
+}
+
+// BrewListViewData contains one page of the brew list with its sort and filter controls
+type BrewListViewData struct {
+	Brews    []*BrewListData
+	Query    models.BrewQuery
+	Page     models.BrewPage
+	Units    models.UnitPreferences
+	ViewMode string
+	Beans    []*models.Bean
+	Roasters []*models.Roaster
+	Grinders []*models.Grinder
+	Brewers  []*models.Brewer
+}
+
+// PageURL returns the shareable brew list URL for another page of the same view
+func (d *BrewListViewData) PageURL(page int) string {
+	return BrewListURL("/brews", d.Query.WithPage(page))
+}
+
+// PartialURL returns the HTMX partial URL for another page of the same view
+func (d *BrewListViewData) PartialURL(page int) string {
+	return BrewListURL("/api/brews", d.Query.WithPage(page))
+}
+
+// BrewListURL appends an encoded brew query to path
+func BrewListURL(path string, q models.BrewQuery) string {
+	if enc := q.Encode(); enc != "" {
+		return path + "?" + enc
+	}
+	return path
+}
+
+// SearchPageData contains data for rendering the search page
+type SearchPageData struct {
+	Title           string
+	Query           string
+	Type            string // Selected type filter, empty for all
+	Author          string // Author filter, community search only
+	Community       bool   // Search all registered users' records instead of the user's own
+	IsAuthenticated bool
+	UserDID         string
+	UserProfile     *UserProfile
+}
+
+// SearchResultsData contains search results for the live results partial
+type SearchResultsData struct {
+	Query     string
+	Community bool
+	Results   []search.Result
+}
+
+// LabelSetting is one adult content label on the settings page
+type LabelSetting struct {
+	Label       string
+	Name        string
+	Description string
+	Visibility  string
+}
+
+// labelDescriptions names the adult labels for the settings page
+var labelDescriptions = map[string][2]string{
+	models.LabelPorn:         {"Adult content", "Explicit sexual images"},
+	models.LabelSexual:       {"Sexually suggestive", "Suggestive content that isn't explicit"},
+	models.LabelNudity:       {"Non-sexual nudity", "Artistic or educational nudity"},
+	models.LabelGraphicMedia: {"Graphic media", "Violent or disturbing images"},
+}
+
+// NewLabelSettings lists the adult labels with the visibility prefs gives them
+func NewLabelSettings(prefs models.LabelPreferences) []LabelSetting {
+	settings := make([]LabelSetting, 0, len(models.AdultLabels))
+	for _, label := range models.AdultLabels {
+		settings = append(settings, LabelSetting{
+			Label:       label,
+			Name:        labelDescriptions[label][0],
+			Description: labelDescriptions[label][1],
+			Visibility:  prefs.Visibility(label),
+		})
+	}
+	return settings
+}
+
+// Permission is an OAuth scope as listed on the settings page
+type Permission struct {
+	Scope       string
+	Description string
+	Granted     bool
+}
+
+// permissionDescriptions explains the OAuth scopes for the settings page
+var permissionDescriptions = map[string]string{
+	"atproto":                      "See which account you signed in with",
+	"transition:generic":           "Broad access to your account, granted before Arabica asked for specific permissions",
+	"repo:" + atproto.NSIDBean:     "Add, edit and delete your beans",
+	"repo:" + atproto.NSIDBrew:     "Add, edit and delete your brews",
+	"repo:" + atproto.NSIDBrewer:   "Add, edit and delete your brewers",
+	"repo:" + atproto.NSIDGraph:    "Save your muted and blocked accounts",
+	"repo:" + atproto.NSIDGrinder:  "Add, edit and delete your grinders",
+	"repo:" + atproto.NSIDRoaster:  "Add, edit and delete your roasters",
+	"repo:" + atproto.NSIDSettings: "Save your display settings",
+}
+
+// NewPermissions lists the scopes Arabica asks for, marking those not covered
+// by granted, followed by any other scopes the session was granted
+func NewPermissions(granted []string) []Permission {
+	missing := atproto.MissingScopes(granted)
+	var permissions []Permission
+	for _, scope := range atproto.Scopes() {
+		permissions = append(permissions, Permission{
+			Scope:       scope,
+			Description: permissionDescriptions[scope],
+			Granted:     !slices.Contains(missing, scope),
+		})
+	}
+	for _, scope := range granted {
+		if !slices.Contains(atproto.Scopes(), scope) {
+			permissions = append(permissions, Permission{
+				Scope:       scope,
+				Description: permissionDescriptions[scope],
+				Granted:     true,
+			})
+		}
+	}
+	return permissions
+}
+
+// SettingsPageData contains data for rendering the settings page
+type SettingsPageData struct {
+	Title           string
+	Units           models.UnitPreferences
+	Labels          []LabelSetting // Nil when no labelers are configured
+	Permissions     []Permission   // OAuth scopes of the current session
+	Settings        *models.Settings
+	Saved           bool // Show a confirmation after saving
+	IsAuthenticated bool
+	UserDID         string
+	UserProfile     *UserProfile
+}
+
+// executePage renders a page inside its layout, in a span named after the page
+func executePage(ctx context.Context, w io.Writer, t *template.Template, page string, data any) error {
+	return execute(ctx, w, t, page, "layout", data)
+}
+
+// executePartial renders a single named partial, in a span named after it
+func executePartial(ctx context.Context, w io.Writer, t *template.Template, name string, data any) error {
+	return execute(ctx, w, t, name, name, data)
+}
+
+func execute(ctx context.Context, w io.Writer, t *template.Template, spanName, name string, data any) (err error) {
+	_, span := tracing.Start(ctx, "render "+spanName,
+		trace.WithAttributes(attribute.String("template.name", spanName)))
+	defer func() { tracing.End(span, err) }()
+
+	return t.ExecuteTemplate(w, name, data)
+}
+
 // RenderTemplate renders a template with layout
 func RenderTemplate(w http.ResponseWriter, r *http.Request, tmpl string, data *PageData) error {
 	t, err := parsePageTemplate(tmpl)
 	if err != nil {
 		return err
 	}
-	return t.ExecuteTemplate(w, "layout", data)
+	return executePage(r.Context(), w, t, tmpl, data)
 }
 
 // RenderTemplateWithProfile renders a template with layout and user profile
@@ -154,7 +295,7 @@ func RenderTemplateWithProfile(w http.ResponseWriter, r *http.Request, tmpl stri
 }
 
 // RenderHome renders the home page
-func RenderHome(w http.ResponseWriter, isAuthenticated bool, userDID string, userProfile *UserProfile, feedItems []*feed.FeedItem) error {
+func RenderHome(ctx context.Context, w http.ResponseWriter, isAuthenticated bool, userDID string, userProfile *UserProfile, feedItems []*feed.FeedItem, devMode bool) error {
 	t, err := parsePageTemplate("home.tmpl")
 	if err != nil {
 		return err
@@ -165,12 +306,13 @@ func RenderHome(w http.ResponseWriter, isAuthenticated bool, userDID string, use
 		UserDID:         userDID,
 		UserProfile:     userProfile,
 		FeedItems:       feedItems,
+		DevMode:         devMode,
 	}
-	return t.ExecuteTemplate(w, "layout", data)
+	return executePage(ctx, w, t, "home.tmpl", data)
 }
 
 // RenderBrewList renders the brew list page
-func RenderBrewList(w http.ResponseWriter, brews []*models.Brew, isAuthenticated bool, userDID string, userProfile *UserProfile) error {
+func RenderBrewList(ctx context.Context, w http.ResponseWriter, brews []*models.Brew, brewQuery models.BrewQuery, units models.UnitPreferences, isAuthenticated bool, userDID string, userProfile *UserProfile) error {
 	t, err := parsePageTemplate("brew_list.tmpl")
 	if err != nil {
 		return err
@@ -179,7 +321,7 @@ func RenderBrewList(w http.ResponseWriter, brews []*models.Brew, isAuthenticated
 	for i, brew := range brews {
 		brewList[i] = &BrewListData{
 			Brew:            brew,
-			TempFormatted:   FormatTemp(brew.Temperature),
+			TempFormatted:   units.FormatTemperature(brew.Temperature),
 			TimeFormatted:   FormatTime(brew.TimeSeconds),
 			RatingFormatted: FormatRating(brew.Rating),
 		}
@@ -188,15 +330,17 @@ func RenderBrewList(w http.ResponseWriter, brews []*models.Brew, isAuthenticated
 	data := &PageData{
 		Title:           "All Brews",
 		Brews:           brewList,
+		BrewQuery:       brewQuery.Encode(),
+		Units:           units,
 		IsAuthenticated: isAuthenticated,
 		UserDID:         userDID,
 		UserProfile:     userProfile,
 	}
-	return t.ExecuteTemplate(w, "layout", data)
+	return executePage(ctx, w, t, "brew_list.tmpl", data)
 }
 
 // RenderBrewForm renders the brew form page
-func RenderBrewForm(w http.ResponseWriter, beans []*models.Bean, roasters []*models.Roaster, grinders []*models.Grinder, brewers []*models.Brewer, brew *models.Brew, isAuthenticated bool, userDID string, userProfile *UserProfile) error {
+func RenderBrewForm(ctx context.Context, w http.ResponseWriter, beans []*models.Bean, roasters []*models.Roaster, grinders []*models.Grinder, brewers []*models.Brewer, brew *models.Brew, units models.UnitPreferences, isAuthenticated bool, userDID string, userProfile *UserProfile) error {
 	t, err := parsePageTemplate("brew_form.tmpl")
 	if err != nil {
 		return err
@@ -208,7 +352,7 @@ func RenderBrewForm(w http.ResponseWriter, beans []*models.Bean, roasters []*mod
 		title = "Edit Brew"
 		brewData = &BrewData{
 			Brew:      brew,
-			PoursJSON: PoursToJSON(brew.Pours),
+			PoursJSON: PoursToJSON(brew.Pours, units),
 		}
 	}
 
@@ -219,15 +363,16 @@ func RenderBrewForm(w http.ResponseWriter, beans []*models.Bean, roasters []*mod
 		Grinders:        grinders,
 		Brewers:         brewers,
 		Brew:            brewData,
+		Units:           units,
 		IsAuthenticated: isAuthenticated,
 		UserDID:         userDID,
 		UserProfile:     userProfile,
 	}
-	return t.ExecuteTemplate(w, "layout", data)
+	return executePage(ctx, w, t, "brew_form.tmpl", data)
 }
 
 // RenderManage renders the manage page
-func RenderManage(w http.ResponseWriter, beans []*models.Bean, roasters []*models.Roaster, grinders []*models.Grinder, brewers []*models.Brewer, isAuthenticated bool, userDID string, userProfile *UserProfile) error {
+func RenderManage(ctx context.Context, w http.ResponseWriter, beans []*models.Bean, roasters []*models.Roaster, grinders []*models.Grinder, brewers []*models.Brewer, isAuthenticated bool, userDID string, userProfile *UserProfile) error {
 	t, err := parsePageTemplate("manage.tmpl")
 	if err != nil {
 		return err
@@ -242,45 +387,73 @@ func RenderManage(w http.ResponseWriter, beans []*models.Bean, roasters []*model
 		UserDID:         userDID,
 		UserProfile:     userProfile,
 	}
-	return t.ExecuteTemplate(w, "layout", data)
+	return executePage(ctx, w, t, "manage.tmpl", data)
+}
+
+// RenderStats renders the brewing stats page with the control chart
+func RenderStats(ctx context.Context, w http.ResponseWriter, brews []*models.Brew, units models.UnitPreferences, isAuthenticated bool, userDID string, userProfile *UserProfile) error {
+	t, err := parsePageTemplate("stats.tmpl")
+	if err != nil {
+		return err
+	}
+	data := &StatsPageData{
+		Title:           "Stats",
+		Stats:           SummarizeBrews(brews),
+		Chart:           NewControlChart(brews),
+		Units:           units,
+		IsAuthenticated: isAuthenticated,
+		UserDID:         userDID,
+		UserProfile:     userProfile,
+	}
+	return executePage(ctx, w, t, "stats.tmpl", data)
 }
 
 // RenderFeedPartial renders just the feed partial (for HTMX async loading)
-func RenderFeedPartial(w http.ResponseWriter, feedItems []*feed.FeedItem) error {
+func RenderFeedPartial(ctx context.Context, w http.ResponseWriter, feedItems []*feed.FeedItem, units models.UnitPreferences, viewMode string) error {
 	t, err := parsePartialTemplate()
 	if err != nil {
 		return err
 	}
 	data := &PageData{
 		FeedItems: feedItems,
+		Units:     units,
+		ViewMode:  viewMode,
 	}
-	return t.ExecuteTemplate(w, "feed", data)
+	return executePartial(ctx, w, t, "feed", data)
 }
 
-// RenderBrewListPartial renders just the brew list partial (for HTMX async loading)
-func RenderBrewListPartial(w http.ResponseWriter, brews []*models.Brew) error {
+// RenderBrewListPartial renders one page of the brew list with its controls (for HTMX async loading)
+func RenderBrewListPartial(ctx context.Context, w http.ResponseWriter, page models.BrewPage, query models.BrewQuery, units models.UnitPreferences, viewMode string, beans []*models.Bean, roasters []*models.Roaster, grinders []*models.Grinder, brewers []*models.Brewer) error {
 	t, err := parsePartialTemplate()
 	if err != nil {
 		return err
 	}
-	brewList := make([]*BrewListData, len(brews))
-	for i, brew := range brews {
+	brewList := make([]*BrewListData, len(page.Brews))
+	for i, brew := range page.Brews {
 		brewList[i] = &BrewListData{
 			Brew:            brew,
-			TempFormatted:   FormatTemp(brew.Temperature),
+			TempFormatted:   units.FormatTemperature(brew.Temperature),
 			TimeFormatted:   FormatTime(brew.TimeSeconds),
 			RatingFormatted: FormatRating(brew.Rating),
 		}
 	}
 
-	data := &PageData{
-		Brews: brewList,
+	data := &BrewListViewData{
+		Brews:    brewList,
+		Query:    query,
+		Page:     page,
+		Units:    units,
+		ViewMode: viewMode,
+		Beans:    beans,
+		Roasters: roasters,
+		Grinders: grinders,
+		Brewers:  brewers,
 	}
-	return t.ExecuteTemplate(w, "brew_list_content", data)
+	return executePartial(ctx, w, t, "brew_list_view", data)
 }
 
 // RenderManagePartial renders just the manage partial (for HTMX async loading)
-func RenderManagePartial(w http.ResponseWriter, beans []*models.Bean, roasters []*models.Roaster, grinders []*models.Grinder, brewers []*models.Brewer) error {
+func RenderManagePartial(ctx context.Context, w http.ResponseWriter, beans []*models.Bean, roasters []*models.Roaster, grinders []*models.Grinder, brewers []*models.Brewer) error {
 	t, err := parsePartialTemplate()
 	if err != nil {
 		return err
@@ -291,13 +464,164 @@ func RenderManagePartial(w http.ResponseWriter, beans []*models.Bean, roasters [
 		Grinders: grinders,
 		Brewers:  brewers,
 	}
-	return t.ExecuteTemplate(w, "manage_content", data)
+	return executePartial(ctx, w, t, "manage_content", data)
+}
+
+// RenderGrindHintsPartial renders the grind size hints for the brew form (for HTMX async loading)
+func RenderGrindHintsPartial(ctx context.Context, w http.ResponseWriter, data *GrindHintsData) error {
+	t, err := parsePartialTemplate()
+	if err != nil {
+		return err
+	}
+	return executePartial(ctx, w, t, "grind_hints", data)
+}
+
+// RenderSearch renders the search page, over either the user's own records or the community's
+func RenderSearch(ctx context.Context, w http.ResponseWriter, query, docType, author string, community bool, isAuthenticated bool, userDID string, userProfile *UserProfile) error {
+	t, err := parsePageTemplate("search.tmpl")
+	if err != nil {
+		return err
+	}
+	title := "Search"
+	if community {
+		title = "Community Search"
+	}
+	data := &SearchPageData{
+		Title:           title,
+		Query:           query,
+		Type:            docType,
+		Author:          author,
+		Community:       community,
+		IsAuthenticated: isAuthenticated,
+		UserDID:         userDID,
+		UserProfile:     userProfile,
+	}
+	return executePage(ctx, w, t, "search.tmpl", data)
+}
+
+// RenderSearchResultsPartial renders search results (for HTMX live search)
+func RenderSearchResultsPartial(ctx context.Context, w http.ResponseWriter, data *SearchResultsData) error {
+	t, err := parsePartialTemplate()
+	if err != nil {
+		return err
+	}
+	return executePartial(ctx, w, t, "search_results", data)
+}
+
+// RenderSettings renders the user settings page
+func RenderSettings(ctx context.Context, w http.ResponseWriter, units models.UnitPreferences, labels []LabelSetting, permissions []Permission, settings *models.Settings, saved bool, isAuthenticated bool, userDID string, userProfile *UserProfile) error {
+	t, err := parsePageTemplate("settings.tmpl")
+	if err != nil {
+		return err
+	}
+	data := &SettingsPageData{
+		Title:           "Settings",
+		Units:           units.Normalize(),
+		Labels:          labels,
+		Permissions:     permissions,
+		Settings:        settings,
+		Saved:           saved,
+		IsAuthenticated: isAuthenticated,
+		UserDID:         userDID,
+		UserProfile:     userProfile,
+	}
+	return executePage(ctx, w, t, "settings.tmpl", data)
+}
+
+// AdminUser is a feed user as shown on the admin dashboard
+type AdminUser struct {
+	DID          string
+	RegisteredAt time.Time
+	Sessions     int
+	Banned       bool
+}
+
+// AdminPageData contains data for rendering the admin dashboard
+type AdminPageData struct {
+	Title           string
+	Users           []AdminUser
+	SessionCount    int
+	Feed            *admin.FeedCache
+	Moderation      *admin.Moderation
+	Errors          []admin.LogEntry
+	Done            string // Confirmation of the last action
+	IsAuthenticated bool
+	UserDID         string
+	UserProfile     *UserProfile
+}
+
+// RenderAdmin renders the admin dashboard
+func RenderAdmin(ctx context.Context, w http.ResponseWriter, data *AdminPageData) error {
+	t, err := parsePageTemplate("admin.tmpl")
+	if err != nil {
+		return err
+	}
+	data.Title = "Admin"
+	return executePage(ctx, w, t, "admin.tmpl", data)
+}
+
+// SessionRow is one of the user's sessions as shown on the sessions page
+type SessionRow struct {
+	Ref        string // Identifies the session to revoke without revealing its ID
+	Current    bool
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	UserAgent  string
+	IP         string
+}
+
+// SessionsPageData contains data for rendering the sessions page
+type SessionsPageData struct {
+	Title           string
+	Sessions        []SessionRow
+	Done            string // Confirmation of the last action
+	IsAuthenticated bool
+	UserDID         string
+	UserProfile     *UserProfile
+}
+
+// RenderSessions renders the page listing the user's sessions
+func RenderSessions(ctx context.Context, w http.ResponseWriter, data *SessionsPageData) error {
+	t, err := parsePageTemplate("sessions.tmpl")
+	if err != nil {
+		return err
+	}
+	data.Title = "Sessions"
+	return executePage(ctx, w, t, "sessions.tmpl", data)
+}
+
+// GraphPageData contains data for rendering the muted and blocked accounts page
+type GraphPageData struct {
+	Title           string
+	Mutes           []string
+	Blocks          []string
+	Done            string // Confirmation of the last change
+	IsAuthenticated bool
+	UserDID         string
+	UserProfile     *UserProfile
+}
+
+// RenderGraph renders the muted and blocked accounts page
+func RenderGraph(ctx context.Context, w http.ResponseWriter, data *GraphPageData) error {
+	t, err := parsePageTemplate("graph.tmpl")
+	if err != nil {
+		return err
+	}
+	data.Title = "Muted and blocked accounts"
+	return executePage(ctx, w, t, "graph.tmpl", data)
+}
+
+// ProfileRelationship is how the viewing user and a profile's owner have
+// muted or blocked each other
+type ProfileRelationship struct {
+	Muted     bool // The viewer muted the owner
+	Blocked   bool // The viewer blocked the owner
+	BlockedBy bool // The owner blocked the viewer
 }
 
-// findTemplatePath finds the correct path to a template file
-func findTemplatePath(name string) string {
-	dir := getTemplateDir()
-	return dir + "/" + name
+// Hidden reports whether a block keeps the profile's records from the viewer
+func (r ProfileRelationship) Hidden() bool {
+	return r.Blocked || r.BlockedBy
 }
 
 // ProfilePageData contains data for rendering the profile page
@@ -313,6 +637,8 @@ type ProfilePageData struct {
 	UserDID         string
 	UserProfile     *UserProfile
 	IsOwnProfile    bool // Whether viewing user is the profile owner
+	Relationship    ProfileRelationship
+	DevMode         bool // Show the profile's DID with a copy button
 }
 
 // ProfileContentData contains data for rendering the profile content partial
@@ -322,11 +648,13 @@ type ProfileContentData struct {
 	Roasters     []*models.Roaster
 	Grinders     []*models.Grinder
 	Brewers      []*models.Brewer
+	Units        models.UnitPreferences
+	ViewMode     string
 	IsOwnProfile bool
 }
 
 // RenderProfile renders a user's public profile page
-func RenderProfile(w http.ResponseWriter, profile *atproto.Profile, brews []*models.Brew, beans []*models.Bean, roasters []*models.Roaster, grinders []*models.Grinder, brewers []*models.Brewer, isAuthenticated bool, userDID string, userProfile *UserProfile, isOwnProfile bool) error {
+func RenderProfile(ctx context.Context, w http.ResponseWriter, profile *atproto.Profile, brews []*models.Brew, beans []*models.Bean, roasters []*models.Roaster, grinders []*models.Grinder, brewers []*models.Brewer, isAuthenticated bool, userDID string, userProfile *UserProfile, isOwnProfile bool, relationship ProfileRelationship, devMode bool) error {
 	t, err := parsePageTemplate("profile.tmpl")
 	if err != nil {
 		return err
@@ -349,12 +677,14 @@ func RenderProfile(w http.ResponseWriter, profile *atproto.Profile, brews []*mod
 		UserDID:         userDID,
 		UserProfile:     userProfile,
 		IsOwnProfile:    isOwnProfile,
+		Relationship:    relationship,
+		DevMode:         devMode,
 	}
-	return t.ExecuteTemplate(w, "layout", data)
+	return executePage(ctx, w, t, "profile.tmpl", data)
 }
 
 // RenderProfilePartial renders just the profile content partial (for HTMX async loading)
-func RenderProfilePartial(w http.ResponseWriter, brews []*models.Brew, beans []*models.Bean, roasters []*models.Roaster, grinders []*models.Grinder, brewers []*models.Brewer, isOwnProfile bool) error {
+func RenderProfilePartial(ctx context.Context, w http.ResponseWriter, brews []*models.Brew, beans []*models.Bean, roasters []*models.Roaster, grinders []*models.Grinder, brewers []*models.Brewer, units models.UnitPreferences, viewMode string, isOwnProfile bool) error {
 	t, err := parsePartialTemplate()
 	if err != nil {
 		return err
@@ -366,13 +696,15 @@ func RenderProfilePartial(w http.ResponseWriter, brews []*models.Brew, beans []*
 		Roasters:     roasters,
 		Grinders:     grinders,
 		Brewers:      brewers,
+		Units:        units,
+		ViewMode:     viewMode,
 		IsOwnProfile: isOwnProfile,
 	}
-	return t.ExecuteTemplate(w, "profile_content", data)
+	return executePartial(ctx, w, t, "profile_content", data)
 }
 
 // Render404 renders the 404 not found page
-func Render404(w http.ResponseWriter, isAuthenticated bool, userDID string, userProfile *UserProfile) error {
+func Render404(ctx context.Context, w http.ResponseWriter, isAuthenticated bool, userDID string, userProfile *UserProfile) error {
 	t, err := parsePageTemplate("404.tmpl")
 	if err != nil {
 		return err
@@ -384,5 +716,5 @@ func Render404(w http.ResponseWriter, isAuthenticated bool, userDID string, user
 		UserProfile:     userProfile,
 	}
 	w.WriteHeader(http.StatusNotFound)
-	return t.ExecuteTemplate(w, "layout", data)
+	return executePage(ctx, w, t, "404.tmpl", data)
 }
@@ -0,0 +1,243 @@
+package bff
+
+import (
+	"fmt"
+	"math"
+
+	"arabica/internal/models"
+)
+
+// Control chart dimensions in SVG user units
+const (
+	chartWidth        = 600.0
+	chartHeight       = 400.0
+	chartMarginLeft   = 56.0
+	chartMarginRight  = 16.0
+	chartMarginTop    = 16.0
+	chartMarginBottom = 48.0
+)
+
+// Default axis ranges, framing the filter coffee ideal box with some room around it
+const (
+	defaultExtractionMin = 14.0
+	defaultExtractionMax = 26.0
+	defaultStrengthMin   = 0.9
+	defaultStrengthMax   = 1.7
+)
+
+// BrewStats summarizes a set of brews for the stats page
+type BrewStats struct {
+	TotalBrews    int
+	MeasuredBrews int // Brews with enough data for an extraction yield
+	IdealBrews    int // Measured brews inside the ideal box
+	AvgRating     float64
+	AvgRatio      float64
+	AvgTDS        float64
+	AvgExtraction float64
+	AvgDose       int     // Grams, rounded
+	AvgTemp       float64 // °C, rounded to a tenth
+}
+
+// ChartPoint is a single brew plotted on the control chart
+type ChartPoint struct {
+	X, Y  float64
+	RKey  string
+	Label string
+	Ideal bool
+}
+
+// ChartTick is an axis tick with its position in chart coordinates
+type ChartTick struct {
+	Pos   float64
+	Label string
+}
+
+// ChartRect is a rectangle in chart coordinates
+type ChartRect struct {
+	X, Y, Width, Height float64
+}
+
+// Right returns the x coordinate of the rectangle's right edge
+func (r ChartRect) Right() float64 {
+	return r.X + r.Width
+}
+
+// Bottom returns the y coordinate of the rectangle's bottom edge
+func (r ChartRect) Bottom() float64 {
+	return r.Y + r.Height
+}
+
+// ControlChart holds pre-computed SVG geometry for a brewing control chart
+// (extraction yield on the x axis, strength/TDS on the y axis)
+type ControlChart struct {
+	Width, Height float64
+	Plot          ChartRect // Plotting area inside the margins
+	IdealBox      ChartRect
+	XTicks        []ChartTick
+	YTicks        []ChartTick
+	Points        []ChartPoint
+}
+
+// StatsPageData contains data for rendering the stats page
+type StatsPageData struct {
+	Title           string
+	Stats           BrewStats
+	Chart           *ControlChart
+	Units           models.UnitPreferences
+	IsAuthenticated bool
+	UserDID         string
+	UserProfile     *UserProfile
+}
+
+// SummarizeBrews computes averages over the brews that have each value
+func SummarizeBrews(brews []*models.Brew) BrewStats {
+	stats := BrewStats{TotalBrews: len(brews)}
+
+	var ratingSum, ratioSum, tdsSum, eySum, doseSum, tempSum float64
+	var ratingCount, ratioCount, tdsCount, doseCount, tempCount int
+	for _, brew := range brews {
+		if brew.Rating > 0 {
+			ratingSum += float64(brew.Rating)
+			ratingCount++
+		}
+		if ratio := brew.BrewRatio(); ratio > 0 {
+			ratioSum += ratio
+			ratioCount++
+		}
+		if brew.TDS > 0 {
+			tdsSum += brew.TDS
+			tdsCount++
+		}
+		if brew.CoffeeAmount > 0 {
+			doseSum += float64(brew.CoffeeAmount)
+			doseCount++
+		}
+		if brew.Temperature > 0 {
+			tempSum += brew.Temperature
+			tempCount++
+		}
+		if ey := brew.ExtractionYield(); ey > 0 {
+			eySum += ey
+			stats.MeasuredBrews++
+			if brew.IsIdealExtraction() {
+				stats.IdealBrews++
+			}
+		}
+	}
+
+	if ratingCount > 0 {
+		stats.AvgRating = ratingSum / float64(ratingCount)
+	}
+	if ratioCount > 0 {
+		stats.AvgRatio = ratioSum / float64(ratioCount)
+	}
+	if tdsCount > 0 {
+		stats.AvgTDS = tdsSum / float64(tdsCount)
+	}
+	if stats.MeasuredBrews > 0 {
+		stats.AvgExtraction = eySum / float64(stats.MeasuredBrews)
+	}
+	if doseCount > 0 {
+		stats.AvgDose = int(math.Round(doseSum / float64(doseCount)))
+	}
+	if tempCount > 0 {
+		stats.AvgTemp = math.Round(tempSum/float64(tempCount)*10) / 10
+	}
+
+	return stats
+}
+
+// NewControlChart plots every brew that has an extraction yield.
+// Axis ranges start at the filter coffee defaults and grow to fit the data.
+func NewControlChart(brews []*models.Brew) *ControlChart {
+	xMin, xMax := defaultExtractionMin, defaultExtractionMax
+	yMin, yMax := defaultStrengthMin, defaultStrengthMax
+
+	var measured []*models.Brew
+	for _, brew := range brews {
+		ey := brew.ExtractionYield()
+		if ey == 0 {
+			continue
+		}
+		measured = append(measured, brew)
+		xMin, xMax = math.Min(xMin, math.Floor(ey-1)), math.Max(xMax, math.Ceil(ey+1))
+		yMin, yMax = math.Min(yMin, brew.TDS*0.9), math.Max(yMax, brew.TDS*1.1)
+	}
+	xMin = math.Max(0, xMin)
+	yMin = math.Max(0, yMin)
+
+	// Snap the y range to whole ticks so grid lines land on round numbers
+	yStep := niceStep(yMax - yMin)
+	yMin = math.Floor(yMin/yStep) * yStep
+	yMax = math.Ceil(yMax/yStep) * yStep
+	xStep := niceStep(xMax - xMin)
+
+	chart := &ControlChart{
+		Width:  chartWidth,
+		Height: chartHeight,
+		Plot: ChartRect{
+			X:      chartMarginLeft,
+			Y:      chartMarginTop,
+			Width:  chartWidth - chartMarginLeft - chartMarginRight,
+			Height: chartHeight - chartMarginTop - chartMarginBottom,
+		},
+	}
+
+	scaleX := func(v float64) float64 {
+		return round1(chart.Plot.X + (v-xMin)/(xMax-xMin)*chart.Plot.Width)
+	}
+	scaleY := func(v float64) float64 {
+		return round1(chart.Plot.Y + chart.Plot.Height - (v-yMin)/(yMax-yMin)*chart.Plot.Height)
+	}
+
+	chart.IdealBox = ChartRect{
+		X:      scaleX(models.IdealExtractionMin),
+		Y:      scaleY(models.IdealStrengthMax),
+		Width:  round1(scaleX(models.IdealExtractionMax) - scaleX(models.IdealExtractionMin)),
+		Height: round1(scaleY(models.IdealStrengthMin) - scaleY(models.IdealStrengthMax)),
+	}
+
+	for v := math.Ceil(xMin/xStep) * xStep; v <= xMax+1e-9; v += xStep {
+		chart.XTicks = append(chart.XTicks, ChartTick{Pos: scaleX(v), Label: fmt.Sprintf("%g%%", round1(v))})
+	}
+	for v := yMin; v <= yMax+1e-9; v += yStep {
+		chart.YTicks = append(chart.YTicks, ChartTick{Pos: scaleY(v), Label: fmt.Sprintf("%.2f%%", v)})
+	}
+
+	for _, brew := range measured {
+		ey := brew.ExtractionYield()
+		label := fmt.Sprintf("%s: TDS %s, EY %s", brew.CreatedAt.Format("Jan 2, 2006"), FormatTDS(brew.TDS), FormatExtraction(ey))
+		if brew.Bean != nil && brew.Bean.Name != "" {
+			label = brew.Bean.Name + " – " + label
+		}
+		chart.Points = append(chart.Points, ChartPoint{
+			X:     scaleX(ey),
+			Y:     scaleY(brew.TDS),
+			RKey:  brew.RKey,
+			Label: label,
+			Ideal: brew.IsIdealExtraction(),
+		})
+	}
+
+	return chart
+}
+
+// niceStep picks a tick interval of 1, 2 or 5 times a power of ten giving
+// roughly 4-10 ticks across span
+func niceStep(span float64) float64 {
+	if span <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(span)))
+	for _, mult := range []float64{0.1, 0.2, 0.5, 1, 2, 5} {
+		if step := magnitude * mult; span/step <= 10 {
+			return step
+		}
+	}
+	return magnitude * 10
+}
+
+// round1 rounds to one decimal place to keep SVG output compact
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
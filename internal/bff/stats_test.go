@@ -0,0 +1,101 @@
+package bff
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"arabica/internal/models"
+)
+
+func TestSummarizeBrews(t *testing.T) {
+	brews := []*models.Brew{
+		{Rating: 8, CoffeeAmount: 15, WaterAmount: 250, TDS: 1.3, BeverageWeight: 230, Temperature: 93},
+		{Rating: 6, CoffeeAmount: 20, WaterAmount: 300, Temperature: 94.5},
+		{},
+	}
+
+	stats := SummarizeBrews(brews)
+
+	if stats.TotalBrews != 3 {
+		t.Errorf("TotalBrews = %d, want 3", stats.TotalBrews)
+	}
+	if stats.MeasuredBrews != 1 || stats.IdealBrews != 1 {
+		t.Errorf("MeasuredBrews, IdealBrews = %d, %d, want 1, 1", stats.MeasuredBrews, stats.IdealBrews)
+	}
+	if stats.AvgRating != 7 {
+		t.Errorf("AvgRating = %v, want 7", stats.AvgRating)
+	}
+	wantRatio := (250.0/15.0 + 15.0) / 2
+	if math.Abs(stats.AvgRatio-wantRatio) > 1e-9 {
+		t.Errorf("AvgRatio = %v, want %v", stats.AvgRatio, wantRatio)
+	}
+	if math.Abs(stats.AvgExtraction-1.3*230/15) > 1e-9 {
+		t.Errorf("AvgExtraction = %v, want %v", stats.AvgExtraction, 1.3*230/15)
+	}
+	if stats.AvgDose != 18 || stats.AvgTemp != 93.8 {
+		t.Errorf("AvgDose, AvgTemp = %d, %v, want 18, 93.8", stats.AvgDose, stats.AvgTemp)
+	}
+
+	if empty := SummarizeBrews(nil); empty != (BrewStats{}) {
+		t.Errorf("SummarizeBrews(nil) = %+v, want zero value", empty)
+	}
+}
+
+func TestNewControlChart(t *testing.T) {
+	createdAt := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	t.Run("points inside the plot", func(t *testing.T) {
+		brews := []*models.Brew{
+			{RKey: "ideal", CoffeeAmount: 15, TDS: 1.3, BeverageWeight: 230, CreatedAt: createdAt},
+			{RKey: "weak", CoffeeAmount: 15, TDS: 1.0, BeverageWeight: 240, CreatedAt: createdAt},
+			{RKey: "unmeasured", CoffeeAmount: 15, WaterAmount: 250, CreatedAt: createdAt},
+		}
+
+		chart := NewControlChart(brews)
+
+		if len(chart.Points) != 2 {
+			t.Fatalf("len(Points) = %d, want 2", len(chart.Points))
+		}
+		for _, p := range chart.Points {
+			if p.X < chart.Plot.X || p.X > chart.Plot.Right() || p.Y < chart.Plot.Y || p.Y > chart.Plot.Bottom() {
+				t.Errorf("point %s at (%v, %v) is outside the plot %+v", p.RKey, p.X, p.Y, chart.Plot)
+			}
+		}
+		if !chart.Points[0].Ideal || chart.Points[1].Ideal {
+			t.Errorf("Ideal flags = %v, %v, want true, false", chart.Points[0].Ideal, chart.Points[1].Ideal)
+		}
+		// Higher TDS plots higher, i.e. at a smaller y
+		if chart.Points[0].Y >= chart.Points[1].Y {
+			t.Errorf("stronger brew y = %v should be above weaker brew y = %v", chart.Points[0].Y, chart.Points[1].Y)
+		}
+	})
+
+	t.Run("ideal box inside plot by default", func(t *testing.T) {
+		chart := NewControlChart(nil)
+
+		box := chart.IdealBox
+		if box.Width <= 0 || box.Height <= 0 {
+			t.Fatalf("IdealBox = %+v, want positive size", box)
+		}
+		if box.X < chart.Plot.X || box.Right() > chart.Plot.Right() || box.Y < chart.Plot.Y || box.Bottom() > chart.Plot.Bottom() {
+			t.Errorf("IdealBox %+v is outside the plot %+v", box, chart.Plot)
+		}
+		if len(chart.XTicks) < 3 || len(chart.YTicks) < 3 {
+			t.Errorf("got %d x ticks and %d y ticks, want at least 3 each", len(chart.XTicks), len(chart.YTicks))
+		}
+	})
+
+	t.Run("range grows for espresso", func(t *testing.T) {
+		brews := []*models.Brew{
+			{RKey: "espresso", CoffeeAmount: 18, TDS: 10, BeverageWeight: 36, CreatedAt: createdAt},
+		}
+
+		chart := NewControlChart(brews)
+
+		p := chart.Points[0]
+		if p.Y < chart.Plot.Y || p.Y > chart.Plot.Bottom() {
+			t.Errorf("espresso point y = %v is outside the plot %+v", p.Y, chart.Plot)
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package bff
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"sync"
+
+	"arabica"
+)
+
+// templateSet holds every page parsed together with the layout and partials,
+// plus the partials on their own for HTMX responses
+type templateSet struct {
+	pages    map[string]*template.Template
+	partials *template.Template
+}
+
+var (
+	templatesMu sync.RWMutex
+	templateFS  fs.FS
+	templates   *templateSet
+	reloadTmpl  bool
+
+	staticURLFunc = func(name string) string { return "/static/" + name }
+)
+
+// LoadTemplates parses all templates in fsys once, so renders only execute
+// them. With reload set, templates are instead re-parsed from fsys on every
+// render, which lets edits on disk show up without a restart during development.
+// Until this is called, templates are parsed from the copy embedded in the binary.
+func LoadTemplates(fsys fs.FS, reload bool) error {
+	var set *templateSet
+	if !reload {
+		var err error
+		if set, err = parseTemplateSet(fsys); err != nil {
+			return err
+		}
+	}
+
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	templateFS = fsys
+	templates = set
+	reloadTmpl = reload
+	return nil
+}
+
+// SetStaticURL sets how the "static" template function builds asset URLs
+func SetStaticURL(fn func(name string) string) {
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	staticURLFunc = fn
+}
+
+// StaticURL returns the URL for a file under web/static (e.g. "css/output.css")
+func StaticURL(name string) string {
+	templatesMu.RLock()
+	defer templatesMu.RUnlock()
+	return staticURLFunc(name)
+}
+
+// CheckTemplates reports whether templates can be rendered: that the set is
+// parsed and has the layout and partials every page depends on
+func CheckTemplates() error {
+	set, err := currentTemplates()
+	if err != nil {
+		return err
+	}
+	if len(set.pages) == 0 {
+		return fmt.Errorf("no page templates loaded")
+	}
+	if set.partials == nil || set.partials.Lookup("feed") == nil {
+		return fmt.Errorf("partial templates not loaded")
+	}
+	return nil
+}
+
+// currentTemplates returns the parsed template set, parsing it if needed
+func currentTemplates() (*templateSet, error) {
+	templatesMu.RLock()
+	set, fsys, reload := templates, templateFS, reloadTmpl
+	templatesMu.RUnlock()
+
+	if reload {
+		return parseTemplateSet(fsys)
+	}
+	if set != nil {
+		return set, nil
+	}
+
+	// Not loaded at startup (e.g. in tests), fall back to the embedded templates
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	if templates == nil {
+		parsed, err := parseTemplateSet(arabica.Templates())
+		if err != nil {
+			return nil, err
+		}
+		templates = parsed
+	}
+	return templates, nil
+}
+
+// parseTemplateSet parses the partials and every page template in fsys
+func parseTemplateSet(fsys fs.FS) (*templateSet, error) {
+	partials, err := template.New("").Funcs(getTemplateFuncs()).ParseFS(fsys, "partials/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	pageFiles, err := fs.Glob(fsys, "*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	set := &templateSet{
+		pages:    make(map[string]*template.Template, len(pageFiles)),
+		partials: partials,
+	}
+	for _, file := range pageFiles {
+		if file == "layout.tmpl" {
+			continue
+		}
+		// Each page gets its own copy of the layout since they all define "content"
+		t, err := template.New("").Funcs(getTemplateFuncs()).ParseFS(fsys, "layout.tmpl", "partials/*.tmpl", file)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", file, err)
+		}
+		set.pages[path.Base(file)] = t
+	}
+	return set, nil
+}
+
+// parsePageTemplate returns a complete page template with layout and partials
+func parsePageTemplate(pageName string) (*template.Template, error) {
+	set, err := currentTemplates()
+	if err != nil {
+		return nil, err
+	}
+	t, ok := set.pages[pageName]
+	if !ok {
+		return nil, fmt.Errorf("template %s not found", pageName)
+	}
+	return t, nil
+}
+
+// parsePartialTemplate returns just the partials (for partial-only renders)
+func parsePartialTemplate() (*template.Template, error) {
+	set, err := currentTemplates()
+	if err != nil {
+		return nil, err
+	}
+	return set.partials, nil
+}
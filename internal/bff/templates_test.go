@@ -0,0 +1,37 @@
+package bff
+
+import (
+	"testing"
+
+	"arabica"
+)
+
+func TestParseTemplateSet(t *testing.T) {
+	set, err := parseTemplateSet(arabica.Templates())
+	if err != nil {
+		t.Fatalf("parseTemplateSet() error = %v", err)
+	}
+
+	if _, ok := set.pages["layout.tmpl"]; ok {
+		t.Error("layout.tmpl should not be parsed as a page")
+	}
+	for _, page := range []string{"home.tmpl", "brew_list.tmpl", "brew_form.tmpl", "profile.tmpl", "settings.tmpl", "graph.tmpl", "sessions.tmpl", "admin.tmpl", "404.tmpl"} {
+		tmpl, ok := set.pages[page]
+		if !ok {
+			t.Errorf("page %s was not parsed", page)
+			continue
+		}
+		if tmpl.Lookup("layout") == nil || tmpl.Lookup("content") == nil {
+			t.Errorf("page %s is missing the layout or its content", page)
+		}
+	}
+	if set.partials.Lookup("feed") == nil {
+		t.Error("partials are missing the feed template")
+	}
+}
+
+func TestCheckTemplates(t *testing.T) {
+	if err := CheckTemplates(); err != nil {
+		t.Errorf("CheckTemplates() error = %v", err)
+	}
+}
@@ -0,0 +1,556 @@
+// Package config loads the server configuration.
+//
+// Settings come from four layers, each overriding the one before it:
+//
+//  1. built-in defaults
+//  2. a YAML file, given by -config or ARABICA_CONFIG
+//  3. environment variables (PORT, OAUTH_CLIENT_ID, ARABICA_* ...)
+//  4. command-line flags
+//
+// The result is validated once at startup so a bad value fails fast instead
+// of surfacing as odd behavior later.
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete server configuration
+type Config struct {
+	Server     ServerConfig     `yaml:"server"`
+	Database   DatabaseConfig   `yaml:"database"`
+	OAuth      OAuthConfig      `yaml:"oauth"`
+	Log        LogConfig        `yaml:"log"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Feed       FeedConfig       `yaml:"feed"`
+	RateLimits RateLimitsConfig `yaml:"rate_limits"`
+	Health     HealthConfig     `yaml:"health"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+	Admin      AdminConfig      `yaml:"admin"`
+	Labels     LabelsConfig     `yaml:"labels"`
+	Sweeper    SweeperConfig    `yaml:"sweeper"`
+}
+
+// ServerConfig controls the HTTP listener
+type ServerConfig struct {
+	Port int `yaml:"port"`
+	// PublicURL is the externally visible root URL when running behind a
+	// reverse proxy, e.g. https://arabica.example.com
+	PublicURL     string `yaml:"public_url"`
+	SecureCookies bool   `yaml:"secure_cookies"`
+	// Dev serves templates and static files from the working tree and
+	// re-parses templates on every render
+	Dev             bool          `yaml:"dev"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// DatabaseConfig locates the BoltDB file and the keys encrypting the OAuth
+// sessions stored in it
+type DatabaseConfig struct {
+	Path string `yaml:"path"`
+	// SessionKey encrypts OAuth sessions at rest: 32 random bytes, base64
+	// encoded, as printed by arabica admin sessions keygen. Sessions are
+	// stored unencrypted when neither it nor SessionKeyFile is set.
+	SessionKey string `yaml:"session_key,omitempty"`
+	// SessionKeyFile reads the session key from a file instead, such as a
+	// mounted secret
+	SessionKeyFile string `yaml:"session_key_file,omitempty"`
+	// OldSessionKeys and OldSessionKeyFiles still decrypt sessions written
+	// before a key rotation, until arabica admin sessions rekey moves them
+	// to the current key
+	OldSessionKeys     []string `yaml:"old_session_keys,omitempty"`
+	OldSessionKeyFiles []string `yaml:"old_session_key_files,omitempty"`
+}
+
+// OAuthConfig overrides the OAuth client identity. Leave both empty to derive
+// them from the public URL, or to use localhost mode when that is unset too.
+type OAuthConfig struct {
+	ClientID    string `yaml:"client_id"`
+	RedirectURI string `yaml:"redirect_uri"`
+	// SigningKeyFile makes the client confidential, which gets longer
+	// sessions from most PDSes. It holds a P-256 private key, multibase or
+	// PEM encoded, as printed by arabica admin oauth keygen.
+	SigningKeyFile string `yaml:"signing_key_file,omitempty"`
+	// OldSigningKeyFiles are keys replaced by SigningKeyFile. Sessions created
+	// with them keep refreshing until they expire.
+	OldSigningKeyFiles []string `yaml:"old_signing_key_files,omitempty"`
+}
+
+// LogConfig controls zerolog output
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// CacheConfig tunes the per-session cache of PDS records
+type CacheConfig struct {
+	TTL             time.Duration `yaml:"ttl"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval"`
+}
+
+// FeedConfig tunes the public feed cache
+type FeedConfig struct {
+	CacheTTL        time.Duration `yaml:"cache_ttl"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+// RateLimitsConfig sets how many requests each client IP may make per window
+type RateLimitsConfig struct {
+	Window time.Duration `yaml:"window"`
+	Auth   int           `yaml:"auth"`
+	API    int           `yaml:"api"`
+	Global int           `yaml:"global"`
+}
+
+// HealthConfig controls the readiness checks
+type HealthConfig struct {
+	// CheckUpstream adds informational PLC directory and AppView checks to /readyz
+	CheckUpstream bool `yaml:"check_upstream"`
+}
+
+// MetricsConfig controls the internal listener serving Prometheus metrics
+type MetricsConfig struct {
+	// Listen is the address serving /metrics, e.g. 127.0.0.1:9464. It is kept
+	// off the public listener; metrics are disabled when it is empty.
+	Listen string `yaml:"listen"`
+}
+
+// AdminConfig controls the local socket used by the admin subcommands and
+// who may use the web dashboard at /admin
+type AdminConfig struct {
+	// Socket is the unix socket path, by default admin.sock next to the database
+	Socket string `yaml:"socket"`
+	// DIDs are the accounts allowed to use the dashboard; it is disabled when empty
+	DIDs []string `yaml:"dids"`
+}
+
+// LabelsConfig lists the labeler services whose labels filter the feed
+type LabelsConfig struct {
+	Labelers []LabelerConfig `yaml:"labelers"`
+	// RefreshInterval is how often labels are fetched from each labeler
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+// LabelerConfig identifies a labeler service
+type LabelerConfig struct {
+	// DID is the labeler's identity; only labels it signed are used
+	DID string `yaml:"did"`
+	// URL is where its XRPC endpoints are served, e.g. https://mod.bsky.app
+	URL string `yaml:"url"`
+}
+
+// SweeperConfig controls the background removal of expired OAuth state
+type SweeperConfig struct {
+	Interval time.Duration `yaml:"interval"`
+	// AuthRequestTTL is how long a started login may take before it is dropped
+	AuthRequestTTL time.Duration `yaml:"auth_request_ttl"`
+	// SessionTTL is how long a session may go unused before it is dropped,
+	// which should match the auth server's refresh token lifetime
+	SessionTTL time.Duration `yaml:"session_ttl"`
+	// ConfidentialSessionTTL replaces SessionTTL when the OAuth client is
+	// confidential, since auth servers let its refresh tokens live longer
+	ConfidentialSessionTTL time.Duration `yaml:"confidential_session_ttl"`
+}
+
+// Default returns the configuration used when nothing is overridden. The
+// database and admin socket paths are left empty and resolved by Load.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            18910,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "console",
+		},
+		Cache: CacheConfig{
+			TTL:             2 * time.Minute,
+			CleanupInterval: 10 * time.Minute,
+		},
+		Feed: FeedConfig{
+			CacheTTL:        5 * time.Minute,
+			RefreshInterval: 4 * time.Minute,
+		},
+		RateLimits: RateLimitsConfig{
+			Window: time.Minute,
+			Auth:   5,
+			API:    60,
+			Global: 120,
+		},
+		Labels: LabelsConfig{
+			RefreshInterval: 5 * time.Minute,
+		},
+		Sweeper: SweeperConfig{
+			Interval:       10 * time.Minute,
+			AuthRequestTTL: 30 * time.Minute,
+			// Refresh tokens issued to public clients last two weeks, and
+			// those issued to confidential clients up to 180 days
+			SessionTTL:             14 * 24 * time.Hour,
+			ConfidentialSessionTTL: 180 * 24 * time.Hour,
+		},
+	}
+}
+
+// Options are the command-line switches that aren't settings themselves
+type Options struct {
+	// ConfigPath is the YAML file that was loaded, if any
+	ConfigPath string
+	// PrintConfig asks main to print the effective configuration and exit
+	PrintConfig bool
+	// Args are the positional arguments left after the flags
+	Args []string
+}
+
+// Load builds the configuration from defaults, the config file, the
+// environment and args (usually os.Args[1:]), then validates it
+func Load(args []string) (*Config, Options, error) {
+	var opts Options
+
+	// First pass only finds -config; the values it sets are thrown away so
+	// that flags can be applied again on top of the file and environment
+	if err := newFlagSet(Default(), &opts).Parse(args); err != nil {
+		return nil, opts, err
+	}
+	if opts.ConfigPath == "" {
+		opts.ConfigPath = os.Getenv("ARABICA_CONFIG")
+	}
+
+	cfg := Default()
+	if opts.ConfigPath != "" {
+		if err := cfg.loadFile(opts.ConfigPath); err != nil {
+			return nil, opts, err
+		}
+	}
+	if err := cfg.applyEnv(os.Getenv); err != nil {
+		return nil, opts, err
+	}
+	fs := newFlagSet(cfg, &opts)
+	if err := fs.Parse(args); err != nil {
+		return nil, opts, err
+	}
+	opts.Args = fs.Args()
+
+	if cfg.Database.Path == "" {
+		path, err := defaultDatabasePath()
+		if err != nil {
+			return nil, opts, err
+		}
+		cfg.Database.Path = path
+	}
+	if cfg.Admin.Socket == "" {
+		cfg.Admin.Socket = filepath.Join(filepath.Dir(cfg.Database.Path), "admin.sock")
+	}
+	cfg.Server.PublicURL = strings.TrimSuffix(cfg.Server.PublicURL, "/")
+
+	if err := cfg.Validate(); err != nil {
+		return nil, opts, err
+	}
+	return cfg, opts, nil
+}
+
+// newFlagSet binds flags directly to cfg so parsing overrides only the
+// values that were actually passed
+func newFlagSet(cfg *Config, opts *Options) *flag.FlagSet {
+	fs := flag.NewFlagSet("arabica", flag.ContinueOnError)
+	fs.StringVar(&opts.ConfigPath, "config", opts.ConfigPath, "path to a YAML config file (env ARABICA_CONFIG)")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective configuration and exit")
+	fs.IntVar(&cfg.Server.Port, "port", cfg.Server.Port, "HTTP port (env PORT)")
+	fs.StringVar(&cfg.Server.PublicURL, "public-url", cfg.Server.PublicURL, "public root URL behind a reverse proxy (env SERVER_PUBLIC_URL)")
+	fs.BoolVar(&cfg.Server.SecureCookies, "secure-cookies", cfg.Server.SecureCookies, "set the Secure flag on cookies (env SECURE_COOKIES)")
+	fs.BoolVar(&cfg.Server.Dev, "dev", cfg.Server.Dev, "serve templates and static files from the working tree (env ARABICA_DEV)")
+	fs.StringVar(&cfg.Database.Path, "db", cfg.Database.Path, "BoltDB path (env ARABICA_DB_PATH)")
+	fs.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "debug, info, warn or error (env LOG_LEVEL)")
+	fs.StringVar(&cfg.Log.Format, "log-format", cfg.Log.Format, "console or json (env LOG_FORMAT)")
+	return fs
+}
+
+// loadFile merges a YAML file over the current values. Unknown keys are
+// rejected so a misspelled setting doesn't silently fall back to its default.
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// envVar maps an environment variable onto a setting
+type envVar struct {
+	name string
+	set  func(c *Config, value string) error
+}
+
+// envVars lists every environment variable read by Load. The names predate
+// the config file and are kept for existing deployments.
+var envVars = []envVar{
+	{"PORT", func(c *Config, v string) (err error) { c.Server.Port, err = strconv.Atoi(v); return }},
+	{"SERVER_PUBLIC_URL", func(c *Config, v string) error { c.Server.PublicURL = v; return nil }},
+	{"SECURE_COOKIES", func(c *Config, v string) (err error) { c.Server.SecureCookies, err = strconv.ParseBool(v); return }},
+	{"ARABICA_DEV", func(c *Config, v string) (err error) { c.Server.Dev, err = strconv.ParseBool(v); return }},
+	{"ARABICA_SHUTDOWN_TIMEOUT", func(c *Config, v string) (err error) { c.Server.ShutdownTimeout, err = time.ParseDuration(v); return }},
+	{"ARABICA_DB_PATH", func(c *Config, v string) error { c.Database.Path = v; return nil }},
+	{"ARABICA_SESSION_KEY", func(c *Config, v string) error { c.Database.SessionKey = v; return nil }},
+	{"ARABICA_SESSION_KEY_FILE", func(c *Config, v string) error { c.Database.SessionKeyFile = v; return nil }},
+	{"ARABICA_OLD_SESSION_KEYS", func(c *Config, v string) error { c.Database.OldSessionKeys = splitList(v); return nil }},
+	{"ARABICA_OLD_SESSION_KEY_FILES", func(c *Config, v string) error { c.Database.OldSessionKeyFiles = splitList(v); return nil }},
+	{"OAUTH_CLIENT_ID", func(c *Config, v string) error { c.OAuth.ClientID = v; return nil }},
+	{"OAUTH_REDIRECT_URI", func(c *Config, v string) error { c.OAuth.RedirectURI = v; return nil }},
+	{"ARABICA_OAUTH_SIGNING_KEY_FILE", func(c *Config, v string) error { c.OAuth.SigningKeyFile = v; return nil }},
+	{"ARABICA_OAUTH_OLD_SIGNING_KEY_FILES", func(c *Config, v string) error { c.OAuth.OldSigningKeyFiles = splitList(v); return nil }},
+	{"LOG_LEVEL", func(c *Config, v string) error { c.Log.Level = v; return nil }},
+	{"LOG_FORMAT", func(c *Config, v string) error { c.Log.Format = v; return nil }},
+	{"ARABICA_CACHE_TTL", func(c *Config, v string) (err error) { c.Cache.TTL, err = time.ParseDuration(v); return }},
+	{"ARABICA_FEED_CACHE_TTL", func(c *Config, v string) (err error) { c.Feed.CacheTTL, err = time.ParseDuration(v); return }},
+	{"ARABICA_FEED_REFRESH_INTERVAL", func(c *Config, v string) (err error) { c.Feed.RefreshInterval, err = time.ParseDuration(v); return }},
+	{"ARABICA_ADMIN_SOCKET", func(c *Config, v string) error { c.Admin.Socket = v; return nil }},
+	{"ARABICA_ADMIN_DIDS", func(c *Config, v string) error { c.Admin.DIDs = splitList(v); return nil }},
+	{"ARABICA_LABELERS", func(c *Config, v string) error { return c.Labels.parseLabelers(v) }},
+	{"ARABICA_LABELS_REFRESH_INTERVAL", func(c *Config, v string) (err error) { c.Labels.RefreshInterval, err = time.ParseDuration(v); return }},
+	{"ARABICA_SWEEP_INTERVAL", func(c *Config, v string) (err error) { c.Sweeper.Interval, err = time.ParseDuration(v); return }},
+	{"ARABICA_AUTH_REQUEST_TTL", func(c *Config, v string) (err error) { c.Sweeper.AuthRequestTTL, err = time.ParseDuration(v); return }},
+	{"ARABICA_SESSION_TTL", func(c *Config, v string) (err error) { c.Sweeper.SessionTTL, err = time.ParseDuration(v); return }},
+	{"ARABICA_CONFIDENTIAL_SESSION_TTL", func(c *Config, v string) (err error) {
+		c.Sweeper.ConfidentialSessionTTL, err = time.ParseDuration(v)
+		return
+	}},
+	{"ARABICA_READYZ_UPSTREAM", func(c *Config, v string) (err error) { c.Health.CheckUpstream, err = strconv.ParseBool(v); return }},
+	{"ARABICA_METRICS_LISTEN", func(c *Config, v string) error { c.Metrics.Listen = v; return nil }},
+}
+
+// applyEnv overrides settings from the environment. Empty variables are
+// ignored, matching how they were treated before the config file existed.
+func (c *Config) applyEnv(getenv func(string) string) error {
+	for _, ev := range envVars {
+		value := getenv(ev.name)
+		if value == "" {
+			continue
+		}
+		if err := ev.set(c, value); err != nil {
+			return fmt.Errorf("invalid %s=%q: %w", ev.name, value, err)
+		}
+	}
+	return nil
+}
+
+// splitList parses a comma-separated environment variable
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseLabelers reads labelers from a comma-separated list of DID=URL pairs
+func (l *LabelsConfig) parseLabelers(v string) error {
+	l.Labelers = nil
+	for _, item := range splitList(v) {
+		did, endpoint, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("labeler %q must be written as DID=URL", item)
+		}
+		l.Labelers = append(l.Labelers, LabelerConfig{DID: strings.TrimSpace(did), URL: strings.TrimSpace(endpoint)})
+	}
+	return nil
+}
+
+// defaultDatabasePath puts the database under the XDG data directory, which
+// keeps it out of read-only locations such as the nix store under nix run
+func defaultDatabasePath() (string, error) {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("resolving default database path: %w", err)
+		}
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataDir, "arabica", "arabica.db"), nil
+}
+
+// Validate reports every invalid setting at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port %d is out of range", c.Server.Port)
+	if c.Server.PublicURL != "" {
+		u, err := url.Parse(c.Server.PublicURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"server.public_url %q must be an absolute http(s) URL", c.Server.PublicURL)
+	}
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Database.Path != "", "database.path must be set")
+	check(c.Database.SessionKey == "" || c.Database.SessionKeyFile == "",
+		"database.session_key and database.session_key_file can't both be set")
+	check(c.Database.SessionKey != "" || c.Database.SessionKeyFile != "" ||
+		len(c.Database.OldSessionKeys)+len(c.Database.OldSessionKeyFiles) == 0,
+		"database.old_session_keys need a current database.session_key")
+	if c.Database.SessionKey != "" {
+		_, err := parseSessionKey(c.Database.SessionKey)
+		check(err == nil, "database.session_key %v", err)
+	}
+	for i, key := range c.Database.OldSessionKeys {
+		_, err := parseSessionKey(key)
+		check(err == nil, "database.old_session_keys entry %d %v", i+1, err)
+	}
+	check((c.OAuth.ClientID == "") == (c.OAuth.RedirectURI == ""),
+		"oauth.client_id and oauth.redirect_uri must be set together")
+	// Localhost clients are always public
+	check(c.OAuth.SigningKeyFile == "" || c.OAuth.ClientID != "" || c.Server.PublicURL != "",
+		"oauth.signing_key_file needs server.public_url or oauth.client_id")
+	check(c.OAuth.SigningKeyFile != "" || len(c.OAuth.OldSigningKeyFiles) == 0,
+		"oauth.old_signing_key_files need a current oauth.signing_key_file")
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level %q must be debug, info, warn or error", c.Log.Level))
+	}
+	switch c.Log.Format {
+	case "console", "pretty", "json":
+	default:
+		errs = append(errs, fmt.Errorf("log.format %q must be console or json", c.Log.Format))
+	}
+
+	check(c.Cache.TTL > 0, "cache.ttl must be positive")
+	check(c.Cache.CleanupInterval > 0, "cache.cleanup_interval must be positive")
+	check(c.Feed.CacheTTL > 0, "feed.cache_ttl must be positive")
+	// Refreshing after the cache expires would leave requests waiting on PDS fetches
+	check(c.Feed.RefreshInterval > 0 && c.Feed.RefreshInterval < c.Feed.CacheTTL,
+		"feed.refresh_interval must be positive and shorter than feed.cache_ttl")
+	check(c.RateLimits.Window > 0, "rate_limits.window must be positive")
+	check(c.RateLimits.Auth > 0 && c.RateLimits.API > 0 && c.RateLimits.Global > 0,
+		"rate_limits.auth, api and global must be positive")
+	if c.Metrics.Listen != "" {
+		_, port, err := net.SplitHostPort(c.Metrics.Listen)
+		check(err == nil && port != "", "metrics.listen %q must be a host:port address", c.Metrics.Listen)
+	}
+	for _, did := range c.Admin.DIDs {
+		check(strings.HasPrefix(did, "did:"), "admin.dids entry %q must be a DID, not a handle", did)
+	}
+	for _, l := range c.Labels.Labelers {
+		check(strings.HasPrefix(l.DID, "did:"), "labels.labelers did %q must be a DID", l.DID)
+		u, err := url.Parse(l.URL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"labels.labelers url %q must be an absolute http(s) URL", l.URL)
+	}
+	check(c.Labels.RefreshInterval > 0, "labels.refresh_interval must be positive")
+	check(c.Sweeper.Interval > 0, "sweeper.interval must be positive")
+	check(c.Sweeper.AuthRequestTTL > 0, "sweeper.auth_request_ttl must be positive")
+	// Sessions are touched at most every few minutes, so a shorter TTL would
+	// drop sessions that are in use
+	check(c.Sweeper.SessionTTL >= time.Hour, "sweeper.session_ttl must be at least 1h")
+	check(c.Sweeper.ConfidentialSessionTTL >= time.Hour, "sweeper.confidential_session_ttl must be at least 1h")
+
+	return errors.Join(errs...)
+}
+
+// sessionKeySize is the length of a decoded session key, for AES-256
+const sessionKeySize = 32
+
+// parseSessionKey decodes a base64 session key
+func parseSessionKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, errors.New("must be base64 encoded")
+	}
+	if len(key) != sessionKeySize {
+		return nil, fmt.Errorf("must be %d bytes, got %d", sessionKeySize, len(key))
+	}
+	return key, nil
+}
+
+// readSessionKey reads a base64 session key from a file
+func readSessionKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading session key: %w", err)
+	}
+	key, err := parseSessionKey(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("session key in %s %w", path, err)
+	}
+	return key, nil
+}
+
+// SessionKeys returns the decoded current and old session keys, reading key
+// files. current is nil when session encryption is off.
+func (d DatabaseConfig) SessionKeys() (current []byte, old [][]byte, err error) {
+	switch {
+	case d.SessionKey != "":
+		if current, err = parseSessionKey(d.SessionKey); err != nil {
+			return nil, nil, fmt.Errorf("database.session_key %w", err)
+		}
+	case d.SessionKeyFile != "":
+		if current, err = readSessionKey(d.SessionKeyFile); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, s := range d.OldSessionKeys {
+		key, err := parseSessionKey(s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("database.old_session_keys entry %w", err)
+		}
+		old = append(old, key)
+	}
+	for _, path := range d.OldSessionKeyFiles {
+		key, err := readSessionKey(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		old = append(old, key)
+	}
+	return current, old, nil
+}
+
+// redacted replaces secrets when the configuration is printed
+const redacted = "REDACTED"
+
+// Write prints the configuration as YAML, in the same format Load reads.
+// Inline session keys are redacted.
+func (c *Config) Write(w io.Writer) error {
+	out := *c
+	if out.Database.SessionKey != "" {
+		out.Database.SessionKey = redacted
+	}
+	if n := len(out.Database.OldSessionKeys); n > 0 {
+		out.Database.OldSessionKeys = slices.Repeat([]string{redacted}, n)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&out); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,344 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "arabica.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// clearEnv blanks every variable Load reads so the caller's environment
+// can't leak into a test
+func clearEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("ARABICA_CONFIG", "")
+	for _, ev := range envVars {
+		t.Setenv(ev.name, "")
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("XDG_DATA_HOME", "/data")
+
+	cfg, opts, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.Port != 18910 {
+		t.Errorf("port = %d, want 18910", cfg.Server.Port)
+	}
+	if want := filepath.Join("/data", "arabica", "arabica.db"); cfg.Database.Path != want {
+		t.Errorf("database path = %q, want %q", cfg.Database.Path, want)
+	}
+	if opts.ConfigPath != "" || opts.PrintConfig {
+		t.Errorf("opts = %+v, want zero", opts)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+server:
+  port: 8000
+  public_url: https://file.example.com/
+log:
+  level: warn
+cache:
+  ttl: 30s
+rate_limits:
+  auth: 10
+`)
+	clearEnv(t)
+	t.Setenv("ARABICA_CONFIG", path)
+	t.Setenv("ARABICA_DB_PATH", "/tmp/arabica.db")
+	t.Setenv("PORT", "9000")
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("ARABICA_ADMIN_DIDS", "did:plc:alice, did:plc:bob,")
+	t.Setenv("ARABICA_LABELERS", "did:plc:labeler=https://labeler.example.com")
+
+	cfg, opts, err := Load([]string{"-port", "9100"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"config path from env", opts.ConfigPath, path},
+		{"flag beats env and file", cfg.Server.Port, 9100},
+		{"env beats file", cfg.Log.Level, "debug"},
+		{"file beats default", cfg.Cache.TTL, 30 * time.Second},
+		{"file value, trailing slash trimmed", cfg.Server.PublicURL, "https://file.example.com"},
+		{"partial section keeps other defaults", cfg.RateLimits.API, 60},
+		{"file overrides one field of section", cfg.RateLimits.Auth, 10},
+		{"untouched default", cfg.Feed.CacheTTL, 5 * time.Minute},
+		{"comma-separated env list", strings.Join(cfg.Admin.DIDs, " "), "did:plc:alice did:plc:bob"},
+		{"labeler from env", len(cfg.Labels.Labelers), 1},
+		{"labeler URL from env", cfg.Labels.Labelers[0].URL, "https://labeler.example.com"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "unknown key in file",
+			file:    "server:\n  prot: 80\n",
+			wantErr: "field prot not found",
+		},
+		{
+			name:    "bad env value",
+			env:     map[string]string{"PORT": "eighty"},
+			wantErr: "invalid PORT",
+		},
+		{
+			name:    "labeler without URL in env",
+			env:     map[string]string{"ARABICA_LABELERS": "did:plc:labeler"},
+			wantErr: "must be written as DID=URL",
+		},
+		{
+			name:    "bad duration in env",
+			env:     map[string]string{"ARABICA_CACHE_TTL": "soon"},
+			wantErr: "invalid ARABICA_CACHE_TTL",
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"-nope"},
+			wantErr: "flag provided but not defined",
+		},
+		{
+			name:    "validation failure",
+			args:    []string{"-log-level", "loud"},
+			wantErr: "log.level",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("ARABICA_DB_PATH", "/tmp/arabica.db")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, tt.file)}, args...)
+			}
+
+			_, _, err := Load(args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{
+			name:   "defaults",
+			modify: func(c *Config) {},
+		},
+		{
+			name:    "port out of range",
+			modify:  func(c *Config) { c.Server.Port = 70000 },
+			wantErr: "server.port",
+		},
+		{
+			name:    "relative public url",
+			modify:  func(c *Config) { c.Server.PublicURL = "arabica.example.com" },
+			wantErr: "server.public_url",
+		},
+		{
+			name:    "only client id",
+			modify:  func(c *Config) { c.OAuth.ClientID = "https://arabica.example.com/oauth-client-metadata.json" },
+			wantErr: "oauth.client_id",
+		},
+		{
+			name:    "metrics address without port",
+			modify:  func(c *Config) { c.Metrics.Listen = "127.0.0.1" },
+			wantErr: "metrics.listen",
+		},
+		{
+			name:    "signing key in localhost mode",
+			modify:  func(c *Config) { c.OAuth.SigningKeyFile = "/run/secrets/oauth-key" },
+			wantErr: "oauth.signing_key_file",
+		},
+		{
+			name:    "refresh slower than cache expiry",
+			modify:  func(c *Config) { c.Feed.RefreshInterval = 10 * time.Minute },
+			wantErr: "feed.refresh_interval",
+		},
+		{
+			name:    "zero rate limit",
+			modify:  func(c *Config) { c.RateLimits.Global = 0 },
+			wantErr: "rate_limits",
+		},
+		{
+			name:    "admin handle instead of DID",
+			modify:  func(c *Config) { c.Admin.DIDs = []string{"alice.bsky.social"} },
+			wantErr: "admin.dids",
+		},
+		{
+			name: "labeler without URL",
+			modify: func(c *Config) {
+				c.Labels.Labelers = []LabelerConfig{{DID: "did:plc:labeler"}}
+			},
+			wantErr: "labels.labelers url",
+		},
+		{
+			name:    "session ttl shorter than touch interval",
+			modify:  func(c *Config) { c.Sweeper.SessionTTL = time.Minute },
+			wantErr: "sweeper.session_ttl",
+		},
+		{
+			name:    "confidential session ttl shorter than touch interval",
+			modify:  func(c *Config) { c.Sweeper.ConfidentialSessionTTL = time.Minute },
+			wantErr: "sweeper.confidential_session_ttl",
+		},
+		{
+			name:    "short session key",
+			modify:  func(c *Config) { c.Database.SessionKey = "c2hvcnQ=" },
+			wantErr: "database.session_key must be 32 bytes",
+		},
+		{
+			name: "session key and key file",
+			modify: func(c *Config) {
+				c.Database.SessionKey = testSessionKey
+				c.Database.SessionKeyFile = "/run/secrets/session_key"
+			},
+			wantErr: "can't both be set",
+		},
+		{
+			name:    "old session key without a current one",
+			modify:  func(c *Config) { c.Database.OldSessionKeys = []string{testSessionKey} },
+			wantErr: "database.old_session_keys need",
+		},
+		{
+			name: "all errors reported",
+			modify: func(c *Config) {
+				c.Log.Format = "xml"
+				c.Cache.TTL = 0
+			},
+			wantErr: "log.format \"xml\" must be console or json\ncache.ttl",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Database.Path = "/tmp/arabica.db"
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Path = "/tmp/arabica.db"
+	cfg.Feed.RefreshInterval = 90 * time.Second
+	cfg.Admin.DIDs = []string{"did:plc:alice"}
+	cfg.Labels.Labelers = []LabelerConfig{{DID: "did:plc:labeler", URL: "https://labeler.example.com"}}
+
+	var buf bytes.Buffer
+	if err := cfg.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "refresh_interval: 1m30s") {
+		t.Errorf("durations should be written in Go syntax, got:\n%s", buf.String())
+	}
+
+	loaded := Default()
+	if err := loaded.loadFile(writeFile(t, buf.String())); err != nil {
+		t.Fatalf("loadFile() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", loaded, cfg)
+	}
+}
+
+// testSessionKey is 32 bytes of 0x01, base64 encoded
+var testSessionKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+
+func TestSessionKeys(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{2}, 32)
+	d := DatabaseConfig{
+		SessionKeyFile:     writeFile(t, testSessionKey+"\n"),
+		OldSessionKeys:     []string{base64.StdEncoding.EncodeToString(oldKey)},
+		OldSessionKeyFiles: []string{writeFile(t, "bm90IGEga2V5")},
+	}
+	if _, _, err := d.SessionKeys(); err == nil || !strings.Contains(err.Error(), "must be 32 bytes") {
+		t.Errorf("SessionKeys() error = %v, want a bad key file error", err)
+	}
+
+	d.OldSessionKeyFiles = nil
+	current, old, err := d.SessionKeys()
+	if err != nil {
+		t.Fatalf("SessionKeys() error = %v", err)
+	}
+	if !bytes.Equal(current, bytes.Repeat([]byte{1}, 32)) || len(old) != 1 || !bytes.Equal(old[0], oldKey) {
+		t.Errorf("SessionKeys() = %x, %x, want the file key and one old key", current, old)
+	}
+
+	if current, _, _ := (DatabaseConfig{}).SessionKeys(); current != nil {
+		t.Errorf("SessionKeys() without keys = %x, want nil", current)
+	}
+}
+
+func TestWriteRedactsSessionKeys(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Path = "/tmp/arabica.db"
+	cfg.Database.SessionKey = testSessionKey
+	cfg.Database.OldSessionKeys = []string{testSessionKey}
+
+	var buf bytes.Buffer
+	if err := cfg.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if strings.Contains(buf.String(), testSessionKey) || !strings.Contains(buf.String(), "session_key: REDACTED") {
+		t.Errorf("session keys should be redacted, got:\n%s", buf.String())
+	}
+	if cfg.Database.SessionKey != testSessionKey {
+		t.Error("Write changed the configuration")
+	}
+}
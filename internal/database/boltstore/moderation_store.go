@@ -0,0 +1,139 @@
+package boltstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ModerationEntry records a moderation action on a DID or a record.
+type ModerationEntry struct {
+	// Subject is the banned DID or the AT-URI of the hidden record
+	Subject   string    `json:"subject"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"` // DID of the admin who acted
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ModerationStore provides persistent storage for instance moderation:
+// DIDs banned from the community feed and individual records hidden from it.
+// Moderation only affects what this server shows; records stay in users' repos.
+type ModerationStore struct {
+	db *bolt.DB
+}
+
+// BanDID keeps a DID out of the community feed and its profile page.
+func (s *ModerationStore) BanDID(did, reason, by string) error {
+	return s.put(BucketBannedDIDs, did, reason, by)
+}
+
+// UnbanDID lifts a ban. Unbanning a DID that isn't banned is a no-op.
+func (s *ModerationStore) UnbanDID(did string) error {
+	return s.delete(BucketBannedDIDs, did)
+}
+
+// IsBanned reports whether a DID is banned.
+func (s *ModerationStore) IsBanned(did string) bool {
+	return s.has(BucketBannedDIDs, did)
+}
+
+// ListBans returns all banned DIDs, most recent first.
+func (s *ModerationStore) ListBans() ([]ModerationEntry, error) {
+	return s.list(BucketBannedDIDs)
+}
+
+// HideRecord keeps a record, identified by its AT-URI, out of the community
+// feed, search and profile pages.
+func (s *ModerationStore) HideRecord(uri, reason, by string) error {
+	return s.put(BucketHiddenRecords, uri, reason, by)
+}
+
+// UnhideRecord makes a hidden record visible again.
+func (s *ModerationStore) UnhideRecord(uri string) error {
+	return s.delete(BucketHiddenRecords, uri)
+}
+
+// IsHidden reports whether the record at an AT-URI is hidden.
+func (s *ModerationStore) IsHidden(uri string) bool {
+	return s.has(BucketHiddenRecords, uri)
+}
+
+// ListHidden returns all hidden records, most recent first.
+func (s *ModerationStore) ListHidden() ([]ModerationEntry, error) {
+	return s.list(BucketHiddenRecords)
+}
+
+func (s *ModerationStore) put(bucketName []byte, subject, reason, by string) error {
+	entry := ModerationEntry{
+		Subject:   subject,
+		Reason:    reason,
+		CreatedBy: by,
+		CreatedAt: time.Now(),
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			return fmt.Errorf("bucket %s not found", bucketName)
+		}
+		return bucket.Put([]byte(subject), data)
+	})
+}
+
+func (s *ModerationStore) delete(bucketName []byte, subject string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(subject))
+	})
+}
+
+func (s *ModerationStore) has(bucketName []byte, subject string) bool {
+	var found bool
+	s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			return nil
+		}
+		found = bucket.Get([]byte(subject)) != nil
+		return nil
+	})
+	return found
+}
+
+func (s *ModerationStore) list(bucketName []byte) ([]ModerationEntry, error) {
+	var entries []ModerationEntry
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			var entry ModerationEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				entry = ModerationEntry{Subject: string(k)}
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read moderation entries: %w", err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
@@ -0,0 +1,116 @@
+package boltstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"arabica/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// UserPreferences holds the app preferences saved for a user.
+type UserPreferences struct {
+	Units     models.UnitPreferences  `json:"units"`
+	Labels    models.LabelPreferences `json:"labels,omitempty"`
+	UpdatedAt time.Time               `json:"updated_at"`
+}
+
+// PreferencesStore provides persistent storage for per-user preferences.
+// Preferences are private to this server and are not published to the PDS,
+// unlike the settings record; see models.Settings for which goes where.
+type PreferencesStore struct {
+	db *bolt.DB
+}
+
+// GetUnits returns the unit preferences for a DID.
+// Users who have not saved any preferences get the zero value (metric).
+func (s *PreferencesStore) GetUnits(did string) (models.UnitPreferences, error) {
+	prefs, err := s.get(did)
+	return prefs.Units, err
+}
+
+// SetUnits saves the unit preferences for a DID.
+func (s *PreferencesStore) SetUnits(did string, units models.UnitPreferences) error {
+	return s.update(did, func(prefs *UserPreferences) {
+		prefs.Units = units
+	})
+}
+
+// GetLabels returns how a DID wants to see labeled content.
+// Users who have not saved any preferences get nil, meaning the defaults.
+func (s *PreferencesStore) GetLabels(did string) (models.LabelPreferences, error) {
+	prefs, err := s.get(did)
+	return prefs.Labels, err
+}
+
+// SetLabels saves how a DID wants to see labeled content.
+func (s *PreferencesStore) SetLabels(did string, labels models.LabelPreferences) error {
+	return s.update(did, func(prefs *UserPreferences) {
+		prefs.Labels = labels
+	})
+}
+
+// get reads all preferences for a DID
+func (s *PreferencesStore) get(did string) (UserPreferences, error) {
+	var prefs UserPreferences
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BucketPreferences)
+		if bucket == nil {
+			return nil
+		}
+
+		data := bucket.Get([]byte(did))
+		if data == nil {
+			return nil
+		}
+
+		return json.Unmarshal(data, &prefs)
+	})
+	if err != nil {
+		return UserPreferences{}, fmt.Errorf("failed to read preferences: %w", err)
+	}
+
+	return prefs, nil
+}
+
+// update changes some preferences for a DID, keeping the rest
+func (s *PreferencesStore) update(did string, change func(*UserPreferences)) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BucketPreferences)
+		if bucket == nil {
+			return nil
+		}
+
+		var prefs UserPreferences
+		if existing := bucket.Get([]byte(did)); existing != nil {
+			if err := json.Unmarshal(existing, &prefs); err != nil {
+				return err
+			}
+		}
+
+		change(&prefs)
+		prefs.UpdatedAt = time.Now()
+
+		data, err := json.Marshal(prefs)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(did), data)
+	})
+}
+
+// Delete removes all preferences for a DID.
+func (s *PreferencesStore) Delete(did string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BucketPreferences)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.Delete([]byte(did))
+	})
+}
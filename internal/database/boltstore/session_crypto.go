@@ -0,0 +1,213 @@
+package boltstore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// SessionKeySize is the length of a session encryption key, for AES-256
+const SessionKeySize = 32
+
+// Sealed entries are laid out as
+//
+//	magic | key ID | nonce, wrapped data key | nonce, ciphertext
+//
+// Each entry has its own random data key, encrypted ("wrapped") with the
+// configured key, so rotating the key only re-encrypts the short data keys.
+// The entry's bucket key is bound to both as additional data, so a sealed
+// value can't be moved to another session. Plaintext JSON never starts with
+// the magic's NUL byte, which is how entries written before encryption was
+// enabled are told apart.
+const sealMagic = "\x00as1"
+
+const (
+	keyIDSize      = 8
+	nonceSize      = 12
+	wrappedKeySize = nonceSize + SessionKeySize + 16
+	sealHeaderSize = len(sealMagic) + keyIDSize + wrappedKeySize
+)
+
+var (
+	// ErrSessionKeyMissing is returned when reading an encrypted entry from a
+	// store opened without session keys
+	ErrSessionKeyMissing = errors.New("session is encrypted but no session key is configured")
+
+	// ErrSessionKeyUnknown is returned when an entry was encrypted with a key
+	// that is neither the current nor an old session key
+	ErrSessionKeyUnknown = errors.New("session is encrypted with an unknown key")
+)
+
+// Keyring holds the key that encrypts OAuth sessions and auth requests at
+// rest, and old keys that can still decrypt entries written before a rotation
+type Keyring struct {
+	current *keyringKey
+	keys    map[string]*keyringKey
+}
+
+type keyringKey struct {
+	id   []byte
+	aead cipher.AEAD
+}
+
+// NewKeyring builds a keyring encrypting with current and decrypting with
+// current or any of old. It returns nil when current is nil, leaving
+// sessions unencrypted.
+func NewKeyring(current []byte, old [][]byte) (*Keyring, error) {
+	if current == nil {
+		if len(old) > 0 {
+			return nil, errors.New("old session keys need a current session key")
+		}
+		return nil, nil
+	}
+
+	k := &Keyring{keys: make(map[string]*keyringKey)}
+	for i, raw := range append([][]byte{current}, old...) {
+		key, err := newKeyringKey(raw)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			k.current = key
+		}
+		if _, dup := k.keys[string(key.id)]; !dup {
+			k.keys[string(key.id)] = key
+		}
+	}
+	return k, nil
+}
+
+func newKeyringKey(raw []byte) (*keyringKey, error) {
+	if len(raw) != SessionKeySize {
+		return nil, fmt.Errorf("session key is %d bytes, want %d", len(raw), SessionKeySize)
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(append([]byte("arabica session key "), raw...))
+	return &keyringKey{id: sum[:keyIDSize], aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// KeyID identifies the current key in logs and admin output without
+// revealing it
+func (k *Keyring) KeyID() string {
+	return hex.EncodeToString(k.current.id)
+}
+
+// seal encrypts plaintext stored under the bucket key ad
+func (k *Keyring) seal(ad, plaintext []byte) ([]byte, error) {
+	dataKey := make([]byte, SessionKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, sealHeaderSize+nonceSize+len(plaintext)+aead.Overhead())
+	out = append(out, sealMagic...)
+	if out, err = k.current.wrap(out, ad, dataKey); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, ad), nil
+}
+
+// open decrypts a sealed value stored under the bucket key ad
+func (k *Keyring) open(ad, sealed []byte) ([]byte, error) {
+	dataKey, err := k.unwrap(ad, sealed)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	body := sealed[sealHeaderSize:]
+	if len(body) < nonceSize {
+		return nil, errors.New("sealed session is truncated")
+	}
+	plaintext, err := aead.Open(nil, body[:nonceSize], body[nonceSize:], ad)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting session: %w", err)
+	}
+	return plaintext, nil
+}
+
+// rewrap re-encrypts the data key of a sealed value with the current key,
+// leaving the ciphertext as it is
+func (k *Keyring) rewrap(ad, sealed []byte) ([]byte, error) {
+	dataKey, err := k.unwrap(ad, sealed)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(sealed))
+	out = append(out, sealMagic...)
+	if out, err = k.current.wrap(out, ad, dataKey); err != nil {
+		return nil, err
+	}
+	return append(out, sealed[sealHeaderSize:]...), nil
+}
+
+// unwrap decrypts the data key of a sealed value with the key that wrapped it
+func (k *Keyring) unwrap(ad, sealed []byte) ([]byte, error) {
+	if len(sealed) < sealHeaderSize {
+		return nil, errors.New("sealed session is truncated")
+	}
+	key, ok := k.keys[string(sealedKeyID(sealed))]
+	if !ok {
+		return nil, fmt.Errorf("%w %x", ErrSessionKeyUnknown, sealedKeyID(sealed))
+	}
+	wrapped := sealed[len(sealMagic)+keyIDSize : sealHeaderSize]
+	dataKey, err := key.aead.Open(nil, wrapped[:nonceSize], wrapped[nonceSize:], ad)
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping session key: %w", err)
+	}
+	return dataKey, nil
+}
+
+// wrap appends the key ID and dataKey encrypted with key to out
+func (key *keyringKey) wrap(out, ad, dataKey []byte) ([]byte, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out = append(out, key.id...)
+	out = append(out, nonce...)
+	return key.aead.Seal(out, nonce, dataKey, ad), nil
+}
+
+// isCurrent reports whether a sealed value's data key is wrapped with the
+// current key
+func (k *Keyring) isCurrent(sealed []byte) bool {
+	return len(sealed) >= sealHeaderSize && bytes.Equal(sealedKeyID(sealed), k.current.id)
+}
+
+// isSealed reports whether a stored value is encrypted
+func isSealed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(sealMagic))
+}
+
+// sealedKeyID returns the ID of the key that wrapped a sealed value
+func sealedKeyID(sealed []byte) []byte {
+	return sealed[len(sealMagic) : len(sealMagic)+keyIDSize]
+}
@@ -0,0 +1,185 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	bolt "go.etcd.io/bbolt"
+)
+
+// testKey returns a session key filled with b
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, SessionKeySize)
+}
+
+func newTestKeyring(t *testing.T, current []byte, old ...[]byte) *Keyring {
+	t.Helper()
+	keys, err := NewKeyring(current, old)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return keys
+}
+
+func TestKeyringSealOpen(t *testing.T) {
+	keys := newTestKeyring(t, testKey(1))
+	ad := []byte("did:plc:alice:session")
+	plaintext := []byte(`{"session_id":"session"}`)
+
+	sealed, err := keys.seal(ad, plaintext)
+	if err != nil {
+		t.Fatalf("seal() error = %v", err)
+	}
+	if !isSealed(sealed) || bytes.Contains(sealed, plaintext) {
+		t.Fatalf("seal() = %q, want an encrypted value", sealed)
+	}
+	if isSealed(plaintext) {
+		t.Error("isSealed() reports plaintext JSON as sealed")
+	}
+
+	got, err := keys.open(ad, sealed)
+	if err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("open() = %q, want %q", got, plaintext)
+	}
+
+	// A value copied under another session's bucket key doesn't open
+	if _, err := keys.open([]byte("did:plc:mallory:session"), sealed); err == nil {
+		t.Error("open() under another bucket key succeeded")
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	oldKey, newKey := testKey(1), testKey(2)
+	ad := []byte("did:plc:alice:session")
+	plaintext := []byte(`{"session_id":"session"}`)
+
+	sealed, err := newTestKeyring(t, oldKey).seal(ad, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newTestKeyring(t, newKey, oldKey)
+	if rotated.isCurrent(sealed) {
+		t.Error("isCurrent() = true for a value sealed with the old key")
+	}
+	if got, err := rotated.open(ad, sealed); err != nil || !bytes.Equal(got, plaintext) {
+		t.Fatalf("open() after rotation = %q, %v, want %q", got, err, plaintext)
+	}
+
+	rewrapped, err := rotated.rewrap(ad, sealed)
+	if err != nil {
+		t.Fatalf("rewrap() error = %v", err)
+	}
+	if !rotated.isCurrent(rewrapped) {
+		t.Error("isCurrent() = false after rewrap()")
+	}
+	if got, err := newTestKeyring(t, newKey).open(ad, rewrapped); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("open() with only the new key = %q, %v, want %q", got, err, plaintext)
+	}
+}
+
+func TestKeyringOpenErrors(t *testing.T) {
+	ad := []byte("did:plc:alice:session")
+	keys := newTestKeyring(t, testKey(1))
+	sealed, err := keys.seal(ad, []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		keys    *Keyring
+		sealed  []byte
+		unknown bool
+	}{
+		{"unknown key", newTestKeyring(t, testKey(2)), sealed, true},
+		{"magic only", keys, []byte(sealMagic), false},
+		{"truncated header", keys, sealed[:sealHeaderSize-1], false},
+		{"truncated body", keys, sealed[:sealHeaderSize+nonceSize-1], false},
+		{"tampered ciphertext", keys, tampered, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.keys.open(ad, tt.sealed)
+			if err == nil {
+				t.Fatal("open() error = nil")
+			}
+			if got := errors.Is(err, ErrSessionKeyUnknown); got != tt.unknown {
+				t.Errorf("open() error = %v, want ErrSessionKeyUnknown: %v", err, tt.unknown)
+			}
+		})
+	}
+}
+
+func TestNewKeyring(t *testing.T) {
+	if keys, err := NewKeyring(nil, nil); keys != nil || err != nil {
+		t.Errorf("NewKeyring(nil) = %v, %v, want no keyring", keys, err)
+	}
+	if _, err := NewKeyring(nil, [][]byte{testKey(1)}); err == nil {
+		t.Error("NewKeyring() accepted old keys without a current key")
+	}
+	if _, err := NewKeyring([]byte("short"), nil); err == nil {
+		t.Error("NewKeyring() accepted a short key")
+	}
+}
+
+func TestEncryptSessions(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+	session := oauth.ClientSessionData{AccountDID: "did:plc:alice", SessionID: "session", HostURL: "https://pds.example.com"}
+
+	// Written before a session key was configured
+	store, err := Open(Options{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SessionStore().SaveSession(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	store, err = Open(Options{Path: path, SessionKeys: newTestKeyring(t, testKey(1))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	s := store.SessionStore()
+
+	result, err := s.EncryptSessions(ctx)
+	if err != nil {
+		t.Fatalf("EncryptSessions() error = %v", err)
+	}
+	if result.Encrypted != 1 {
+		t.Errorf("EncryptSessions() encrypted %d entries, want 1", result.Encrypted)
+	}
+
+	var raw []byte
+	store.db.View(func(tx *bolt.Tx) error {
+		raw = append(raw, tx.Bucket(BucketSessions).Get(sessionKey(session.AccountDID, session.SessionID))...)
+		return nil
+	})
+	if !isSealed(raw) {
+		t.Errorf("stored session = %q, want it encrypted", raw)
+	}
+	got, err := s.GetSession(ctx, session.AccountDID, session.SessionID)
+	if err != nil {
+		t.Fatalf("GetSession() error = %v", err)
+	}
+	if got.HostURL != session.HostURL {
+		t.Errorf("GetSession() host = %q, want %q", got.HostURL, session.HostURL)
+	}
+
+	// Running it again has nothing left to do
+	if result, err := s.EncryptSessions(ctx); err != nil || result.Encrypted != 0 {
+		t.Errorf("second EncryptSessions() = %+v, %v, want nothing encrypted", result, err)
+	}
+}
@@ -1,9 +1,13 @@
 package boltstore
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/bluesky-social/indigo/atproto/auth/oauth"
 	"github.com/bluesky-social/indigo/atproto/syntax"
@@ -12,14 +16,37 @@ import (
 
 // SessionStore implements oauth.ClientAuthStore using BoltDB for persistence.
 // It stores OAuth sessions and auth request data, allowing sessions to survive
-// server restarts.
+// server restarts. When the store was opened with a Keyring, sessions and
+// auth requests, which hold refresh tokens and DPoP private keys, are
+// encrypted at rest.
 type SessionStore struct {
-	db *bolt.DB
+	db   *bolt.DB
+	keys *Keyring
 }
 
 // Ensure SessionStore implements oauth.ClientAuthStore
 var _ oauth.ClientAuthStore = (*SessionStore)(nil)
 
+// sessionTouchInterval is how stale a session's last use may get before
+// TouchSession writes it again, so busy sessions don't write on every request
+const sessionTouchInterval = 5 * time.Minute
+
+// SessionActivity records when and from where a session was used, so users can
+// recognize their sessions. Sessions created before activity was tracked have
+// a zero CreatedAt until they log in again.
+type SessionActivity struct {
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+}
+
+// UserSession is one of an account's sessions with its activity
+type UserSession struct {
+	SessionID string
+	SessionActivity
+}
+
 // sessionKey generates a composite key for session storage: "did:sessionID"
 func sessionKey(did syntax.DID, sessionID string) []byte {
 	return []byte(did.String() + ":" + sessionID)
@@ -36,12 +63,13 @@ func (s *SessionStore) GetSession(ctx context.Context, did syntax.DID, sessionID
 			return fmt.Errorf("session bucket not found")
 		}
 
-		data := bucket.Get(sessionKey(did, sessionID))
+		key := sessionKey(did, sessionID)
+		data := bucket.Get(key)
 		if data == nil {
 			return fmt.Errorf("session not found")
 		}
 
-		return json.Unmarshal(data, &session)
+		return s.unmarshal(key, data, &session)
 	})
 
 	if err != nil {
@@ -54,7 +82,8 @@ func (s *SessionStore) GetSession(ctx context.Context, did syntax.DID, sessionID
 // SaveSession persists a session (upsert operation).
 // If a session with the same DID and sessionID exists, it will be updated.
 func (s *SessionStore) SaveSession(ctx context.Context, sess oauth.ClientSessionData) error {
-	data, err := json.Marshal(sess)
+	key := sessionKey(sess.AccountDID, sess.SessionID)
+	data, err := s.marshal(key, sess)
 	if err != nil {
 		return fmt.Errorf("failed to marshal session: %w", err)
 	}
@@ -65,7 +94,7 @@ func (s *SessionStore) SaveSession(ctx context.Context, sess oauth.ClientSession
 			return fmt.Errorf("session bucket not found")
 		}
 
-		return bucket.Put(sessionKey(sess.AccountDID, sess.SessionID), data)
+		return bucket.Put(key, data)
 	})
 }
 
@@ -77,7 +106,10 @@ func (s *SessionStore) DeleteSession(ctx context.Context, did syntax.DID, sessio
 			return fmt.Errorf("session bucket not found")
 		}
 
-		return bucket.Delete(sessionKey(did, sessionID))
+		if err := bucket.Delete(sessionKey(did, sessionID)); err != nil {
+			return err
+		}
+		return deleteSessionMeta(tx, sessionKey(did, sessionID))
 	})
 }
 
@@ -96,7 +128,7 @@ func (s *SessionStore) GetAuthRequestInfo(ctx context.Context, state string) (*o
 			return fmt.Errorf("auth request not found")
 		}
 
-		return json.Unmarshal(data, &info)
+		return s.unmarshal([]byte(state), data, &info)
 	})
 
 	if err != nil {
@@ -109,7 +141,7 @@ func (s *SessionStore) GetAuthRequestInfo(ctx context.Context, state string) (*o
 // SaveAuthRequestInfo stores auth request data keyed by state token.
 // This is a create-only operation per the oauth.ClientAuthStore contract.
 func (s *SessionStore) SaveAuthRequestInfo(ctx context.Context, info oauth.AuthRequestData) error {
-	data, err := json.Marshal(info)
+	data, err := s.marshal([]byte(info.State), info)
 	if err != nil {
 		return fmt.Errorf("failed to marshal auth request: %w", err)
 	}
@@ -120,7 +152,10 @@ func (s *SessionStore) SaveAuthRequestInfo(ctx context.Context, info oauth.AuthR
 			return fmt.Errorf("auth requests bucket not found")
 		}
 
-		return bucket.Put([]byte(info.State), data)
+		if err := bucket.Put([]byte(info.State), data); err != nil {
+			return err
+		}
+		return putAuthRequestTime(tx, []byte(info.State), time.Now())
 	})
 }
 
@@ -132,7 +167,13 @@ func (s *SessionStore) DeleteAuthRequestInfo(ctx context.Context, state string)
 			return fmt.Errorf("auth requests bucket not found")
 		}
 
-		return bucket.Delete([]byte(state))
+		if err := bucket.Delete([]byte(state)); err != nil {
+			return err
+		}
+		if times := tx.Bucket(BucketAuthRequestTimes); times != nil {
+			return times.Delete([]byte(state))
+		}
+		return nil
 	})
 }
 
@@ -148,8 +189,8 @@ func (s *SessionStore) ListSessions(ctx context.Context) ([]oauth.ClientSessionD
 
 		return bucket.ForEach(func(k, v []byte) error {
 			var session oauth.ClientSessionData
-			if err := json.Unmarshal(v, &session); err != nil {
-				// Skip malformed entries
+			if err := s.unmarshal(k, v, &session); err != nil {
+				// Skip malformed entries and ones that can't be decrypted
 				return nil
 			}
 			sessions = append(sessions, session)
@@ -200,8 +241,297 @@ func (s *SessionStore) DeleteAllSessionsForDID(ctx context.Context, did syntax.D
 			if err := bucket.Delete(k); err != nil {
 				return err
 			}
+			if err := deleteSessionMeta(tx, k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// RecordLogin stores the activity of a session that was just created
+func (s *SessionStore) RecordLogin(ctx context.Context, did syntax.DID, sessionID, userAgent, ip string) error {
+	now := time.Now()
+	activity := &SessionActivity{
+		CreatedAt:  now,
+		LastUsedAt: now,
+		UserAgent:  userAgent,
+		IP:         ip,
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putActivity(tx, sessionKey(did, sessionID), activity)
+	})
+}
+
+// TouchSession records that a session was just used. The write is skipped
+// when the last recorded use is recent.
+func (s *SessionStore) TouchSession(ctx context.Context, did syntax.DID, sessionID string) error {
+	key := sessionKey(did, sessionID)
+	activity, err := s.getActivity(key)
+	if err != nil {
+		return err
+	}
+	if time.Since(activity.LastUsedAt) < sessionTouchInterval {
+		return nil
+	}
+	activity.LastUsedAt = time.Now()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		// Don't bring back the activity of a session revoked in the meantime
+		if sessions := tx.Bucket(BucketSessions); sessions == nil || sessions.Get(key) == nil {
+			return nil
+		}
+		return putActivity(tx, key, activity)
+	})
+}
+
+// ListSessionsForDID returns the sessions of did, most recently used first
+func (s *SessionStore) ListSessionsForDID(ctx context.Context, did syntax.DID) ([]UserSession, error) {
+	prefix := []byte(did.String() + ":")
+	var sessions []UserSession
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BucketSessions)
+		if bucket == nil {
+			return nil
+		}
+		activity := tx.Bucket(BucketSessionActivity)
+
+		c := bucket.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			sess := UserSession{SessionID: string(k[len(prefix):])}
+			if activity != nil {
+				if data := activity.Get(k); data != nil {
+					// Unreadable activity just leaves the fields empty
+					_ = json.Unmarshal(data, &sess.SessionActivity)
+				}
+			}
+			sessions = append(sessions, sess)
+		}
+		return nil
+	})
+
+	slices.SortStableFunc(sessions, func(a, b UserSession) int {
+		return b.LastUsedAt.Compare(a.LastUsedAt)
+	})
+	return sessions, err
+}
+
+// DeleteOtherSessionsForDID removes every session of did except keep, logging
+// it out on other devices, and returns how many were removed
+func (s *SessionStore) DeleteOtherSessionsForDID(ctx context.Context, did syntax.DID, keep string) (int, error) {
+	prefix := []byte(did.String() + ":")
+	keepKey := sessionKey(did, keep)
+	count := 0
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BucketSessions)
+		if bucket == nil {
+			return nil
+		}
+
+		// Collect keys to delete (can't delete while iterating)
+		var keysToDelete [][]byte
+		c := bucket.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if !bytes.Equal(k, keepKey) {
+				keysToDelete = append(keysToDelete, append([]byte{}, k...))
+			}
+		}
+
+		for _, k := range keysToDelete {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+			if err := deleteSessionMeta(tx, k); err != nil {
+				return err
+			}
+		}
+		count = len(keysToDelete)
+		return nil
+	})
+
+	return count, err
+}
+
+// getActivity reads a session's activity, returning an empty one if none was recorded
+func (s *SessionStore) getActivity(key []byte) (*SessionActivity, error) {
+	var activity SessionActivity
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BucketSessionActivity)
+		if bucket == nil {
+			return fmt.Errorf("session activity bucket not found")
 		}
+		data := bucket.Get(key)
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &activity)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &activity, nil
+}
 
+// putActivity stores a session's activity
+func putActivity(tx *bolt.Tx, key []byte, activity *SessionActivity) error {
+	data, err := json.Marshal(activity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session activity: %w", err)
+	}
+
+	bucket := tx.Bucket(BucketSessionActivity)
+	if bucket == nil {
+		return fmt.Errorf("session activity bucket not found")
+	}
+	return bucket.Put(key, data)
+}
+
+// deleteSessionMeta removes a session's activity and client key along with the session
+func deleteSessionMeta(tx *bolt.Tx, key []byte) error {
+	for _, name := range [][]byte{BucketSessionActivity, BucketSessionClientKeys} {
+		if bucket := tx.Bucket(name); bucket != nil {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetSessionClientKey records the ID of the client key a confidential OAuth
+// client created a session with
+func (s *SessionStore) SetSessionClientKey(ctx context.Context, did syntax.DID, sessionID, keyID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BucketSessionClientKeys)
+		if bucket == nil {
+			return fmt.Errorf("session client keys bucket not found")
+		}
+		return bucket.Put(sessionKey(did, sessionID), []byte(keyID))
+	})
+}
+
+// GetSessionClientKey returns the ID of the client key a session was created
+// with, or "" for sessions created by a public client
+func (s *SessionStore) GetSessionClientKey(ctx context.Context, did syntax.DID, sessionID string) (string, error) {
+	var keyID string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket(BucketSessionClientKeys); bucket != nil {
+			keyID = string(bucket.Get(sessionKey(did, sessionID)))
+		}
+		return nil
+	})
+	return keyID, err
+}
+
+// marshal encodes a session or auth request stored under key, encrypting it
+// when the store has a keyring
+func (s *SessionStore) marshal(key []byte, v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || s.keys == nil {
+		return data, err
+	}
+	return s.keys.seal(key, data)
+}
+
+// unmarshal decodes a session or auth request stored under key. Entries
+// written before encryption was enabled are read as they are.
+func (s *SessionStore) unmarshal(key, data []byte, v any) error {
+	if isSealed(data) {
+		if s.keys == nil {
+			return ErrSessionKeyMissing
+		}
+		var err error
+		if data, err = s.keys.open(key, data); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// EncryptResult counts the entries changed by EncryptSessions and RekeySessions
+type EncryptResult struct {
+	// KeyID identifies the current session key
+	KeyID string `json:"key_id"`
+	// Encrypted entries were stored in plaintext before
+	Encrypted int `json:"encrypted"`
+	// Rewrapped entries were encrypted with an old key
+	Rewrapped int `json:"rewrapped"`
+	// Unreadable entries, counted by RekeySessions, are encrypted with a key
+	// the store doesn't have
+	Unreadable int `json:"unreadable"`
+}
+
+// EncryptSessions encrypts sessions and auth requests still stored in
+// plaintext, which happens once after a session key is first configured.
+// It does nothing when the store has no keyring.
+func (s *SessionStore) EncryptSessions(ctx context.Context) (EncryptResult, error) {
+	return s.reseal(false)
+}
+
+// RekeySessions encrypts plaintext entries like EncryptSessions and moves
+// entries encrypted with an old key to the current one. Once it reports no
+// unreadable entries, the old keys can be removed from the configuration.
+func (s *SessionStore) RekeySessions(ctx context.Context) (EncryptResult, error) {
+	if s.keys == nil {
+		return EncryptResult{}, errors.New("no session key is configured")
+	}
+	return s.reseal(true)
+}
+
+// reseal encrypts plaintext entries and, if rewrap is set, rewraps entries
+// encrypted with old keys, in a single transaction
+func (s *SessionStore) reseal(rewrap bool) (EncryptResult, error) {
+	if s.keys == nil {
+		return EncryptResult{}, nil
+	}
+	result := EncryptResult{KeyID: s.keys.KeyID()}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{BucketSessions, BucketAuthRequests} {
+			bucket := tx.Bucket(name)
+			if bucket == nil {
+				continue
+			}
+
+			// Collect updates first; bolt doesn't allow writes while iterating
+			updates := make(map[string][]byte)
+			err := bucket.ForEach(func(k, v []byte) error {
+				var err error
+				switch {
+				case !isSealed(v):
+					if updates[string(k)], err = s.keys.seal(k, v); err != nil {
+						return err
+					}
+					result.Encrypted++
+				case !rewrap || s.keys.isCurrent(v):
+				default:
+					sealed, err := s.keys.rewrap(k, v)
+					if err != nil {
+						result.Unreadable++
+						return nil
+					}
+					updates[string(k)] = sealed
+					result.Rewrapped++
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			for k, v := range updates {
+				if err := bucket.Put([]byte(k), v); err != nil {
+					return err
+				}
+			}
+		}
 		return nil
 	})
+	if err != nil {
+		return EncryptResult{}, err
+	}
+	return result, nil
 }
@@ -0,0 +1,139 @@
+package boltstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	bolt "go.etcd.io/bbolt"
+)
+
+// newTestStore opens a store in a temporary directory, encrypting sessions
+// with keys when they are set
+func newTestStore(t *testing.T, keys *Keyring) *Store {
+	t.Helper()
+	store, err := Open(Options{Path: filepath.Join(t.TempDir(), "test.db"), SessionKeys: keys})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// saveSession stores a session with its login activity
+func saveSession(t *testing.T, s *SessionStore, did syntax.DID, sessionID string) {
+	t.Helper()
+	ctx := context.Background()
+	if err := s.SaveSession(ctx, oauth.ClientSessionData{AccountDID: did, SessionID: sessionID, HostURL: "https://pds.example.com"}); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+	if err := s.RecordLogin(ctx, did, sessionID, "test-agent", "192.0.2.1"); err != nil {
+		t.Fatalf("RecordLogin() error = %v", err)
+	}
+}
+
+// setLastUsed backdates a session's recorded activity
+func setLastUsed(t *testing.T, s *SessionStore, did syntax.DID, sessionID string, at time.Time) {
+	t.Helper()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putActivity(tx, sessionKey(did, sessionID), &SessionActivity{CreatedAt: at, LastUsedAt: at})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSessionsForDID(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t, nil).SessionStore()
+	alice, bob := syntax.DID("did:plc:alice"), syntax.DID("did:plc:bob")
+
+	saveSession(t, s, alice, "old")
+	saveSession(t, s, alice, "current")
+	saveSession(t, s, alice, "other")
+	saveSession(t, s, bob, "bob")
+	setLastUsed(t, s, alice, "old", time.Now().Add(-time.Hour))
+
+	sessions, err := s.ListSessionsForDID(ctx, alice)
+	if err != nil {
+		t.Fatalf("ListSessionsForDID() error = %v", err)
+	}
+	if len(sessions) != 3 || sessions[2].SessionID != "old" {
+		t.Fatalf("ListSessionsForDID() = %+v, want 3 sessions with old last", sessions)
+	}
+	if sessions[0].UserAgent != "test-agent" || sessions[0].IP != "192.0.2.1" {
+		t.Errorf("session activity = %+v, want the recorded login", sessions[0].SessionActivity)
+	}
+
+	count, err := s.DeleteOtherSessionsForDID(ctx, alice, "current")
+	if err != nil || count != 2 {
+		t.Fatalf("DeleteOtherSessionsForDID() = %d, %v, want 2", count, err)
+	}
+	sessions, _ = s.ListSessionsForDID(ctx, alice)
+	if len(sessions) != 1 || sessions[0].SessionID != "current" {
+		t.Errorf("sessions left = %+v, want only current", sessions)
+	}
+	if _, err := s.GetSession(ctx, alice, "other"); err == nil {
+		t.Error("GetSession() found a deleted session")
+	}
+	if sessions, _ := s.ListSessionsForDID(ctx, bob); len(sessions) != 1 {
+		t.Errorf("bob has %d sessions, want 1", len(sessions))
+	}
+
+	// Deleting a session removes its activity with it
+	if err := s.DeleteSession(ctx, alice, "current"); err != nil {
+		t.Fatal(err)
+	}
+	if activity, _ := s.getActivity(sessionKey(alice, "current")); !activity.CreatedAt.IsZero() {
+		t.Errorf("activity left after DeleteSession: %+v", activity)
+	}
+}
+
+func TestTouchSession(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t, nil).SessionStore()
+	did := syntax.DID("did:plc:alice")
+	saveSession(t, s, did, "session")
+
+	lastUsed := func() time.Time {
+		activity, err := s.getActivity(sessionKey(did, "session"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return activity.LastUsedAt
+	}
+
+	// Recent use isn't written again
+	recent := time.Now().Add(-time.Minute).Truncate(time.Second)
+	setLastUsed(t, s, did, "session", recent)
+	if err := s.TouchSession(ctx, did, "session"); err != nil {
+		t.Fatalf("TouchSession() error = %v", err)
+	}
+	if got := lastUsed(); !got.Equal(recent) {
+		t.Errorf("last use = %v, want unchanged %v", got, recent)
+	}
+
+	// Older use is
+	stale := time.Now().Add(-sessionTouchInterval - time.Minute)
+	setLastUsed(t, s, did, "session", stale)
+	if err := s.TouchSession(ctx, did, "session"); err != nil {
+		t.Fatalf("TouchSession() error = %v", err)
+	}
+	if got := lastUsed(); time.Since(got) > time.Minute {
+		t.Errorf("last use = %v, want now", got)
+	}
+
+	// A revoked session's activity isn't brought back
+	if err := s.DeleteSession(ctx, did, "session"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.TouchSession(ctx, did, "session"); err != nil {
+		t.Fatalf("TouchSession() error = %v", err)
+	}
+	if got := lastUsed(); !got.IsZero() {
+		t.Errorf("revoked session has activity from %v", got)
+	}
+}
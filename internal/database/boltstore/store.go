@@ -1,10 +1,11 @@
 // Package boltstore provides persistent storage using BoltDB (bbolt).
 // It implements the oauth.ClientAuthStore interface for session persistence
-// and provides storage for the feed registry.
+// and provides storage for the feed registry and user preferences.
 package boltstore
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -17,16 +18,41 @@ var (
 	// BucketSessions stores OAuth session data keyed by "did:sessionID"
 	BucketSessions = []byte("oauth_sessions")
 
+	// BucketSessionActivity stores when and from where each OAuth session was
+	// used, keyed like BucketSessions
+	BucketSessionActivity = []byte("oauth_session_activity")
+
+	// BucketSessionClientKeys stores the ID of the client key each OAuth
+	// session was created with, keyed like BucketSessions
+	BucketSessionClientKeys = []byte("oauth_session_client_keys")
+
 	// BucketAuthRequests stores pending OAuth auth requests keyed by state
 	BucketAuthRequests = []byte("oauth_auth_requests")
 
+	// BucketAuthRequestTimes stores when each pending auth request was
+	// started, keyed like BucketAuthRequests, so abandoned ones can expire
+	BucketAuthRequestTimes = []byte("oauth_auth_request_times")
+
 	// BucketFeedRegistry stores registered user DIDs for the community feed
 	BucketFeedRegistry = []byte("feed_registry")
+
+	// BucketPreferences stores per-user app preferences keyed by DID
+	BucketPreferences = []byte("user_preferences")
+
+	// BucketHealth stores the heartbeat written by readiness checks
+	BucketHealth = []byte("health")
+
+	// BucketBannedDIDs stores DIDs banned from the community feed
+	BucketBannedDIDs = []byte("moderation_banned_dids")
+
+	// BucketHiddenRecords stores AT-URIs of records hidden by moderators
+	BucketHiddenRecords = []byte("moderation_hidden_records")
 )
 
 // Store wraps a BoltDB database and provides access to specialized stores.
 type Store struct {
-	db *bolt.DB
+	db   *bolt.DB
+	keys *Keyring
 }
 
 // Options configures the BoltDB store.
@@ -41,6 +67,10 @@ type Options struct {
 	// FileMode for creating the database file.
 	// If zero, 0600 is used.
 	FileMode os.FileMode
+
+	// SessionKeys encrypts OAuth sessions and auth requests at rest.
+	// If nil, they are stored as plain JSON.
+	SessionKeys *Keyring
 }
 
 // DefaultOptions returns sensible defaults for development.
@@ -85,8 +115,15 @@ func Open(opts Options) (*Store, error) {
 	err = db.Update(func(tx *bolt.Tx) error {
 		buckets := [][]byte{
 			BucketSessions,
+			BucketSessionActivity,
+			BucketSessionClientKeys,
 			BucketAuthRequests,
+			BucketAuthRequestTimes,
 			BucketFeedRegistry,
+			BucketPreferences,
+			BucketHealth,
+			BucketBannedDIDs,
+			BucketHiddenRecords,
 		}
 
 		for _, bucket := range buckets {
@@ -103,7 +140,7 @@ func Open(opts Options) (*Store, error) {
 		return nil, err
 	}
 
-	return &Store{db: db}, nil
+	return &Store{db: db, keys: opts.SessionKeys}, nil
 }
 
 // Close closes the database.
@@ -121,7 +158,7 @@ func (s *Store) DB() *bolt.DB {
 
 // SessionStore returns an OAuth session store backed by this database.
 func (s *Store) SessionStore() *SessionStore {
-	return &SessionStore{db: s.db}
+	return &SessionStore{db: s.db, keys: s.keys}
 }
 
 // FeedStore returns a feed registry store backed by this database.
@@ -129,7 +166,83 @@ func (s *Store) FeedStore() *FeedStore {
 	return &FeedStore{db: s.db}
 }
 
+// PreferencesStore returns a user preferences store backed by this database.
+func (s *Store) PreferencesStore() *PreferencesStore {
+	return &PreferencesStore{db: s.db}
+}
+
+// ModerationStore returns a store for bans and hidden records backed by this database.
+func (s *Store) ModerationStore() *ModerationStore {
+	return &ModerationStore{db: s.db}
+}
+
 // Stats returns database statistics.
 func (s *Store) Stats() bolt.Stats {
 	return s.db.Stats()
 }
+
+// Ping verifies the database is open and writable by updating a heartbeat key.
+// Each call commits a transaction, so it should not run more than every few seconds.
+func (s *Store) Ping() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BucketHealth)
+		if bucket == nil {
+			return fmt.Errorf("bucket %s not found", BucketHealth)
+		}
+		return bucket.Put([]byte("heartbeat"), []byte(time.Now().UTC().Format(time.RFC3339Nano)))
+	})
+}
+
+// Backup writes a consistent snapshot of the database to w. It runs in a
+// read transaction, so it is safe while the server is handling requests.
+func (s *Store) Backup(w io.Writer) (int64, error) {
+	var n int64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	return n, err
+}
+
+// Compact rewrites the database at path into a new file, reclaiming pages
+// freed by deletes, and replaces the original with it. It needs exclusive
+// access, so it fails if the server has the database open. It returns the
+// file sizes before and after.
+func Compact(path string, timeout time.Duration) (before, after int64, err error) {
+	src, err := bolt.Open(path, 0600, &bolt.Options{Timeout: timeout, ReadOnly: true})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer src.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tmpPath := path + ".compact"
+	dst, err := bolt.Open(tmpPath, info.Mode().Perm(), &bolt.Options{Timeout: timeout})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create compacted database: %w", err)
+	}
+	if err := bolt.Compact(dst, src, 64*1024*1024); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return 0, 0, fmt.Errorf("failed to compact database: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return 0, 0, err
+	}
+
+	compacted, err := os.Stat(tmpPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return 0, 0, fmt.Errorf("failed to replace database: %w", err)
+	}
+	return info.Size(), compacted.Size(), nil
+}
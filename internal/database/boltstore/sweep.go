@@ -0,0 +1,208 @@
+package boltstore
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+// SweepOptions sets when the janitor drops OAuth state that can no longer be used
+type SweepOptions struct {
+	// Interval between sweeps
+	Interval time.Duration
+
+	// AuthRequestTTL is how long a login may take between leaving for the
+	// auth server and returning to the callback
+	AuthRequestTTL time.Duration
+
+	// SessionTTL is how long a session may go unused before it is dropped.
+	// Set it to the auth server's refresh token lifetime; after that the
+	// session can't be refreshed anyway.
+	SessionTTL time.Duration
+
+	// OnSweep, if set, is called after every sweep that started at start,
+	// e.g. to record metrics
+	OnSweep func(start time.Time, result SweepResult, err error)
+}
+
+// SweepResult counts the entries removed by a sweep
+type SweepResult struct {
+	AuthRequests int
+	Sessions     int
+}
+
+// SweepLoop removes expired auth requests and sessions right away and then
+// every interval, until ctx is cancelled
+func (s *SessionStore) SweepLoop(ctx context.Context, opts SweepOptions) {
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		result, err := s.Sweep(ctx, opts.AuthRequestTTL, opts.SessionTTL)
+		if opts.OnSweep != nil {
+			opts.OnSweep(start, result, err)
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("boltstore: session sweep failed")
+		} else if result.AuthRequests > 0 || result.Sessions > 0 {
+			log.Info().
+				Int("auth_requests", result.AuthRequests).
+				Int("sessions", result.Sessions).
+				Msg("boltstore: removed expired OAuth state")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sweep removes auth requests older than authRequestTTL and sessions unused
+// for longer than sessionTTL. Entries with no recorded time, written before
+// times were kept, are given the current time so they expire one TTL later.
+func (s *SessionStore) Sweep(ctx context.Context, authRequestTTL, sessionTTL time.Duration) (SweepResult, error) {
+	var result SweepResult
+	now := time.Now()
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		if result.AuthRequests, err = sweepAuthRequests(tx, now, now.Add(-authRequestTTL)); err != nil {
+			return err
+		}
+		result.Sessions, err = sweepSessions(tx, now, now.Add(-sessionTTL))
+		return err
+	})
+	if err != nil {
+		return SweepResult{}, err
+	}
+	return result, nil
+}
+
+// sweepAuthRequests deletes auth requests started before cutoff
+func sweepAuthRequests(tx *bolt.Tx, now, cutoff time.Time) (int, error) {
+	requests := tx.Bucket(BucketAuthRequests)
+	times := tx.Bucket(BucketAuthRequestTimes)
+	if requests == nil || times == nil {
+		return 0, nil
+	}
+
+	// Collect keys first; bolt doesn't allow writes while iterating
+	var expired, unstamped [][]byte
+	err := requests.ForEach(func(k, _ []byte) error {
+		data := times.Get(k)
+		if data == nil {
+			unstamped = append(unstamped, append([]byte{}, k...))
+			return nil
+		}
+		var created time.Time
+		if err := created.UnmarshalText(data); err != nil || created.Before(cutoff) {
+			expired = append(expired, append([]byte{}, k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, k := range unstamped {
+		if err := putAuthRequestTime(tx, k, now); err != nil {
+			return 0, err
+		}
+	}
+	for _, k := range expired {
+		if err := requests.Delete(k); err != nil {
+			return 0, err
+		}
+		if err := times.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+
+	// Times left behind by requests deleted some other way
+	var orphans [][]byte
+	err = times.ForEach(func(k, _ []byte) error {
+		if requests.Get(k) == nil {
+			orphans = append(orphans, append([]byte{}, k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, k := range orphans {
+		if err := times.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(expired), nil
+}
+
+// sweepSessions deletes sessions last used before cutoff, along with their activity
+func sweepSessions(tx *bolt.Tx, now, cutoff time.Time) (int, error) {
+	sessions := tx.Bucket(BucketSessions)
+	activity := tx.Bucket(BucketSessionActivity)
+	if sessions == nil || activity == nil {
+		return 0, nil
+	}
+
+	var expired, unstamped [][]byte
+	err := sessions.ForEach(func(k, _ []byte) error {
+		data := activity.Get(k)
+		if data == nil {
+			unstamped = append(unstamped, append([]byte{}, k...))
+			return nil
+		}
+		var a SessionActivity
+		if err := json.Unmarshal(data, &a); err != nil {
+			// Unreadable activity is rewritten rather than taken as expired
+			unstamped = append(unstamped, append([]byte{}, k...))
+			return nil
+		}
+		lastUsed := a.LastUsedAt
+		if lastUsed.IsZero() {
+			lastUsed = a.CreatedAt
+		}
+		if lastUsed.Before(cutoff) {
+			expired = append(expired, append([]byte{}, k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, k := range unstamped {
+		if err := putActivity(tx, k, &SessionActivity{LastUsedAt: now}); err != nil {
+			return 0, err
+		}
+	}
+	for _, k := range expired {
+		if err := sessions.Delete(k); err != nil {
+			return 0, err
+		}
+		if err := deleteSessionMeta(tx, k); err != nil {
+			return 0, err
+		}
+	}
+	return len(expired), nil
+}
+
+// putAuthRequestTime records when the auth request for state was started
+func putAuthRequestTime(tx *bolt.Tx, state []byte, t time.Time) error {
+	bucket := tx.Bucket(BucketAuthRequestTimes)
+	if bucket == nil {
+		return nil
+	}
+	data, err := t.UTC().MarshalText()
+	if err != nil {
+		return err
+	}
+	return bucket.Put(state, data)
+}
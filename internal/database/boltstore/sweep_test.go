@@ -0,0 +1,129 @@
+package boltstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	testAuthRequestTTL = 30 * time.Minute
+	testSessionTTL     = 14 * 24 * time.Hour
+)
+
+// authRequestTime returns when the auth request for state was recorded as
+// started, or the zero time if it has no time
+func authRequestTime(t *testing.T, s *SessionStore, state string) time.Time {
+	t.Helper()
+	var created time.Time
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if data := tx.Bucket(BucketAuthRequestTimes).Get([]byte(state)); data != nil {
+			return created.UnmarshalText(data)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created
+}
+
+func TestSweepAuthRequests(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t, nil).SessionStore()
+	for _, state := range []string{"expired", "fresh", "unstamped"} {
+		if err := s.SaveAuthRequestInfo(ctx, oauth.AuthRequestData{State: state}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := putAuthRequestTime(tx, []byte("expired"), time.Now().Add(-time.Hour)); err != nil {
+			return err
+		}
+		// Written before times were kept
+		if err := tx.Bucket(BucketAuthRequestTimes).Delete([]byte("unstamped")); err != nil {
+			return err
+		}
+		// Left behind by a request deleted some other way
+		if err := putAuthRequestTime(tx, []byte("orphan"), time.Now()); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Sweep(ctx, testAuthRequestTTL, testSessionTTL)
+	if err != nil {
+		t.Fatalf("Sweep() error = %v", err)
+	}
+	if result.AuthRequests != 1 {
+		t.Errorf("Sweep() removed %d auth requests, want 1", result.AuthRequests)
+	}
+
+	if _, err := s.GetAuthRequestInfo(ctx, "expired"); err == nil {
+		t.Error("expired auth request is still stored")
+	}
+	if !authRequestTime(t, s, "expired").IsZero() {
+		t.Error("expired auth request's time is still stored")
+	}
+	if _, err := s.GetAuthRequestInfo(ctx, "fresh"); err != nil {
+		t.Errorf("fresh auth request was removed: %v", err)
+	}
+	if _, err := s.GetAuthRequestInfo(ctx, "unstamped"); err != nil {
+		t.Errorf("unstamped auth request was removed: %v", err)
+	}
+	if created := authRequestTime(t, s, "unstamped"); time.Since(created) > time.Minute {
+		t.Errorf("unstamped auth request time = %v, want now", created)
+	}
+	if !authRequestTime(t, s, "orphan").IsZero() {
+		t.Error("orphaned auth request time is still stored")
+	}
+}
+
+func TestSweepSessions(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t, nil).SessionStore()
+	did := syntax.DID("did:plc:alice")
+	saveSession(t, s, did, "expired")
+	saveSession(t, s, did, "fresh")
+	if err := s.SaveSession(ctx, oauth.ClientSessionData{AccountDID: did, SessionID: "unstamped"}); err != nil {
+		t.Fatal(err)
+	}
+	setLastUsed(t, s, did, "expired", time.Now().Add(-testSessionTTL-time.Hour))
+	if err := s.SetSessionClientKey(ctx, did, "expired", "key"); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Sweep(ctx, testAuthRequestTTL, testSessionTTL)
+	if err != nil {
+		t.Fatalf("Sweep() error = %v", err)
+	}
+	if result.Sessions != 1 {
+		t.Errorf("Sweep() removed %d sessions, want 1", result.Sessions)
+	}
+
+	if _, err := s.GetSession(ctx, did, "expired"); err == nil {
+		t.Error("expired session is still stored")
+	}
+	if activity, _ := s.getActivity(sessionKey(did, "expired")); !activity.LastUsedAt.IsZero() {
+		t.Error("expired session's activity is still stored")
+	}
+	if keyID, _ := s.GetSessionClientKey(ctx, did, "expired"); keyID != "" {
+		t.Errorf("expired session's client key %q is still stored", keyID)
+	}
+	for _, id := range []string{"fresh", "unstamped"} {
+		if _, err := s.GetSession(ctx, did, id); err != nil {
+			t.Errorf("session %s was removed: %v", id, err)
+		}
+	}
+	activity, err := s.getActivity(sessionKey(did, "unstamped"))
+	if err != nil || time.Since(activity.LastUsedAt) > time.Minute {
+		t.Errorf("unstamped session activity = %+v, %v, want used now", activity, err)
+	}
+}
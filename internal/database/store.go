@@ -48,6 +48,18 @@ type Store interface {
 	UpdateBrewerByRKey(ctx context.Context, rkey string, brewer *models.UpdateBrewerRequest) error
 	DeleteBrewerByRKey(ctx context.Context, rkey string) error
 
+	// Settings operations
+	// GetSettings returns defaults when the user has not saved settings yet
+	GetSettings(ctx context.Context) (*models.Settings, error)
+	UpdateSettings(ctx context.Context, settings *models.Settings) error
+
+	// Mute and block operations
+	// GetGraph returns empty lists when the user has not saved any yet
+	GetGraph(ctx context.Context) (*models.Graph, error)
+	UpdateGraph(ctx context.Context, graph *models.Graph) error
+	// ListBlueskyBlocks returns the DIDs the user blocked on Bluesky, for importing
+	ListBlueskyBlocks(ctx context.Context) ([]string, error)
+
 	// Close the database connection
 	Close() error
 }
@@ -44,6 +44,15 @@ type MockStore struct {
 	UpdateBrewerByRKeyFunc func(ctx context.Context, rkey string, brewer *models.UpdateBrewerRequest) error
 	DeleteBrewerByRKeyFunc func(ctx context.Context, rkey string) error
 
+	// Settings operations
+	GetSettingsFunc    func(ctx context.Context) (*models.Settings, error)
+	UpdateSettingsFunc func(ctx context.Context, settings *models.Settings) error
+
+	// Mute and block operations
+	GetGraphFunc          func(ctx context.Context) (*models.Graph, error)
+	UpdateGraphFunc       func(ctx context.Context, graph *models.Graph) error
+	ListBlueskyBlocksFunc func(ctx context.Context) ([]string, error)
+
 	CloseFunc func() error
 }
 
@@ -247,6 +256,46 @@ func (m *MockStore) DeleteBrewerByRKey(ctx context.Context, rkey string) error {
 	return nil
 }
 
+// GetSettings calls the mock function or returns default settings if not set
+func (m *MockStore) GetSettings(ctx context.Context) (*models.Settings, error) {
+	if m.GetSettingsFunc != nil {
+		return m.GetSettingsFunc(ctx)
+	}
+	return models.DefaultSettings(), nil
+}
+
+// UpdateSettings calls the mock function or returns nil if not set
+func (m *MockStore) UpdateSettings(ctx context.Context, settings *models.Settings) error {
+	if m.UpdateSettingsFunc != nil {
+		return m.UpdateSettingsFunc(ctx, settings)
+	}
+	return nil
+}
+
+// GetGraph calls the mock function or returns empty lists if not set
+func (m *MockStore) GetGraph(ctx context.Context) (*models.Graph, error) {
+	if m.GetGraphFunc != nil {
+		return m.GetGraphFunc(ctx)
+	}
+	return &models.Graph{}, nil
+}
+
+// UpdateGraph calls the mock function or returns nil if not set
+func (m *MockStore) UpdateGraph(ctx context.Context, graph *models.Graph) error {
+	if m.UpdateGraphFunc != nil {
+		return m.UpdateGraphFunc(ctx, graph)
+	}
+	return nil
+}
+
+// ListBlueskyBlocks calls the mock function or returns nil if not set
+func (m *MockStore) ListBlueskyBlocks(ctx context.Context) ([]string, error) {
+	if m.ListBlueskyBlocksFunc != nil {
+		return m.ListBlueskyBlocksFunc(ctx)
+	}
+	return nil, nil
+}
+
 // Close calls the mock function or returns nil if not set
 func (m *MockStore) Close() error {
 	if m.CloseFunc != nil {
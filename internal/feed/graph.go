@@ -0,0 +1,93 @@
+package feed
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"arabica/internal/atproto"
+	"arabica/internal/models"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Viewer is the signed-in user a feed is built for. Accounts they muted or
+// blocked, and accounts that blocked them, are left out of their feed.
+type Viewer struct {
+	DID string
+
+	// Graph holds the accounts the viewer muted or blocked
+	Graph *models.Graph
+}
+
+// graphCache keeps the mute and block lists users publish in their repos, so
+// checking whether they blocked a viewer doesn't cost a PDS request each time
+type graphCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedGraph
+}
+
+type cachedGraph struct {
+	graph     *models.Graph
+	expiresAt time.Time
+}
+
+// userGraph returns the mute and block lists published by did, or nil if
+// they can't be read. Users without a graph record get empty lists.
+func (s *Service) userGraph(ctx context.Context, did string) *models.Graph {
+	s.graphs.mu.Lock()
+	entry, ok := s.graphs.entries[did]
+	s.graphs.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.graph
+	}
+
+	var graph *models.Graph
+	record, err := s.publicClient.GetRecord(ctx, did, atproto.NSIDGraph, atproto.GraphRKey)
+	switch {
+	case atproto.IsRecordNotFound(err):
+		graph = &models.Graph{}
+	case err != nil:
+		log.Warn().Err(err).Str("did", did).Msg("feed: failed to fetch mute and block lists")
+		return nil
+	default:
+		if graph, err = atproto.RecordToGraph(record.Value); err != nil {
+			log.Warn().Err(err).Str("did", did).Msg("feed: failed to parse mute and block lists")
+			return nil
+		}
+	}
+
+	s.graphs.mu.Lock()
+	s.graphs.entries[did] = cachedGraph{graph: graph, expiresAt: time.Now().Add(s.cacheTTL)}
+	s.graphs.mu.Unlock()
+	return graph
+}
+
+// BlocksViewer reports whether did blocked viewerDID. Lists are cached for the
+// public feed cache TTL, so a new block can take that long to apply to the
+// blocked user; call InvalidateGraph when a user on this instance changes theirs.
+// Lists that can't be read don't block anyone.
+func (s *Service) BlocksViewer(ctx context.Context, did, viewerDID string) bool {
+	if did == viewerDID {
+		return false
+	}
+	return s.userGraph(ctx, did).IsBlocked(viewerDID)
+}
+
+// VisibleTo reports whether records by did may be shown to viewer: the viewer
+// hasn't muted or blocked them, and they haven't blocked the viewer. Every
+// account is visible to a nil viewer.
+func (s *Service) VisibleTo(ctx context.Context, did string, viewer *Viewer) bool {
+	if viewer == nil {
+		return true
+	}
+	return !viewer.Graph.Hides(did) && !s.BlocksViewer(ctx, did, viewer.DID)
+}
+
+// InvalidateGraph drops the cached mute and block lists of did, after they
+// change them
+func (s *Service) InvalidateGraph(did string) {
+	s.graphs.mu.Lock()
+	delete(s.graphs.entries, did)
+	s.graphs.mu.Unlock()
+}
@@ -0,0 +1,77 @@
+package feed
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"arabica/internal/atproto"
+
+	"github.com/rs/zerolog/log"
+)
+
+// privacyCache keeps whether each user has private mode turned on, so a feed
+// refresh doesn't fetch every user's settings record from their PDS
+type privacyCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedPrivacy
+}
+
+type cachedPrivacy struct {
+	private   bool
+	fetchedAt time.Time
+}
+
+// isPrivate reports whether a user has private mode turned on. Users without a
+// settings record are public. Answers are cached for the public feed cache TTL.
+// When the settings record can't be read, the last known answer is used; users
+// never read before are treated as private so a PDS hiccup never leaks a
+// private user into the feed.
+func (s *Service) isPrivate(ctx context.Context, did string) bool {
+	s.privacy.mu.Lock()
+	entry, known := s.privacy.entries[did]
+	s.privacy.mu.Unlock()
+	if known && time.Since(entry.fetchedAt) < s.cacheTTL {
+		return entry.private
+	}
+
+	private, err := s.fetchPrivate(ctx, did)
+	if err != nil {
+		if known {
+			log.Warn().Err(err).Str("did", did).Time("last_fetched", entry.fetchedAt).Bool("private", entry.private).
+				Msg("feed: failed to read settings, using last known private mode; check the user's PDS is reachable")
+			return entry.private
+		}
+		log.Warn().Err(err).Str("did", did).
+			Msg("feed: failed to read settings, leaving user out until they can be read; check the user's PDS is reachable")
+		return true
+	}
+
+	s.privacy.mu.Lock()
+	s.privacy.entries[did] = cachedPrivacy{private: private, fetchedAt: time.Now()}
+	s.privacy.mu.Unlock()
+	return private
+}
+
+// fetchPrivate reads the private mode setting from the settings record in did's repo
+func (s *Service) fetchPrivate(ctx context.Context, did string) (bool, error) {
+	entry, err := s.publicClient.GetRecord(ctx, did, atproto.NSIDSettings, atproto.SettingsRKey)
+	if atproto.IsRecordNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	settings, err := atproto.RecordToSettings(entry.Value)
+	if err != nil {
+		return false, err
+	}
+	return settings.PrivateMode, nil
+}
+
+// InvalidatePrivacy drops the cached private mode of did, after they change it
+func (s *Service) InvalidatePrivacy(did string) {
+	s.privacy.mu.Lock()
+	delete(s.privacy.entries, did)
+	s.privacy.mu.Unlock()
+}
@@ -0,0 +1,149 @@
+package feed
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"arabica/internal/models"
+	"arabica/internal/search"
+
+	"github.com/rs/zerolog/log"
+)
+
+// communityIndex holds a search index over the records most recently fetched
+// for the feed. It is rebuilt every time the feed is fetched from the PDSes.
+type communityIndex struct {
+	index     *search.Index
+	updatedAt time.Time
+	ttl       time.Duration
+	mu        sync.RWMutex
+	refreshMu sync.Mutex // Serializes refreshes triggered by searches
+}
+
+// current returns the index if it is fresh enough to search
+func (c *communityIndex) current() *search.Index {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.index == nil || time.Since(c.updatedAt) > c.ttl {
+		return nil
+	}
+	return c.index
+}
+
+// stale returns the last built index regardless of age, or nil if there is none
+func (c *communityIndex) stale() *search.Index {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.index
+}
+
+// replace swaps in a freshly built index
+func (c *communityIndex) replace(idx *search.Index) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.index = idx
+	c.updatedAt = time.Now()
+}
+
+// reset drops the index so the next search rebuilds it
+func (c *communityIndex) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.index = nil
+	c.updatedAt = time.Time{}
+}
+
+// updateSearchIndex rebuilds the community index from fetched feed items
+func (s *Service) updateSearchIndex(items []*FeedItem) {
+	idx := search.NewIndex()
+	for _, item := range items {
+		// Search results aren't filtered per viewer, so leave out anything
+		// that would be hidden or blurred by default
+		if visibility, _ := models.LabelPreferences(nil).Decide(item.Labels); visibility != models.LabelShow {
+			continue
+		}
+		if doc := itemDocument(item); doc != nil {
+			idx.Add(doc)
+		}
+	}
+	s.searchIndex.replace(idx)
+
+	log.Debug().Int("document_count", idx.Len()).Msg("feed: rebuilt community search index")
+}
+
+// Search searches the recent records of registered users: the ones fetched for
+// the community feed, each user's latest ten of every kind. Older records
+// aren't indexed. The index is refreshed from the PDSes when it is older than
+// the public feed cache TTL. With a viewer, records by users hidden from them
+// by a mute or block are left out before the limit is applied.
+func (s *Service) Search(ctx context.Context, query string, opts search.Options, viewer *Viewer) ([]search.Result, error) {
+	idx := s.searchIndex.current()
+	if idx == nil {
+		s.searchIndex.refreshMu.Lock()
+		// Double-check if another search already refreshed the index
+		if idx = s.searchIndex.current(); idx == nil {
+			if _, err := s.GetRecentRecords(ctx, PublicFeedLimit, nil); err != nil {
+				log.Warn().Err(err).Msg("feed: failed to refresh community search index")
+			}
+			// Fall back to stale results rather than failing outright
+			idx = s.searchIndex.stale()
+		}
+		s.searchIndex.refreshMu.Unlock()
+	}
+
+	if idx == nil {
+		return nil, nil
+	}
+	if viewer == nil {
+		return idx.Search(query, opts), nil
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = search.DefaultLimit
+	}
+	opts.Limit = idx.Len()
+	visible := make(map[string]bool) // author DID -> visible to the viewer
+	var results []search.Result
+	for _, result := range idx.Search(query, opts) {
+		did := result.Doc.AuthorDID
+		ok, checked := visible[did]
+		if !checked {
+			ok = s.VisibleTo(ctx, did, viewer)
+			visible[did] = ok
+		}
+		if !ok {
+			continue
+		}
+		results = append(results, result)
+		if len(results) == limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+// itemDocument converts a feed item into a search document attributed to its author
+func itemDocument(item *FeedItem) *search.Document {
+	var doc *search.Document
+	switch {
+	case item.Brew != nil:
+		doc = search.BrewDocument(item.Brew)
+	case item.Bean != nil:
+		doc = search.BeanDocument(item.Bean)
+	case item.Roaster != nil:
+		doc = search.RoasterDocument(item.Roaster)
+	case item.Grinder != nil:
+		doc = search.GrinderDocument(item.Grinder)
+	case item.Brewer != nil:
+		doc = search.BrewerDocument(item.Brewer)
+	default:
+		return nil
+	}
+
+	if item.Author == nil {
+		return nil
+	}
+	return doc.WithAuthor(item.Author.DID, item.Author.Handle)
+}
@@ -3,21 +3,31 @@ package feed
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sort"
 	"sync"
 	"time"
 
 	"arabica/internal/atproto"
+	"arabica/internal/metrics"
 	"arabica/internal/models"
+	"arabica/internal/tracing"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// PublicFeedCacheTTL is the duration for which the public feed cache is valid.
+// PublicFeedCacheTTL is the default duration for which the public feed cache is valid.
 // This value can be adjusted based on desired freshness vs. performance tradeoff.
 // Consider values between 5-10 minutes for a good balance.
 const PublicFeedCacheTTL = 5 * time.Minute
 
+// PublicFeedRefreshInterval is the default for how often the background refresh rebuilds the
+// public feed. It is shorter than the TTL so the cache never expires under
+// normal operation and requests don't have to wait on a refresh.
+const PublicFeedRefreshInterval = 4 * time.Minute
+
 // PublicFeedLimit is the number of items to show for unauthenticated users
 const PublicFeedLimit = 5
 
@@ -36,6 +46,15 @@ type FeedItem struct {
 	Author    *atproto.Profile
 	Timestamp time.Time
 	TimeAgo   string // "2 hours ago", "yesterday", etc.
+
+	// URI is the AT-URI of the record, used to hide it from the feed
+	URI string
+
+	// Labels are the label values on the record and its author
+	Labels []string
+	// Warning lists the labels a viewer asked to be warned about. It is only
+	// set on the copies returned by ApplyLabelPreferences.
+	Warning []string
 }
 
 // publicFeedCache holds cached feed items for unauthenticated users
@@ -45,19 +64,58 @@ type publicFeedCache struct {
 	mu        sync.RWMutex
 }
 
+// Moderation decides which users and records are kept out of the feed
+type Moderation interface {
+	IsBanned(did string) bool
+	IsHidden(uri string) bool
+}
+
+// LabelSource provides labels applied by labeler services
+type LabelSource interface {
+	// Labels returns the label values on any of the subjects, DIDs or AT-URIs
+	Labels(subjects ...string) []string
+}
+
+// Config holds tunables for the feed service. Zero values use the defaults above.
+type Config struct {
+	// CacheTTL is how long the public feed and community search index stay fresh
+	CacheTTL time.Duration
+
+	// Moderation filters banned users and hidden records, if set
+	Moderation Moderation
+
+	// Labels attaches labels to feed items, if set
+	Labels LabelSource
+}
+
 // Service fetches and aggregates brews from registered users
 type Service struct {
 	registry     *Registry
 	publicClient *atproto.PublicClient
 	cache        *publicFeedCache
+	cacheTTL     time.Duration
+	moderation   Moderation
+	labels       LabelSource
+	searchIndex  *communityIndex
+	graphs       *graphCache
+	privacy      *privacyCache
 }
 
 // NewService creates a new feed service
-func NewService(registry *Registry) *Service {
+func NewService(registry *Registry, cfg Config) *Service {
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = PublicFeedCacheTTL
+	}
 	return &Service{
 		registry:     registry,
 		publicClient: atproto.NewPublicClient(),
 		cache:        &publicFeedCache{},
+		cacheTTL:     cfg.CacheTTL,
+		moderation:   cfg.Moderation,
+		labels:       cfg.Labels,
+		searchIndex:  &communityIndex{ttl: cfg.CacheTTL},
+		graphs:       &graphCache{entries: make(map[string]cachedGraph)},
+		privacy:      &privacyCache{entries: make(map[string]cachedPrivacy)},
 	}
 }
 
@@ -87,10 +145,7 @@ func (s *Service) refreshPublicFeedCache(ctx context.Context) ([]*FeedItem, erro
 		return s.cache.items, nil
 	}
 
-	log.Debug().Msg("feed: refreshing public feed cache")
-
-	// Fetch fresh feed items (limited to PublicFeedLimit)
-	items, err := s.GetRecentRecords(ctx, PublicFeedLimit)
+	items, err := s.fetchPublicFeed(ctx)
 	if err != nil {
 		// If we have stale data, return it rather than failing
 		if len(s.cache.items) > 0 {
@@ -100,24 +155,158 @@ func (s *Service) refreshPublicFeedCache(ctx context.Context) ([]*FeedItem, erro
 		return nil, err
 	}
 
-	// Update cache
+	s.setPublicFeed(items)
+	return items, nil
+}
+
+// RefreshLoop rebuilds the public feed cache now and then every interval until
+// ctx is cancelled, so page loads are served from a warm cache instead of
+// waiting on every registered user's PDS. Readers keep getting the previous
+// items while a refresh is in flight.
+func (s *Service) RefreshLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Warn().Err(err).Msg("feed: background refresh failed, keeping cached items")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Refresh rebuilds the public feed cache now, whether or not it has expired.
+// On failure the cached items are left in place.
+func (s *Service) Refresh(ctx context.Context) ([]*FeedItem, error) {
+	items, err := s.fetchPublicFeed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.mu.Lock()
+	s.setPublicFeed(items)
+	s.cache.mu.Unlock()
+	return items, nil
+}
+
+// CachedItems returns the cached public feed and when it expires, without
+// refreshing it. Items are nil if the cache has never been filled.
+func (s *Service) CachedItems() ([]*FeedItem, time.Time) {
+	s.cache.mu.RLock()
+	defer s.cache.mu.RUnlock()
+	return s.cache.items, s.cache.expiresAt
+}
+
+// fetchPublicFeed fetches fresh items for the public feed. A fetch cut short
+// by ctx is reported as an error so partial results never replace the cache.
+func (s *Service) fetchPublicFeed(ctx context.Context) ([]*FeedItem, error) {
+	log.Debug().Msg("feed: refreshing public feed cache")
+
+	// Fetch fresh feed items (limited to PublicFeedLimit)
+	start := time.Now()
+	items, err := s.GetRecentRecords(ctx, PublicFeedLimit, nil)
+	metrics.FeedRefreshDuration.Observe(time.Since(start).Seconds())
+	if err == nil {
+		err = ctx.Err()
+	}
+	return items, err
+}
+
+// setPublicFeed replaces the cached items. The caller must hold s.cache.mu.
+func (s *Service) setPublicFeed(items []*FeedItem) {
 	s.cache.items = items
-	s.cache.expiresAt = time.Now().Add(PublicFeedCacheTTL)
+	s.cache.expiresAt = time.Now().Add(s.cacheTTL)
 
 	log.Debug().
 		Int("item_count", len(items)).
 		Time("expires_at", s.cache.expiresAt).
 		Msg("feed: updated public feed cache")
+}
 
-	return items, nil
+// InvalidateCache drops the cached public feed and community search index, so
+// the next request refetches from the PDSes. Call this when a user's private
+// mode changes, since private users are filtered out when the feed is fetched.
+func (s *Service) InvalidateCache() {
+	s.cache.mu.Lock()
+	s.cache.items = nil
+	s.cache.expiresAt = time.Time{}
+	s.cache.mu.Unlock()
+
+	s.searchIndex.reset()
+}
+
+// isBanned reports whether moderators have banned a user from the feed
+func (s *Service) isBanned(did string) bool {
+	return s.moderation != nil && s.moderation.IsBanned(did)
+}
+
+// isHidden reports whether moderators have hidden a record from the feed
+func (s *Service) isHidden(uri string) bool {
+	return s.moderation != nil && s.moderation.IsHidden(uri)
+}
+
+// itemLabels returns the labels on a record and its author. It reports false
+// when the record must be left out for everyone: hidden by moderators or
+// labeled !hide.
+func (s *Service) itemLabels(did, uri string) ([]string, bool) {
+	if s.isHidden(uri) {
+		return nil, false
+	}
+	if s.labels == nil {
+		return nil, true
+	}
+	labels := s.labels.Labels(uri, did)
+	if slices.Contains(labels, models.LabelHide) {
+		return nil, false
+	}
+	return labels, true
+}
+
+// ApplyLabelPreferences prepares feed items for one viewer: items with labels
+// the viewer hides are dropped, and items with labels they want a warning for
+// are replaced by copies with Warning set. Cached items are never modified.
+func ApplyLabelPreferences(items []*FeedItem, prefs models.LabelPreferences) []*FeedItem {
+	filtered := make([]*FeedItem, 0, len(items))
+	for _, item := range items {
+		visibility, reasons := prefs.Decide(item.Labels)
+		switch visibility {
+		case models.LabelDrop:
+			continue
+		case models.LabelBlur:
+			warned := *item
+			warned.Warning = reasons
+			item = &warned
+		}
+		filtered = append(filtered, item)
+	}
+	return filtered
 }
 
 // GetRecentRecords fetches recent activity (brews and other records) from all registered users
+// who are not in private mode or banned, leaving out records hidden by moderators or labeled
+// !hide. Other labels are attached to the items; see ApplyLabelPreferences. Private
+// users stay in the registry so they reappear as soon as they turn private mode off.
+// With a viewer, users the viewer muted or blocked and users who blocked the viewer
+// are left out too; pass nil for the public feed. Only the public feed updates the
+// community search index, so one viewer's lists never affect what others see.
 // Returns up to `limit` items sorted by most recent first
-func (s *Service) GetRecentRecords(ctx context.Context, limit int) ([]*FeedItem, error) {
+func (s *Service) GetRecentRecords(ctx context.Context, limit int, viewer *Viewer) ([]*FeedItem, error) {
 	dids := s.registry.List()
+
+	ctx, span := tracing.Start(ctx, "feed.GetRecentRecords",
+		trace.WithAttributes(attribute.Int("feed.user_count", len(dids))))
+	defer span.End()
+
 	if len(dids) == 0 {
 		log.Debug().Msg("feed: no registered users")
+		if viewer == nil {
+			s.updateSearchIndex(nil)
+		}
 		return nil, nil
 	}
 
@@ -132,6 +321,7 @@ func (s *Service) GetRecentRecords(ctx context.Context, limit int) ([]*FeedItem,
 		roasters []*models.Roaster
 		grinders []*models.Grinder
 		brewers  []*models.Brewer
+		excluded bool // private mode, banned, or hidden from the viewer
 		err      error
 	}
 
@@ -145,6 +335,12 @@ func (s *Service) GetRecentRecords(ctx context.Context, limit int) ([]*FeedItem,
 
 			result := userActivity{did: did}
 
+			if s.isBanned(did) || !s.VisibleTo(ctx, did, viewer) || s.isPrivate(ctx, did) {
+				result.excluded = true
+				results <- result
+				return
+			}
+
 			// Fetch profile
 			profile, err := s.publicClient.GetProfile(ctx, did)
 			if err != nil {
@@ -364,6 +560,9 @@ func (s *Service) GetRecentRecords(ctx context.Context, limit int) ([]*FeedItem,
 	var items []*FeedItem
 	for result := range results {
 		if result.err != nil {
+			metrics.FeedUserFailures.Inc()
+		}
+		if result.err != nil || result.excluded {
 			continue
 		}
 
@@ -382,7 +581,14 @@ func (s *Service) GetRecentRecords(ctx context.Context, limit int) ([]*FeedItem,
 
 		// Add brews to feed
 		for _, brew := range result.brews {
+			uri := atproto.BuildATURI(result.did, atproto.NSIDBrew, brew.RKey)
+			labels, ok := s.itemLabels(result.did, uri)
+			if !ok {
+				continue
+			}
 			items = append(items, &FeedItem{
+				URI:        uri,
+				Labels:     labels,
 				RecordType: "brew",
 				Action:     "☕ added a new brew",
 				Brew:       brew,
@@ -394,7 +600,14 @@ func (s *Service) GetRecentRecords(ctx context.Context, limit int) ([]*FeedItem,
 
 		// Add beans to feed
 		for _, bean := range result.beans {
+			uri := atproto.BuildATURI(result.did, atproto.NSIDBean, bean.RKey)
+			labels, ok := s.itemLabels(result.did, uri)
+			if !ok {
+				continue
+			}
 			items = append(items, &FeedItem{
+				URI:        uri,
+				Labels:     labels,
 				RecordType: "bean",
 				Action:     "🫘 added a new bean",
 				Bean:       bean,
@@ -406,7 +619,14 @@ func (s *Service) GetRecentRecords(ctx context.Context, limit int) ([]*FeedItem,
 
 		// Add roasters to feed
 		for _, roaster := range result.roasters {
+			uri := atproto.BuildATURI(result.did, atproto.NSIDRoaster, roaster.RKey)
+			labels, ok := s.itemLabels(result.did, uri)
+			if !ok {
+				continue
+			}
 			items = append(items, &FeedItem{
+				URI:        uri,
+				Labels:     labels,
 				RecordType: "roaster",
 				Action:     "🏪 added a new roaster",
 				Roaster:    roaster,
@@ -418,7 +638,14 @@ func (s *Service) GetRecentRecords(ctx context.Context, limit int) ([]*FeedItem,
 
 		// Add grinders to feed
 		for _, grinder := range result.grinders {
+			uri := atproto.BuildATURI(result.did, atproto.NSIDGrinder, grinder.RKey)
+			labels, ok := s.itemLabels(result.did, uri)
+			if !ok {
+				continue
+			}
 			items = append(items, &FeedItem{
+				URI:        uri,
+				Labels:     labels,
 				RecordType: "grinder",
 				Action:     "⚙️ added a new grinder",
 				Grinder:    grinder,
@@ -430,7 +657,14 @@ func (s *Service) GetRecentRecords(ctx context.Context, limit int) ([]*FeedItem,
 
 		// Add brewers to feed
 		for _, brewer := range result.brewers {
+			uri := atproto.BuildATURI(result.did, atproto.NSIDBrewer, brewer.RKey)
+			labels, ok := s.itemLabels(result.did, uri)
+			if !ok {
+				continue
+			}
 			items = append(items, &FeedItem{
+				URI:        uri,
+				Labels:     labels,
 				RecordType: "brewer",
 				Action:     "☕ added a new brewer",
 				Brewer:     brewer,
@@ -446,6 +680,11 @@ func (s *Service) GetRecentRecords(ctx context.Context, limit int) ([]*FeedItem,
 		return items[i].Timestamp.After(items[j].Timestamp)
 	})
 
+	// Index everything fetched, not just the page being returned
+	if viewer == nil {
+		s.updateSearchIndex(items)
+	}
+
 	// Limit results
 	if len(items) > limit {
 		items = items[:limit]
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"arabica/internal/admin"
+	"arabica/internal/atproto"
+	"arabica/internal/bff"
+
+	"github.com/rs/zerolog/log"
+)
+
+// adminDone maps the done query parameter set after an admin action to the
+// confirmation shown on the dashboard
+var adminDone = map[string]string{
+	"banned":   "Account banned.",
+	"unbanned": "Ban lifted.",
+	"hidden":   "Record hidden.",
+	"unhidden": "Record visible again.",
+}
+
+// isBannedDID reports whether a DID is banned by an instance admin
+func (h *Handler) isBannedDID(did string) bool {
+	return h.moderation != nil && h.moderation.IsBanned(did)
+}
+
+// isHiddenRecord reports whether the record at an AT-URI was hidden by an
+// instance admin
+func (h *Handler) isHiddenRecord(uri string) bool {
+	return h.moderation != nil && h.moderation.IsHidden(uri)
+}
+
+// visibleRecords returns the records that weren't hidden by an instance admin
+func (h *Handler) visibleRecords(records []atproto.PublicRecordEntry) []atproto.PublicRecordEntry {
+	visible := make([]atproto.PublicRecordEntry, 0, len(records))
+	for _, record := range records {
+		if !h.isHiddenRecord(record.URI) {
+			visible = append(visible, record)
+		}
+	}
+	return visible
+}
+
+// requireAdmin returns the authenticated admin's DID. Anyone else gets the
+// not found page, so the dashboard's existence isn't advertised.
+func (h *Handler) requireAdmin(w http.ResponseWriter, r *http.Request) (string, bool) {
+	didStr, err := atproto.GetAuthenticatedDID(r.Context())
+	if err != nil || didStr == "" {
+		if r.Method == http.MethodGet {
+			http.Redirect(w, r, "/login", http.StatusFound)
+		} else {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+		}
+		return "", false
+	}
+	if h.admin == nil || !slices.Contains(h.config.AdminDIDs, didStr) {
+		h.HandleNotFound(w, r)
+		return "", false
+	}
+	return didStr, true
+}
+
+// Admin dashboard
+func (h *Handler) HandleAdmin(w http.ResponseWriter, r *http.Request) {
+	didStr, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	users, err := h.admin.ListFeedUsers(ctx)
+	if err != nil {
+		http.Error(w, "Failed to load users", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to list feed users")
+		return
+	}
+	sessions, err := h.admin.ListSessions(ctx)
+	if err != nil {
+		http.Error(w, "Failed to load sessions", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to list sessions")
+		return
+	}
+	moderation, err := h.admin.ListModeration(ctx)
+	if err != nil {
+		http.Error(w, "Failed to load moderation", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to list moderation entries")
+		return
+	}
+	feedCache, err := h.admin.InspectFeed(ctx)
+	if err != nil {
+		feedCache = &admin.FeedCache{}
+	}
+
+	sessionCounts := make(map[string]int)
+	for _, sess := range sessions {
+		sessionCounts[sess.DID]++
+	}
+	banned := make(map[string]bool)
+	for _, ban := range moderation.Bans {
+		banned[ban.Subject] = true
+	}
+
+	data := &bff.AdminPageData{
+		SessionCount:    len(sessions),
+		Feed:            feedCache,
+		Moderation:      moderation,
+		Done:            adminDone[r.URL.Query().Get("done")],
+		IsAuthenticated: true,
+		UserDID:         didStr,
+		UserProfile:     h.getUserProfile(ctx, didStr),
+	}
+	for _, u := range users {
+		data.Users = append(data.Users, bff.AdminUser{
+			DID:          u.DID,
+			RegisteredAt: u.RegisteredAt,
+			Sessions:     sessionCounts[u.DID],
+			Banned:       banned[u.DID],
+		})
+	}
+	if h.admin.Errors != nil {
+		data.Errors = h.admin.Errors.Entries()
+	}
+
+	if err := bff.RenderAdmin(ctx, w, data); err != nil {
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to render admin page")
+	}
+}
+
+// Ban a DID, or the DID behind a handle, from the feed and profiles
+func (h *Handler) HandleAdminBan(w http.ResponseWriter, r *http.Request) {
+	adminDID, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	did := strings.TrimSpace(r.PostForm.Get("did"))
+	if did != "" && !strings.HasPrefix(did, "did:") {
+		resolved, err := atproto.NewPublicClient().ResolveHandle(r.Context(), strings.TrimPrefix(did, "@"))
+		if err != nil {
+			http.Error(w, "Could not resolve handle", http.StatusBadRequest)
+			return
+		}
+		did = resolved
+	}
+	if did == adminDID {
+		http.Error(w, "You can't ban yourself", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.admin.BanDID(r.Context(), did, strings.TrimSpace(r.PostForm.Get("reason")), adminDID); err != nil {
+		http.Error(w, "Invalid DID", http.StatusBadRequest)
+		log.Warn().Err(err).Str("did", did).Msg("Failed to ban DID")
+		return
+	}
+	log.Info().Str("did", did).Str("admin", adminDID).Msg("Banned DID")
+	http.Redirect(w, r, "/admin?done=banned", http.StatusSeeOther)
+}
+
+// Lift a ban
+func (h *Handler) HandleAdminUnban(w http.ResponseWriter, r *http.Request) {
+	adminDID, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	did := r.PostForm.Get("did")
+	if err := h.admin.UnbanDID(r.Context(), did); err != nil {
+		h.adminActionFailed(w, err, "DID is not banned")
+		return
+	}
+	log.Info().Str("did", did).Str("admin", adminDID).Msg("Unbanned DID")
+	http.Redirect(w, r, "/admin?done=unbanned", http.StatusSeeOther)
+}
+
+// Hide a record from the feed and profiles
+func (h *Handler) HandleAdminHide(w http.ResponseWriter, r *http.Request) {
+	adminDID, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	uri := strings.TrimSpace(r.PostForm.Get("uri"))
+	if err := h.admin.HideRecord(r.Context(), uri, strings.TrimSpace(r.PostForm.Get("reason")), adminDID); err != nil {
+		http.Error(w, "Invalid record URI", http.StatusBadRequest)
+		log.Warn().Err(err).Str("uri", uri).Msg("Failed to hide record")
+		return
+	}
+	log.Info().Str("uri", uri).Str("admin", adminDID).Msg("Hid record")
+	http.Redirect(w, r, "/admin?done=hidden", http.StatusSeeOther)
+}
+
+// Make a hidden record visible again
+func (h *Handler) HandleAdminUnhide(w http.ResponseWriter, r *http.Request) {
+	adminDID, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	uri := r.PostForm.Get("uri")
+	if err := h.admin.UnhideRecord(r.Context(), uri); err != nil {
+		h.adminActionFailed(w, err, "Record is not hidden")
+		return
+	}
+	log.Info().Str("uri", uri).Str("admin", adminDID).Msg("Unhid record")
+	http.Redirect(w, r, "/admin?done=unhidden", http.StatusSeeOther)
+}
+
+// adminActionFailed reports an undo that had nothing to undo as a bad request
+// and anything else as a server error
+func (h *Handler) adminActionFailed(w http.ResponseWriter, err error, notFoundMsg string) {
+	if errors.Is(err, admin.ErrNotFound) {
+		http.Error(w, notFoundMsg, http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "Failed to update moderation", http.StatusInternalServerError)
+	log.Error().Err(err).Msg("Failed to update moderation")
+}
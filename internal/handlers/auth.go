@@ -7,6 +7,9 @@ import (
 	"net/http"
 	"time"
 
+	"arabica/internal/atproto"
+	"arabica/internal/middleware"
+
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/rs/zerolog/log"
 )
@@ -75,26 +78,15 @@ func (h *Handler) HandleOAuthCallback(w http.ResponseWriter, r *http.Request) {
 		h.feedRegistry.Register(sessData.AccountDID.String())
 	}
 
-	// Set session cookies
-	http.SetCookie(w, &http.Cookie{
-		Name:     "account_did",
-		Value:    sessData.AccountDID.String(),
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   h.config.SecureCookies,
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   86400 * 30, // 30 days
-	})
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    sessData.SessionID,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   h.config.SecureCookies,
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   86400 * 30, // 30 days
-	})
+	// Remember where the session was created for the sessions page
+	if h.sessions != nil {
+		if err := h.sessions.RecordLogin(r.Context(), sessData.AccountDID, sessData.SessionID, r.UserAgent(), middleware.ClientIP(r)); err != nil {
+			log.Warn().Err(err).Str("user_did", sessData.AccountDID.String()).Msg("Failed to record session activity")
+		}
+	}
+
+	// Make the new session the active account, keeping others signed in
+	h.signIn(w, r, atproto.Account{DID: sessData.AccountDID, SessionID: sessData.SessionID, Handle: sessData.Handle})
 
 	log.Info().
 		Str("user_did", sessData.AccountDID.String()).
@@ -105,45 +97,53 @@ func (h *Handler) HandleOAuthCallback(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/", http.StatusFound)
 }
 
-// HandleLogout logs out the user
+// deleteReplacedSession deletes the session in the request's cookies if it
+// belongs to did and isn't the new session
+func (h *Handler) deleteReplacedSession(r *http.Request, did syntax.DID, sessionID string) {
+	didCookie, err1 := r.Cookie("account_did")
+	sessionCookie, err2 := r.Cookie("session_id")
+	if err1 != nil || err2 != nil || didCookie.Value != did.String() || sessionCookie.Value == sessionID {
+		return
+	}
+	if err := h.oauth.DeleteSession(r.Context(), did, sessionCookie.Value); err != nil {
+		log.Warn().Err(err).Str("user_did", did.String()).Msg("Failed to delete replaced session")
+		return
+	}
+	if h.sessionCache != nil {
+		h.sessionCache.Invalidate(sessionCookie.Value)
+	}
+}
+
+// HandleLogout logs out the active account, switching to another signed-in
+// account if there is one. With the all form field set, every account is
+// logged out.
 func (h *Handler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	if h.oauth == nil {
 		http.Error(w, "OAuth not configured", http.StatusInternalServerError)
 		return
 	}
+	ctx := r.Context()
 
-	// Get session cookies
-	didCookie, err1 := r.Cookie("account_did")
-	sessionCookie, err2 := r.Cookie("session_id")
+	active, ok := cookieSession(r)
+	if ok {
+		h.endSession(ctx, active.DID, active.SessionID)
+	}
 
-	if err1 == nil && err2 == nil {
-		// Parse DID
-		did, err := syntax.ParseDID(didCookie.Value)
-		if err == nil {
-			// Delete session from store
-			err = h.oauth.DeleteSession(r.Context(), did, sessionCookie.Value)
-			if err != nil {
-				log.Warn().Err(err).Str("user_did", did.String()).Msg("Failed to delete session during logout")
-			}
+	var accounts []atproto.Account
+	for _, account := range h.liveAccounts(r) {
+		if r.FormValue("all") != "" || account.DID == active.DID {
+			h.endSession(ctx, account.DID, account.SessionID)
+			continue
 		}
+		accounts = append(accounts, account)
 	}
 
-	// Clear session cookies
-	http.SetCookie(w, &http.Cookie{
-		Name:     "account_did",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   -1,
-	})
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   -1,
-	})
+	if len(accounts) == 0 {
+		clearSessionCookies(w)
+	} else {
+		h.setSessionCookies(w, accounts[0])
+		h.setAccountsCookie(w, accounts[1:])
+	}
 
 	// Redirect to home page
 	http.Redirect(w, r, "/", http.StatusFound)
@@ -171,6 +171,27 @@ func (h *Handler) HandleWellKnownOAuth(w http.ResponseWriter, r *http.Request) {
 	h.HandleClientMetadata(w, r)
 }
 
+// HandleJWKS serves the public keys of a confidential OAuth client, which
+// auth servers use to verify its client assertions
+func (h *Handler) HandleJWKS(w http.ResponseWriter, r *http.Request) {
+	if h.oauth == nil || !h.oauth.IsConfidential() {
+		http.NotFound(w, r)
+		return
+	}
+
+	jwks, err := h.oauth.JWKS()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build JWKS")
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(jwks); err != nil {
+		log.Error().Err(err).Msg("Failed to encode JWKS")
+	}
+}
+
 // HandleResolveHandle resolves an AT Protocol handle and returns basic profile info
 // This is used for the autocomplete login feature
 func (h *Handler) HandleResolveHandle(w http.ResponseWriter, r *http.Request) {
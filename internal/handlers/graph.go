@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"arabica/internal/atproto"
+	"arabica/internal/bff"
+	"arabica/internal/feed"
+	"arabica/internal/models"
+
+	"github.com/rs/zerolog/log"
+)
+
+// graphDone maps the done query parameter set after changing the mute and
+// block lists to the confirmation shown on the muted and blocked accounts page
+var graphDone = map[string]string{
+	"muted":     "Account muted.",
+	"unmuted":   "Account unmuted.",
+	"blocked":   "Account blocked.",
+	"unblocked": "Account unblocked.",
+	"imported":  "Bluesky blocks imported.",
+}
+
+// feedViewer returns the authenticated user with their mute and block lists,
+// or nil for visitors. If the lists can't be read the viewer has none, but
+// blocks by other users still apply.
+func (h *Handler) feedViewer(r *http.Request) *feed.Viewer {
+	store, authenticated := h.getAtprotoStore(r)
+	if !authenticated {
+		return nil
+	}
+	didStr, _ := atproto.GetAuthenticatedDID(r.Context())
+	graph, err := store.GetGraph(r.Context())
+	if err != nil {
+		log.Warn().Err(err).Str("did", didStr).Msg("Failed to read mute and block lists")
+	}
+	return &feed.Viewer{DID: didStr, Graph: graph}
+}
+
+// profileRelationship returns how the authenticated user and the owner of a
+// profile have muted or blocked each other
+func (h *Handler) profileRelationship(r *http.Request, did string) bff.ProfileRelationship {
+	viewer := h.feedViewer(r)
+	if viewer == nil || viewer.DID == did {
+		return bff.ProfileRelationship{}
+	}
+	rel := bff.ProfileRelationship{
+		Muted:   viewer.Graph.IsMuted(did),
+		Blocked: viewer.Graph.IsBlocked(did),
+	}
+	if h.feedService != nil {
+		rel.BlockedBy = h.feedService.BlocksViewer(r.Context(), did, viewer.DID)
+	}
+	return rel
+}
+
+// resolveDID returns the DID for an actor given as a DID or handle
+func resolveDID(ctx context.Context, actor string) (string, error) {
+	actor = strings.TrimSpace(actor)
+	if strings.HasPrefix(actor, "did:") {
+		return actor, nil
+	}
+	return atproto.NewPublicClient().ResolveHandle(ctx, strings.TrimPrefix(actor, "@"))
+}
+
+// graphRedirect returns to the profile the change was made from, or to the
+// muted and blocked accounts page with a confirmation
+func graphRedirect(w http.ResponseWriter, r *http.Request, done string) {
+	if back := r.PostForm.Get("return"); strings.HasPrefix(back, "/profile/") {
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/settings/graph?done="+done, http.StatusSeeOther)
+}
+
+// updateGraph applies change to the authenticated user's mute and block lists
+// for the account in the actor form field, a DID or handle, and saves them
+func (h *Handler) updateGraph(w http.ResponseWriter, r *http.Request, done string, change func(g *models.Graph, did string)) {
+	store, authenticated := h.getAtprotoStore(r)
+	if !authenticated {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	didStr, _ := atproto.GetAuthenticatedDID(ctx)
+	did, err := resolveDID(ctx, r.PostForm.Get("actor"))
+	if err != nil || did == "" {
+		http.Error(w, "Could not resolve handle", http.StatusBadRequest)
+		return
+	}
+	if did == didStr {
+		http.Error(w, "You can't mute or block yourself", http.StatusBadRequest)
+		return
+	}
+
+	graph, err := store.GetGraph(ctx)
+	if err != nil {
+		http.Error(w, "Failed to load muted and blocked accounts", http.StatusInternalServerError)
+		log.Error().Err(err).Str("did", didStr).Msg("Failed to load mute and block lists")
+		return
+	}
+	// The graph comes from the session cache, so change a copy
+	updated := graph.Clone()
+	change(updated, did)
+
+	if err := store.UpdateGraph(ctx, updated); err != nil {
+		h.graphUpdateFailed(w, err, didStr)
+		return
+	}
+	if h.feedService != nil {
+		h.feedService.InvalidateGraph(didStr)
+	}
+	graphRedirect(w, r, done)
+}
+
+// graphUpdateFailed reports a rejected mute or block list as a bad request and
+// anything else as a server error
+func (h *Handler) graphUpdateFailed(w http.ResponseWriter, err error, did string) {
+	switch {
+	case errors.Is(err, models.ErrGraphTooLarge):
+		http.Error(w, "Too many muted or blocked accounts", http.StatusBadRequest)
+	case errors.Is(err, models.ErrGraphDIDInvalid):
+		http.Error(w, "Invalid DID", http.StatusBadRequest)
+	default:
+		http.Error(w, "Failed to save muted and blocked accounts", http.StatusInternalServerError)
+		log.Error().Err(err).Str("did", did).Msg("Failed to save mute and block lists")
+	}
+}
+
+// Muted and blocked accounts page
+func (h *Handler) HandleGraph(w http.ResponseWriter, r *http.Request) {
+	// Require authentication
+	store, authenticated := h.getAtprotoStore(r)
+	if !authenticated {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	graph, err := store.GetGraph(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to load muted and blocked accounts", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to load mute and block lists")
+		return
+	}
+
+	didStr, _ := atproto.GetAuthenticatedDID(r.Context())
+	data := &bff.GraphPageData{
+		Mutes:           graph.Mutes,
+		Blocks:          graph.Blocks,
+		Done:            graphDone[r.URL.Query().Get("done")],
+		IsAuthenticated: true,
+		UserDID:         didStr,
+		UserProfile:     h.getUserProfile(r.Context(), didStr),
+	}
+	if err := bff.RenderGraph(r.Context(), w, data); err != nil {
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to render muted and blocked accounts page")
+	}
+}
+
+// Mute an account, leaving it out of the user's community feed
+func (h *Handler) HandleMute(w http.ResponseWriter, r *http.Request) {
+	h.updateGraph(w, r, "muted", func(g *models.Graph, did string) { g.Mute(did) })
+}
+
+// Unmute an account
+func (h *Handler) HandleUnmute(w http.ResponseWriter, r *http.Request) {
+	h.updateGraph(w, r, "unmuted", func(g *models.Graph, did string) { g.Unmute(did) })
+}
+
+// Block an account, hiding it and the user from each other
+func (h *Handler) HandleBlock(w http.ResponseWriter, r *http.Request) {
+	h.updateGraph(w, r, "blocked", func(g *models.Graph, did string) { g.Block(did) })
+}
+
+// Unblock an account
+func (h *Handler) HandleUnblock(w http.ResponseWriter, r *http.Request) {
+	h.updateGraph(w, r, "unblocked", func(g *models.Graph, did string) { g.Unblock(did) })
+}
+
+// Add the accounts the user blocked on Bluesky to their block list
+func (h *Handler) HandleImportBlocks(w http.ResponseWriter, r *http.Request) {
+	// Require authentication
+	store, authenticated := h.getAtprotoStore(r)
+	if !authenticated {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	didStr, _ := atproto.GetAuthenticatedDID(ctx)
+	blocks, err := store.ListBlueskyBlocks(ctx)
+	if err != nil {
+		http.Error(w, "Failed to read Bluesky blocks", http.StatusBadGateway)
+		log.Error().Err(err).Str("did", didStr).Msg("Failed to list Bluesky blocks")
+		return
+	}
+
+	graph, err := store.GetGraph(ctx)
+	if err != nil {
+		http.Error(w, "Failed to load muted and blocked accounts", http.StatusInternalServerError)
+		log.Error().Err(err).Str("did", didStr).Msg("Failed to load mute and block lists")
+		return
+	}
+	updated := graph.Clone()
+	added := updated.Block(blocks...)
+
+	if added > 0 {
+		if err := store.UpdateGraph(ctx, updated); err != nil {
+			h.graphUpdateFailed(w, err, didStr)
+			return
+		}
+		if h.feedService != nil {
+			h.feedService.InvalidateGraph(didStr)
+		}
+	}
+	log.Info().Str("did", didStr).Int("found", len(blocks)).Int("added", added).Msg("Imported Bluesky blocks")
+	http.Redirect(w, r, "/settings/graph?done=imported", http.StatusSeeOther)
+}
@@ -3,13 +3,17 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
+	"arabica/internal/admin"
 	"arabica/internal/atproto"
 	"arabica/internal/bff"
 	"arabica/internal/database"
+	"arabica/internal/database/boltstore"
 	"arabica/internal/feed"
 	"arabica/internal/models"
 
@@ -22,6 +26,12 @@ type Config struct {
 	// SecureCookies sets the Secure flag on authentication cookies
 	// Should be true in production (HTTPS), false for local development (HTTP)
 	SecureCookies bool
+
+	// AdminDIDs are the accounts allowed to use the admin dashboard
+	AdminDIDs []string
+
+	// LabelsEnabled offers content filtering settings, set when labelers are configured
+	LabelsEnabled bool
 }
 
 // Handler contains all HTTP handler methods and their dependencies.
@@ -33,6 +43,10 @@ type Handler struct {
 	config        Config
 	feedService   *feed.Service
 	feedRegistry  *feed.Registry
+	preferences   *boltstore.PreferencesStore
+	moderation    *boltstore.ModerationStore
+	sessions      *boltstore.SessionStore
+	admin         *admin.Local
 }
 
 // NewHandler creates a new Handler with all required dependencies.
@@ -43,6 +57,10 @@ func NewHandler(
 	sessionCache *atproto.SessionCache,
 	feedService *feed.Service,
 	feedRegistry *feed.Registry,
+	preferences *boltstore.PreferencesStore,
+	moderation *boltstore.ModerationStore,
+	sessions *boltstore.SessionStore,
+	adminOps *admin.Local,
 	config Config,
 ) *Handler {
 	return &Handler{
@@ -52,6 +70,10 @@ func NewHandler(
 		config:        config,
 		feedService:   feedService,
 		feedRegistry:  feedRegistry,
+		preferences:   preferences,
+		moderation:    moderation,
+		sessions:      sessions,
+		admin:         adminOps,
 	}
 }
 
@@ -105,6 +127,12 @@ func (h *Handler) getUserProfile(ctx context.Context, did string) *bff.UserProfi
 		userProfile.Avatar = *profile.Avatar
 	}
 
+	// Sessions from before a collection was added can't write to it
+	if sessData, err := atproto.GetSessionDataFromContext(ctx); err == nil && sessData.AccountDID.String() == did {
+		userProfile.NeedsPermissions = len(atproto.MissingScopes(sessData.Scopes)) > 0
+		userProfile.Accounts = atproto.GetAccountsFromContext(ctx)
+	}
+
 	return userProfile
 }
 
@@ -147,7 +175,7 @@ func (h *Handler) HandleHome(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Don't fetch feed items here - let them load async via HTMX
-	if err := bff.RenderHome(w, isAuthenticated, didStr, userProfile, nil); err != nil {
+	if err := bff.RenderHome(r.Context(), w, isAuthenticated, didStr, userProfile, nil, h.userSettings(r).DevMode); err != nil {
 		http.Error(w, "Failed to render page", http.StatusInternalServerError)
 		log.Error().Err(err).Msg("Failed to render home page")
 	}
@@ -164,20 +192,44 @@ func (h *Handler) HandleFeedPartial(w http.ResponseWriter, r *http.Request) {
 
 		if isAuthenticated {
 			// Authenticated users get the full feed (20 items), fetched fresh
-			feedItems, _ = h.feedService.GetRecentRecords(r.Context(), 20)
+			// without the accounts they muted or blocked
+			feedItems, _ = h.feedService.GetRecentRecords(r.Context(), 20, h.feedViewer(r))
 		} else {
 			// Unauthenticated users get a limited feed from the cache
 			feedItems, _ = h.feedService.GetCachedPublicFeed(r.Context())
 		}
 	}
 
-	if err := bff.RenderFeedPartial(w, feedItems); err != nil {
+	feedItems = feed.ApplyLabelPreferences(feedItems, h.labelPreferences(r))
+
+	if err := bff.RenderFeedPartial(r.Context(), w, feedItems, h.unitPreferences(r), h.userSettings(r).ViewMode); err != nil {
 		http.Error(w, "Failed to render feed", http.StatusInternalServerError)
 		log.Error().Err(err).Msg("Failed to render feed partial")
 	}
 }
 
+// parseBrewListQuery reads the brew list sort, filter and page parameters.
+// Returns an error message if any parameter is invalid.
+func parseBrewListQuery(values url.Values) (models.BrewQuery, string) {
+	q, err := models.ParseBrewQuery(values)
+	if err != nil {
+		return models.BrewQuery{}, err.Error()
+	}
+	for _, ref := range []struct{ rkey, name string }{
+		{q.BeanRKey, "Bean filter"},
+		{q.RoasterRKey, "Roaster filter"},
+		{q.BrewerRKey, "Brewer filter"},
+		{q.GrinderRKey, "Grinder filter"},
+	} {
+		if errMsg := validateOptionalRKey(ref.rkey, ref.name); errMsg != "" {
+			return models.BrewQuery{}, errMsg
+		}
+	}
+	return q, ""
+}
+
 // Brew list partial (loaded async via HTMX)
+// Sorting, filtering and pagination are applied to the cached brew list
 func (h *Handler) HandleBrewListPartial(w http.ResponseWriter, r *http.Request) {
 	// Require authentication
 	store, authenticated := h.getAtprotoStore(r)
@@ -186,14 +238,59 @@ func (h *Handler) HandleBrewListPartial(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	brews, err := store.ListBrews(r.Context(), 1) // User ID is not used with atproto
-	if err != nil {
+	query, errMsg := parseBrewListQuery(r.URL.Query())
+	if errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	// Fetch brews along with the records used to populate the filter controls
+	g, ctx := errgroup.WithContext(r.Context())
+
+	var brews []*models.Brew
+	var beans []*models.Bean
+	var roasters []*models.Roaster
+	var grinders []*models.Grinder
+	var brewers []*models.Brewer
+
+	g.Go(func() error {
+		var err error
+		brews, err = store.ListBrews(ctx, 1) // User ID is not used with atproto
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		beans, err = store.ListBeans(ctx)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		roasters, err = store.ListRoasters(ctx)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		grinders, err = store.ListGrinders(ctx)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		brewers, err = store.ListBrewers(ctx)
+		return err
+	})
+
+	if err := g.Wait(); err != nil {
 		http.Error(w, "Failed to fetch brews", http.StatusInternalServerError)
 		log.Error().Err(err).Msg("Failed to fetch brews")
 		return
 	}
 
-	if err := bff.RenderBrewListPartial(w, brews); err != nil {
+	page := query.Apply(brews)
+
+	// Keep the address bar in sync with the view so it can be shared or bookmarked
+	w.Header().Set("HX-Push-Url", bff.BrewListURL("/brews", query))
+
+	if err := bff.RenderBrewListPartial(r.Context(), w, page, query, h.unitPreferences(r), h.userSettings(r).ViewMode, beans, roasters, grinders, brewers); err != nil {
 		http.Error(w, "Failed to render content", http.StatusInternalServerError)
 		log.Error().Err(err).Msg("Failed to render brew list partial")
 	}
@@ -249,12 +346,45 @@ func (h *Handler) HandleManagePartial(w http.ResponseWriter, r *http.Request) {
 	// Link beans to their roasters
 	atproto.LinkBeansToRoasters(beans, roasters)
 
-	if err := bff.RenderManagePartial(w, beans, roasters, grinders, brewers); err != nil {
+	if err := bff.RenderManagePartial(r.Context(), w, beans, roasters, grinders, brewers); err != nil {
 		http.Error(w, "Failed to render content", http.StatusInternalServerError)
 		log.Error().Err(err).Msg("Failed to render manage partial")
 	}
 }
 
+// Grind size hints partial for the brew form (loaded via HTMX as the grinder or setting changes)
+func (h *Handler) HandleGrindHintsPartial(w http.ResponseWriter, r *http.Request) {
+	// Require authentication
+	store, authenticated := h.getAtprotoStore(r)
+	if !authenticated {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	grinderRKey := r.URL.Query().Get("grinder_rkey")
+	if errMsg := validateOptionalRKey(grinderRKey, "Grinder selection"); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	var grinders []*models.Grinder
+	if grinderRKey != "" {
+		var err error
+		grinders, err = store.ListGrinders(r.Context())
+		if err != nil {
+			http.Error(w, "Failed to fetch grinders", http.StatusInternalServerError)
+			log.Error().Err(err).Msg("Failed to fetch grinders for grind hints")
+			return
+		}
+	}
+
+	data := buildGrindHints(grinders, grinderRKey, strings.TrimSpace(r.URL.Query().Get("grind_size")))
+	if err := bff.RenderGrindHintsPartial(r.Context(), w, data); err != nil {
+		http.Error(w, "Failed to render content", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to render grind hints partial")
+	}
+}
+
 // List all brews
 func (h *Handler) HandleBrewList(w http.ResponseWriter, r *http.Request) {
 	// Require authentication
@@ -267,13 +397,41 @@ func (h *Handler) HandleBrewList(w http.ResponseWriter, r *http.Request) {
 	didStr, _ := atproto.GetAuthenticatedDID(r.Context())
 	userProfile := h.getUserProfile(r.Context(), didStr)
 
+	// Invalid parameters fall back to the default view rather than erroring on a shared link
+	query, _ := parseBrewListQuery(r.URL.Query())
+
 	// Don't fetch brews here - let them load async via HTMX
-	if err := bff.RenderBrewList(w, nil, authenticated, didStr, userProfile); err != nil {
+	if err := bff.RenderBrewList(r.Context(), w, nil, query, h.unitPreferences(r), authenticated, didStr, userProfile); err != nil {
 		http.Error(w, "Failed to render page", http.StatusInternalServerError)
 		log.Error().Err(err).Msg("Failed to render brew list page")
 	}
 }
 
+// Brewing stats page with ratio/extraction summary and control chart
+func (h *Handler) HandleStats(w http.ResponseWriter, r *http.Request) {
+	// Require authentication
+	store, authenticated := h.getAtprotoStore(r)
+	if !authenticated {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	brews, err := store.ListBrews(r.Context(), 1) // User ID is not used with atproto
+	if err != nil {
+		http.Error(w, "Failed to fetch brews", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to fetch brews for stats")
+		return
+	}
+
+	didStr, _ := atproto.GetAuthenticatedDID(r.Context())
+	userProfile := h.getUserProfile(r.Context(), didStr)
+
+	if err := bff.RenderStats(r.Context(), w, brews, h.unitPreferences(r), authenticated, didStr, userProfile); err != nil {
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to render stats page")
+	}
+}
+
 // Show new brew form
 func (h *Handler) HandleBrewNew(w http.ResponseWriter, r *http.Request) {
 	// Require authentication
@@ -288,7 +446,7 @@ func (h *Handler) HandleBrewNew(w http.ResponseWriter, r *http.Request) {
 
 	// Don't fetch data from PDS - client will populate dropdowns from cache
 	// This makes the page load much faster
-	if err := bff.RenderBrewForm(w, nil, nil, nil, nil, nil, authenticated, didStr, userProfile); err != nil {
+	if err := bff.RenderBrewForm(r.Context(), w, nil, nil, nil, nil, nil, h.unitPreferences(r), authenticated, didStr, userProfile); err != nil {
 		http.Error(w, "Failed to render page", http.StatusInternalServerError)
 		log.Error().Err(err).Msg("Failed to render brew form")
 	}
@@ -320,7 +478,7 @@ func (h *Handler) HandleBrewEdit(w http.ResponseWriter, r *http.Request) {
 
 	// Don't fetch dropdown data from PDS - client will populate from cache
 	// This makes the page load much faster
-	if err := bff.RenderBrewForm(w, nil, nil, nil, nil, brew, authenticated, didStr, userProfile); err != nil {
+	if err := bff.RenderBrewForm(r.Context(), w, nil, nil, nil, nil, brew, h.unitPreferences(r), authenticated, didStr, userProfile); err != nil {
 		http.Error(w, "Failed to render page", http.StatusInternalServerError)
 		log.Error().Err(err).Msg("Failed to render brew edit form")
 	}
@@ -329,8 +487,9 @@ func (h *Handler) HandleBrewEdit(w http.ResponseWriter, r *http.Request) {
 // maxPours is the maximum number of pours allowed in a single brew
 const maxPours = 100
 
-// parsePours extracts pour data from form values with bounds checking
-func parsePours(r *http.Request) []models.CreatePourData {
+// parsePours extracts pour data from form values with bounds checking.
+// Pour water is entered in the user's volume unit and returned in milliliters.
+func parsePours(r *http.Request, units models.UnitPreferences) []models.CreatePourData {
 	var pours []models.CreatePourData
 
 	for i := 0; i < maxPours; i++ {
@@ -344,7 +503,8 @@ func parsePours(r *http.Request) []models.CreatePourData {
 			break
 		}
 
-		water, _ := strconv.Atoi(waterStr)
+		waterValue, _ := strconv.ParseFloat(waterStr, 64)
+		water := units.VolumeToMilliliters(waterValue)
 		pourTime, _ := strconv.Atoi(timeStr)
 
 		if water > 0 && pourTime >= 0 {
@@ -364,48 +524,68 @@ type ValidationError struct {
 	Message string
 }
 
-// validateBrewRequest validates brew form input and returns any validation errors
-func validateBrewRequest(r *http.Request) (temperature float64, waterAmount, coffeeAmount, timeSeconds, rating int, pours []models.CreatePourData, errs []ValidationError) {
+// Brew form limits in canonical units
+const (
+	maxBrewTemperature = 100.0 // °C
+	maxWaterAmount     = 10000 // ml
+	maxCoffeeAmount    = 1000  // g
+	maxBeverageWeight  = 10000 // g
+)
+
+// brewInput holds the measurements parsed from the brew form
+type brewInput struct {
+	temperature    float64
+	waterAmount    int
+	coffeeAmount   int
+	timeSeconds    int
+	rating         int
+	tds            float64
+	beverageWeight int
+	pours          []models.CreatePourData
+}
+
+// validateBrewRequest validates brew form input and returns any validation errors.
+// Measurements are entered in the user's preferred units and returned in canonical
+// units: °C, grams of coffee and beverage, milliliters of water.
+func validateBrewRequest(r *http.Request, units models.UnitPreferences) (in brewInput, errs []ValidationError) {
 	// Parse and validate temperature
 	if tempStr := r.FormValue("temperature"); tempStr != "" {
-		var err error
-		temperature, err = strconv.ParseFloat(tempStr, 64)
+		value, err := strconv.ParseFloat(tempStr, 64)
 		if err != nil {
 			errs = append(errs, ValidationError{Field: "temperature", Message: "invalid temperature format"})
-		} else if temperature < 0 || temperature > 212 {
-			errs = append(errs, ValidationError{Field: "temperature", Message: "temperature must be between 0 and 212"})
+		} else if in.temperature = units.TemperatureToCelsius(value); in.temperature < 0 || in.temperature > maxBrewTemperature {
+			errs = append(errs, ValidationError{Field: "temperature", Message: "temperature must be between " +
+				units.TemperatureInput(0) + units.TemperatureLabel() + " and " + units.TemperatureInput(maxBrewTemperature) + units.TemperatureLabel()})
 		}
 	}
 
 	// Parse and validate water amount
 	if waterStr := r.FormValue("water_amount"); waterStr != "" {
-		var err error
-		waterAmount, err = strconv.Atoi(waterStr)
+		value, err := strconv.ParseFloat(waterStr, 64)
 		if err != nil {
 			errs = append(errs, ValidationError{Field: "water_amount", Message: "invalid water amount"})
-		} else if waterAmount < 0 || waterAmount > 10000 {
-			errs = append(errs, ValidationError{Field: "water_amount", Message: "water amount must be between 0 and 10000ml"})
+		} else if in.waterAmount = units.VolumeToMilliliters(value); in.waterAmount < 0 || in.waterAmount > maxWaterAmount {
+			errs = append(errs, ValidationError{Field: "water_amount", Message: "water amount must be between 0 and " + units.FormatVolume(maxWaterAmount)})
 		}
 	}
 
 	// Parse and validate coffee amount
 	if coffeeStr := r.FormValue("coffee_amount"); coffeeStr != "" {
-		var err error
-		coffeeAmount, err = strconv.Atoi(coffeeStr)
+		value, err := strconv.ParseFloat(coffeeStr, 64)
 		if err != nil {
 			errs = append(errs, ValidationError{Field: "coffee_amount", Message: "invalid coffee amount"})
-		} else if coffeeAmount < 0 || coffeeAmount > 1000 {
-			errs = append(errs, ValidationError{Field: "coffee_amount", Message: "coffee amount must be between 0 and 1000g"})
+		} else if in.coffeeAmount = units.WeightToGrams(value); in.coffeeAmount < 0 || in.coffeeAmount > maxCoffeeAmount {
+			errs = append(errs, ValidationError{Field: "coffee_amount", Message: "coffee amount must be between 0 and " + units.FormatWeight(maxCoffeeAmount)})
 		}
 	}
 
 	// Parse and validate time
 	if timeStr := r.FormValue("time_seconds"); timeStr != "" {
 		var err error
-		timeSeconds, err = strconv.Atoi(timeStr)
+		in.timeSeconds, err = strconv.Atoi(timeStr)
 		if err != nil {
 			errs = append(errs, ValidationError{Field: "time_seconds", Message: "invalid time"})
-		} else if timeSeconds < 0 || timeSeconds > 3600 {
+		} else if in.timeSeconds < 0 || in.timeSeconds > 3600 {
 			errs = append(errs, ValidationError{Field: "time_seconds", Message: "brew time must be between 0 and 3600 seconds"})
 		}
 	}
@@ -413,20 +593,120 @@ func validateBrewRequest(r *http.Request) (temperature float64, waterAmount, cof
 	// Parse and validate rating
 	if ratingStr := r.FormValue("rating"); ratingStr != "" {
 		var err error
-		rating, err = strconv.Atoi(ratingStr)
+		in.rating, err = strconv.Atoi(ratingStr)
 		if err != nil {
 			errs = append(errs, ValidationError{Field: "rating", Message: "invalid rating"})
-		} else if rating < 0 || rating > 10 {
+		} else if in.rating < 0 || in.rating > 10 {
 			errs = append(errs, ValidationError{Field: "rating", Message: "rating must be between 0 and 10"})
 		}
 	}
 
+	// Parse and validate TDS (percent)
+	if tdsStr := r.FormValue("tds"); tdsStr != "" {
+		var err error
+		in.tds, err = strconv.ParseFloat(tdsStr, 64)
+		if err != nil {
+			errs = append(errs, ValidationError{Field: "tds", Message: "invalid TDS"})
+		} else if in.tds < 0 || in.tds > models.MaxTDS {
+			errs = append(errs, ValidationError{Field: "tds", Message: "TDS must be between 0 and 30%"})
+		}
+	}
+
+	// Parse and validate beverage weight
+	if beverageStr := r.FormValue("beverage_weight"); beverageStr != "" {
+		value, err := strconv.ParseFloat(beverageStr, 64)
+		if err != nil {
+			errs = append(errs, ValidationError{Field: "beverage_weight", Message: "invalid beverage weight"})
+		} else if in.beverageWeight = units.WeightToGrams(value); in.beverageWeight < 0 || in.beverageWeight > maxBeverageWeight {
+			errs = append(errs, ValidationError{Field: "beverage_weight", Message: "beverage weight must be between 0 and " + units.FormatWeight(maxBeverageWeight)})
+		}
+	}
+
 	// Parse pours
-	pours = parsePours(r)
+	in.pours = parsePours(r, units)
 
 	return
 }
 
+// findGrinder returns the grinder with the given rkey, or nil
+func findGrinder(grinders []*models.Grinder, rkey string) *models.Grinder {
+	for _, g := range grinders {
+		if g.RKey == rkey {
+			return g
+		}
+	}
+	return nil
+}
+
+// validateGrindSize checks a grind size against the selected grinder's scale.
+// Grinders without a scale accept any value, including descriptions like "Medium".
+func validateGrindSize(grinders []*models.Grinder, grinderRKey, grindSize string) string {
+	if grinderRKey == "" || grindSize == "" {
+		return ""
+	}
+	grinder := findGrinder(grinders, grinderRKey)
+	if grinder == nil || grinder.Scale == nil {
+		return ""
+	}
+	if _, err := grinder.Scale.ParseSetting(grindSize); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// checkGrindSize validates the grind size using the user's (cached) grinders.
+// A failed lookup is logged and does not block saving the brew.
+func checkGrindSize(ctx context.Context, store database.Store, grinderRKey, grindSize string) string {
+	if grinderRKey == "" || grindSize == "" {
+		return ""
+	}
+	grinders, err := store.ListGrinders(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to fetch grinders for grind size validation")
+		return ""
+	}
+	return validateGrindSize(grinders, grinderRKey, grindSize)
+}
+
+// buildGrindHints computes the range and cross-grinder equivalents shown under
+// the grind size input for the selected grinder
+func buildGrindHints(grinders []*models.Grinder, grinderRKey, grindSize string) *bff.GrindHintsData {
+	data := &bff.GrindHintsData{
+		Grinder: findGrinder(grinders, grinderRKey),
+	}
+	if data.Grinder == nil || data.Grinder.Scale == nil || grindSize == "" {
+		return data
+	}
+
+	setting, err := data.Grinder.Scale.ParseSetting(grindSize)
+	if err != nil {
+		data.Error = err.Error()
+		return data
+	}
+
+	microns, ok := data.Grinder.SettingToMicrons(setting)
+	if !ok {
+		return data
+	}
+	data.Microns = int(math.Round(microns))
+
+	for _, other := range grinders {
+		if other.RKey == grinderRKey || !other.IsCalibrated() {
+			continue
+		}
+		equivalent, ok := other.MicronsToSetting(microns)
+		if !ok {
+			continue
+		}
+		data.Equivalents = append(data.Equivalents, bff.GrindEquivalent{
+			Grinder: other,
+			Setting: models.FormatGrindSetting(equivalent),
+		})
+	}
+
+	return data
+}
+
 // Create new brew
 func (h *Handler) HandleBrewCreate(w http.ResponseWriter, r *http.Request) {
 	// Require authentication first
@@ -442,7 +722,7 @@ func (h *Handler) HandleBrewCreate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate input
-	temperature, waterAmount, coffeeAmount, timeSeconds, rating, pours, validationErrs := validateBrewRequest(r)
+	in, validationErrs := validateBrewRequest(r, h.unitPreferences(r))
 	if len(validationErrs) > 0 {
 		// Return first validation error
 		http.Error(w, validationErrs[0].Message, http.StatusBadRequest)
@@ -471,20 +751,27 @@ func (h *Handler) HandleBrewCreate(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, errMsg, http.StatusBadRequest)
 		return
 	}
+	if errMsg := checkGrindSize(r.Context(), store, grinderRKey, r.FormValue("grind_size")); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
 
 	req := &models.CreateBrewRequest{
 		BeanRKey:     beanRKey,
 		Method:       r.FormValue("method"),
-		Temperature:  temperature,
-		WaterAmount:  waterAmount,
-		CoffeeAmount: coffeeAmount,
-		TimeSeconds:  timeSeconds,
+		Temperature:  in.temperature,
+		WaterAmount:  in.waterAmount,
+		CoffeeAmount: in.coffeeAmount,
+		TimeSeconds:  in.timeSeconds,
 		GrindSize:    r.FormValue("grind_size"),
 		GrinderRKey:  grinderRKey,
 		BrewerRKey:   brewerRKey,
 		TastingNotes: r.FormValue("tasting_notes"),
-		Rating:       rating,
-		Pours:        pours,
+		Rating:       in.rating,
+		Pours:        in.pours,
+
+		TDS:            in.tds,
+		BeverageWeight: in.beverageWeight,
 	}
 
 	_, err := store.CreateBrew(r.Context(), req, 1) // User ID not used with atproto
@@ -519,7 +806,7 @@ func (h *Handler) HandleBrewUpdate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate input
-	temperature, waterAmount, coffeeAmount, timeSeconds, rating, pours, validationErrs := validateBrewRequest(r)
+	in, validationErrs := validateBrewRequest(r, h.unitPreferences(r))
 	if len(validationErrs) > 0 {
 		http.Error(w, validationErrs[0].Message, http.StatusBadRequest)
 		return
@@ -547,20 +834,27 @@ func (h *Handler) HandleBrewUpdate(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, errMsg, http.StatusBadRequest)
 		return
 	}
+	if errMsg := checkGrindSize(r.Context(), store, grinderRKey, r.FormValue("grind_size")); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
 
 	req := &models.CreateBrewRequest{
 		BeanRKey:     beanRKey,
 		Method:       r.FormValue("method"),
-		Temperature:  temperature,
-		WaterAmount:  waterAmount,
-		CoffeeAmount: coffeeAmount,
-		TimeSeconds:  timeSeconds,
+		Temperature:  in.temperature,
+		WaterAmount:  in.waterAmount,
+		CoffeeAmount: in.coffeeAmount,
+		TimeSeconds:  in.timeSeconds,
 		GrindSize:    r.FormValue("grind_size"),
 		GrinderRKey:  grinderRKey,
 		BrewerRKey:   brewerRKey,
 		TastingNotes: r.FormValue("tasting_notes"),
-		Rating:       rating,
-		Pours:        pours,
+		Rating:       in.rating,
+		Pours:        in.pours,
+
+		TDS:            in.tds,
+		BeverageWeight: in.beverageWeight,
 	}
 
 	err := store.UpdateBrewByRKey(r.Context(), rkey, req)
@@ -619,7 +913,7 @@ func (h *Handler) HandleBrewExport(w http.ResponseWriter, r *http.Request) {
 
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(brews); err != nil {
+	if err := encoder.Encode(bff.NewBrewExport(brews, h.unitPreferences(r))); err != nil {
 		log.Error().Err(err).Msg("Failed to encode brews for export")
 	}
 }
@@ -780,7 +1074,7 @@ func (h *Handler) HandleManage(w http.ResponseWriter, r *http.Request) {
 	userProfile := h.getUserProfile(r.Context(), didStr)
 
 	// Don't fetch data here - let it load async via HTMX
-	if err := bff.RenderManage(w, nil, nil, nil, nil, authenticated, didStr, userProfile); err != nil {
+	if err := bff.RenderManage(r.Context(), w, nil, nil, nil, nil, authenticated, didStr, userProfile); err != nil {
 		http.Error(w, "Failed to render page", http.StatusInternalServerError)
 		log.Error().Err(err).Msg("Failed to render manage page")
 	}
@@ -1241,6 +1535,11 @@ func (h *Handler) HandleProfile(w http.ResponseWriter, r *http.Request) {
 		// For now, continue with the DID we have
 	}
 
+	if h.isBannedDID(did) {
+		h.HandleNotFound(w, r)
+		return
+	}
+
 	// Fetch profile
 	profile, err := publicClient.GetProfile(ctx, did)
 	if err != nil {
@@ -1255,6 +1554,17 @@ func (h *Handler) HandleProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A block either way keeps the records from the viewer, so don't fetch them
+	relationship := h.profileRelationship(r, did)
+	if relationship.Hidden() {
+		didStr, _ := atproto.GetAuthenticatedDID(ctx)
+		if err := bff.RenderProfile(ctx, w, profile, nil, nil, nil, nil, nil, true, didStr, h.getUserProfile(ctx, didStr), false, relationship, h.userSettings(r).DevMode); err != nil {
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+			log.Error().Err(err).Msg("Failed to render profile page")
+		}
+		return
+	}
+
 	// Fetch all user data in parallel
 	g, gCtx := errgroup.WithContext(ctx)
 
@@ -1280,7 +1590,7 @@ func (h *Handler) HandleProfile(w http.ResponseWriter, r *http.Request) {
 		beanMap = make(map[string]*models.Bean)
 		beanRoasterRefMap = make(map[string]string)
 		beans = make([]*models.Bean, 0, len(output.Records))
-		for _, record := range output.Records {
+		for _, record := range h.visibleRecords(output.Records) {
 			bean, err := atproto.RecordToBean(record.Value, record.URI)
 			if err != nil {
 				continue
@@ -1302,7 +1612,7 @@ func (h *Handler) HandleProfile(w http.ResponseWriter, r *http.Request) {
 		}
 		roasterMap = make(map[string]*models.Roaster)
 		roasters = make([]*models.Roaster, 0, len(output.Records))
-		for _, record := range output.Records {
+		for _, record := range h.visibleRecords(output.Records) {
 			roaster, err := atproto.RecordToRoaster(record.Value, record.URI)
 			if err != nil {
 				continue
@@ -1321,7 +1631,7 @@ func (h *Handler) HandleProfile(w http.ResponseWriter, r *http.Request) {
 		}
 		grinderMap = make(map[string]*models.Grinder)
 		grinders = make([]*models.Grinder, 0, len(output.Records))
-		for _, record := range output.Records {
+		for _, record := range h.visibleRecords(output.Records) {
 			grinder, err := atproto.RecordToGrinder(record.Value, record.URI)
 			if err != nil {
 				continue
@@ -1340,7 +1650,7 @@ func (h *Handler) HandleProfile(w http.ResponseWriter, r *http.Request) {
 		}
 		brewerMap = make(map[string]*models.Brewer)
 		brewers = make([]*models.Brewer, 0, len(output.Records))
-		for _, record := range output.Records {
+		for _, record := range h.visibleRecords(output.Records) {
 			brewer, err := atproto.RecordToBrewer(record.Value, record.URI)
 			if err != nil {
 				continue
@@ -1358,7 +1668,7 @@ func (h *Handler) HandleProfile(w http.ResponseWriter, r *http.Request) {
 			return err
 		}
 		brews = make([]*models.Brew, 0, len(output.Records))
-		for _, record := range output.Records {
+		for _, record := range h.visibleRecords(output.Records) {
 			brew, err := atproto.RecordToBrew(record.Value, record.URI)
 			if err != nil {
 				continue
@@ -1429,7 +1739,7 @@ func (h *Handler) HandleProfile(w http.ResponseWriter, r *http.Request) {
 	isOwnProfile := isAuthenticated && didStr == did
 
 	// Render profile page
-	if err := bff.RenderProfile(w, profile, brews, beans, roasters, grinders, brewers, isAuthenticated, didStr, userProfile, isOwnProfile); err != nil {
+	if err := bff.RenderProfile(r.Context(), w, profile, brews, beans, roasters, grinders, brewers, isAuthenticated, didStr, userProfile, isOwnProfile, relationship, h.userSettings(r).DevMode); err != nil {
 		http.Error(w, "Failed to render page", http.StatusInternalServerError)
 		log.Error().Err(err).Msg("Failed to render profile page")
 	}
@@ -1461,6 +1771,17 @@ func (h *Handler) HandleProfilePartial(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if h.isBannedDID(did) {
+		h.HandleNotFound(w, r)
+		return
+	}
+
+	// The profile page leaves out the content when either user blocked the other
+	if h.profileRelationship(r, did).Hidden() {
+		http.Error(w, "Profile unavailable", http.StatusForbidden)
+		return
+	}
+
 	// Fetch all user data in parallel
 	g, gCtx := errgroup.WithContext(ctx)
 
@@ -1486,7 +1807,7 @@ func (h *Handler) HandleProfilePartial(w http.ResponseWriter, r *http.Request) {
 		beanMap = make(map[string]*models.Bean)
 		beanRoasterRefMap = make(map[string]string)
 		beans = make([]*models.Bean, 0, len(output.Records))
-		for _, record := range output.Records {
+		for _, record := range h.visibleRecords(output.Records) {
 			bean, err := atproto.RecordToBean(record.Value, record.URI)
 			if err != nil {
 				continue
@@ -1508,7 +1829,7 @@ func (h *Handler) HandleProfilePartial(w http.ResponseWriter, r *http.Request) {
 		}
 		roasterMap = make(map[string]*models.Roaster)
 		roasters = make([]*models.Roaster, 0, len(output.Records))
-		for _, record := range output.Records {
+		for _, record := range h.visibleRecords(output.Records) {
 			roaster, err := atproto.RecordToRoaster(record.Value, record.URI)
 			if err != nil {
 				continue
@@ -1527,7 +1848,7 @@ func (h *Handler) HandleProfilePartial(w http.ResponseWriter, r *http.Request) {
 		}
 		grinderMap = make(map[string]*models.Grinder)
 		grinders = make([]*models.Grinder, 0, len(output.Records))
-		for _, record := range output.Records {
+		for _, record := range h.visibleRecords(output.Records) {
 			grinder, err := atproto.RecordToGrinder(record.Value, record.URI)
 			if err != nil {
 				continue
@@ -1546,7 +1867,7 @@ func (h *Handler) HandleProfilePartial(w http.ResponseWriter, r *http.Request) {
 		}
 		brewerMap = make(map[string]*models.Brewer)
 		brewers = make([]*models.Brewer, 0, len(output.Records))
-		for _, record := range output.Records {
+		for _, record := range h.visibleRecords(output.Records) {
 			brewer, err := atproto.RecordToBrewer(record.Value, record.URI)
 			if err != nil {
 				continue
@@ -1564,7 +1885,7 @@ func (h *Handler) HandleProfilePartial(w http.ResponseWriter, r *http.Request) {
 			return err
 		}
 		brews = make([]*models.Brew, 0, len(output.Records))
-		for _, record := range output.Records {
+		for _, record := range h.visibleRecords(output.Records) {
 			brew, err := atproto.RecordToBrew(record.Value, record.URI)
 			if err != nil {
 				continue
@@ -1628,7 +1949,7 @@ func (h *Handler) HandleProfilePartial(w http.ResponseWriter, r *http.Request) {
 	isOwnProfile := isAuthenticated && didStr == did
 
 	// Render profile content partial
-	if err := bff.RenderProfilePartial(w, brews, beans, roasters, grinders, brewers, isOwnProfile); err != nil {
+	if err := bff.RenderProfilePartial(r.Context(), w, brews, beans, roasters, grinders, brewers, h.unitPreferences(r), h.userSettings(r).ViewMode, isOwnProfile); err != nil {
 		http.Error(w, "Failed to render content", http.StatusInternalServerError)
 		log.Error().Err(err).Msg("Failed to render profile partial")
 	}
@@ -1645,7 +1966,7 @@ func (h *Handler) HandleNotFound(w http.ResponseWriter, r *http.Request) {
 		userProfile = h.getUserProfile(r.Context(), didStr)
 	}
 
-	if err := bff.Render404(w, isAuthenticated, didStr, userProfile); err != nil {
+	if err := bff.Render404(r.Context(), w, isAuthenticated, didStr, userProfile); err != nil {
 		http.Error(w, "Page not found", http.StatusNotFound)
 		log.Error().Err(err).Msg("Failed to render 404 page")
 	}
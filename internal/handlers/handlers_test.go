@@ -5,14 +5,21 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"arabica/internal/atproto"
+	"arabica/internal/bff"
+	"arabica/internal/database/boltstore"
 	"arabica/internal/models"
 
+	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -381,7 +388,7 @@ func TestParsePours(t *testing.T) {
 			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 			req.ParseForm()
 
-			pours := parsePours(req)
+			pours := parsePours(req, models.UnitPreferences{})
 
 			assert.Len(t, pours, tt.wantPours)
 		})
@@ -420,6 +427,21 @@ func TestValidateBrewRequest(t *testing.T) {
 			},
 			wantErrs: 1,
 		},
+		{
+			name: "tds and beverage weight",
+			formData: url.Values{
+				"tds":             []string{"1.38"},
+				"beverage_weight": []string{"220"},
+			},
+			wantErrs: 0,
+		},
+		{
+			name: "tds too high",
+			formData: url.Values{
+				"tds": []string{"45"},
+			},
+			wantErrs: 1,
+		},
 		{
 			name: "multiple errors",
 			formData: url.Values{
@@ -437,9 +459,822 @@ func TestValidateBrewRequest(t *testing.T) {
 			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 			req.ParseForm()
 
-			_, _, _, _, _, _, errs := validateBrewRequest(req)
+			_, errs := validateBrewRequest(req, models.UnitPreferences{})
 
 			assert.Equal(t, tt.wantErrs, len(errs))
 		})
 	}
 }
+
+// testCalibratedGrinders returns two grinders with scales and micron calibration
+func testCalibratedGrinders() []*models.Grinder {
+	return []*models.Grinder{
+		{
+			RKey:  "comandante",
+			Name:  "Comandante",
+			Scale: &models.GrindScale{Min: 0, Max: 50, Step: 1, Unit: models.GrindUnitClicks},
+			Calibration: []models.CalibrationPoint{
+				{Setting: 10, Microns: 300},
+				{Setting: 30, Microns: 900},
+			},
+		},
+		{
+			RKey:  "jx",
+			Name:  "1Zpresso JX",
+			Scale: &models.GrindScale{Min: 0, Max: 40, Step: 0.5, Unit: models.GrindUnitNumbers},
+			Calibration: []models.CalibrationPoint{
+				{Setting: 5, Microns: 300},
+				{Setting: 25, Microns: 1100},
+			},
+		},
+		{
+			RKey: "blade",
+			Name: "Blade Grinder",
+		},
+	}
+}
+
+// TestValidateGrindSize tests grind size validation against grinder scales
+func TestValidateGrindSize(t *testing.T) {
+	grinders := testCalibratedGrinders()
+
+	tests := []struct {
+		name        string
+		grinderRKey string
+		grindSize   string
+		wantError   bool
+	}{
+		{"no grinder selected", "", "Medium", false},
+		{"empty grind size", "comandante", "", false},
+		{"within scale", "comandante", "18", false},
+		{"outside scale", "comandante", "60", true},
+		{"off step", "jx", "12.3", true},
+		{"description on scaled grinder", "jx", "Fine", true},
+		{"grinder without scale", "blade", "Fine", false},
+		{"unknown grinder", "missing", "Fine", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validateGrindSize(grinders, tt.grinderRKey, tt.grindSize)
+			assert.Equal(t, tt.wantError, result != "", "validateGrindSize() = %q", result)
+		})
+	}
+}
+
+// TestBuildGrindHints tests equivalent setting hints for the brew form
+func TestBuildGrindHints(t *testing.T) {
+	grinders := testCalibratedGrinders()
+
+	t.Run("equivalents for calibrated grinders", func(t *testing.T) {
+		data := buildGrindHints(grinders, "comandante", "20")
+
+		assert.Equal(t, "Comandante", data.Grinder.Name)
+		assert.Empty(t, data.Error)
+		assert.Equal(t, 600, data.Microns)
+		if assert.Len(t, data.Equivalents, 1) {
+			assert.Equal(t, "jx", data.Equivalents[0].Grinder.RKey)
+			assert.Equal(t, "12.5", data.Equivalents[0].Setting)
+		}
+	})
+
+	t.Run("invalid setting reports error", func(t *testing.T) {
+		data := buildGrindHints(grinders, "comandante", "99")
+
+		assert.NotEmpty(t, data.Error)
+		assert.Empty(t, data.Equivalents)
+	})
+
+	t.Run("grinder without scale", func(t *testing.T) {
+		data := buildGrindHints(grinders, "blade", "Fine")
+
+		assert.Equal(t, "Blade Grinder", data.Grinder.Name)
+		assert.Empty(t, data.Error)
+		assert.Empty(t, data.Equivalents)
+	})
+
+	t.Run("unknown grinder", func(t *testing.T) {
+		data := buildGrindHints(grinders, "missing", "18")
+
+		assert.Nil(t, data.Grinder)
+	})
+}
+
+func TestParseSearchType(t *testing.T) {
+	tests := []struct {
+		name    string
+		docType string
+		want    []string
+		wantErr bool
+	}{
+		{"empty searches everything", "", nil, false},
+		{"brews", "brew", []string{"brew"}, false},
+		{"gear", "grinder", []string{"grinder"}, false},
+		{"unknown", "pour", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errMsg := parseSearchType(tt.docType)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantErr, errMsg != "")
+		})
+	}
+}
+
+// TestHandleSearchPartial_Unauthenticated tests unauthenticated access to personal search
+func TestHandleSearchPartial_Unauthenticated(t *testing.T) {
+	tc := NewTestContext()
+
+	req := NewUnauthenticatedRequest("GET", "/api/search/mine?q=ethiopia")
+	rec := httptest.NewRecorder()
+
+	tc.Handler.HandleSearchPartial(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Authentication required")
+}
+
+func TestParseCommunitySearch(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantQuery  string
+		wantAuthor string
+		wantLimit  int
+		wantErr    bool
+	}{
+		{"defaults", "q=kenya", "kenya", "", defaultCommunitySearchLimit, false},
+		{"author and limit", "q=kenya&author=alice.example.com&limit=5", "kenya", "alice.example.com", 5, false},
+		{"limit too high", "q=kenya&limit=1000", "", "", 0, true},
+		{"limit not a number", "q=kenya&limit=many", "", "", 0, true},
+		{"invalid type", "q=kenya&type=pour", "", "", 0, true},
+		{"query too long", "q=" + strings.Repeat("a", maxSearchQueryLength+1), "", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/search?"+tt.query, nil)
+			query, opts, errMsg := parseCommunitySearch(req)
+			assert.Equal(t, tt.wantErr, errMsg != "")
+			if tt.wantErr {
+				return
+			}
+			assert.Equal(t, tt.wantQuery, query)
+			assert.Equal(t, tt.wantAuthor, opts.Author)
+			assert.Equal(t, tt.wantLimit, opts.Limit)
+		})
+	}
+}
+
+// TestHandleCommunitySearchAPI tests the public search API without a feed service
+func TestHandleCommunitySearchAPI(t *testing.T) {
+	tc := NewTestContext()
+
+	req := httptest.NewRequest("GET", "/api/search?q=kenya", nil)
+	rec := httptest.NewRecorder()
+	tc.Handler.HandleCommunitySearchAPI(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var response struct {
+		Query   string                  `json:"query"`
+		Results []communitySearchResult `json:"results"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "kenya", response.Query)
+	assert.Empty(t, response.Results)
+
+	req = httptest.NewRequest("GET", "/api/search", nil)
+	rec = httptest.NewRecorder()
+	tc.Handler.HandleCommunitySearchAPI(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestParseBrewListQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"filters", "bean=3jzfcijpj2z2a&min_rating=7&sort=rating", false},
+		{"invalid bean rkey", "bean=../etc", true},
+		{"invalid grinder rkey", "grinder=a%20b", true},
+		{"invalid sort", "sort=temperature", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			_, errMsg := parseBrewListQuery(values)
+			assert.Equal(t, tt.wantErr, errMsg != "", errMsg)
+		})
+	}
+}
+
+// TestValidateBrewRequestImperial tests that imperial input is stored in canonical units
+func TestValidateBrewRequestImperial(t *testing.T) {
+	formData := url.Values{
+		"temperature":     []string{"200"},
+		"water_amount":    []string{"8.45"},
+		"coffee_amount":   []string{"0.53"},
+		"beverage_weight": []string{"7"},
+		"pour_water_0":    []string{"1.69"},
+		"pour_time_0":     []string{"30"},
+	}
+	req := httptest.NewRequest("POST", "/", strings.NewReader(formData.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.ParseForm()
+
+	in, errs := validateBrewRequest(req, models.ImperialUnits())
+
+	assert.Empty(t, errs)
+	assert.Equal(t, 93.3, in.temperature)
+	assert.Equal(t, 250, in.waterAmount)
+	assert.Equal(t, 15, in.coffeeAmount)
+	assert.Equal(t, 198, in.beverageWeight)
+	assert.Equal(t, []models.CreatePourData{{WaterAmount: 50, TimeSeconds: 30}}, in.pours)
+
+	// 250°F is over boiling, so the error is reported in °F
+	formData = url.Values{"temperature": []string{"250"}}
+	req = httptest.NewRequest("POST", "/", strings.NewReader(formData.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.ParseForm()
+
+	_, errs = validateBrewRequest(req, models.ImperialUnits())
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Message, "212.0°F")
+	}
+}
+
+func TestParseUnitPreferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    models.UnitPreferences
+		wantErr bool
+	}{
+		{"metric", "system=metric", models.MetricUnits(), false},
+		{"imperial ignores fields", "system=imperial&temperature=C", models.ImperialUnits(), false},
+		{"custom", "system=custom&temperature=F&weight=g&volume=ml", models.UnitPreferences{Temperature: "F", Weight: "g", Volume: "ml"}, false},
+		{"custom fills defaults", "system=custom&volume=floz", models.UnitPreferences{Temperature: "C", Weight: "g", Volume: "floz"}, false},
+		{"bad system", "system=nautical", models.UnitPreferences{}, true},
+		{"bad unit", "system=custom&temperature=K", models.UnitPreferences{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			got, errMsg := parseUnitPreferences(values)
+			assert.Equal(t, tt.wantErr, errMsg != "")
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseLabelPreferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    models.LabelPreferences
+		wantErr bool
+	}{
+		{"no choices", "", models.LabelPreferences{}, false},
+		{"choices", "label_porn=warn&label_nudity=hide", models.LabelPreferences{"porn": "warn", "nudity": "hide"}, false},
+		{"unknown labels ignored", "label_!hide=show", models.LabelPreferences{}, false},
+		{"bad visibility", "label_sexual=blur", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			got, errMsg := parseLabelPreferences(values)
+			assert.Equal(t, tt.wantErr, errMsg != "")
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    *models.Settings
+		wantErr bool
+	}{
+		{"defaults", "", &models.Settings{ViewMode: models.ViewModePosts}, false},
+		{"all set", "private_mode=1&dev_mode=1&view_mode=posts", &models.Settings{PrivateMode: true, DevMode: true, ViewMode: models.ViewModePosts}, false},
+		{"bad view mode", "view_mode=grid", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			got, errMsg := parseSettings(values)
+			assert.Equal(t, tt.wantErr, errMsg != "")
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHandleSettingsUpdate_Unauthenticated(t *testing.T) {
+	tc := NewTestContext()
+
+	req := NewUnauthenticatedRequest("POST", "/settings")
+	rec := httptest.NewRecorder()
+
+	tc.Handler.HandleSettingsUpdate(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestHandleAdmin_Unauthenticated(t *testing.T) {
+	tc := NewTestContext()
+
+	req := NewUnauthenticatedRequest("GET", "/admin")
+	rec := httptest.NewRecorder()
+	tc.Handler.HandleAdmin(rec, req)
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "/login", rec.Header().Get("Location"))
+
+	req = NewUnauthenticatedRequest("POST", "/admin/ban")
+	rec = httptest.NewRecorder()
+	tc.Handler.HandleAdminBan(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestModerationWithoutStore(t *testing.T) {
+	tc := NewTestContext()
+
+	assert.False(t, tc.Handler.isBannedDID("did:plc:test123456789"))
+	assert.False(t, tc.Handler.isHiddenRecord("at://did:plc:test123456789/social.arabica.alpha.brew/3kabc"))
+}
+
+func TestHandleGraph_Unauthenticated(t *testing.T) {
+	tc := NewTestContext()
+
+	req := NewUnauthenticatedRequest("GET", "/settings/graph")
+	rec := httptest.NewRecorder()
+	tc.Handler.HandleGraph(rec, req)
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "/login", rec.Header().Get("Location"))
+
+	for _, handle := range []http.HandlerFunc{tc.Handler.HandleMute, tc.Handler.HandleUnmute, tc.Handler.HandleBlock, tc.Handler.HandleUnblock, tc.Handler.HandleImportBlocks} {
+		req = NewUnauthenticatedRequest("POST", "/settings/graph/mute")
+		rec = httptest.NewRecorder()
+		handle(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+}
+
+func TestVisitorHasNoRelationship(t *testing.T) {
+	tc := NewTestContext()
+
+	req := NewUnauthenticatedRequest("GET", "/profile/did:plc:test123456789")
+	assert.Nil(t, tc.Handler.feedViewer(req))
+	assert.Equal(t, bff.ProfileRelationship{}, tc.Handler.profileRelationship(req, "did:plc:test123456789"))
+}
+
+func TestGraphRedirect(t *testing.T) {
+	tests := []struct {
+		name string
+		back string
+		want string
+	}{
+		{"graph page", "", "/settings/graph?done=muted"},
+		{"profile", "/profile/alice.bsky.social", "/profile/alice.bsky.social"},
+		{"offsite", "https://example.com/profile/x", "/settings/graph?done=muted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/settings/graph/mute", strings.NewReader(url.Values{"return": {tt.back}}.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			assert.NoError(t, req.ParseForm())
+			rec := httptest.NewRecorder()
+
+			graphRedirect(rec, req, "muted")
+
+			assert.Equal(t, http.StatusSeeOther, rec.Code)
+			assert.Equal(t, tt.want, rec.Header().Get("Location"))
+		})
+	}
+}
+
+func TestHandleSessions_Unauthenticated(t *testing.T) {
+	tc := NewTestContext()
+
+	req := NewUnauthenticatedRequest("GET", "/settings/sessions")
+	rec := httptest.NewRecorder()
+	tc.Handler.HandleSessions(rec, req)
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "/login", rec.Header().Get("Location"))
+
+	for _, handle := range []http.HandlerFunc{tc.Handler.HandleRevokeSession, tc.Handler.HandleRevokeOtherSessions} {
+		req = NewUnauthenticatedRequest("POST", "/settings/sessions/revoke")
+		rec = httptest.NewRecorder()
+		handle(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+}
+
+func TestSessionRef(t *testing.T) {
+	ref := sessionRef("secret-session-id")
+
+	assert.Len(t, ref, 16)
+	assert.NotContains(t, ref, "secret")
+	assert.Equal(t, ref, sessionRef("secret-session-id"))
+	assert.NotEqual(t, ref, sessionRef("other-session-id"))
+}
+
+func TestHandleSwitchAccount_Unauthenticated(t *testing.T) {
+	tc := NewTestContext()
+
+	req := NewUnauthenticatedRequest("POST", "/auth/switch")
+	rec := httptest.NewRecorder()
+	tc.Handler.HandleSwitchAccount(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+// newTestDB opens a BoltDB store in a temporary directory
+func newTestDB(t *testing.T) *boltstore.Store {
+	t.Helper()
+	store, err := boltstore.Open(boltstore.Options{Path: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestModeration(t *testing.T) {
+	tc := NewTestContext()
+	tc.Handler.moderation = newTestDB(t).ModerationStore()
+
+	hidden := "at://did:plc:alice/social.arabica.alpha.brew/3khidden"
+	assert.NoError(t, tc.Handler.moderation.HideRecord(hidden, "spam", "did:plc:admin"))
+	assert.NoError(t, tc.Handler.moderation.BanDID("did:plc:banned", "spam", "did:plc:admin"))
+
+	records := []atproto.PublicRecordEntry{
+		{URI: "at://did:plc:alice/social.arabica.alpha.brew/3kshown"},
+		{URI: hidden},
+	}
+	visible := tc.Handler.visibleRecords(records)
+	assert.Len(t, visible, 1)
+	assert.Equal(t, records[0].URI, visible[0].URI)
+
+	// Banned profiles get the regular not found page
+	for _, handle := range []http.HandlerFunc{tc.Handler.HandleProfile, tc.Handler.HandleProfilePartial} {
+		req := NewUnauthenticatedRequest("GET", "/profile/did:plc:banned")
+		req.SetPathValue("actor", "did:plc:banned")
+		rec := httptest.NewRecorder()
+		handle(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "User not found")
+	}
+}
+
+// newTestSessions returns a session store holding the given sessions of
+// did:plc:alice and an OAuth manager backed by it
+func newTestSessions(t *testing.T, sessionIDs ...string) (*boltstore.SessionStore, *atproto.OAuthManager) {
+	t.Helper()
+	sessions := newTestDB(t).SessionStore()
+	for _, id := range sessionIDs {
+		saveTestSession(t, sessions, "did:plc:alice", id)
+	}
+	manager, err := atproto.NewOAuthManager("", "http://127.0.0.1:18910/oauth/callback", sessions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sessions, manager
+}
+
+// saveTestSession stores a session with its login activity
+func saveTestSession(t *testing.T, sessions *boltstore.SessionStore, did syntax.DID, sessionID string) {
+	t.Helper()
+	data := oauth.ClientSessionData{AccountDID: did, SessionID: sessionID, HostURL: "https://pds.example.com"}
+	assert.NoError(t, sessions.SaveSession(context.Background(), data))
+	assert.NoError(t, sessions.RecordLogin(context.Background(), did, sessionID, "test-agent", "192.0.2.1"))
+}
+
+// serveSignedIn runs handle behind the auth middleware for a request signed
+// in to did:plc:alice with sessionID
+func serveSignedIn(manager *atproto.OAuthManager, handle http.HandlerFunc, sessionID string, form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/settings/sessions/revoke", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "account_did", Value: "did:plc:alice"})
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	manager.AuthMiddleware(handle).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleRevokeSession(t *testing.T) {
+	sessions, manager := newTestSessions(t, "current", "laptop", "phone")
+	tc := NewTestContext()
+	tc.Handler.sessions = sessions
+	remaining := func() []string {
+		list, err := sessions.ListSessionsForDID(context.Background(), "did:plc:alice")
+		assert.NoError(t, err)
+		var ids []string
+		for _, sess := range list {
+			ids = append(ids, sess.SessionID)
+		}
+		return ids
+	}
+
+	rec := serveSignedIn(manager, tc.Handler.HandleRevokeSession, "current", url.Values{"session": {sessionRef("laptop")}})
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/settings/sessions?done=revoked", rec.Header().Get("Location"))
+	assert.ElementsMatch(t, []string{"current", "phone"}, remaining())
+
+	// The raw session ID isn't accepted in place of its ref
+	rec = serveSignedIn(manager, tc.Handler.HandleRevokeSession, "current", url.Values{"session": {"phone"}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serveSignedIn(manager, tc.Handler.HandleRevokeSession, "current", url.Values{"session": {sessionRef("current")}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.ElementsMatch(t, []string{"current", "phone"}, remaining())
+
+	// A revoked session can no longer be used
+	rec = serveSignedIn(manager, tc.Handler.HandleRevokeSession, "laptop", url.Values{"session": {sessionRef("phone")}})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestHandleRevokeOtherSessions(t *testing.T) {
+	sessions, manager := newTestSessions(t, "current", "laptop", "phone")
+	tc := NewTestContext()
+	tc.Handler.sessions = sessions
+
+	rec := serveSignedIn(manager, tc.Handler.HandleRevokeOtherSessions, "current", nil)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/settings/sessions?done=revoked-others", rec.Header().Get("Location"))
+
+	list, err := sessions.ListSessionsForDID(context.Background(), "did:plc:alice")
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, "current", list[0].SessionID)
+	}
+}
+
+func TestHandleJWKS(t *testing.T) {
+	tc := NewTestContext()
+
+	// Public clients have no keys to publish
+	req := NewUnauthenticatedRequest("GET", "/oauth/jwks.json")
+	rec := httptest.NewRecorder()
+	tc.Handler.HandleJWKS(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	newKey := func() *atproto.ClientKey {
+		text, err := atproto.GenerateClientKey()
+		assert.NoError(t, err)
+		key, err := atproto.ParseClientKey([]byte(text))
+		assert.NoError(t, err)
+		return key
+	}
+	current, old := newKey(), newKey()
+	manager, err := atproto.NewOAuthManager("https://arabica.example.com/client-metadata.json", "https://arabica.example.com/oauth/callback",
+		newTestDB(t).SessionStore())
+	assert.NoError(t, err)
+	assert.NoError(t, manager.SetClientKeys(current, old))
+	tc.Handler.oauth = manager
+
+	rec = httptest.NewRecorder()
+	tc.Handler.HandleJWKS(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Keys from before a rotation stay published until they are removed
+	var jwks struct {
+		Keys []struct {
+			KeyID string `json:"kid"`
+			D     string `json:"d"`
+		} `json:"keys"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &jwks))
+	if assert.Len(t, jwks.Keys, 2) {
+		assert.Equal(t, current.ID, jwks.Keys[0].KeyID)
+		assert.Equal(t, old.ID, jwks.Keys[1].KeyID)
+		assert.Empty(t, jwks.Keys[0].D, "private key published")
+	}
+}
+
+// accountCookies returns the cookies of a browser whose active account is
+// active, with others signed in besides it
+func accountCookies(active atproto.Account, others []atproto.Account) []*http.Cookie {
+	cookies := []*http.Cookie{
+		{Name: "account_did", Value: active.DID.String()},
+		{Name: "session_id", Value: active.SessionID},
+	}
+	if active.Handle != "" {
+		cookies = append(cookies, &http.Cookie{Name: "account_handle", Value: active.Handle})
+	}
+	if len(others) > 0 {
+		cookies = append(cookies, &http.Cookie{Name: atproto.AccountsCookie, Value: atproto.EncodeAccounts(others)})
+	}
+	return cookies
+}
+
+// signedInAs returns the active account and the other accounts a response
+// left the browser with
+func signedInAs(rec *httptest.ResponseRecorder) (atproto.Account, []atproto.Account) {
+	var active atproto.Account
+	var others []atproto.Account
+	for _, cookie := range rec.Result().Cookies() {
+		switch cookie.Name {
+		case "account_did":
+			active.DID = syntax.DID(cookie.Value)
+		case "session_id":
+			active.SessionID = cookie.Value
+		case "account_handle":
+			active.Handle = cookie.Value
+		case atproto.AccountsCookie:
+			others = atproto.DecodeAccounts(cookie.Value)
+		}
+	}
+	return active, others
+}
+
+// sessionExists reports whether a session is still in the store
+func sessionExists(sessions *boltstore.SessionStore, did syntax.DID, sessionID string) bool {
+	_, err := sessions.GetSession(context.Background(), did, sessionID)
+	return err == nil
+}
+
+func TestSignIn(t *testing.T) {
+	alice := atproto.Account{DID: "did:plc:alice", SessionID: "alice", Handle: "alice.example.com"}
+	eve := atproto.Account{DID: "did:plc:eve", SessionID: "eve", Handle: "eve.example.com"}
+
+	t.Run("evicts the least recently used account", func(t *testing.T) {
+		sessions, manager := newTestSessions(t, alice.SessionID)
+		tc := NewTestContext()
+		tc.Handler.oauth = manager
+		var others []atproto.Account
+		for i := range atproto.MaxAccounts - 1 {
+			account := atproto.Account{DID: syntax.DID(fmt.Sprintf("did:plc:user%d", i)), SessionID: fmt.Sprintf("user%d", i)}
+			saveTestSession(t, sessions, account.DID, account.SessionID)
+			others = append(others, account)
+		}
+		saveTestSession(t, sessions, eve.DID, eve.SessionID)
+
+		req := httptest.NewRequest("GET", "/oauth/callback", nil)
+		for _, cookie := range accountCookies(alice, others) {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		tc.Handler.signIn(rec, req, eve)
+
+		active, accounts := signedInAs(rec)
+		assert.Equal(t, eve, active)
+		assert.Equal(t, append([]atproto.Account{alice}, others[:len(others)-1]...), accounts)
+		last := others[len(others)-1]
+		assert.False(t, sessionExists(sessions, last.DID, last.SessionID), "evicted session is still stored")
+		assert.True(t, sessionExists(sessions, alice.DID, alice.SessionID))
+	})
+
+	t.Run("logging in again replaces the session", func(t *testing.T) {
+		sessions, manager := newTestSessions(t, alice.SessionID, "alice-new")
+		tc := NewTestContext()
+		tc.Handler.oauth = manager
+		relogin := atproto.Account{DID: alice.DID, SessionID: "alice-new", Handle: alice.Handle}
+
+		req := httptest.NewRequest("GET", "/oauth/callback", nil)
+		for _, cookie := range accountCookies(alice, nil) {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		tc.Handler.signIn(rec, req, relogin)
+
+		active, accounts := signedInAs(rec)
+		assert.Equal(t, relogin, active)
+		assert.Empty(t, accounts)
+		assert.False(t, sessionExists(sessions, alice.DID, alice.SessionID), "replaced session is still stored")
+	})
+
+	t.Run("logging in again to another signed-in account", func(t *testing.T) {
+		sessions, manager := newTestSessions(t, alice.SessionID, "alice-new")
+		tc := NewTestContext()
+		tc.Handler.oauth = manager
+		saveTestSession(t, sessions, eve.DID, eve.SessionID)
+		relogin := atproto.Account{DID: alice.DID, SessionID: "alice-new"}
+
+		req := httptest.NewRequest("GET", "/oauth/callback", nil)
+		for _, cookie := range accountCookies(eve, []atproto.Account{alice}) {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		tc.Handler.signIn(rec, req, relogin)
+
+		active, accounts := signedInAs(rec)
+		assert.Equal(t, relogin, active)
+		assert.Equal(t, []atproto.Account{eve}, accounts)
+		assert.False(t, sessionExists(sessions, alice.DID, alice.SessionID), "replaced session is still stored")
+	})
+
+	t.Run("tampered accounts cookie", func(t *testing.T) {
+		_, manager := newTestSessions(t, alice.SessionID)
+		tc := NewTestContext()
+		tc.Handler.oauth = manager
+
+		req := httptest.NewRequest("GET", "/oauth/callback", nil)
+		req.AddCookie(&http.Cookie{Name: atproto.AccountsCookie, Value: "eyJub3QiOiJhY2NvdW50cyJ9!"})
+		rec := httptest.NewRecorder()
+		tc.Handler.signIn(rec, req, alice)
+
+		active, accounts := signedInAs(rec)
+		assert.Equal(t, alice, active)
+		assert.Empty(t, accounts)
+	})
+}
+
+func TestHandleSwitchAccount(t *testing.T) {
+	alice := atproto.Account{DID: "did:plc:alice", SessionID: "alice", Handle: "alice.example.com"}
+	carol := atproto.Account{DID: "did:plc:carol", SessionID: "carol", Handle: "carol.example.com"}
+	revoked := atproto.Account{DID: "did:plc:revoked", SessionID: "revoked"}
+	sessions, manager := newTestSessions(t, alice.SessionID)
+	saveTestSession(t, sessions, carol.DID, carol.SessionID)
+	tc := NewTestContext()
+	tc.Handler.oauth = manager
+	cookies := []*http.Cookie{
+		{Name: "account_handle", Value: alice.Handle},
+		{Name: atproto.AccountsCookie, Value: atproto.EncodeAccounts([]atproto.Account{revoked, carol})},
+	}
+
+	rec := serveSignedIn(manager, tc.Handler.HandleSwitchAccount, alice.SessionID, url.Values{"did": {carol.DID.String()}}, cookies...)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	active, accounts := signedInAs(rec)
+	assert.Equal(t, carol, active)
+	assert.Equal(t, []atproto.Account{alice, revoked}, accounts)
+
+	// An account whose session is gone is dropped from the switcher
+	rec = serveSignedIn(manager, tc.Handler.HandleSwitchAccount, alice.SessionID, url.Values{"did": {revoked.DID.String()}}, cookies...)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	_, accounts = signedInAs(rec)
+	assert.Equal(t, []atproto.Account{carol}, accounts)
+
+	// A tampered cookie lists no accounts to switch to
+	tampered := &http.Cookie{Name: atproto.AccountsCookie, Value: "tampered"}
+	rec = serveSignedIn(manager, tc.Handler.HandleSwitchAccount, alice.SessionID, url.Values{"did": {carol.DID.String()}}, tampered)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandleLogout(t *testing.T) {
+	alice := atproto.Account{DID: "did:plc:alice", SessionID: "alice"}
+	revoked := atproto.Account{DID: "did:plc:revoked", SessionID: "revoked"}
+	carol := atproto.Account{DID: "did:plc:carol", SessionID: "carol", Handle: "carol.example.com"}
+	dave := atproto.Account{DID: "did:plc:dave", SessionID: "dave"}
+
+	t.Run("switches to the next live account", func(t *testing.T) {
+		sessions, manager := newTestSessions(t, alice.SessionID)
+		saveTestSession(t, sessions, carol.DID, carol.SessionID)
+		saveTestSession(t, sessions, dave.DID, dave.SessionID)
+		tc := NewTestContext()
+		tc.Handler.oauth = manager
+
+		req := httptest.NewRequest("POST", "/logout", nil)
+		for _, cookie := range accountCookies(alice, []atproto.Account{revoked, carol, dave}) {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		tc.Handler.HandleLogout(rec, req)
+
+		assert.Equal(t, http.StatusFound, rec.Code)
+		active, accounts := signedInAs(rec)
+		assert.Equal(t, carol, active)
+		assert.Equal(t, []atproto.Account{dave}, accounts)
+		assert.False(t, sessionExists(sessions, alice.DID, alice.SessionID), "logged out session is still stored")
+	})
+
+	t.Run("all accounts", func(t *testing.T) {
+		sessions, manager := newTestSessions(t, alice.SessionID)
+		saveTestSession(t, sessions, carol.DID, carol.SessionID)
+		tc := NewTestContext()
+		tc.Handler.oauth = manager
+
+		req := httptest.NewRequest("POST", "/logout", strings.NewReader("all=1"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for _, cookie := range accountCookies(alice, []atproto.Account{carol}) {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		tc.Handler.HandleLogout(rec, req)
+
+		active, accounts := signedInAs(rec)
+		assert.Empty(t, active.DID)
+		assert.Empty(t, accounts)
+		assert.False(t, sessionExists(sessions, carol.DID, carol.SessionID), "logged out session is still stored")
+	})
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"arabica/internal/atproto"
+	"arabica/internal/bff"
+	"arabica/internal/models"
+	"arabica/internal/search"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxSearchQueryLength bounds the work done per keystroke of live search
+const maxSearchQueryLength = 200
+
+// Result limits for the public community search API
+const (
+	defaultCommunitySearchLimit = 20
+	maxCommunitySearchLimit     = 100
+)
+
+// parseSearchType validates the optional type filter.
+// Returns the types to search (nil for all) and an error message if invalid.
+func parseSearchType(docType string) ([]string, string) {
+	switch docType {
+	case "":
+		return nil, ""
+	case search.TypeBrew, search.TypeBean, search.TypeRoaster, search.TypeGrinder, search.TypeBrewer:
+		return []string{docType}, ""
+	default:
+		return nil, "Invalid search type"
+	}
+}
+
+// Search page
+func (h *Handler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	// Require authentication
+	_, authenticated := h.getAtprotoStore(r)
+	if !authenticated {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	docType := r.URL.Query().Get("type")
+	if _, errMsg := parseSearchType(docType); errMsg != "" {
+		docType = ""
+	}
+
+	didStr, _ := atproto.GetAuthenticatedDID(r.Context())
+	userProfile := h.getUserProfile(r.Context(), didStr)
+
+	// Results load via HTMX so the page renders without waiting on the PDS
+	if err := bff.RenderSearch(r.Context(), w, query, docType, "", false, authenticated, didStr, userProfile); err != nil {
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to render search page")
+	}
+}
+
+// Search results partial for the user's own records (loaded via HTMX as the query changes)
+func (h *Handler) HandleSearchPartial(w http.ResponseWriter, r *http.Request) {
+	// Require authentication
+	store, authenticated := h.getAtprotoStore(r)
+	if !authenticated {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len(query) > maxSearchQueryLength {
+		http.Error(w, "Search query is too long", http.StatusBadRequest)
+		return
+	}
+	types, errMsg := parseSearchType(r.URL.Query().Get("type"))
+	if errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	data := &bff.SearchResultsData{Query: query}
+	if query != "" {
+		// Build the index from the session cache; lists are only fetched from
+		// the PDS when the cache is cold
+		g, ctx := errgroup.WithContext(r.Context())
+
+		var beans []*models.Bean
+		var roasters []*models.Roaster
+		var grinders []*models.Grinder
+		var brewers []*models.Brewer
+		var brews []*models.Brew
+
+		g.Go(func() error {
+			var err error
+			beans, err = store.ListBeans(ctx)
+			return err
+		})
+		g.Go(func() error {
+			var err error
+			roasters, err = store.ListRoasters(ctx)
+			return err
+		})
+		g.Go(func() error {
+			var err error
+			grinders, err = store.ListGrinders(ctx)
+			return err
+		})
+		g.Go(func() error {
+			var err error
+			brewers, err = store.ListBrewers(ctx)
+			return err
+		})
+		g.Go(func() error {
+			var err error
+			brews, err = store.ListBrews(ctx, 1) // User ID not used with atproto
+			return err
+		})
+
+		if err := g.Wait(); err != nil {
+			http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+			log.Error().Err(err).Msg("Failed to fetch data for search")
+			return
+		}
+
+		atproto.LinkBeansToRoasters(beans, roasters)
+
+		idx := search.BuildIndex(beans, roasters, grinders, brewers, brews)
+		data.Results = idx.Search(query, search.Options{Types: types})
+	}
+
+	if err := bff.RenderSearchResultsPartial(r.Context(), w, data); err != nil {
+		http.Error(w, "Failed to render content", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to render search results partial")
+	}
+}
+
+// parseCommunitySearch reads the query, type, author and limit parameters
+// shared by the community search page, partial and JSON API.
+// Returns an error message if any parameter is invalid.
+func parseCommunitySearch(r *http.Request) (string, search.Options, string) {
+	q := r.URL.Query()
+	query := strings.TrimSpace(q.Get("q"))
+	if len(query) > maxSearchQueryLength {
+		return "", search.Options{}, "Search query is too long"
+	}
+
+	types, errMsg := parseSearchType(q.Get("type"))
+	if errMsg != "" {
+		return "", search.Options{}, errMsg
+	}
+
+	opts := search.Options{
+		Types:  types,
+		Author: strings.TrimSpace(q.Get("author")),
+		Limit:  defaultCommunitySearchLimit,
+	}
+	if limitStr := q.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxCommunitySearchLimit {
+			return "", search.Options{}, "Limit must be between 1 and " + strconv.Itoa(maxCommunitySearchLimit)
+		}
+		opts.Limit = limit
+	}
+
+	return query, opts, ""
+}
+
+// searchCommunity runs a community search, returning no results for an empty
+// query or when the feed service is not configured. Records by accounts hidden
+// from the authenticated user by a mute or block are left out.
+func (h *Handler) searchCommunity(r *http.Request, query string, opts search.Options) ([]search.Result, error) {
+	if query == "" || h.feedService == nil {
+		return nil, nil
+	}
+	return h.feedService.Search(r.Context(), query, opts, h.feedViewer(r))
+}
+
+// Community search page, searching records recently published by registered users
+func (h *Handler) HandleCommunitySearch(w http.ResponseWriter, r *http.Request) {
+	query, opts, errMsg := parseCommunitySearch(r)
+	if errMsg != "" {
+		query, opts = "", search.Options{}
+	}
+	docType := ""
+	if len(opts.Types) == 1 {
+		docType = opts.Types[0]
+	}
+
+	didStr, err := atproto.GetAuthenticatedDID(r.Context())
+	isAuthenticated := err == nil && didStr != ""
+
+	var userProfile *bff.UserProfile
+	if isAuthenticated {
+		userProfile = h.getUserProfile(r.Context(), didStr)
+	}
+
+	if err := bff.RenderSearch(r.Context(), w, query, docType, opts.Author, true, isAuthenticated, didStr, userProfile); err != nil {
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to render community search page")
+	}
+}
+
+// Community search results partial (loaded via HTMX as the query changes)
+func (h *Handler) HandleCommunitySearchPartial(w http.ResponseWriter, r *http.Request) {
+	query, opts, errMsg := parseCommunitySearch(r)
+	if errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.searchCommunity(r, query, opts)
+	if err != nil {
+		http.Error(w, "Failed to search", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to search community records")
+		return
+	}
+
+	data := &bff.SearchResultsData{Query: query, Community: true, Results: results}
+	if err := bff.RenderSearchResultsPartial(r.Context(), w, data); err != nil {
+		http.Error(w, "Failed to render content", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to render community search results partial")
+	}
+}
+
+// communitySearchResult is a single result in the public search API response
+type communitySearchResult struct {
+	Type      string                   `json:"type"`
+	Title     string                   `json:"title"`
+	Subtitle  string                   `json:"subtitle,omitempty"`
+	URL       string                   `json:"url"`
+	Author    communitySearchAuthor    `json:"author"`
+	CreatedAt time.Time                `json:"created_at"`
+	Snippets  []communitySearchSnippet `json:"snippets,omitempty"`
+}
+
+type communitySearchAuthor struct {
+	DID    string `json:"did"`
+	Handle string `json:"handle,omitempty"`
+}
+
+type communitySearchSnippet struct {
+	Field string `json:"field"`
+	Text  string `json:"text"`
+}
+
+// toCommunitySearchResults converts search results into their JSON form
+func toCommunitySearchResults(results []search.Result) []communitySearchResult {
+	out := make([]communitySearchResult, len(results))
+	for i, res := range results {
+		out[i] = communitySearchResult{
+			Type:      res.Doc.Type,
+			Title:     res.Doc.Title,
+			Subtitle:  res.Doc.Subtitle,
+			URL:       res.Doc.URL,
+			Author:    communitySearchAuthor{DID: res.Doc.AuthorDID, Handle: res.Doc.AuthorHandle},
+			CreatedAt: res.Doc.CreatedAt,
+		}
+		for _, snippet := range res.Snippets {
+			out[i].Snippets = append(out[i].Snippets, communitySearchSnippet{
+				Field: snippet.Name,
+				Text:  search.PlainText(snippet.Segments),
+			})
+		}
+	}
+	return out
+}
+
+// Public API endpoint for searching records published by registered users
+func (h *Handler) HandleCommunitySearchAPI(w http.ResponseWriter, r *http.Request) {
+	query, opts, errMsg := parseCommunitySearch(r)
+	if errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+	if query == "" {
+		http.Error(w, "Query parameter 'q' is required", http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.searchCommunity(r, query, opts)
+	if err != nil {
+		http.Error(w, "Failed to search", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to search community records")
+		return
+	}
+
+	response := map[string]interface{}{
+		"query":   query,
+		"results": toCommunitySearchResults(results),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode search response")
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"arabica/internal/atproto"
+	"arabica/internal/bff"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/rs/zerolog/log"
+)
+
+// sessionsDone maps the done query parameter set after revoking sessions to
+// the confirmation shown on the sessions page
+var sessionsDone = map[string]string{
+	"revoked":        "Session revoked.",
+	"revoked-others": "Signed out of all other sessions.",
+}
+
+// sessionRef identifies a session on the sessions page without putting the
+// session ID, which works as a credential, into the page
+func sessionRef(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// requireSessions returns the authenticated user's DID and current session ID
+func (h *Handler) requireSessions(w http.ResponseWriter, r *http.Request) (syntax.DID, string, bool) {
+	didStr, err := atproto.GetAuthenticatedDID(r.Context())
+	if err != nil || didStr == "" {
+		if r.Method == http.MethodGet {
+			http.Redirect(w, r, "/login", http.StatusFound)
+		} else {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+		}
+		return "", "", false
+	}
+	if h.sessions == nil {
+		http.Error(w, "Sessions are unavailable", http.StatusServiceUnavailable)
+		return "", "", false
+	}
+	did, err := syntax.ParseDID(didStr)
+	if err != nil {
+		http.Error(w, "Invalid DID", http.StatusBadRequest)
+		return "", "", false
+	}
+	sessionID, _ := atproto.GetSessionIDFromContext(r.Context())
+	return did, sessionID, true
+}
+
+// Sessions page, listing where the user is signed in
+func (h *Handler) HandleSessions(w http.ResponseWriter, r *http.Request) {
+	did, current, ok := h.requireSessions(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	sessions, err := h.sessions.ListSessionsForDID(ctx, did)
+	if err != nil {
+		http.Error(w, "Failed to load sessions", http.StatusInternalServerError)
+		log.Error().Err(err).Str("did", did.String()).Msg("Failed to list sessions")
+		return
+	}
+
+	data := &bff.SessionsPageData{
+		Done:            sessionsDone[r.URL.Query().Get("done")],
+		IsAuthenticated: true,
+		UserDID:         did.String(),
+		UserProfile:     h.getUserProfile(ctx, did.String()),
+	}
+	for _, sess := range sessions {
+		data.Sessions = append(data.Sessions, bff.SessionRow{
+			Ref:        sessionRef(sess.SessionID),
+			Current:    sess.SessionID == current,
+			CreatedAt:  sess.CreatedAt,
+			LastUsedAt: sess.LastUsedAt,
+			UserAgent:  sess.UserAgent,
+			IP:         sess.IP,
+		})
+	}
+
+	if err := bff.RenderSessions(ctx, w, data); err != nil {
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to render sessions page")
+	}
+}
+
+// Revoke one of the user's other sessions
+func (h *Handler) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	did, current, ok := h.requireSessions(w, r)
+	if !ok {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+
+	sessions, err := h.sessions.ListSessionsForDID(ctx, did)
+	if err != nil {
+		http.Error(w, "Failed to load sessions", http.StatusInternalServerError)
+		log.Error().Err(err).Str("did", did.String()).Msg("Failed to list sessions")
+		return
+	}
+
+	ref := r.PostForm.Get("session")
+	for _, sess := range sessions {
+		if sessionRef(sess.SessionID) != ref {
+			continue
+		}
+		if sess.SessionID == current {
+			http.Error(w, "Log out to end the current session", http.StatusBadRequest)
+			return
+		}
+		if err := h.sessions.DeleteSession(ctx, did, sess.SessionID); err != nil {
+			http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+			log.Error().Err(err).Str("did", did.String()).Msg("Failed to revoke session")
+			return
+		}
+		if h.sessionCache != nil {
+			h.sessionCache.Invalidate(sess.SessionID)
+		}
+		log.Info().Str("did", did.String()).Msg("Revoked session")
+		http.Redirect(w, r, "/settings/sessions?done=revoked", http.StatusSeeOther)
+		return
+	}
+	http.Error(w, "Session not found", http.StatusBadRequest)
+}
+
+// Revoke all of the user's sessions except the current one
+func (h *Handler) HandleRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	did, current, ok := h.requireSessions(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	sessions, err := h.sessions.ListSessionsForDID(ctx, did)
+	if err != nil {
+		http.Error(w, "Failed to load sessions", http.StatusInternalServerError)
+		log.Error().Err(err).Str("did", did.String()).Msg("Failed to list sessions")
+		return
+	}
+
+	count, err := h.sessions.DeleteOtherSessionsForDID(ctx, did, current)
+	if err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		log.Error().Err(err).Str("did", did.String()).Msg("Failed to revoke other sessions")
+		return
+	}
+	if h.sessionCache != nil {
+		for _, sess := range sessions {
+			if sess.SessionID != current {
+				h.sessionCache.Invalidate(sess.SessionID)
+			}
+		}
+	}
+	log.Info().Str("did", did.String()).Int("count", count).Msg("Revoked other sessions")
+	http.Redirect(w, r, "/settings/sessions?done=revoked-others", http.StatusSeeOther)
+}
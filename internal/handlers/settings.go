@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"arabica/internal/atproto"
+	"arabica/internal/bff"
+	"arabica/internal/models"
+
+	"github.com/rs/zerolog/log"
+)
+
+// unitPreferences returns the authenticated user's unit preferences.
+// Visitors, and users whose preferences can't be read, get metric.
+func (h *Handler) unitPreferences(r *http.Request) models.UnitPreferences {
+	if h.preferences == nil {
+		return models.UnitPreferences{}
+	}
+	didStr, err := atproto.GetAuthenticatedDID(r.Context())
+	if err != nil {
+		return models.UnitPreferences{}
+	}
+	units, err := h.preferences.GetUnits(didStr)
+	if err != nil {
+		log.Warn().Err(err).Str("did", didStr).Msg("Failed to read unit preferences")
+		return models.UnitPreferences{}
+	}
+	return units
+}
+
+// labelPreferences returns how the authenticated user wants to see labeled
+// content. Visitors, and users whose preferences can't be read, get the defaults.
+func (h *Handler) labelPreferences(r *http.Request) models.LabelPreferences {
+	if h.preferences == nil {
+		return nil
+	}
+	didStr, err := atproto.GetAuthenticatedDID(r.Context())
+	if err != nil {
+		return nil
+	}
+	prefs, err := h.preferences.GetLabels(didStr)
+	if err != nil {
+		log.Warn().Err(err).Str("did", didStr).Msg("Failed to read label preferences")
+		return nil
+	}
+	return prefs
+}
+
+// userSettings returns the authenticated user's settings record.
+// Visitors, and users whose settings can't be read, get the defaults.
+func (h *Handler) userSettings(r *http.Request) *models.Settings {
+	store, authenticated := h.getAtprotoStore(r)
+	if !authenticated {
+		return models.DefaultSettings()
+	}
+	settings, err := store.GetSettings(r.Context())
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read settings")
+		return models.DefaultSettings()
+	}
+	return settings
+}
+
+// parseSettings reads the settings record fields from the settings form.
+// Returns the settings and an error message if invalid.
+func parseSettings(values url.Values) (*models.Settings, string) {
+	settings := &models.Settings{
+		PrivateMode: values.Get("private_mode") != "",
+		DevMode:     values.Get("dev_mode") != "",
+		ViewMode:    values.Get("view_mode"),
+	}
+	if settings.ViewMode == "" {
+		settings.ViewMode = models.ViewModePosts
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, "Invalid view mode"
+	}
+	return settings, ""
+}
+
+// parseUnitPreferences reads unit preferences from the settings form.
+// A unit system other than "custom" overrides the individual unit fields.
+// Returns the preferences and an error message if invalid.
+func parseUnitPreferences(values url.Values) (models.UnitPreferences, string) {
+	system := values.Get("system")
+	if units, ok := models.UnitsForSystem(system); ok {
+		return units, ""
+	}
+	if system != "" && system != models.UnitSystemCustom {
+		return models.UnitPreferences{}, "Invalid unit system"
+	}
+
+	units := models.UnitPreferences{
+		Temperature: values.Get("temperature"),
+		Weight:      values.Get("weight"),
+		Volume:      values.Get("volume"),
+	}
+	if err := units.Validate(); err != nil {
+		return models.UnitPreferences{}, "Invalid unit"
+	}
+	return units.Normalize(), ""
+}
+
+// parseLabelPreferences reads content filtering choices from the settings form.
+// Returns the preferences and an error message if invalid.
+func parseLabelPreferences(values url.Values) (models.LabelPreferences, string) {
+	prefs := models.LabelPreferences{}
+	for _, label := range models.AdultLabels {
+		if v := values.Get("label_" + label); v != "" {
+			prefs[label] = v
+		}
+	}
+	if err := prefs.Validate(); err != nil {
+		return nil, "Invalid content filtering choice"
+	}
+	return prefs, ""
+}
+
+// Settings page
+func (h *Handler) HandleSettings(w http.ResponseWriter, r *http.Request) {
+	// Require authentication
+	store, authenticated := h.getAtprotoStore(r)
+	if !authenticated {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	settings, err := store.GetSettings(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to load settings")
+		return
+	}
+
+	didStr, _ := atproto.GetAuthenticatedDID(r.Context())
+	userProfile := h.getUserProfile(r.Context(), didStr)
+	saved := r.URL.Query().Get("saved") != ""
+
+	var labels []bff.LabelSetting
+	if h.config.LabelsEnabled {
+		labels = bff.NewLabelSettings(h.labelPreferences(r))
+	}
+
+	var permissions []bff.Permission
+	if sessData, err := atproto.GetSessionDataFromContext(r.Context()); err == nil {
+		permissions = bff.NewPermissions(sessData.Scopes)
+	}
+
+	if err := bff.RenderSettings(r.Context(), w, h.unitPreferences(r), labels, permissions, settings, saved, authenticated, didStr, userProfile); err != nil {
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to render settings page")
+	}
+}
+
+// Save settings
+func (h *Handler) HandleSettingsUpdate(w http.ResponseWriter, r *http.Request) {
+	// Require authentication
+	store, authenticated := h.getAtprotoStore(r)
+	if !authenticated {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	units, errMsg := parseUnitPreferences(r.PostForm)
+	if errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	settings, errMsg := parseSettings(r.PostForm)
+	if errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	labels, errMsg := parseLabelPreferences(r.PostForm)
+	if errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	if h.preferences == nil {
+		http.Error(w, "Settings are unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	didStr, _ := atproto.GetAuthenticatedDID(r.Context())
+	if err := h.preferences.SetUnits(didStr, units); err != nil {
+		http.Error(w, "Failed to save settings", http.StatusInternalServerError)
+		log.Error().Err(err).Str("did", didStr).Msg("Failed to save unit preferences")
+		return
+	}
+
+	// The form only has content filtering choices when labelers are configured
+	if h.config.LabelsEnabled {
+		if err := h.preferences.SetLabels(didStr, labels); err != nil {
+			http.Error(w, "Failed to save settings", http.StatusInternalServerError)
+			log.Error().Err(err).Str("did", didStr).Msg("Failed to save label preferences")
+			return
+		}
+	}
+
+	previous, err := store.GetSettings(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to save settings", http.StatusInternalServerError)
+		log.Error().Err(err).Str("did", didStr).Msg("Failed to load settings")
+		return
+	}
+
+	if err := store.UpdateSettings(r.Context(), settings); err != nil {
+		http.Error(w, "Failed to save settings", http.StatusInternalServerError)
+		log.Error().Err(err).Str("did", didStr).Msg("Failed to save settings")
+		return
+	}
+
+	// The community feed filters private users when it is fetched, so drop the
+	// cached copy for the change to show up right away
+	if previous.PrivateMode != settings.PrivateMode && h.feedService != nil {
+		h.feedService.InvalidatePrivacy(didStr)
+		h.feedService.InvalidateCache()
+	}
+
+	http.Redirect(w, r, "/settings?saved=1", http.StatusSeeOther)
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"arabica/internal/atproto"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/rs/zerolog/log"
+)
+
+// sessionCookieMaxAge is how long the browser keeps session cookies
+const sessionCookieMaxAge = 86400 * 30 // 30 days
+
+// cookieSession returns the active account from the request's cookies,
+// without checking the session exists
+func cookieSession(r *http.Request) (atproto.Account, bool) {
+	didCookie, err1 := r.Cookie("account_did")
+	sessionCookie, err2 := r.Cookie("session_id")
+	if err1 != nil || err2 != nil {
+		return atproto.Account{}, false
+	}
+	did, err := syntax.ParseDID(didCookie.Value)
+	if err != nil || sessionCookie.Value == "" {
+		return atproto.Account{}, false
+	}
+	account := atproto.Account{DID: did, SessionID: sessionCookie.Value}
+	if handleCookie, err := r.Cookie("account_handle"); err == nil {
+		account.Handle = handleCookie.Value
+	}
+	return account, true
+}
+
+// liveAccounts returns the accounts in the request's accounts cookie whose
+// sessions are still in the store
+func (h *Handler) liveAccounts(r *http.Request) []atproto.Account {
+	cookie, err := r.Cookie(atproto.AccountsCookie)
+	if err != nil {
+		return nil
+	}
+	var accounts []atproto.Account
+	for _, account := range atproto.DecodeAccounts(cookie.Value) {
+		if _, err := h.oauth.GetSession(r.Context(), account.DID, account.SessionID); err == nil {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts
+}
+
+// setSessionCookies makes the account the browser's active one. Its handle is
+// kept for the account switcher once another account becomes active.
+func (h *Handler) setSessionCookies(w http.ResponseWriter, account atproto.Account) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "account_did",
+		Value:    account.DID.String(),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   h.config.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   sessionCookieMaxAge,
+	})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session_id",
+		Value:    account.SessionID,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   h.config.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   sessionCookieMaxAge,
+	})
+
+	handleCookie := &http.Cookie{
+		Name:     "account_handle",
+		Value:    account.Handle,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   h.config.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   sessionCookieMaxAge,
+	}
+	if account.Handle == "" {
+		handleCookie.MaxAge = -1
+	}
+	http.SetCookie(w, handleCookie)
+}
+
+// setAccountsCookie stores the accounts signed in besides the active one,
+// clearing the cookie when there are none
+func (h *Handler) setAccountsCookie(w http.ResponseWriter, accounts []atproto.Account) {
+	cookie := &http.Cookie{
+		Name:     atproto.AccountsCookie,
+		Value:    atproto.EncodeAccounts(accounts),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   h.config.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   sessionCookieMaxAge,
+	}
+	if len(accounts) == 0 {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+}
+
+// clearSessionCookies signs the browser out of every account
+func clearSessionCookies(w http.ResponseWriter) {
+	for _, name := range []string{"account_did", "session_id", "account_handle", atproto.AccountsCookie} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			HttpOnly: true,
+			MaxAge:   -1,
+		})
+	}
+}
+
+// endSession deletes a session and drops its cached records
+func (h *Handler) endSession(ctx context.Context, did syntax.DID, sessionID string) {
+	if err := h.oauth.DeleteSession(ctx, did, sessionID); err != nil {
+		log.Warn().Err(err).Str("user_did", did.String()).Msg("Failed to delete session")
+	}
+	if h.sessionCache != nil {
+		h.sessionCache.Invalidate(sessionID)
+	}
+}
+
+// signIn makes a new session the active account. The previously active
+// account stays signed in unless it is the same account, which is logging in
+// again, e.g. to grant new permissions. Past MaxAccounts, the account used
+// least recently is signed out.
+func (h *Handler) signIn(w http.ResponseWriter, r *http.Request, account atproto.Account) {
+	ctx := r.Context()
+	h.deleteReplacedSession(r, account.DID, account.SessionID)
+
+	var accounts []atproto.Account
+	if prev, ok := cookieSession(r); ok && prev.DID != account.DID {
+		if _, err := h.oauth.GetSession(ctx, prev.DID, prev.SessionID); err == nil {
+			accounts = append(accounts, prev)
+		}
+	}
+	for _, other := range h.liveAccounts(r) {
+		switch {
+		case other.SessionID == account.SessionID:
+		case other.DID == account.DID || len(accounts) == atproto.MaxAccounts-1:
+			h.endSession(ctx, other.DID, other.SessionID)
+		default:
+			accounts = append(accounts, other)
+		}
+	}
+
+	h.setSessionCookies(w, account)
+	h.setAccountsCookie(w, accounts)
+}
+
+// Switch the active account to another one signed in in this browser
+func (h *Handler) HandleSwitchAccount(w http.ResponseWriter, r *http.Request) {
+	active, ok := cookieSession(r)
+	if _, err := atproto.GetAuthenticatedDID(r.Context()); err != nil || !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+
+	// The previously active account goes first, so accounts stay ordered by
+	// when they were last used
+	target := r.PostForm.Get("did")
+	accounts := []atproto.Account{active}
+	var next *atproto.Account
+	for _, account := range atproto.GetAccountsFromContext(ctx) {
+		if account.DID.String() == target {
+			next = &account
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+	if next == nil {
+		http.Error(w, "Account is not signed in", http.StatusBadRequest)
+		return
+	}
+
+	// The switcher lists accounts without checking their sessions, so one may
+	// have been revoked or swept since
+	if _, err := h.oauth.GetSession(ctx, next.DID, next.SessionID); err != nil {
+		h.setAccountsCookie(w, accounts[1:])
+		http.Error(w, "Account is no longer signed in", http.StatusBadRequest)
+		return
+	}
+
+	h.setSessionCookies(w, *next)
+	h.setAccountsCookie(w, accounts)
+	log.Info().Str("user_did", next.DID.String()).Str("previous_did", active.DID.String()).Msg("Switched account")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,166 @@
+// Package health serves the liveness and readiness endpoints used by process
+// supervisors and orchestrators.
+//
+// /healthz only reports that the process is serving HTTP. /readyz runs the
+// registered checks and answers 503 when a critical one fails. Non-critical
+// checks, such as reachability of the PLC directory or AppView, are reported
+// in the body but never fail readiness, since an upstream outage would
+// otherwise take every instance out of rotation at once.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// checkTimeout bounds how long a single check may run per request
+const checkTimeout = 3 * time.Second
+
+// Check is a single named dependency check
+type Check struct {
+	Name string
+	// Critical checks fail readiness; others are informational
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+// Checker runs dependency checks for the readiness endpoint
+type Checker struct {
+	checks []Check
+}
+
+// NewChecker creates a checker running the given checks in order
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{checks: checks}
+}
+
+// Status values reported in responses
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+)
+
+// CheckResult is the outcome of one check in a readiness response
+type CheckResult struct {
+	Status     string  `json:"status"`
+	Critical   bool    `json:"critical"`
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Report is the JSON body served by both endpoints
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Run executes every check and summarizes the results. The overall status is
+// fail if a critical check failed, degraded if only informational ones did.
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Run(checkCtx)
+			result := CheckResult{
+				Status:     StatusOK,
+				Critical:   check.Critical,
+				DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			switch {
+			case err == nil:
+			case check.Critical:
+				report.Status = StatusFail
+			case report.Status == StatusOK:
+				report.Status = StatusDegraded
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// HandleLiveness answers 200 as long as the process can serve requests
+func (c *Checker) HandleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, http.StatusOK, Report{Status: StatusOK})
+}
+
+// HandleReadiness runs the checks and answers 503 if a critical one failed
+func (c *Checker) HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+	code := http.StatusOK
+	if report.Status == StatusFail {
+		code = http.StatusServiceUnavailable
+	}
+	writeReport(w, code, report)
+}
+
+func writeReport(w http.ResponseWriter, code int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
+
+// Cached wraps a check so its result is reused for ttl. Use it for checks
+// against third-party services so frequent probes don't hammer them.
+func Cached(ttl time.Duration, run func(ctx context.Context) error) func(ctx context.Context) error {
+	var mu sync.Mutex
+	var lastErr error
+	var checkedAt time.Time
+
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !checkedAt.IsZero() && time.Since(checkedAt) < ttl {
+			return lastErr
+		}
+		lastErr = run(ctx)
+		checkedAt = time.Now()
+		return lastErr
+	}
+}
+
+// HTTPCheck returns a check that succeeds when a GET to url answers below 500.
+// Any response from the server proves it is reachable.
+func HTTPCheck(client *http.Client, url string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
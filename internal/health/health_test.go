@@ -0,0 +1,146 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func ok(context.Context) error      { return nil }
+func failing(context.Context) error { return errors.New("down") }
+
+func TestHandleReadiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantCode   int
+		wantStatus string
+	}{
+		{
+			name:       "all passing",
+			checks:     []Check{{Name: "database", Critical: true, Run: ok}, {Name: "plc", Run: ok}},
+			wantCode:   http.StatusOK,
+			wantStatus: StatusOK,
+		},
+		{
+			name:       "informational check failing",
+			checks:     []Check{{Name: "database", Critical: true, Run: ok}, {Name: "plc", Run: failing}},
+			wantCode:   http.StatusOK,
+			wantStatus: StatusDegraded,
+		},
+		{
+			name:       "critical check failing",
+			checks:     []Check{{Name: "database", Critical: true, Run: failing}, {Name: "plc", Run: failing}},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: StatusFail,
+		},
+		{
+			name:       "no checks",
+			wantCode:   http.StatusOK,
+			wantStatus: StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			NewChecker(tt.checks...).HandleReadiness(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+
+			var report Report
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatalf("decoding report: %v", err)
+			}
+			if report.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", report.Status, tt.wantStatus)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Errorf("got %d check results, want %d", len(report.Checks), len(tt.checks))
+			}
+			for _, check := range tt.checks {
+				result, found := report.Checks[check.Name]
+				if !found {
+					t.Errorf("missing result for %s", check.Name)
+					continue
+				}
+				if (result.Error != "") != (result.Status == StatusFail) {
+					t.Errorf("%s: error %q inconsistent with status %q", check.Name, result.Error, result.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleLiveness(t *testing.T) {
+	// Liveness never runs the checks, so a failing dependency can't get the process restarted
+	checker := NewChecker(Check{Name: "database", Critical: true, Run: failing})
+
+	rec := httptest.NewRecorder()
+	checker.HandleLiveness(rec, httptest.NewRequest("GET", "/healthz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status code = %d, want 200", rec.Code)
+	}
+}
+
+func TestCached(t *testing.T) {
+	calls := 0
+	check := Cached(time.Hour, func(context.Context) error {
+		calls++
+		return errors.New("down")
+	})
+
+	for range 3 {
+		if err := check(context.Background()); err == nil {
+			t.Error("cached check should keep returning the error")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("underlying check ran %d times, want 1", calls)
+	}
+}
+
+func TestHTTPCheck(t *testing.T) {
+	tests := []struct {
+		status  int
+		wantErr bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusNotFound, false},
+		{http.StatusServiceUnavailable, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := HTTPCheck(server.Client(), server.URL)(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HTTPCheck() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		url := server.URL
+		server.Close()
+
+		if err := HTTPCheck(http.DefaultClient, url)(context.Background()); err == nil {
+			t.Error("HTTPCheck() should fail for a closed server")
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package labels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queryLimit is the largest page com.atproto.label.queryLabels allows
+const queryLimit = 250
+
+// Client queries one labeler service
+type Client struct {
+	did        string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the labeler with the given DID, served at
+// baseURL. Only labels signed by that DID are requested.
+func NewClient(did, baseURL string) *Client {
+	return &Client{
+		did:        did,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// DID returns the labeler's DID
+func (c *Client) DID() string {
+	return c.did
+}
+
+// queryLabelsOutput is the response body of com.atproto.label.queryLabels
+type queryLabelsOutput struct {
+	Cursor string  `json:"cursor,omitempty"`
+	Labels []Label `json:"labels"`
+}
+
+// QueryLabels returns one page of labels on subjects matching uriPatterns.
+// A pattern is a full DID or AT-URI, or a prefix ending in "*". Pass the
+// returned cursor to get the next page; it is empty after the last one.
+func (c *Client) QueryLabels(ctx context.Context, uriPatterns []string, cursor string) ([]Label, string, error) {
+	params := url.Values{}
+	for _, p := range uriPatterns {
+		params.Add("uriPatterns", p)
+	}
+	params.Set("sources", c.did)
+	params.Set("limit", strconv.Itoa(queryLimit))
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+
+	reqURL := c.baseURL + "/xrpc/com.atproto.label.queryLabels?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("querying labeler %s: %w", c.did, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, "", fmt.Errorf("labeler %s returned %d: %s", c.did, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var out queryLabelsOutput
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, "", fmt.Errorf("decoding labels from %s: %w", c.did, err)
+	}
+	return out.Labels, out.Cursor, nil
+}
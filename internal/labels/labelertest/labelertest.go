@@ -0,0 +1,141 @@
+// Package labelertest provides a stand-in labeler service for tests.
+//
+// It serves com.atproto.label.queryLabels from labels added in memory, with
+// the same pattern matching and paging as a real labeler, so code consuming
+// labels can be tested without the network:
+//
+//	labeler := labelertest.NewServer("did:plc:labeler")
+//	defer labeler.Close()
+//	labeler.Add("at://did:plc:alice/social.arabica.alpha.brew/3kabc", "porn")
+//	svc := labels.NewService(labels.NewClient(labeler.DID, labeler.URL))
+package labelertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"arabica/internal/labels"
+)
+
+// Server is a labeler publishing labels as DID
+type Server struct {
+	DID string
+	URL string
+
+	// PageSize limits labels per response to exercise paging; 0 means 250
+	PageSize int
+
+	srv    *httptest.Server
+	mu     sync.Mutex
+	labels []labels.Label
+	// requests counts queryLabels calls
+	requests int
+}
+
+// NewServer starts a labeler publishing labels as did
+func NewServer(did string) *Server {
+	s := &Server{DID: did}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /xrpc/com.atproto.label.queryLabels", s.queryLabels)
+	s.srv = httptest.NewServer(mux)
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Add labels a subject, a DID or AT-URI, with val
+func (s *Server) Add(subject, val string) {
+	s.Publish(labels.Label{URI: subject, Val: val})
+}
+
+// Negate removes an earlier label by publishing its negation
+func (s *Server) Negate(subject, val string) {
+	s.Publish(labels.Label{URI: subject, Val: val, Neg: true})
+}
+
+// Publish adds a label as given, filling in the source and creation time
+func (s *Server) Publish(l labels.Label) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l.Src == "" {
+		l.Src = s.DID
+	}
+	if l.Cts.IsZero() {
+		// Keep labels strictly ordered even when added within one clock tick
+		l.Cts = time.Now().Add(time.Duration(len(s.labels)) * time.Microsecond)
+	}
+	s.labels = append(s.labels, l)
+}
+
+// Requests returns how many queryLabels calls have been served
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) queryLabels(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	patterns := query["uriPatterns"]
+	if len(patterns) == 0 {
+		http.Error(w, `{"error":"InvalidRequest","message":"uriPatterns is required"}`, http.StatusBadRequest)
+		return
+	}
+	sources := query["sources"]
+	start, _ := strconv.Atoi(query.Get("cursor"))
+	limit := s.PageSize
+	if limit == 0 {
+		limit = 250
+	}
+	if n, err := strconv.Atoi(query.Get("limit")); err == nil && n < limit {
+		limit = n
+	}
+
+	s.mu.Lock()
+	s.requests++
+	var matched []labels.Label
+	for _, l := range s.labels {
+		if matchesAny(l.URI, patterns) && (len(sources) == 0 || slices.Contains(sources, l.Src)) {
+			matched = append(matched, l)
+		}
+	}
+	s.mu.Unlock()
+
+	out := struct {
+		Cursor string         `json:"cursor,omitempty"`
+		Labels []labels.Label `json:"labels"`
+	}{Labels: []labels.Label{}}
+	if start < len(matched) {
+		end := min(start+limit, len(matched))
+		out.Labels = matched[start:end]
+		if end < len(matched) {
+			out.Cursor = strconv.Itoa(end)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+func matchesAny(uri string, patterns []string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(uri, prefix) {
+				return true
+			}
+		} else if uri == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+// Package labels reads moderation labels from atproto labeler services.
+//
+// Labelers publish labels on accounts (the subject is a DID) and on records
+// (the subject is an AT-URI). Service polls each configured labeler's
+// com.atproto.label.queryLabels endpoint for the feed's registered users and
+// keeps the labels currently in effect in memory. Deciding what a label means
+// for a viewer is left to models.LabelPreferences.
+package labels
+
+import (
+	"sort"
+	"time"
+)
+
+// Label is a label as returned by com.atproto.label.queryLabels
+type Label struct {
+	Src string     `json:"src"` // DID of the labeler
+	URI string     `json:"uri"` // DID or AT-URI of the subject
+	CID string     `json:"cid,omitempty"`
+	Val string     `json:"val"`
+	Neg bool       `json:"neg,omitempty"` // Removes an earlier label with the same src, uri and val
+	Cts time.Time  `json:"cts"`
+	Exp *time.Time `json:"exp,omitempty"`
+}
+
+// Expired reports whether the label no longer applies at now
+func (l Label) Expired(now time.Time) bool {
+	return l.Exp != nil && !l.Exp.After(now)
+}
+
+// active resolves negations, keeping for each subject and value only the
+// latest label, and only if it wasn't a negation. Labels are grouped by
+// subject.
+func active(labels []Label) map[string][]Label {
+	sorted := make([]Label, len(labels))
+	copy(sorted, labels)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Cts.Before(sorted[j].Cts)
+	})
+
+	type key struct{ src, uri, val string }
+	latest := make(map[key]Label)
+	for _, l := range sorted {
+		latest[key{l.Src, l.URI, l.Val}] = l
+	}
+
+	bySubject := make(map[string][]Label)
+	for _, l := range latest {
+		if !l.Neg {
+			bySubject[l.URI] = append(bySubject[l.URI], l)
+		}
+	}
+	return bySubject
+}
@@ -0,0 +1,141 @@
+package labels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"arabica/internal/tracing"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// patternsPerQuery keeps request URLs to a reasonable length
+	patternsPerQuery = 50
+
+	// maxPages stops a misbehaving labeler that never ends its cursor
+	maxPages = 100
+)
+
+// Service keeps the labels that configured labelers currently apply to the
+// feed's users and their records
+type Service struct {
+	clients []*Client
+
+	mu sync.RWMutex
+	// bySource holds each labeler's active labels grouped by subject, so a
+	// labeler that fails to refresh keeps its previous labels
+	bySource map[string]map[string][]Label
+}
+
+// NewService creates a service reading labels from clients
+func NewService(clients ...*Client) *Service {
+	return &Service{
+		clients:  clients,
+		bySource: make(map[string]map[string][]Label),
+	}
+}
+
+// Refresh fetches the labels each labeler has applied to the given accounts
+// and their records. Labelers that fail keep the labels from their last
+// successful refresh; their errors are joined in the result.
+func (s *Service) Refresh(ctx context.Context, dids []string) (err error) {
+	ctx, span := tracing.Start(ctx, "labels.Refresh",
+		trace.WithAttributes(attribute.Int("labels.subjects", len(dids))))
+	defer func() { tracing.End(span, err) }()
+
+	patterns := make([]string, 0, 2*len(dids))
+	for _, did := range dids {
+		patterns = append(patterns, did, "at://"+did+"/*")
+	}
+
+	var errs []error
+	for _, c := range s.clients {
+		labels, err := fetchAll(ctx, c, patterns)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		bySubject := active(labels)
+		s.mu.Lock()
+		s.bySource[c.DID()] = bySubject
+		s.mu.Unlock()
+
+		log.Debug().Str("labeler", c.DID()).Int("labels", len(labels)).Int("subjects", len(bySubject)).Msg("labels: refreshed")
+	}
+	return errors.Join(errs...)
+}
+
+// fetchAll pages through every label matching patterns
+func fetchAll(ctx context.Context, c *Client, patterns []string) ([]Label, error) {
+	var all []Label
+	for batch := range slices.Chunk(patterns, patternsPerQuery) {
+		cursor := ""
+		for page := 0; ; page++ {
+			if page == maxPages {
+				return nil, fmt.Errorf("labeler %s returned more than %d pages", c.DID(), maxPages)
+			}
+			labels, next, err := c.QueryLabels(ctx, batch, cursor)
+			if err != nil {
+				return nil, err
+			}
+			for _, l := range labels {
+				// Only trust labels from the labeler we asked
+				if l.Src == c.DID() {
+					all = append(all, l)
+				}
+			}
+			if next == "" || len(labels) == 0 {
+				break
+			}
+			cursor = next
+		}
+	}
+	return all, nil
+}
+
+// RefreshLoop refreshes labels for the DIDs returned by subjects every
+// interval until ctx is cancelled. Call Refresh first to load labels at startup.
+func (s *Service) RefreshLoop(ctx context.Context, interval time.Duration, subjects func() []string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		if err := s.Refresh(ctx, subjects()); err != nil && ctx.Err() == nil {
+			log.Warn().Err(err).Msg("labels: refresh failed, keeping previous labels")
+		}
+	}
+}
+
+// Labels returns the distinct values of unexpired labels on any of subjects
+func (s *Service) Labels(subjects ...string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	var values []string
+	for _, bySubject := range s.bySource {
+		for _, subject := range subjects {
+			for _, l := range bySubject[subject] {
+				if !l.Expired(now) && !slices.Contains(values, l.Val) {
+					values = append(values, l.Val)
+				}
+			}
+		}
+	}
+	slices.Sort(values)
+	return values
+}
@@ -0,0 +1,81 @@
+package labels_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"arabica/internal/labels"
+	"arabica/internal/labels/labelertest"
+)
+
+const (
+	alice     = "did:plc:alice"
+	bob       = "did:plc:bob"
+	aliceBrew = "at://did:plc:alice/social.arabica.alpha.brew/3kabc"
+)
+
+func TestServiceRefresh(t *testing.T) {
+	labeler := labelertest.NewServer("did:plc:labeler")
+	defer labeler.Close()
+	labeler.PageSize = 2
+
+	labeler.Add(aliceBrew, "porn")
+	labeler.Add(aliceBrew, "spam")
+	labeler.Negate(aliceBrew, "spam")
+	labeler.Add(bob, "!hide")
+	labeler.Add("did:plc:carol", "!hide") // Not registered, never requested
+	labeler.Publish(labels.Label{URI: alice, Val: "sexual", Exp: ptr(time.Now().Add(-time.Minute))})
+	labeler.Publish(labels.Label{Src: "did:plc:impostor", URI: alice, Val: "!hide"})
+
+	svc := labels.NewService(labels.NewClient(labeler.DID, labeler.URL+"/"))
+	if err := svc.Refresh(context.Background(), []string{alice, bob}); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		subjects []string
+		want     []string
+	}{
+		{"record label, negation applied", []string{aliceBrew}, []string{"porn"}},
+		{"account label", []string{bob}, []string{"!hide"}},
+		{"expired and foreign labels ignored", []string{alice}, nil},
+		{"record and author combined", []string{aliceBrew, bob}, []string{"!hide", "porn"}},
+		{"unrequested subject", []string{"did:plc:carol"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := svc.Labels(tt.subjects...); !slices.Equal(got, tt.want) {
+				t.Errorf("Labels(%v) = %v, want %v", tt.subjects, got, tt.want)
+			}
+		})
+	}
+
+	if labeler.Requests() < 2 {
+		t.Errorf("labeler served %d requests, want paging", labeler.Requests())
+	}
+}
+
+func TestServiceRefreshKeepsLabelsOnError(t *testing.T) {
+	labeler := labelertest.NewServer("did:plc:labeler")
+	labeler.Add(bob, "!hide")
+
+	svc := labels.NewService(labels.NewClient(labeler.DID, labeler.URL))
+	if err := svc.Refresh(context.Background(), []string{bob}); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	labeler.Close()
+	if err := svc.Refresh(context.Background(), []string{bob}); err == nil {
+		t.Error("Refresh() with the labeler down succeeded, want error")
+	}
+	if got := svc.Labels(bob); !slices.Equal(got, []string{"!hide"}) {
+		t.Errorf("Labels() after failed refresh = %v, want previous labels", got)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,87 @@
+// Package lifecycle owns the server's background goroutines so they can be
+// stopped in a known order on shutdown.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// task is a registered background task and the function that stops it
+type task struct {
+	name string
+	stop func()
+}
+
+// Manager tracks background tasks and stops them in reverse registration
+// order, like deferred calls, so a task can rely on anything registered
+// before it still running until it has stopped.
+type Manager struct {
+	mu       sync.Mutex
+	tasks    []task
+	shutdown bool
+}
+
+// NewManager creates an empty lifecycle manager
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// Go runs fn in a goroutine with a context that is cancelled at shutdown.
+// Shutdown waits for fn to return.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(ctx)
+	}()
+
+	m.Add(name, func() {
+		cancel()
+		<-done
+	})
+}
+
+// Add registers a task started elsewhere by its stop function, which must
+// block until the task has finished
+func (m *Manager) Add(name string, stop func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.shutdown {
+		// Too late to be stopped in order, so stop it right away
+		go stop()
+		return
+	}
+	m.tasks = append(m.tasks, task{name: name, stop: stop})
+}
+
+// Shutdown stops every task, newest first. It returns early with an error if
+// ctx expires; tasks that were still stopping keep going in the background.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	tasks := m.tasks
+	m.tasks = nil
+	m.shutdown = true
+	m.mu.Unlock()
+
+	for i := len(tasks) - 1; i >= 0; i-- {
+		t := tasks[i]
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			t.stop()
+		}()
+
+		select {
+		case <-stopped:
+			log.Debug().Str("task", t.name).Msg("Background task stopped")
+		case <-ctx.Done():
+			return fmt.Errorf("stopping %s: %w", t.name, ctx.Err())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShutdownOrder(t *testing.T) {
+	m := NewManager()
+
+	var mu sync.Mutex
+	var order []string
+	stop := func(name string) func() {
+		return func() {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+		}
+	}
+
+	m.Add("cache", stop("cache"))
+	m.Add("limiter", stop("limiter"))
+	m.Go("feed", func(ctx context.Context) {
+		<-ctx.Done()
+		stop("feed")()
+	})
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	want := []string{"feed", "limiter", "cache"}
+	if !slices.Equal(order, want) {
+		t.Errorf("stop order = %v, want %v", order, want)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	m := NewManager()
+	release := make(chan struct{})
+	defer close(release)
+
+	m.Add("stuck", func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := m.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestAddAfterShutdown(t *testing.T) {
+	m := NewManager()
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	stopped := make(chan struct{})
+	m.Add("late", func() { close(stopped) })
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("task added after shutdown was never stopped")
+	}
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltCollector reads BoltDB statistics at scrape time
+type boltCollector struct {
+	stats func() bolt.Stats
+
+	txTotal       *prometheus.Desc
+	openTx        *prometheus.Desc
+	freePages     *prometheus.Desc
+	pendingPages  *prometheus.Desc
+	freeAlloc     *prometheus.Desc
+	freelistInuse *prometheus.Desc
+	pageAlloc     *prometheus.Desc
+	writes        *prometheus.Desc
+	writeSeconds  *prometheus.Desc
+}
+
+// NewBoltCollector returns a collector exporting the stats returned by fn,
+// typically boltstore.Store.Stats
+func NewBoltCollector(fn func() bolt.Stats) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "boltdb", name), help, nil, nil)
+	}
+	return &boltCollector{
+		stats:         fn,
+		txTotal:       desc("read_tx_total", "Total number of started read transactions."),
+		openTx:        desc("open_read_tx", "Number of currently open read transactions."),
+		freePages:     desc("free_pages", "Number of free pages on the freelist."),
+		pendingPages:  desc("pending_pages", "Number of pending pages on the freelist."),
+		freeAlloc:     desc("free_alloc_bytes", "Bytes allocated in free pages."),
+		freelistInuse: desc("freelist_inuse_bytes", "Bytes used by the freelist."),
+		pageAlloc:     desc("page_alloc_bytes_total", "Total bytes allocated for pages by write transactions."),
+		writes:        desc("writes_total", "Total number of page writes."),
+		writeSeconds:  desc("write_seconds_total", "Total time spent writing pages to disk."),
+	}
+}
+
+func (c *boltCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.txTotal
+	ch <- c.openTx
+	ch <- c.freePages
+	ch <- c.pendingPages
+	ch <- c.freeAlloc
+	ch <- c.freelistInuse
+	ch <- c.pageAlloc
+	ch <- c.writes
+	ch <- c.writeSeconds
+}
+
+func (c *boltCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.txTotal, prometheus.CounterValue, float64(s.TxN))
+	ch <- prometheus.MustNewConstMetric(c.openTx, prometheus.GaugeValue, float64(s.OpenTxN))
+	ch <- prometheus.MustNewConstMetric(c.freePages, prometheus.GaugeValue, float64(s.FreePageN))
+	ch <- prometheus.MustNewConstMetric(c.pendingPages, prometheus.GaugeValue, float64(s.PendingPageN))
+	ch <- prometheus.MustNewConstMetric(c.freeAlloc, prometheus.GaugeValue, float64(s.FreeAlloc))
+	ch <- prometheus.MustNewConstMetric(c.freelistInuse, prometheus.GaugeValue, float64(s.FreelistInuse))
+	ch <- prometheus.MustNewConstMetric(c.pageAlloc, prometheus.CounterValue, float64(s.TxStats.GetPageAlloc()))
+	ch <- prometheus.MustNewConstMetric(c.writes, prometheus.CounterValue, float64(s.TxStats.GetWrite()))
+	ch <- prometheus.MustNewConstMetric(c.writeSeconds, prometheus.CounterValue, s.TxStats.GetWriteTime().Seconds())
+}
@@ -0,0 +1,183 @@
+// Package metrics defines the Prometheus collectors exported on /metrics.
+//
+// Collectors are package-level so instrumented code can record without having
+// a registry threaded through constructors; they are registered on a private
+// registry rather than the global default so tests and libraries can't leak
+// unrelated series into the endpoint.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "arabica"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestDuration tracks request latency by matched route pattern
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method, route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// PDSRequestDuration tracks XRPC calls made to users' PDSes
+	PDSRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "pds",
+		Name:      "request_duration_seconds",
+		Help:      "PDS XRPC call latency by method and collection.",
+		Buckets:   []float64{.025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"method", "collection"})
+
+	// PDSRequestErrors counts failed XRPC calls
+	PDSRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "pds",
+		Name:      "request_errors_total",
+		Help:      "Failed PDS XRPC calls by method and collection.",
+	}, []string{"method", "collection"})
+
+	// CacheLookups counts SessionCache reads by collection and result (hit or miss)
+	CacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "session_cache",
+		Name:      "lookups_total",
+		Help:      "Session cache lookups by collection and result.",
+	}, []string{"collection", "result"})
+
+	// FeedRefreshDuration tracks how long rebuilding the public feed takes
+	FeedRefreshDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "feed",
+		Name:      "refresh_duration_seconds",
+		Help:      "Time taken to rebuild the public feed from registered users' PDSes.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	})
+
+	// FeedUserFailures counts users whose records could not be fetched for the
+	// feed. The failing DIDs are logged rather than used as a label, which
+	// would grow with every registered user.
+	FeedUserFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "feed",
+		Name:      "user_failures_total",
+		Help:      "Feed refreshes where a registered user's profile or brews could not be fetched.",
+	})
+
+	// SweepExpired counts OAuth auth requests and sessions removed by the
+	// session sweeper, by kind
+	SweepExpired = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "session_sweeper",
+		Name:      "expired_total",
+		Help:      "Expired OAuth entries removed by the session sweeper, by kind (auth_request or session).",
+	}, []string{"kind"})
+
+	// SweepDuration tracks how long each session sweep takes
+	SweepDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "session_sweeper",
+		Name:      "duration_seconds",
+		Help:      "Time taken by each session sweep.",
+		Buckets:   []float64{.001, .005, .01, .05, .1, .5, 1, 5},
+	})
+
+	// SweepErrors counts session sweeps that failed
+	SweepErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "session_sweeper",
+		Name:      "errors_total",
+		Help:      "Session sweeps that failed.",
+	})
+
+	// SweepLastSuccess is when the last session sweep succeeded
+	SweepLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "session_sweeper",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful session sweep.",
+	})
+
+	// RateLimitRejections counts requests refused with 429 by limiter
+	RateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ratelimit",
+		Name:      "rejections_total",
+		Help:      "Requests rejected by the rate limiter, by limiter.",
+	}, []string{"limiter"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		PDSRequestDuration,
+		PDSRequestErrors,
+		CacheLookups,
+		FeedRefreshDuration,
+		FeedUserFailures,
+		RateLimitRejections,
+		SweepExpired,
+		SweepDuration,
+		SweepErrors,
+		SweepLastSuccess,
+	)
+}
+
+// Register adds extra collectors, such as the BoltDB stats collector, to the
+// registry served by Handler
+func Register(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the registered metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObservePDSRequest records the latency of an XRPC call and counts it as an
+// error when failed is set. Callers decide what counts as a failure, since a
+// missing record is an expected answer for some lookups.
+func ObservePDSRequest(method, collection string, start time.Time, failed bool) {
+	PDSRequestDuration.WithLabelValues(method, collection).Observe(time.Since(start).Seconds())
+	if failed {
+		PDSRequestErrors.WithLabelValues(method, collection).Inc()
+	}
+}
+
+// ObserveCacheLookup counts a session cache read as a hit or a miss
+func ObserveCacheLookup(collection string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	CacheLookups.WithLabelValues(collection, result).Inc()
+}
+
+// ObserveSweep records a session sweep that started at start and removed the
+// given numbers of auth requests and sessions, or failed with err
+func ObserveSweep(start time.Time, authRequests, sessions int, err error) {
+	SweepDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		SweepErrors.Inc()
+		return
+	}
+	SweepExpired.WithLabelValues("auth_request").Add(float64(authRequests))
+	SweepExpired.WithLabelValues("session").Add(float64(sessions))
+	SweepLastSuccess.SetToCurrentTime()
+}
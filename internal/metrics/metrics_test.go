@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestHandler(t *testing.T) {
+	ObservePDSRequest("listRecords", "social.arabica.alpha.brew", time.Now(), true)
+	ObserveCacheLookup("brews", true)
+	ObserveCacheLookup("brews", false)
+	ObserveSweep(time.Now(), 2, 1, nil)
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("bolt.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	collector := NewBoltCollector(db.Stats)
+	if err := Register(collector); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	defer registry.Unregister(collector)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	out := string(body)
+
+	for _, want := range []string{
+		`arabica_pds_request_duration_seconds_count{collection="social.arabica.alpha.brew",method="listRecords"} 1`,
+		`arabica_pds_request_errors_total{collection="social.arabica.alpha.brew",method="listRecords"} 1`,
+		`arabica_session_cache_lookups_total{collection="brews",result="hit"} 1`,
+		`arabica_session_cache_lookups_total{collection="brews",result="miss"} 1`,
+		`arabica_session_sweeper_expired_total{kind="auth_request"} 2`,
+		`arabica_session_sweeper_expired_total{kind="session"} 1`,
+		`arabica_session_sweeper_duration_seconds_count 1`,
+		`arabica_boltdb_open_read_tx 0`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// minCompressSize is the smallest body worth compressing; below this the
+// encoding overhead outweighs the savings
+const minCompressSize = 1024
+
+// Content encodings, in order of preference
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+var (
+	gzipWriters = sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}}
+	brotliWriters = sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(io.Discard, 5) // Fast enough for per-request use
+	}}
+)
+
+// CompressMiddleware buffers successful GET responses, tags them with a strong
+// ETag over the rendered body, and compresses them with brotli or gzip when the
+// client accepts it. A request whose If-None-Match matches gets 304 Not Modified
+// with no body, so HTMX partial reloads and /api/data refreshes only cost a
+// round trip when nothing changed. ETags set by the handler are kept.
+func CompressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		buf := &bufferedResponse{header: w.Header(), statusCode: http.StatusOK}
+		next.ServeHTTP(buf, r)
+
+		h := w.Header()
+		body := buf.body.Bytes()
+
+		// Errors, redirects, partial content and pre-encoded bodies go out as-is
+		if buf.statusCode != http.StatusOK || h.Get("Content-Encoding") != "" {
+			w.WriteHeader(buf.statusCode)
+			w.Write(body)
+			return
+		}
+
+		// Set the type now, since sniffing after compression would see gibberish
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(body))
+		}
+
+		var encoding string
+		if compressible(h.Get("Content-Type")) {
+			h.Add("Vary", "Accept-Encoding")
+			if len(body) >= minCompressSize {
+				encoding = negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			}
+		}
+
+		// Each encoding is a different representation, so it gets its own tag
+		etag := h.Get("ETag")
+		if etag == "" {
+			sum := sha256.Sum256(body)
+			etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+		}
+		if encoding != "" {
+			etag = strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+		}
+		h.Set("ETag", etag)
+		if h.Get("Cache-Control") == "" {
+			// Pages are per-user, so browsers may keep them but must revalidate
+			h.Set("Cache-Control", "private, no-cache")
+		}
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			h.Del("Content-Type")
+			h.Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		if encoding != "" {
+			compressed, err := compress(body, encoding)
+			if err == nil {
+				body = compressed
+				h.Set("Content-Encoding", encoding)
+				h.Del("Accept-Ranges") // Byte ranges would refer to the uncompressed file
+			} else {
+				// Fall back to the uncompressed body under its own tag
+				h.Set("ETag", strings.TrimSuffix(etag, "-"+encoding+`"`)+`"`)
+			}
+		}
+
+		h.Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	})
+}
+
+// bufferedResponse collects a handler's response so it can be tagged and compressed
+type bufferedResponse struct {
+	header      http.Header
+	statusCode  int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	if !b.wroteHeader {
+		b.statusCode = code
+		b.wroteHeader = true
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
+
+// compressible reports whether a content type benefits from compression.
+// Images other than SVG and archives are already compressed.
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case strings.HasSuffix(mediaType, "json"), strings.HasSuffix(mediaType, "javascript"),
+		strings.HasSuffix(mediaType, "xml"), mediaType == "image/svg+xml":
+		return true
+	default:
+		return false
+	}
+}
+
+// negotiateEncoding picks brotli or gzip from an Accept-Encoding header,
+// preferring brotli when both are equally acceptable. Returns "" for identity.
+func negotiateEncoding(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	wildcardQ := -1.0
+	qualities := map[string]float64{}
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if name == "*" {
+			wildcardQ = q
+			continue
+		}
+		qualities[name] = q
+	}
+
+	for _, encoding := range []string{encodingBrotli, encodingGzip} {
+		q, ok := qualities[encoding]
+		if !ok {
+			q = wildcardQ
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// etagMatches implements the weak comparison If-None-Match calls for
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// compress encodes body with the given content encoding
+func compress(body []byte, encoding string) ([]byte, error) {
+	var out bytes.Buffer
+	switch encoding {
+	case encodingBrotli:
+		bw := brotliWriters.Get().(*brotli.Writer)
+		defer brotliWriters.Put(bw)
+		bw.Reset(&out)
+		if _, err := bw.Write(body); err != nil {
+			return nil, err
+		}
+		if err := bw.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		gw := gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(gw)
+		gw.Reset(&out)
+		if _, err := gw.Write(body); err != nil {
+			return nil, err
+		}
+		if err := gw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,213 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+var largeHTML = "<html>" + strings.Repeat("<p>coffee</p>", 200) + "</html>"
+
+func htmlHandler(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, body)
+	})
+}
+
+func serve(handler http.Handler, method string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCompressMiddleware_Encodings(t *testing.T) {
+	handler := CompressMiddleware(htmlHandler(largeHTML))
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		wantEncoding   string
+		decode         func(io.Reader) (io.Reader, error)
+	}{
+		{"brotli preferred", "gzip, deflate, br", "br", func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }},
+		{"gzip only", "gzip", "gzip", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"identity", "", "", func(r io.Reader) (io.Reader, error) { return r, nil }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(handler, "GET", map[string]string{"Accept-Encoding": tt.acceptEncoding})
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+
+			r, err := tt.decode(rec.Body)
+			if err != nil {
+				t.Fatalf("decode error = %v", err)
+			}
+			body, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("read error = %v", err)
+			}
+			if string(body) != largeHTML {
+				t.Error("decoded body does not match the original")
+			}
+		})
+	}
+}
+
+func TestCompressMiddleware_ETag(t *testing.T) {
+	handler := CompressMiddleware(htmlHandler(largeHTML))
+
+	plain := serve(handler, "GET", nil)
+	etag := plain.Header().Get("ETag")
+	if etag == "" || strings.HasPrefix(etag, "W/") {
+		t.Fatalf("ETag = %q, want a strong tag", etag)
+	}
+	if got := plain.Header().Get("Cache-Control"); got != "private, no-cache" {
+		t.Errorf("Cache-Control = %q, want private, no-cache", got)
+	}
+
+	gzipped := serve(handler, "GET", map[string]string{"Accept-Encoding": "gzip"})
+	gzipTag := gzipped.Header().Get("ETag")
+	if gzipTag == etag {
+		t.Error("compressed and uncompressed responses should have different ETags")
+	}
+
+	t.Run("matching tag returns 304", func(t *testing.T) {
+		rec := serve(handler, "GET", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": gzipTag})
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("status = %d, want 304", rec.Code)
+		}
+		if rec.Body.Len() != 0 {
+			t.Error("304 response should have no body")
+		}
+	})
+
+	t.Run("tag for another encoding returns 200", func(t *testing.T) {
+		rec := serve(handler, "GET", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": etag})
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want 200", rec.Code)
+		}
+	})
+
+	t.Run("changed body returns 200", func(t *testing.T) {
+		changed := CompressMiddleware(htmlHandler(largeHTML + "<p>more</p>"))
+		rec := serve(changed, "GET", map[string]string{"If-None-Match": etag})
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want 200", rec.Code)
+		}
+	})
+}
+
+func TestCompressMiddleware_PassThrough(t *testing.T) {
+	t.Run("small bodies are not compressed", func(t *testing.T) {
+		rec := serve(CompressMiddleware(htmlHandler("<p>hi</p>")), "GET", map[string]string{"Accept-Encoding": "br"})
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want none", got)
+		}
+		if rec.Body.String() != "<p>hi</p>" {
+			t.Errorf("body = %q", rec.Body.String())
+		}
+	})
+
+	t.Run("errors are untouched", func(t *testing.T) {
+		handler := CompressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, strings.Repeat("x", 2000), http.StatusInternalServerError)
+		}))
+		rec := serve(handler, "GET", map[string]string{"Accept-Encoding": "gzip"})
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d, want 500", rec.Code)
+		}
+		if rec.Header().Get("ETag") != "" || rec.Header().Get("Content-Encoding") != "" {
+			t.Error("error responses should not be tagged or compressed")
+		}
+	})
+
+	t.Run("non-GET requests are untouched", func(t *testing.T) {
+		rec := serve(CompressMiddleware(htmlHandler(largeHTML)), "POST", map[string]string{"Accept-Encoding": "gzip"})
+		if rec.Header().Get("ETag") != "" || rec.Header().Get("Content-Encoding") != "" {
+			t.Error("POST responses should not be tagged or compressed")
+		}
+	})
+
+	t.Run("images are not compressed", func(t *testing.T) {
+		handler := CompressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(bytes.Repeat([]byte{0}, 4096))
+		}))
+		rec := serve(handler, "GET", map[string]string{"Accept-Encoding": "gzip"})
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want none", got)
+		}
+		if rec.Header().Get("ETag") == "" {
+			t.Error("images should still get an ETag")
+		}
+	})
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"br", "br"},
+		{"gzip, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, gzip;q=0", ""},
+		{"*", "br"},
+		{"*;q=0, gzip", "gzip"},
+		{"deflate", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := negotiateEncoding(tt.header); got != tt.want {
+				t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		ifNoneMatch string
+		etag        string
+		want        bool
+	}{
+		{"", `"abc"`, false},
+		{`"abc"`, `"abc"`, true},
+		{`W/"abc"`, `"abc"`, true},
+		{`"xyz", "abc"`, `"abc"`, true},
+		{`"xyz"`, `"abc"`, false},
+		{"*", `"abc"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ifNoneMatch, func(t *testing.T) {
+			if got := etagMatches(tt.ifNoneMatch, tt.etag); got != tt.want {
+				t.Errorf("etagMatches(%q, %q) = %v, want %v", tt.ifNoneMatch, tt.etag, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,19 +1,28 @@
 package middleware
 
 import (
+	"context"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"arabica/internal/atproto"
+	"arabica/internal/metrics"
+	"arabica/internal/tracing"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// getClientIP extracts the real client IP address from the request,
+// ClientIP extracts the real client IP address from the request,
 // checking X-Forwarded-For and X-Real-IP headers for reverse proxy setups.
-func getClientIP(r *http.Request) string {
+func ClientIP(r *http.Request) string {
 	// Check X-Forwarded-For header (can contain multiple IPs: client, proxy1, proxy2)
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
 		// Take the first IP (the original client)
@@ -37,12 +46,44 @@ func getClientIP(r *http.Request) string {
 	return ip
 }
 
-// LoggingMiddleware returns a middleware that logs HTTP request details with structured logging
+// routeKey is the context key for the matched route pattern, filled in by
+// RouteMiddleware and read back by LoggingMiddleware
+type routeKey struct{}
+
+// RouteMiddleware records which ServeMux pattern matched the request, so
+// LoggingMiddleware can label metrics by route instead of by raw path. It must
+// wrap the mux directly: the mux sets Pattern on the request it is handed, and
+// outer middleware only ever sees its own copy.
+func RouteMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if route, ok := r.Context().Value(routeKey{}).(*string); ok {
+			*route = r.Pattern
+		}
+	})
+}
+
+// LoggingMiddleware returns a middleware that logs HTTP request details with structured logging,
+// records request latency per route and starts the root trace span for the request
 func LoggingMiddleware(logger zerolog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 
+			// Continue a trace started by an upstream proxy, if any
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracing.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			route := new(string)
+			r = r.WithContext(context.WithValue(ctx, routeKey{}, route))
+
 			// Create a response writer wrapper to capture status code and bytes written
 			rw := &responseWriter{
 				ResponseWriter: w,
@@ -56,6 +97,25 @@ func LoggingMiddleware(logger zerolog.Logger) func(http.Handler) http.Handler {
 			// Calculate duration
 			duration := time.Since(start)
 
+			// Requests rejected before reaching the mux (rate limits) have no route
+			routeLabel := *route
+			if routeLabel == "" {
+				routeLabel = "unmatched"
+			}
+			metrics.HTTPRequestDuration.
+				WithLabelValues(r.Method, routeLabel, strconv.Itoa(rw.statusCode)).
+				Observe(duration.Seconds())
+
+			// Patterns already include the method, e.g. "GET /brews/{id}"
+			if *route != "" {
+				span.SetName(*route)
+				span.SetAttributes(semconv.HTTPRoute(*route))
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(rw.statusCode))
+			if rw.statusCode >= 500 {
+				span.SetStatus(codes.Error, http.StatusText(rw.statusCode))
+			}
+
 			// Select log level based on status code
 			var logEvent *zerolog.Event
 			if rw.statusCode >= 500 {
@@ -70,10 +130,11 @@ func LoggingMiddleware(logger zerolog.Logger) func(http.Handler) http.Handler {
 			logEvent.
 				Str("method", r.Method).
 				Str("path", r.URL.Path).
+				Str("route", routeLabel).
 				Str("query", r.URL.RawQuery).
 				Int("status", rw.statusCode).
 				Dur("duration", duration).
-				Str("client_ip", getClientIP(r)).
+				Str("client_ip", ClientIP(r)).
 				Str("user_agent", r.UserAgent()).
 				Int64("bytes_written", rw.bytesWritten).
 				Str("proto", r.Proto)
@@ -85,6 +146,9 @@ func LoggingMiddleware(logger zerolog.Logger) func(http.Handler) http.Handler {
 			if reqID := r.Header.Get("X-Request-ID"); reqID != "" {
 				logEvent.Str("request_id", reqID)
 			}
+			if sc := span.SpanContext(); sc.IsValid() {
+				logEvent.Str("trace_id", sc.TraceID().String())
+			}
 			if contentType := r.Header.Get("Content-Type"); contentType != "" {
 				logEvent.Str("content_type", contentType)
 			}
@@ -7,6 +7,11 @@ import (
 	"testing"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func TestResponseWriter_WriteHeader(t *testing.T) {
@@ -268,4 +273,73 @@ func TestLoggingMiddleware(t *testing.T) {
 			t.Errorf("log should contain bytes_written:11, got: %s", logOutput)
 		}
 	})
+	t.Run("logs the matched route pattern", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := zerolog.New(&buf)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /brews/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+		// Copy the request on the way down, as the auth middleware does
+		copying := func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r.WithContext(r.Context()))
+			})
+		}
+		wrapped := LoggingMiddleware(logger)(copying(RouteMiddleware(mux)))
+
+		req := httptest.NewRequest(http.MethodGet, "/brews/abc123", nil)
+		wrapped.ServeHTTP(httptest.NewRecorder(), req)
+
+		logOutput := buf.String()
+		if !bytes.Contains([]byte(logOutput), []byte(`"route":"GET /brews/{id}"`)) {
+			t.Errorf("log should contain the route pattern, got: %s", logOutput)
+		}
+	})
+
+	t.Run("marks requests that never reach the mux", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := zerolog.New(&buf)
+
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		wrapped := LoggingMiddleware(logger)(handler)
+
+		wrapped.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		logOutput := buf.String()
+		if !bytes.Contains([]byte(logOutput), []byte(`"route":"unmatched"`)) {
+			t.Errorf("log should mark the route as unmatched, got: %s", logOutput)
+		}
+	})
+	t.Run("names the trace span after the route", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+		var buf bytes.Buffer
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /brews/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		wrapped := LoggingMiddleware(zerolog.New(&buf))(RouteMiddleware(mux))
+
+		wrapped.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/brews/abc123", nil))
+
+		spans := recorder.Ended()
+		if len(spans) != 1 {
+			t.Fatalf("ended %d spans, want 1", len(spans))
+		}
+		if got := spans[0].Name(); got != "GET /brews/{id}" {
+			t.Errorf("span name = %q, want %q", got, "GET /brews/{id}")
+		}
+		if got := spans[0].Status().Code; got != codes.Error {
+			t.Errorf("span status = %v, want Error for a 500", got)
+		}
+		traceID := spans[0].SpanContext().TraceID().String()
+		if !bytes.Contains(buf.Bytes(), []byte(`"trace_id":"`+traceID+`"`)) {
+			t.Errorf("log should contain the trace id, got: %s", buf.String())
+		}
+	})
 }
@@ -5,6 +5,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"arabica/internal/metrics"
 )
 
 // SecurityHeadersMiddleware adds security headers to all responses
@@ -53,6 +55,10 @@ type RateLimiter struct {
 	rate     int           // requests per window
 	window   time.Duration // time window
 	cleanup  time.Duration // cleanup interval for old entries
+
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
 }
 
 type visitor struct {
@@ -69,6 +75,8 @@ func NewRateLimiter(rate int, window time.Duration) *RateLimiter {
 		rate:     rate,
 		window:   window,
 		cleanup:  window * 2,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
 	}
 
 	// Start cleanup goroutine
@@ -78,21 +86,34 @@ func NewRateLimiter(rate int, window time.Duration) *RateLimiter {
 }
 
 func (rl *RateLimiter) cleanupLoop() {
+	defer close(rl.stopped)
 	ticker := time.NewTicker(rl.cleanup)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		rl.mu.Lock()
-		now := time.Now()
-		for ip, v := range rl.visitors {
-			if now.Sub(v.lastReset) > rl.cleanup {
-				delete(rl.visitors, ip)
+	for {
+		select {
+		case <-ticker.C:
+			rl.mu.Lock()
+			now := time.Now()
+			for ip, v := range rl.visitors {
+				if now.Sub(v.lastReset) > rl.cleanup {
+					delete(rl.visitors, ip)
+				}
 			}
+			rl.mu.Unlock()
+		case <-rl.done:
+			return
 		}
-		rl.mu.Unlock()
 	}
 }
 
+// Stop ends the cleanup goroutine and waits for it to exit. The limiter keeps
+// working afterwards, it just no longer forgets idle visitors.
+func (rl *RateLimiter) Stop() {
+	rl.stopOnce.Do(func() { close(rl.done) })
+	<-rl.stopped
+}
+
 // Allow checks if a request from the given IP is allowed
 func (rl *RateLimiter) Allow(ip string) bool {
 	rl.mu.Lock()
@@ -137,33 +158,57 @@ type RateLimitConfig struct {
 
 // NewDefaultRateLimitConfig creates rate limiters with sensible defaults
 func NewDefaultRateLimitConfig() *RateLimitConfig {
+	return NewRateLimitConfig(
+		5,   // 5 auth attempts per minute
+		60,  // 60 API calls per minute
+		120, // 120 requests per minute
+		time.Minute,
+	)
+}
+
+// NewRateLimitConfig creates rate limiters allowing the given number of
+// requests per window for each endpoint type
+func NewRateLimitConfig(auth, api, global int, window time.Duration) *RateLimitConfig {
 	return &RateLimitConfig{
-		AuthLimiter:   NewRateLimiter(5, time.Minute),   // 5 auth attempts per minute
-		APILimiter:    NewRateLimiter(60, time.Minute),  // 60 API calls per minute
-		GlobalLimiter: NewRateLimiter(120, time.Minute), // 120 requests per minute
+		AuthLimiter:   NewRateLimiter(auth, window),
+		APILimiter:    NewRateLimiter(api, window),
+		GlobalLimiter: NewRateLimiter(global, window),
 	}
 }
 
+// Stop ends the cleanup goroutines of all limiters
+func (c *RateLimitConfig) Stop() {
+	c.AuthLimiter.Stop()
+	c.APILimiter.Stop()
+	c.GlobalLimiter.Stop()
+}
+
 // RateLimitMiddleware creates a rate limiting middleware
 func RateLimitMiddleware(config *RateLimitConfig) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := getClientIP(r)
+			ip := ClientIP(r)
 			path := r.URL.Path
 
 			var limiter *RateLimiter
+			var name string
 
 			// Select appropriate limiter based on path
+			// Switching accounts only checks a session this server already has,
+			// so it gets the global limit rather than the one for signing in
 			switch {
+			case path == "/auth/switch":
+				limiter, name = config.GlobalLimiter, "global"
 			case strings.HasPrefix(path, "/auth/") || path == "/login" || path == "/oauth/callback":
-				limiter = config.AuthLimiter
+				limiter, name = config.AuthLimiter, "auth"
 			case strings.HasPrefix(path, "/api/"):
-				limiter = config.APILimiter
+				limiter, name = config.APILimiter, "api"
 			default:
-				limiter = config.GlobalLimiter
+				limiter, name = config.GlobalLimiter, "global"
 			}
 
 			if !limiter.Allow(ip) {
+				metrics.RateLimitRejections.WithLabelValues(name).Inc()
 				w.Header().Set("Retry-After", "60")
 				http.Error(w, "Too many requests", http.StatusTooManyRequests)
 				return
@@ -174,7 +219,7 @@ func RateLimitMiddleware(config *RateLimitConfig) func(http.Handler) http.Handle
 	}
 }
 
-// getClientIP is defined in logging.go
+// ClientIP is defined in logging.go
 
 // RequireHTMXMiddleware ensures that certain API routes are only accessible via HTMX requests.
 // This prevents direct browser access to internal API endpoints that return fragments or JSON.
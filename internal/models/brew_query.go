@@ -0,0 +1,330 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Brew list sort keys
+const (
+	BrewSortDate   = "date"
+	BrewSortRating = "rating"
+	BrewSortBean   = "bean"
+	BrewSortMethod = "method"
+)
+
+// Brew list page sizes
+const (
+	DefaultBrewsPerPage = 20
+	MaxBrewsPerPage     = 100
+)
+
+// BrewQueryDateLayout is the format of the from/to date parameters
+const BrewQueryDateLayout = "2006-01-02"
+
+// BrewQuery sorts, filters and paginates a brew list. The zero value lists
+// every brew newest first.
+type BrewQuery struct {
+	Sort      string // One of the BrewSort keys, empty for date
+	Ascending bool
+
+	BeanRKey    string
+	RoasterRKey string
+	BrewerRKey  string
+	GrinderRKey string
+	MinRating   int // 0 means no lower bound
+	MaxRating   int // 0 means no upper bound
+	From        time.Time
+	To          time.Time // Inclusive; brews on this day match
+
+	Page    int // 1-based, 0 means the first page
+	PerPage int // 0 means DefaultBrewsPerPage
+}
+
+// BrewPage is one page of a sorted and filtered brew list
+type BrewPage struct {
+	Brews      []*Brew
+	Total      int // Brews matching the filters, across all pages
+	Page       int
+	PerPage    int
+	TotalPages int
+}
+
+// HasPrev reports whether there is a page before this one
+func (p BrewPage) HasPrev() bool {
+	return p.Page > 1
+}
+
+// HasNext reports whether there is a page after this one
+func (p BrewPage) HasNext() bool {
+	return p.Page < p.TotalPages
+}
+
+// PrevPage returns the number of the previous page
+func (p BrewPage) PrevPage() int {
+	return p.Page - 1
+}
+
+// NextPage returns the number of the next page
+func (p BrewPage) NextPage() int {
+	return p.Page + 1
+}
+
+// ParseBrewQuery reads a brew query from URL parameters. Record keys are
+// returned as given and should be validated by the caller.
+func ParseBrewQuery(values url.Values) (BrewQuery, error) {
+	q := BrewQuery{
+		BeanRKey:    values.Get("bean"),
+		RoasterRKey: values.Get("roaster"),
+		BrewerRKey:  values.Get("brewer"),
+		GrinderRKey: values.Get("grinder"),
+	}
+
+	switch sortKey := values.Get("sort"); sortKey {
+	case "", BrewSortDate:
+	case BrewSortRating, BrewSortBean, BrewSortMethod:
+		q.Sort = sortKey
+	default:
+		return BrewQuery{}, fmt.Errorf("invalid sort %q", sortKey)
+	}
+
+	switch order := values.Get("order"); order {
+	case "", "desc":
+	case "asc":
+		q.Ascending = true
+	default:
+		return BrewQuery{}, fmt.Errorf("invalid order %q", order)
+	}
+
+	var err error
+	if q.MinRating, err = parseQueryInt(values, "min_rating", 0, 10); err != nil {
+		return BrewQuery{}, err
+	}
+	if q.MaxRating, err = parseQueryInt(values, "max_rating", 0, 10); err != nil {
+		return BrewQuery{}, err
+	}
+	if q.Page, err = parseQueryInt(values, "page", 0, 1<<20); err != nil {
+		return BrewQuery{}, err
+	}
+	if q.PerPage, err = parseQueryInt(values, "per_page", 0, MaxBrewsPerPage); err != nil {
+		return BrewQuery{}, err
+	}
+
+	if q.From, err = parseQueryDate(values, "from"); err != nil {
+		return BrewQuery{}, err
+	}
+	if q.To, err = parseQueryDate(values, "to"); err != nil {
+		return BrewQuery{}, err
+	}
+
+	return q, nil
+}
+
+// parseQueryInt parses an optional integer parameter within [min, max]
+func parseQueryInt(values url.Values, name string, min, max int) (int, error) {
+	s := values.Get(name)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s must be a number between %d and %d", name, min, max)
+	}
+	return n, nil
+}
+
+// parseQueryDate parses an optional YYYY-MM-DD parameter as a UTC date
+func parseQueryDate(values url.Values, name string) (time.Time, error) {
+	s := values.Get(name)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(BrewQueryDateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a date (YYYY-MM-DD)", name)
+	}
+	return t, nil
+}
+
+// Values encodes the query as URL parameters, omitting defaults so URLs stay short
+func (q BrewQuery) Values() url.Values {
+	v := url.Values{}
+	set := func(name, value string) {
+		if value != "" {
+			v.Set(name, value)
+		}
+	}
+	setInt := func(name string, n int) {
+		if n != 0 {
+			v.Set(name, strconv.Itoa(n))
+		}
+	}
+
+	set("sort", q.Sort)
+	if q.Ascending {
+		v.Set("order", "asc")
+	}
+	set("bean", q.BeanRKey)
+	set("roaster", q.RoasterRKey)
+	set("brewer", q.BrewerRKey)
+	set("grinder", q.GrinderRKey)
+	setInt("min_rating", q.MinRating)
+	setInt("max_rating", q.MaxRating)
+	set("from", q.FromString())
+	set("to", q.ToString())
+	if q.Page > 1 {
+		v.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.PerPage != 0 && q.PerPage != DefaultBrewsPerPage {
+		v.Set("per_page", strconv.Itoa(q.PerPage))
+	}
+	return v
+}
+
+// Encode returns the query as a URL query string
+func (q BrewQuery) Encode() string {
+	return q.Values().Encode()
+}
+
+// FromString returns the from date in BrewQueryDateLayout, or "" if unset
+func (q BrewQuery) FromString() string {
+	if q.From.IsZero() {
+		return ""
+	}
+	return q.From.Format(BrewQueryDateLayout)
+}
+
+// ToString returns the to date in BrewQueryDateLayout, or "" if unset
+func (q BrewQuery) ToString() string {
+	if q.To.IsZero() {
+		return ""
+	}
+	return q.To.Format(BrewQueryDateLayout)
+}
+
+// IsFiltered reports whether any filter is set
+func (q BrewQuery) IsFiltered() bool {
+	return q.BeanRKey != "" || q.RoasterRKey != "" || q.BrewerRKey != "" || q.GrinderRKey != "" ||
+		q.MinRating != 0 || q.MaxRating != 0 || !q.From.IsZero() || !q.To.IsZero()
+}
+
+// SortKey returns the effective sort key
+func (q BrewQuery) SortKey() string {
+	if q.Sort == "" {
+		return BrewSortDate
+	}
+	return q.Sort
+}
+
+// WithPage returns a copy of the query for another page
+func (q BrewQuery) WithPage(page int) BrewQuery {
+	q.Page = page
+	return q
+}
+
+// Matches reports whether a brew passes every filter
+func (q BrewQuery) Matches(b *Brew) bool {
+	if q.BeanRKey != "" && b.BeanRKey != q.BeanRKey {
+		return false
+	}
+	if q.RoasterRKey != "" && (b.Bean == nil || b.Bean.RoasterRKey != q.RoasterRKey) {
+		return false
+	}
+	if q.BrewerRKey != "" && b.BrewerRKey != q.BrewerRKey {
+		return false
+	}
+	if q.GrinderRKey != "" && b.GrinderRKey != q.GrinderRKey {
+		return false
+	}
+	if q.MinRating != 0 && b.Rating < q.MinRating {
+		return false
+	}
+	if q.MaxRating != 0 && b.Rating > q.MaxRating {
+		return false
+	}
+	if !q.From.IsZero() && b.CreatedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !b.CreatedAt.Before(q.To.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
+// Apply filters, sorts and paginates brews. The input slice is not modified.
+func (q BrewQuery) Apply(brews []*Brew) BrewPage {
+	matched := make([]*Brew, 0, len(brews))
+	for _, b := range brews {
+		if q.Matches(b) {
+			matched = append(matched, b)
+		}
+	}
+
+	compare := brewCompare(q.SortKey())
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if c := compare(a, b); c != 0 {
+			if q.Ascending {
+				return c < 0
+			}
+			return c > 0
+		}
+		// Ties show the newest brew first regardless of order
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+
+	perPage := q.PerPage
+	if perPage <= 0 {
+		perPage = DefaultBrewsPerPage
+	}
+	totalPages := max(1, (len(matched)+perPage-1)/perPage)
+	page := min(max(1, q.Page), totalPages)
+
+	start := (page - 1) * perPage
+	end := min(start+perPage, len(matched))
+
+	return BrewPage{
+		Brews:      matched[start:end],
+		Total:      len(matched),
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: totalPages,
+	}
+}
+
+// brewCompare returns a three-way comparison for the sort key
+func brewCompare(key string) func(a, b *Brew) int {
+	switch key {
+	case BrewSortRating:
+		return func(a, b *Brew) int { return a.Rating - b.Rating }
+	case BrewSortBean:
+		return func(a, b *Brew) int { return strings.Compare(brewBeanName(a), brewBeanName(b)) }
+	case BrewSortMethod:
+		return func(a, b *Brew) int { return strings.Compare(brewMethodName(a), brewMethodName(b)) }
+	default:
+		return func(a, b *Brew) int { return a.CreatedAt.Compare(b.CreatedAt) }
+	}
+}
+
+// brewBeanName is the bean label shown in the brew list, lowercased for sorting
+func brewBeanName(b *Brew) string {
+	if b.Bean == nil {
+		return ""
+	}
+	if b.Bean.Name != "" {
+		return strings.ToLower(b.Bean.Name)
+	}
+	return strings.ToLower(b.Bean.Origin)
+}
+
+// brewMethodName is the brewer or method shown in the brew list, lowercased for sorting
+func brewMethodName(b *Brew) string {
+	if b.BrewerObj != nil && b.BrewerObj.Name != "" {
+		return strings.ToLower(b.BrewerObj.Name)
+	}
+	return strings.ToLower(b.Method)
+}
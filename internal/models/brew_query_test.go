@@ -0,0 +1,123 @@
+package models
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func testBrews() []*Brew {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 8, 0, 0, 0, time.UTC) }
+	kenya := &Bean{RKey: "b1", Name: "Kenya", RoasterRKey: "r1"}
+	brazil := &Bean{RKey: "b2", Name: "brazil", RoasterRKey: "r2"}
+	return []*Brew{
+		{RKey: "1", BeanRKey: "b1", Bean: kenya, Rating: 8, Method: "V60", BrewerRKey: "w1", CreatedAt: day(1)},
+		{RKey: "2", BeanRKey: "b2", Bean: brazil, Rating: 6, Method: "AeroPress", GrinderRKey: "g1", CreatedAt: day(2)},
+		{RKey: "3", BeanRKey: "b1", Bean: kenya, Rating: 9, Method: "Chemex", GrinderRKey: "g1", CreatedAt: day(3)},
+		{RKey: "4", BeanRKey: "b2", Bean: brazil, Rating: 8, Method: "V60", BrewerRKey: "w1", CreatedAt: day(4)},
+	}
+}
+
+func rkeys(brews []*Brew) string {
+	s := ""
+	for _, b := range brews {
+		s += b.RKey
+	}
+	return s
+}
+
+func TestBrewQueryApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		query BrewQuery
+		want  string
+		total int
+	}{
+		{"default is newest first", BrewQuery{}, "4321", 4},
+		{"date ascending", BrewQuery{Ascending: true}, "1234", 4},
+		{"rating ties newest first", BrewQuery{Sort: BrewSortRating}, "3412", 4},
+		{"bean ignores case", BrewQuery{Sort: BrewSortBean, Ascending: true}, "4231", 4},
+		{"method", BrewQuery{Sort: BrewSortMethod, Ascending: true}, "2341", 4},
+		{"bean filter", BrewQuery{BeanRKey: "b1"}, "31", 2},
+		{"roaster filter", BrewQuery{RoasterRKey: "r2"}, "42", 2},
+		{"brewer filter", BrewQuery{BrewerRKey: "w1"}, "41", 2},
+		{"grinder filter", BrewQuery{GrinderRKey: "g1"}, "32", 2},
+		{"rating range", BrewQuery{MinRating: 7, MaxRating: 8}, "41", 2},
+		{"date range is inclusive", BrewQuery{From: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), To: time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)}, "32", 2},
+		{"first page", BrewQuery{PerPage: 3}, "432", 4},
+		{"second page", BrewQuery{PerPage: 3, Page: 2}, "1", 4},
+		{"page past the end clamps", BrewQuery{PerPage: 3, Page: 9}, "1", 4},
+		{"no matches", BrewQuery{BeanRKey: "nope"}, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := tt.query.Apply(testBrews())
+			if got := rkeys(page.Brews); got != tt.want {
+				t.Errorf("Apply() brews = %q, want %q", got, tt.want)
+			}
+			if page.Total != tt.total {
+				t.Errorf("Apply() total = %d, want %d", page.Total, tt.total)
+			}
+		})
+	}
+}
+
+func TestBrewPageNavigation(t *testing.T) {
+	page := BrewQuery{PerPage: 2, Page: 2}.Apply(append(testBrews(), testBrews()...))
+	if page.TotalPages != 4 {
+		t.Errorf("TotalPages = %d, want 4", page.TotalPages)
+	}
+	if !page.HasPrev() || !page.HasNext() {
+		t.Errorf("middle page should have previous and next pages")
+	}
+
+	empty := BrewQuery{}.Apply(nil)
+	if empty.Page != 1 || empty.TotalPages != 1 || empty.HasNext() || empty.HasPrev() {
+		t.Errorf("empty list = %+v, want a single empty page", empty)
+	}
+}
+
+func TestParseBrewQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"everything", "sort=rating&order=asc&bean=b1&roaster=r1&brewer=w1&grinder=g1&min_rating=3&max_rating=9&from=2026-01-01&to=2026-02-01&page=2&per_page=50", false},
+		{"bad sort", "sort=temperature", true},
+		{"bad order", "order=up", true},
+		{"rating out of range", "min_rating=11", true},
+		{"per page too large", "per_page=1000", true},
+		{"bad date", "from=yesterday", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			q, err := ParseBrewQuery(values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBrewQuery(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			// Parsing the encoded query should give the same query back
+			again, err := ParseBrewQuery(q.Values())
+			if err != nil {
+				t.Fatalf("re-parse error = %v", err)
+			}
+			if again.Encode() != q.Encode() {
+				t.Errorf("round trip = %q, want %q", again.Encode(), q.Encode())
+			}
+		})
+	}
+}
+
+func TestBrewQueryEncodeOmitsDefaults(t *testing.T) {
+	q, _ := ParseBrewQuery(url.Values{"sort": {"date"}, "order": {"desc"}, "page": {"1"}, "per_page": {"20"}})
+	if got := q.Encode(); got != "" {
+		t.Errorf("Encode() = %q, want empty", got)
+	}
+}
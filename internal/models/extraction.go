@@ -0,0 +1,41 @@
+package models
+
+// SCA brewing control chart "ideal" region for filter coffee
+const (
+	IdealExtractionMin = 18.0 // percent
+	IdealExtractionMax = 22.0 // percent
+	IdealStrengthMin   = 1.15 // percent TDS
+	IdealStrengthMax   = 1.35 // percent TDS
+)
+
+// MaxTDS is the highest total dissolved solids reading accepted (percent).
+// Espresso tops out around 12-14%, so this leaves room for ristretto.
+const MaxTDS = 30.0
+
+// BrewRatio returns grams of water per gram of coffee (e.g. 16.7 for 1:16.7).
+// Returns 0 if either amount is missing.
+func (b *Brew) BrewRatio() float64 {
+	if b.CoffeeAmount <= 0 || b.WaterAmount <= 0 {
+		return 0
+	}
+	return float64(b.WaterAmount) / float64(b.CoffeeAmount)
+}
+
+// ExtractionYield returns the percentage of the dose that ended up in the cup,
+// computed as TDS% * beverage weight / dose. Returns 0 if any input is missing.
+func (b *Brew) ExtractionYield() float64 {
+	if b.TDS <= 0 || b.BeverageWeight <= 0 || b.CoffeeAmount <= 0 {
+		return 0
+	}
+	return b.TDS * float64(b.BeverageWeight) / float64(b.CoffeeAmount)
+}
+
+// IsIdealExtraction reports whether the brew lands in the control chart's ideal box
+func (b *Brew) IsIdealExtraction() bool {
+	ey := b.ExtractionYield()
+	if ey == 0 {
+		return false
+	}
+	return ey >= IdealExtractionMin && ey <= IdealExtractionMax &&
+		b.TDS >= IdealStrengthMin && b.TDS <= IdealStrengthMax
+}
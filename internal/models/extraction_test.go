@@ -0,0 +1,75 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBrew_BrewRatio(t *testing.T) {
+	tests := []struct {
+		name   string
+		coffee int
+		water  int
+		want   float64
+	}{
+		{"standard pour over", 15, 250, 250.0 / 15.0},
+		{"espresso style", 18, 36, 2},
+		{"missing coffee", 0, 250, 0},
+		{"missing water", 15, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Brew{CoffeeAmount: tt.coffee, WaterAmount: tt.water}
+			if got := b.BrewRatio(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("BrewRatio() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBrew_ExtractionYield(t *testing.T) {
+	tests := []struct {
+		name     string
+		coffee   int
+		tds      float64
+		beverage int
+		want     float64
+	}{
+		{"filter", 15, 1.35, 225, 20.25},
+		{"espresso", 18, 10, 36, 20},
+		{"missing tds", 15, 0, 225, 0},
+		{"missing beverage weight", 15, 1.35, 0, 0},
+		{"missing dose", 0, 1.35, 225, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Brew{CoffeeAmount: tt.coffee, TDS: tt.tds, BeverageWeight: tt.beverage}
+			if got := b.ExtractionYield(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("ExtractionYield() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBrew_IsIdealExtraction(t *testing.T) {
+	tests := []struct {
+		name string
+		brew Brew
+		want bool
+	}{
+		{"in the box", Brew{CoffeeAmount: 15, TDS: 1.3, BeverageWeight: 230}, true},
+		{"under extracted", Brew{CoffeeAmount: 15, TDS: 1.2, BeverageWeight: 200}, false},
+		{"too strong", Brew{CoffeeAmount: 15, TDS: 1.5, BeverageWeight: 210}, false},
+		{"no measurement", Brew{CoffeeAmount: 15}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.brew.IsIdealExtraction(); got != tt.want {
+				t.Errorf("IsIdealExtraction() = %v, want %v (EY %.2f)", got, tt.want, tt.brew.ExtractionYield())
+			}
+		})
+	}
+}
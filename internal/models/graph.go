@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxGraphAccounts caps each of a user's mute and block lists, keeping the
+// graph record well under PDS record size limits
+const MaxGraphAccounts = 5000
+
+var (
+	// ErrGraphDIDInvalid is returned when a muted or blocked account is not a DID
+	ErrGraphDIDInvalid = errors.New("muted and blocked accounts must be DIDs")
+
+	// ErrGraphTooLarge is returned when a mute or block list exceeds MaxGraphAccounts
+	ErrGraphTooLarge = errors.New("too many muted or blocked accounts")
+)
+
+// Graph holds the accounts a user muted or blocked, stored as a singleton
+// record in their repo. Muted accounts are left out of the user's community
+// feed. Blocked accounts are too, and the block also hides the user from them.
+type Graph struct {
+	Mutes  []string `json:"mutes"`
+	Blocks []string `json:"blocks"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Clone returns a copy whose lists can be changed without affecting g
+func (g *Graph) Clone() *Graph {
+	return &Graph{
+		Mutes:     slices.Clone(g.Mutes),
+		Blocks:    slices.Clone(g.Blocks),
+		UpdatedAt: g.UpdatedAt,
+	}
+}
+
+// IsMuted reports whether the user muted did
+func (g *Graph) IsMuted(did string) bool {
+	return g != nil && slices.Contains(g.Mutes, did)
+}
+
+// IsBlocked reports whether the user blocked did
+func (g *Graph) IsBlocked(did string) bool {
+	return g != nil && slices.Contains(g.Blocks, did)
+}
+
+// Hides reports whether records by did are left out of the user's feed
+func (g *Graph) Hides(did string) bool {
+	return g.IsMuted(did) || g.IsBlocked(did)
+}
+
+// Mute adds did to the mute list. Returns false if it was already muted.
+func (g *Graph) Mute(did string) bool {
+	if g.IsMuted(did) {
+		return false
+	}
+	g.Mutes = append(g.Mutes, did)
+	return true
+}
+
+// Unmute removes did from the mute list. Returns false if it wasn't muted.
+func (g *Graph) Unmute(did string) bool {
+	if !g.IsMuted(did) {
+		return false
+	}
+	g.Mutes = slices.DeleteFunc(g.Mutes, func(d string) bool { return d == did })
+	return true
+}
+
+// Block adds dids to the block list, skipping ones already blocked.
+// Returns how many were added.
+func (g *Graph) Block(dids ...string) int {
+	added := 0
+	for _, did := range dids {
+		if !g.IsBlocked(did) {
+			g.Blocks = append(g.Blocks, did)
+			added++
+		}
+	}
+	return added
+}
+
+// Unblock removes did from the block list. Returns false if it wasn't blocked.
+func (g *Graph) Unblock(did string) bool {
+	if !g.IsBlocked(did) {
+		return false
+	}
+	g.Blocks = slices.DeleteFunc(g.Blocks, func(d string) bool { return d == did })
+	return true
+}
+
+// Validate checks the graph before it is saved
+func (g *Graph) Validate() error {
+	if len(g.Mutes) > MaxGraphAccounts || len(g.Blocks) > MaxGraphAccounts {
+		return ErrGraphTooLarge
+	}
+	for _, did := range slices.Concat(g.Mutes, g.Blocks) {
+		if !strings.HasPrefix(did, "did:") {
+			return ErrGraphDIDInvalid
+		}
+	}
+	return nil
+}
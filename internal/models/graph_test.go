@@ -0,0 +1,77 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestGraphMuteAndBlock(t *testing.T) {
+	g := &Graph{}
+
+	if !g.Mute("did:plc:alice") || g.Mute("did:plc:alice") {
+		t.Errorf("Mute should add alice once, got mutes %v", g.Mutes)
+	}
+	if n := g.Block("did:plc:bob", "did:plc:carol", "did:plc:bob"); n != 2 {
+		t.Errorf("Block added %d, want 2", n)
+	}
+
+	tests := []struct {
+		did                 string
+		muted, blocked, hid bool
+	}{
+		{"did:plc:alice", true, false, true},
+		{"did:plc:bob", false, true, true},
+		{"did:plc:dave", false, false, false},
+	}
+	for _, tt := range tests {
+		if g.IsMuted(tt.did) != tt.muted || g.IsBlocked(tt.did) != tt.blocked || g.Hides(tt.did) != tt.hid {
+			t.Errorf("%s: muted %v blocked %v hides %v, want %v %v %v", tt.did,
+				g.IsMuted(tt.did), g.IsBlocked(tt.did), g.Hides(tt.did), tt.muted, tt.blocked, tt.hid)
+		}
+	}
+
+	clone := g.Clone()
+	clone.Block("did:plc:dave")
+	if g.IsBlocked("did:plc:dave") {
+		t.Error("changing a clone changed the original")
+	}
+
+	if !g.Unmute("did:plc:alice") || g.Unmute("did:plc:alice") {
+		t.Errorf("Unmute should remove alice once, got mutes %v", g.Mutes)
+	}
+	if !g.Unblock("did:plc:bob") || !slices.Equal(g.Blocks, []string{"did:plc:carol"}) {
+		t.Errorf("Unblock left blocks %v, want [did:plc:carol]", g.Blocks)
+	}
+
+	var none *Graph
+	if none.Hides("did:plc:alice") {
+		t.Error("nil graph should hide nobody")
+	}
+}
+
+func TestGraphValidate(t *testing.T) {
+	tooMany := make([]string, MaxGraphAccounts+1)
+	for i := range tooMany {
+		tooMany[i] = "did:plc:x"
+	}
+
+	tests := []struct {
+		name    string
+		graph   Graph
+		wantErr error
+	}{
+		{"empty", Graph{}, nil},
+		{"dids", Graph{Mutes: []string{"did:plc:alice"}, Blocks: []string{"did:web:example.com"}}, nil},
+		{"handle", Graph{Mutes: []string{"alice.bsky.social"}}, ErrGraphDIDInvalid},
+		{"too many", Graph{Blocks: tooMany}, ErrGraphTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.graph.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
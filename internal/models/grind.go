@@ -0,0 +1,222 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Grind scale units
+const (
+	GrindUnitClicks    = "clicks"
+	GrindUnitNumbers   = "numbers"
+	GrindUnitRotations = "rotations"
+	GrindUnitMicrons   = "microns"
+)
+
+// MaxCalibrationPoints limits how many micron calibration points a grinder can carry
+const MaxCalibrationPoints = 20
+
+// grindEpsilon absorbs float rounding when checking settings against a step
+const grindEpsilon = 1e-6
+
+// Grind validation errors
+var (
+	ErrGrindScaleInvalid   = errors.New("grind scale must have min < max and a positive step")
+	ErrGrindPrecision      = errors.New("grind scale and calibration settings can have at most one decimal place")
+	ErrGrindUnitInvalid    = errors.New("grind scale unit is invalid")
+	ErrCalibrationTooMany  = errors.New("too many calibration points")
+	ErrCalibrationInvalid  = errors.New("calibration points need a unique setting and a positive micron value")
+	ErrCalibrationNotOrder = errors.New("calibration microns must increase with the setting")
+	ErrGrindNotNumeric     = errors.New("grind size must be a number for this grinder")
+	ErrGrindOutOfRange     = errors.New("grind size is outside the grinder's range")
+	ErrGrindOffStep        = errors.New("grind size does not match the grinder's step")
+)
+
+// GrindScale describes the adjustment range of a grinder.
+// Values are in the grinder's own unit (e.g. clicks from zero, or dial numbers).
+type GrindScale struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Step float64 `json:"step"`
+	Unit string  `json:"unit"`
+}
+
+// CalibrationPoint maps a grinder setting to a measured or published particle size
+type CalibrationPoint struct {
+	Setting float64 `json:"setting"`
+	Microns int     `json:"microns"`
+}
+
+// IsValidGrindUnit reports whether unit is one of the known grind scale units
+func IsValidGrindUnit(unit string) bool {
+	switch unit {
+	case GrindUnitClicks, GrindUnitNumbers, GrindUnitRotations, GrindUnitMicrons:
+		return true
+	}
+	return false
+}
+
+// Validate checks that the scale describes a usable range
+func (s *GrindScale) Validate() error {
+	if s.Step <= 0 || s.Min < 0 || s.Min >= s.Max {
+		return ErrGrindScaleInvalid
+	}
+	if !isTenths(s.Min) || !isTenths(s.Max) || !isTenths(s.Step) {
+		return ErrGrindPrecision
+	}
+	if !IsValidGrindUnit(s.Unit) {
+		return ErrGrindUnitInvalid
+	}
+	return nil
+}
+
+// ParseSetting parses a grind size string and checks it against the scale.
+// Returns the numeric setting or one of the ErrGrind* errors.
+func (s *GrindScale) ParseSetting(grindSize string) (float64, error) {
+	setting, err := strconv.ParseFloat(strings.TrimSpace(grindSize), 64)
+	if err != nil || math.IsNaN(setting) || math.IsInf(setting, 0) {
+		return 0, ErrGrindNotNumeric
+	}
+	if setting < s.Min-grindEpsilon || setting > s.Max+grindEpsilon {
+		return 0, fmt.Errorf("%w (%s–%s)", ErrGrindOutOfRange, FormatGrindSetting(s.Min), FormatGrindSetting(s.Max))
+	}
+	steps := (setting - s.Min) / s.Step
+	if math.Abs(steps-math.Round(steps)) > grindEpsilon {
+		return 0, fmt.Errorf("%w (%s)", ErrGrindOffStep, FormatGrindSetting(s.Step))
+	}
+	return setting, nil
+}
+
+// Snap rounds a setting to the nearest step and clamps it to the range
+func (s *GrindScale) Snap(setting float64) float64 {
+	snapped := s.Min + math.Round((setting-s.Min)/s.Step)*s.Step
+	return math.Max(s.Min, math.Min(s.Max, snapped))
+}
+
+// isTenths reports whether v is a whole number of tenths. Records store scale
+// and calibration settings in tenths, so finer values wouldn't survive saving.
+func isTenths(v float64) bool {
+	return math.Abs(v*10-math.Round(v*10)) < grindEpsilon
+}
+
+// FormatGrindSetting formats a setting without trailing zeros (18 -> "18", 3.5 -> "3.5")
+func FormatGrindSetting(setting float64) string {
+	return strconv.FormatFloat(math.Round(setting*10)/10, 'f', -1, 64)
+}
+
+// ValidateCalibration checks calibration points against an optional scale.
+// Points must have distinct settings and microns that increase with the setting.
+func ValidateCalibration(points []CalibrationPoint, scale *GrindScale) error {
+	if len(points) > MaxCalibrationPoints {
+		return ErrCalibrationTooMany
+	}
+	sorted := sortedCalibration(points)
+	for i, p := range sorted {
+		if p.Microns <= 0 || p.Setting < 0 {
+			return ErrCalibrationInvalid
+		}
+		if !isTenths(p.Setting) {
+			return ErrGrindPrecision
+		}
+		if scale != nil && (p.Setting < scale.Min-grindEpsilon || p.Setting > scale.Max+grindEpsilon) {
+			return ErrGrindOutOfRange
+		}
+		if i > 0 {
+			if p.Setting == sorted[i-1].Setting {
+				return ErrCalibrationInvalid
+			}
+			if p.Microns <= sorted[i-1].Microns {
+				return ErrCalibrationNotOrder
+			}
+		}
+	}
+	return nil
+}
+
+// IsCalibrated reports whether the grinder can convert settings to microns
+func (g *Grinder) IsCalibrated() bool {
+	if len(g.Calibration) >= 2 {
+		return true
+	}
+	return g.Scale != nil && g.Scale.Unit == GrindUnitMicrons
+}
+
+// SettingToMicrons estimates the particle size for a setting by linear
+// interpolation between calibration points, extrapolating from the nearest
+// segment outside the calibrated range.
+func (g *Grinder) SettingToMicrons(setting float64) (float64, bool) {
+	if len(g.Calibration) < 2 {
+		if g.Scale != nil && g.Scale.Unit == GrindUnitMicrons {
+			return setting, true
+		}
+		return 0, false
+	}
+	points := sortedCalibration(g.Calibration)
+	lo, hi := segmentFor(len(points), func(i int) float64 { return points[i].Setting }, setting)
+	a, b := points[lo], points[hi]
+	microns := lerp(setting, a.Setting, b.Setting, float64(a.Microns), float64(b.Microns))
+	return math.Max(0, microns), true
+}
+
+// MicronsToSetting finds the setting expected to produce the given particle
+// size. The result is snapped to the grinder's scale when one is defined.
+func (g *Grinder) MicronsToSetting(microns float64) (float64, bool) {
+	var setting float64
+	if len(g.Calibration) < 2 {
+		if g.Scale == nil || g.Scale.Unit != GrindUnitMicrons {
+			return 0, false
+		}
+		setting = microns
+	} else {
+		points := sortedCalibration(g.Calibration)
+		lo, hi := segmentFor(len(points), func(i int) float64 { return float64(points[i].Microns) }, microns)
+		a, b := points[lo], points[hi]
+		setting = lerp(microns, float64(a.Microns), float64(b.Microns), a.Setting, b.Setting)
+	}
+	if g.Scale != nil {
+		setting = g.Scale.Snap(setting)
+	}
+	return math.Max(0, setting), true
+}
+
+// EquivalentSetting converts a setting on one grinder to the closest setting
+// on another, going through their micron calibrations.
+func EquivalentSetting(from *Grinder, setting float64, to *Grinder) (float64, bool) {
+	microns, ok := from.SettingToMicrons(setting)
+	if !ok {
+		return 0, false
+	}
+	return to.MicronsToSetting(microns)
+}
+
+// sortedCalibration returns a copy of points ordered by setting
+func sortedCalibration(points []CalibrationPoint) []CalibrationPoint {
+	sorted := make([]CalibrationPoint, len(points))
+	copy(sorted, points)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Setting < sorted[j].Setting })
+	return sorted
+}
+
+// segmentFor picks the pair of neighbouring indices whose keys bracket x,
+// falling back to the first or last segment when x is outside the range.
+// Keys must be ascending and n must be at least 2.
+func segmentFor(n int, key func(int) float64, x float64) (int, int) {
+	for i := 1; i < n-1; i++ {
+		if x < key(i) {
+			return i - 1, i
+		}
+	}
+	return n - 2, n - 1
+}
+
+// lerp maps x from the range [x0, x1] onto [y0, y1]
+func lerp(x, x0, x1, y0, y1 float64) float64 {
+	if x1 == x0 {
+		return y0
+	}
+	return y0 + (x-x0)*(y1-y0)/(x1-x0)
+}
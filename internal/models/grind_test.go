@@ -0,0 +1,193 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestGrindScale_ParseSetting(t *testing.T) {
+	scale := &GrindScale{Min: 0, Max: 40, Step: 0.5, Unit: GrindUnitClicks}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    float64
+		wantErr error
+	}{
+		{"whole number", "18", 18, nil},
+		{"half step", "3.5", 3.5, nil},
+		{"surrounding whitespace", " 12 ", 12, nil},
+		{"minimum", "0", 0, nil},
+		{"maximum", "40", 40, nil},
+		{"description", "Medium", 0, ErrGrindNotNumeric},
+		{"above range", "41", 0, ErrGrindOutOfRange},
+		{"below range", "-1", 0, ErrGrindOutOfRange},
+		{"off step", "3.3", 0, ErrGrindOffStep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scale.ParseSetting(tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseSetting(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSetting(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrindScale_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		scale   GrindScale
+		wantErr error
+	}{
+		{"valid", GrindScale{Min: 0, Max: 40, Step: 1, Unit: GrindUnitClicks}, nil},
+		{"zero step", GrindScale{Min: 0, Max: 40, Step: 0, Unit: GrindUnitClicks}, ErrGrindScaleInvalid},
+		{"inverted range", GrindScale{Min: 40, Max: 0, Step: 1, Unit: GrindUnitClicks}, ErrGrindScaleInvalid},
+		{"unknown unit", GrindScale{Min: 0, Max: 40, Step: 1, Unit: "furlongs"}, ErrGrindUnitInvalid},
+		{"tenths", GrindScale{Min: 0.5, Max: 9.9, Step: 0.1, Unit: GrindUnitNumbers}, nil},
+		{"quarter step", GrindScale{Min: 0, Max: 40, Step: 0.25, Unit: GrindUnitClicks}, ErrGrindPrecision},
+		{"hundredth step", GrindScale{Min: 0, Max: 40, Step: 0.01, Unit: GrindUnitClicks}, ErrGrindPrecision},
+		{"hundredths max", GrindScale{Min: 0, Max: 40.05, Step: 1, Unit: GrindUnitClicks}, ErrGrindPrecision},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.scale.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCalibration(t *testing.T) {
+	scale := &GrindScale{Min: 0, Max: 40, Step: 1, Unit: GrindUnitClicks}
+
+	tests := []struct {
+		name    string
+		points  []CalibrationPoint
+		wantErr error
+	}{
+		{"empty", nil, nil},
+		{"unordered input", []CalibrationPoint{{Setting: 30, Microns: 900}, {Setting: 10, Microns: 400}}, nil},
+		{"duplicate setting", []CalibrationPoint{{Setting: 10, Microns: 400}, {Setting: 10, Microns: 500}}, ErrCalibrationInvalid},
+		{"zero microns", []CalibrationPoint{{Setting: 10, Microns: 0}}, ErrCalibrationInvalid},
+		{"microns decrease", []CalibrationPoint{{Setting: 10, Microns: 600}, {Setting: 20, Microns: 500}}, ErrCalibrationNotOrder},
+		{"outside scale", []CalibrationPoint{{Setting: 50, Microns: 600}}, ErrGrindOutOfRange},
+		{"hundredths setting", []CalibrationPoint{{Setting: 12.25, Microns: 600}}, ErrGrindPrecision},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateCalibration(tt.points, scale); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateCalibration() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGrinder_SettingToMicrons(t *testing.T) {
+	grinder := &Grinder{
+		Calibration: []CalibrationPoint{
+			{Setting: 20, Microns: 600},
+			{Setting: 10, Microns: 300},
+			{Setting: 30, Microns: 1000},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		setting float64
+		want    float64
+	}{
+		{"on a point", 20, 600},
+		{"between points", 15, 450},
+		{"upper segment", 25, 800},
+		{"extrapolate below", 5, 150},
+		{"extrapolate above", 35, 1200},
+		{"clamped at zero", -20, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := grinder.SettingToMicrons(tt.setting)
+			if !ok {
+				t.Fatal("SettingToMicrons() reported uncalibrated grinder")
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("SettingToMicrons(%v) = %v, want %v", tt.setting, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("uncalibrated", func(t *testing.T) {
+		g := &Grinder{Calibration: []CalibrationPoint{{Setting: 10, Microns: 300}}}
+		if _, ok := g.SettingToMicrons(10); ok {
+			t.Error("SettingToMicrons() should fail with a single calibration point")
+		}
+	})
+
+	t.Run("micron scale needs no calibration", func(t *testing.T) {
+		g := &Grinder{Scale: &GrindScale{Min: 100, Max: 1400, Step: 10, Unit: GrindUnitMicrons}}
+		got, ok := g.SettingToMicrons(550)
+		if !ok || got != 550 {
+			t.Errorf("SettingToMicrons(550) = %v, %v, want 550, true", got, ok)
+		}
+	})
+}
+
+func TestEquivalentSetting(t *testing.T) {
+	comandante := &Grinder{
+		Scale: &GrindScale{Min: 0, Max: 50, Step: 1, Unit: GrindUnitClicks},
+		Calibration: []CalibrationPoint{
+			{Setting: 10, Microns: 300},
+			{Setting: 30, Microns: 900},
+		},
+	}
+	jx := &Grinder{
+		Scale: &GrindScale{Min: 0, Max: 40, Step: 0.5, Unit: GrindUnitNumbers},
+		Calibration: []CalibrationPoint{
+			{Setting: 5, Microns: 300},
+			{Setting: 25, Microns: 1100},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		from    *Grinder
+		setting float64
+		to      *Grinder
+		want    float64
+	}{
+		// 20 clicks = 600µm; JX is 40µm per number from 5 @ 300µm -> 12.5
+		{"exact step", comandante, 20, jx, 12.5},
+		// 21 clicks = 630µm -> 13.25, snapped to the 0.5 step
+		{"snapped to step", comandante, 21, jx, 13.5},
+		// 12.5 on the JX = 600µm -> 20 clicks
+		{"reverse direction", jx, 12.5, comandante, 20},
+		// 40 on the JX = 1700µm, far past the Comandante's max of 50 clicks
+		{"clamped to max", jx, 40, comandante, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := EquivalentSetting(tt.from, tt.setting, tt.to)
+			if !ok {
+				t.Fatal("EquivalentSetting() reported uncalibrated grinder")
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("EquivalentSetting(%v) = %v, want %v", tt.setting, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("target not calibrated", func(t *testing.T) {
+		if _, ok := EquivalentSetting(comandante, 20, &Grinder{}); ok {
+			t.Error("EquivalentSetting() should fail when the target has no calibration")
+		}
+	})
+}
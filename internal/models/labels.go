@@ -0,0 +1,104 @@
+package models
+
+import (
+	"errors"
+	"slices"
+)
+
+// Label values with a fixed meaning across the network. Values starting with
+// "!" are imperative and apply to everyone regardless of preferences.
+const (
+	LabelHide         = "!hide"
+	LabelWarn         = "!warn"
+	LabelPorn         = "porn"
+	LabelSexual       = "sexual"
+	LabelNudity       = "nudity"
+	LabelGraphicMedia = "graphic-media"
+)
+
+// How labeled content is shown
+const (
+	LabelShow = "show" // Shown as if it had no label
+	LabelBlur = "warn" // Blurred behind a warning that can be dismissed
+	LabelDrop = "hide" // Left out entirely
+)
+
+// ErrLabelVisibilityInvalid is returned when a label preference is not a known visibility
+var ErrLabelVisibilityInvalid = errors.New("label visibility is invalid")
+
+// AdultLabels are the labels users can choose how to see, in display order
+var AdultLabels = []string{LabelPorn, LabelSexual, LabelNudity, LabelGraphicMedia}
+
+// defaultLabelVisibility matches the defaults of the main atproto apps
+var defaultLabelVisibility = map[string]string{
+	LabelPorn:         LabelDrop,
+	LabelSexual:       LabelBlur,
+	LabelNudity:       LabelShow,
+	LabelGraphicMedia: LabelBlur,
+}
+
+// LabelPreferences maps adult label values to how a user wants to see content
+// carrying them. Missing entries use the defaults, so nil is valid.
+type LabelPreferences map[string]string
+
+// Visibility returns how content with the label should be shown. Imperative
+// labels can't be overridden, and labels other than the adult ones, such as a
+// labeler's own, show a warning.
+func (p LabelPreferences) Visibility(label string) string {
+	switch label {
+	case LabelHide:
+		return LabelDrop
+	case LabelWarn:
+		return LabelBlur
+	}
+	if v, ok := p[label]; ok {
+		return v
+	}
+	if v, ok := defaultLabelVisibility[label]; ok {
+		return v
+	}
+	return LabelBlur
+}
+
+// Decide returns the strictest visibility among labels, and the labels that
+// caused it. Content without labels is shown.
+func (p LabelPreferences) Decide(labels []string) (string, []string) {
+	visibility := LabelShow
+	var reasons []string
+	for _, label := range labels {
+		v := p.Visibility(label)
+		switch {
+		case labelRank(v) > labelRank(visibility):
+			visibility = v
+			reasons = []string{label}
+		case v == visibility && v != LabelShow && !slices.Contains(reasons, label):
+			reasons = append(reasons, label)
+		}
+	}
+	return visibility, reasons
+}
+
+// Validate checks the preferences before they are saved
+func (p LabelPreferences) Validate() error {
+	for label, v := range p {
+		if !slices.Contains(AdultLabels, label) {
+			return ErrLabelVisibilityInvalid
+		}
+		switch v {
+		case LabelShow, LabelBlur, LabelDrop:
+		default:
+			return ErrLabelVisibilityInvalid
+		}
+	}
+	return nil
+}
+
+func labelRank(visibility string) int {
+	switch visibility {
+	case LabelDrop:
+		return 2
+	case LabelBlur:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLabelPreferencesDecide(t *testing.T) {
+	tests := []struct {
+		name        string
+		prefs       LabelPreferences
+		labels      []string
+		want        string
+		wantReasons []string
+	}{
+		{"no labels", nil, nil, LabelShow, nil},
+		{"default hides porn", nil, []string{LabelPorn}, LabelDrop, []string{LabelPorn}},
+		{"default shows nudity", nil, []string{LabelNudity}, LabelShow, nil},
+		{"preference overrides default", LabelPreferences{LabelPorn: LabelBlur}, []string{LabelPorn}, LabelBlur, []string{LabelPorn}},
+		{"hide can't be overridden", LabelPreferences{LabelPorn: LabelShow}, []string{LabelHide}, LabelDrop, []string{LabelHide}},
+		{"unknown label warns", nil, []string{"spam"}, LabelBlur, []string{"spam"}},
+		{"strictest wins", nil, []string{LabelSexual, LabelPorn, LabelGraphicMedia}, LabelDrop, []string{LabelPorn}},
+		{"reasons collected", nil, []string{LabelSexual, LabelGraphicMedia, LabelSexual}, LabelBlur, []string{LabelSexual, LabelGraphicMedia}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reasons := tt.prefs.Decide(tt.labels)
+			if got != tt.want || !slices.Equal(reasons, tt.wantReasons) {
+				t.Errorf("Decide(%v) = %q, %v, want %q, %v", tt.labels, got, reasons, tt.want, tt.wantReasons)
+			}
+		})
+	}
+}
+
+func TestLabelPreferencesValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefs   LabelPreferences
+		wantErr bool
+	}{
+		{"empty", nil, false},
+		{"valid", LabelPreferences{LabelPorn: LabelShow, LabelNudity: LabelDrop}, false},
+		{"imperative label", LabelPreferences{LabelHide: LabelShow}, true},
+		{"bad visibility", LabelPreferences{LabelSexual: "blur"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.prefs.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -63,6 +63,10 @@ type Grinder struct {
 	BurrType    string    `json:"burr_type"`    // Conical, Flat, Blade, or empty
 	Notes       string    `json:"notes"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	// Optional grind setting scale and micron calibration
+	Scale       *GrindScale        `json:"scale,omitempty"`
+	Calibration []CalibrationPoint `json:"calibration,omitempty"`
 }
 
 type Brewer struct {
@@ -95,6 +99,10 @@ type Brew struct {
 	Rating       int       `json:"rating"`
 	CreatedAt    time.Time `json:"created_at"`
 
+	// Optional refractometer measurement
+	TDS            float64 `json:"tds"`             // Total dissolved solids, percent
+	BeverageWeight int     `json:"beverage_weight"` // Grams of brewed coffee
+
 	// Joined data for display
 	Bean       *Bean    `json:"bean,omitempty"`
 	GrinderObj *Grinder `json:"grinder_obj,omitempty"`
@@ -115,6 +123,9 @@ type CreateBrewRequest struct {
 	TastingNotes string           `json:"tasting_notes"`
 	Rating       int              `json:"rating"`
 	Pours        []CreatePourData `json:"pours"`
+
+	TDS            float64 `json:"tds"`
+	BeverageWeight int     `json:"beverage_weight"`
 }
 
 type CreatePourData struct {
@@ -138,10 +149,12 @@ type CreateRoasterRequest struct {
 }
 
 type CreateGrinderRequest struct {
-	Name        string `json:"name"`
-	GrinderType string `json:"grinder_type"`
-	BurrType    string `json:"burr_type"`
-	Notes       string `json:"notes"`
+	Name        string             `json:"name"`
+	GrinderType string             `json:"grinder_type"`
+	BurrType    string             `json:"burr_type"`
+	Notes       string             `json:"notes"`
+	Scale       *GrindScale        `json:"scale,omitempty"`
+	Calibration []CalibrationPoint `json:"calibration,omitempty"`
 }
 
 type CreateBrewerRequest struct {
@@ -166,10 +179,12 @@ type UpdateRoasterRequest struct {
 }
 
 type UpdateGrinderRequest struct {
-	Name        string `json:"name"`
-	GrinderType string `json:"grinder_type"`
-	BurrType    string `json:"burr_type"`
-	Notes       string `json:"notes"`
+	Name        string             `json:"name"`
+	GrinderType string             `json:"grinder_type"`
+	BurrType    string             `json:"burr_type"`
+	Notes       string             `json:"notes"`
+	Scale       *GrindScale        `json:"scale,omitempty"`
+	Calibration []CalibrationPoint `json:"calibration,omitempty"`
 }
 
 type UpdateBrewerRequest struct {
@@ -275,7 +290,12 @@ func (r *CreateGrinderRequest) Validate() error {
 	if len(r.Notes) > MaxNotesLength {
 		return ErrNotesTooLong
 	}
-	return nil
+	if r.Scale != nil {
+		if err := r.Scale.Validate(); err != nil {
+			return err
+		}
+	}
+	return ValidateCalibration(r.Calibration, r.Scale)
 }
 
 // Validate checks that all fields are within acceptable limits
@@ -295,7 +315,12 @@ func (r *UpdateGrinderRequest) Validate() error {
 	if len(r.Notes) > MaxNotesLength {
 		return ErrNotesTooLong
 	}
-	return nil
+	if r.Scale != nil {
+		if err := r.Scale.Validate(); err != nil {
+			return err
+		}
+	}
+	return ValidateCalibration(r.Calibration, r.Scale)
 }
 
 // Validate checks that all fields are within acceptable limits
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// View modes for brew lists and the feed
+const (
+	ViewModePosts = "posts" // Mobile-friendly post cards, the default
+	ViewModeTable = "table" // Legacy table layout
+)
+
+// ErrViewModeInvalid is returned when a settings view mode is not a known mode
+var ErrViewModeInvalid = errors.New("view mode is invalid")
+
+// Settings are a user's app settings, stored as a singleton record in their repo.
+// Records in a repo are public, so only settings that are fine to publish and
+// that should follow the user to other instances belong here. Unit and content
+// filtering preferences stay in this server's database instead (see
+// boltstore.PreferencesStore): which adult labels someone chooses to see is
+// nobody else's business, and units are read on nearly every page, where a PDS
+// round trip per request would be too slow.
+type Settings struct {
+	// PrivateMode keeps the user out of the community feed and community search.
+	// Their records are still public through the PDS.
+	PrivateMode bool `json:"private_mode"`
+
+	// DevMode shows DIDs and other protocol details in the UI
+	DevMode bool `json:"dev_mode"`
+
+	// ViewMode is how brew lists and the feed are shown, one of the ViewMode constants
+	ViewMode string `json:"view_mode"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DefaultSettings returns the settings used until a user saves their own
+func DefaultSettings() *Settings {
+	return &Settings{ViewMode: ViewModePosts}
+}
+
+// Validate checks the settings before they are saved
+func (s *Settings) Validate() error {
+	switch s.ViewMode {
+	case "", ViewModeTable, ViewModePosts:
+	default:
+		return ErrViewModeInvalid
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+// Brews are stored in canonical units: temperature in °C, coffee and beverage
+// weight in grams, water in grams (1 g of water is taken as 1 ml). Unit
+// preferences only change how values are entered and displayed.
+
+// Temperature units
+const (
+	TempUnitCelsius    = "C"
+	TempUnitFahrenheit = "F"
+)
+
+// Weight units, used for the coffee dose and beverage weight
+const (
+	WeightUnitGrams  = "g"
+	WeightUnitOunces = "oz"
+)
+
+// Volume units, used for water and pours
+const (
+	VolumeUnitMilliliters = "ml"
+	VolumeUnitFluidOunces = "floz"
+)
+
+// Unit systems, presets for all three units at once
+const (
+	UnitSystemMetric   = "metric"
+	UnitSystemImperial = "imperial"
+	UnitSystemCustom   = "custom" // A mix that matches neither preset
+)
+
+// Conversion factors
+const (
+	GramsPerOunce            = 28.349523125
+	MillilitersPerFluidOunce = 29.5735295625
+)
+
+// ErrUnitInvalid is returned when a unit preference is not a known unit
+var ErrUnitInvalid = errors.New("unit is invalid")
+
+// UnitPreferences is a user's choice of display and input units.
+// Empty fields fall back to metric, so the zero value is metric.
+type UnitPreferences struct {
+	Temperature string `json:"temperature,omitempty"`
+	Weight      string `json:"weight,omitempty"`
+	Volume      string `json:"volume,omitempty"`
+}
+
+// MetricUnits returns °C, grams and milliliters
+func MetricUnits() UnitPreferences {
+	return UnitPreferences{
+		Temperature: TempUnitCelsius,
+		Weight:      WeightUnitGrams,
+		Volume:      VolumeUnitMilliliters,
+	}
+}
+
+// ImperialUnits returns °F, ounces and fluid ounces
+func ImperialUnits() UnitPreferences {
+	return UnitPreferences{
+		Temperature: TempUnitFahrenheit,
+		Weight:      WeightUnitOunces,
+		Volume:      VolumeUnitFluidOunces,
+	}
+}
+
+// UnitsForSystem returns the preset for a unit system name
+func UnitsForSystem(system string) (UnitPreferences, bool) {
+	switch system {
+	case UnitSystemMetric:
+		return MetricUnits(), true
+	case UnitSystemImperial:
+		return ImperialUnits(), true
+	default:
+		return UnitPreferences{}, false
+	}
+}
+
+// Validate checks that every set unit is known
+func (u UnitPreferences) Validate() error {
+	switch u.Temperature {
+	case "", TempUnitCelsius, TempUnitFahrenheit:
+	default:
+		return ErrUnitInvalid
+	}
+	switch u.Weight {
+	case "", WeightUnitGrams, WeightUnitOunces:
+	default:
+		return ErrUnitInvalid
+	}
+	switch u.Volume {
+	case "", VolumeUnitMilliliters, VolumeUnitFluidOunces:
+	default:
+		return ErrUnitInvalid
+	}
+	return nil
+}
+
+// Normalize fills empty fields with their metric defaults
+func (u UnitPreferences) Normalize() UnitPreferences {
+	m := MetricUnits()
+	if u.Temperature == "" {
+		u.Temperature = m.Temperature
+	}
+	if u.Weight == "" {
+		u.Weight = m.Weight
+	}
+	if u.Volume == "" {
+		u.Volume = m.Volume
+	}
+	return u
+}
+
+// System returns the preset the preferences match, or UnitSystemCustom
+func (u UnitPreferences) System() string {
+	switch u.Normalize() {
+	case MetricUnits():
+		return UnitSystemMetric
+	case ImperialUnits():
+		return UnitSystemImperial
+	default:
+		return UnitSystemCustom
+	}
+}
+
+// Fahrenheit reports whether temperatures are shown in °F
+func (u UnitPreferences) Fahrenheit() bool {
+	return u.Temperature == TempUnitFahrenheit
+}
+
+// Ounces reports whether weights are shown in ounces
+func (u UnitPreferences) Ounces() bool {
+	return u.Weight == WeightUnitOunces
+}
+
+// FluidOunces reports whether volumes are shown in fluid ounces
+func (u UnitPreferences) FluidOunces() bool {
+	return u.Volume == VolumeUnitFluidOunces
+}
+
+// CelsiusToFahrenheit converts °C to °F
+func CelsiusToFahrenheit(c float64) float64 {
+	return c*9/5 + 32
+}
+
+// FahrenheitToCelsius converts °F to °C
+func FahrenheitToCelsius(f float64) float64 {
+	return (f - 32) * 5 / 9
+}
+
+// ========== Labels ==========
+
+// TemperatureLabel returns the temperature unit symbol (e.g. "°C")
+func (u UnitPreferences) TemperatureLabel() string {
+	if u.Fahrenheit() {
+		return "°F"
+	}
+	return "°C"
+}
+
+// WeightLabel returns the weight unit symbol (e.g. "g")
+func (u UnitPreferences) WeightLabel() string {
+	if u.Ounces() {
+		return "oz"
+	}
+	return "g"
+}
+
+// VolumeLabel returns the volume unit symbol (e.g. "ml")
+func (u UnitPreferences) VolumeLabel() string {
+	if u.FluidOunces() {
+		return "fl oz"
+	}
+	return "ml"
+}
+
+// GramsPerWeightUnit returns how many grams one preferred weight unit is
+func (u UnitPreferences) GramsPerWeightUnit() float64 {
+	if u.Ounces() {
+		return GramsPerOunce
+	}
+	return 1
+}
+
+// MillilitersPerVolumeUnit returns how many milliliters one preferred volume unit is
+func (u UnitPreferences) MillilitersPerVolumeUnit() float64 {
+	if u.FluidOunces() {
+		return MillilitersPerFluidOunce
+	}
+	return 1
+}
+
+// ========== Canonical to preferred ==========
+
+// TemperatureValue converts °C to the preferred temperature unit
+func (u UnitPreferences) TemperatureValue(celsius float64) float64 {
+	if u.Fahrenheit() {
+		return CelsiusToFahrenheit(celsius)
+	}
+	return celsius
+}
+
+// WeightValue converts grams to the preferred weight unit
+func (u UnitPreferences) WeightValue(grams int) float64 {
+	if u.Ounces() {
+		return float64(grams) / GramsPerOunce
+	}
+	return float64(grams)
+}
+
+// VolumeValue converts milliliters to the preferred volume unit
+func (u UnitPreferences) VolumeValue(ml int) float64 {
+	if u.FluidOunces() {
+		return float64(ml) / MillilitersPerFluidOunce
+	}
+	return float64(ml)
+}
+
+// ========== Preferred to canonical ==========
+
+// TemperatureToCelsius converts a temperature entered in the preferred unit
+// to °C, rounded to the tenth of a degree the lexicon stores
+func (u UnitPreferences) TemperatureToCelsius(value float64) float64 {
+	if u.Fahrenheit() {
+		value = FahrenheitToCelsius(value)
+	}
+	return roundTo(value, 1)
+}
+
+// WeightToGrams converts a weight entered in the preferred unit to whole grams
+func (u UnitPreferences) WeightToGrams(value float64) int {
+	if u.Ounces() {
+		value *= GramsPerOunce
+	}
+	return int(math.Round(value))
+}
+
+// VolumeToMilliliters converts a volume entered in the preferred unit to whole milliliters
+func (u UnitPreferences) VolumeToMilliliters(value float64) int {
+	if u.FluidOunces() {
+		value *= MillilitersPerFluidOunce
+	}
+	return int(math.Round(value))
+}
+
+// ========== Display ==========
+
+// FormatTemperature formats a °C value in the preferred unit (e.g. "93.5°C").
+// Returns "N/A" if the temperature is 0.
+func (u UnitPreferences) FormatTemperature(celsius float64) string {
+	if celsius == 0 {
+		return "N/A"
+	}
+	return strconv.FormatFloat(u.TemperatureValue(celsius), 'f', 1, 64) + u.TemperatureLabel()
+}
+
+// FormatWeight formats a gram value in the preferred unit (e.g. "18g" or "0.63 oz")
+func (u UnitPreferences) FormatWeight(grams int) string {
+	if u.Ounces() {
+		return u.WeightInput(grams) + " oz"
+	}
+	return strconv.Itoa(grams) + "g"
+}
+
+// FormatVolume formats a milliliter value in the preferred unit (e.g. "250ml" or "8.5 fl oz")
+func (u UnitPreferences) FormatVolume(ml int) string {
+	if u.FluidOunces() {
+		return strconv.FormatFloat(roundTo(u.VolumeValue(ml), 1), 'f', -1, 64) + " fl oz"
+	}
+	return strconv.Itoa(ml) + "ml"
+}
+
+// ========== Form inputs ==========
+
+// TemperatureInput formats a °C value for a form field in the preferred unit
+func (u UnitPreferences) TemperatureInput(celsius float64) string {
+	return strconv.FormatFloat(roundTo(u.TemperatureValue(celsius), 1), 'f', 1, 64)
+}
+
+// WeightInput formats a gram value for a form field in the preferred unit
+func (u UnitPreferences) WeightInput(grams int) string {
+	return strconv.FormatFloat(roundTo(u.WeightValue(grams), 2), 'f', -1, 64)
+}
+
+// VolumeInput formats a milliliter value for a form field in the preferred unit
+func (u UnitPreferences) VolumeInput(ml int) string {
+	return strconv.FormatFloat(roundTo(u.VolumeValue(ml), 2), 'f', -1, 64)
+}
+
+// WeightStep is the input step for weights; ounces need hundredths to reach whole grams
+func (u UnitPreferences) WeightStep() string {
+	if u.Ounces() {
+		return "0.01"
+	}
+	return "0.1"
+}
+
+// VolumeStep is the input step for volumes; fluid ounces need hundredths to reach whole milliliters
+func (u UnitPreferences) VolumeStep() string {
+	if u.FluidOunces() {
+		return "0.01"
+	}
+	return "1"
+}
+
+// roundTo rounds v to the given number of decimal places
+func roundTo(v float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(v*scale) / scale
+}
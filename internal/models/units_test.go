@@ -0,0 +1,112 @@
+package models
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestUnitPreferencesSystem(t *testing.T) {
+	tests := []struct {
+		name  string
+		units UnitPreferences
+		want  string
+	}{
+		{"zero value is metric", UnitPreferences{}, UnitSystemMetric},
+		{"metric", MetricUnits(), UnitSystemMetric},
+		{"imperial", ImperialUnits(), UnitSystemImperial},
+		{"mixed", UnitPreferences{Temperature: TempUnitFahrenheit}, UnitSystemCustom},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.units.System(); got != tt.want {
+				t.Errorf("System() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnitPreferencesValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		units   UnitPreferences
+		wantErr bool
+	}{
+		{"empty", UnitPreferences{}, false},
+		{"imperial", ImperialUnits(), false},
+		{"bad temperature", UnitPreferences{Temperature: "K"}, true},
+		{"bad weight", UnitPreferences{Weight: "lb"}, true},
+		{"bad volume", UnitPreferences{Volume: "cup"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.units.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUnitPreferencesFormat(t *testing.T) {
+	metric, imperial := MetricUnits(), ImperialUnits()
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"celsius", metric.FormatTemperature(93.5), "93.5°C"},
+		{"fahrenheit", imperial.FormatTemperature(93.5), "200.3°F"},
+		{"zero temperature", imperial.FormatTemperature(0), "N/A"},
+		{"grams", metric.FormatWeight(18), "18g"},
+		{"ounces", imperial.FormatWeight(18), "0.63 oz"},
+		{"milliliters", metric.FormatVolume(250), "250ml"},
+		{"fluid ounces", imperial.FormatVolume(250), "8.5 fl oz"},
+		{"temperature input", imperial.TemperatureInput(100), "212.0"},
+		{"weight input", metric.WeightInput(18), "18"},
+		{"volume input", imperial.VolumeInput(300), "10.14"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnitPreferencesRoundTrip(t *testing.T) {
+	imperial := ImperialUnits()
+
+	if got := imperial.TemperatureToCelsius(200); got != 93.3 {
+		t.Errorf("TemperatureToCelsius(200°F) = %v, want 93.3", got)
+	}
+	if got := imperial.WeightToGrams(0.63); got != 18 {
+		t.Errorf("WeightToGrams(0.63oz) = %d, want 18", got)
+	}
+	if got := imperial.VolumeToMilliliters(8.5); got != 251 {
+		t.Errorf("VolumeToMilliliters(8.5floz) = %d, want 251", got)
+	}
+
+	// Values shown in a form should come back unchanged when resubmitted
+	parse := func(s string) float64 {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	for n := 1; n <= 1000; n++ {
+		if got := imperial.WeightToGrams(parse(imperial.WeightInput(n))); got != n {
+			t.Fatalf("weight %dg round trips to %dg", n, got)
+		}
+		if got := imperial.VolumeToMilliliters(parse(imperial.VolumeInput(n))); got != n {
+			t.Fatalf("volume %dml round trips to %dml", n, got)
+		}
+		celsius := float64(n) / 10
+		if got := imperial.TemperatureToCelsius(parse(imperial.TemperatureInput(celsius))); got != celsius {
+			t.Fatalf("temperature %v°C round trips to %v°C", celsius, got)
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 
 	"arabica/internal/atproto"
 	"arabica/internal/handlers"
+	"arabica/internal/health"
 	"arabica/internal/middleware"
+	"arabica/internal/static"
 
 	"github.com/rs/zerolog"
 )
@@ -14,6 +16,9 @@ import (
 type Config struct {
 	Handlers     *handlers.Handler
 	OAuthManager *atproto.OAuthManager
+	Assets       *static.Assets
+	Health       *health.Checker
+	RateLimits   *middleware.RateLimitConfig
 	Logger       zerolog.Logger
 }
 
@@ -30,14 +35,19 @@ func SetupRouter(cfg Config) http.Handler {
 	mux.Handle("POST /auth/login", cop.Handler(http.HandlerFunc(h.HandleLoginSubmit)))
 	mux.HandleFunc("GET /oauth/callback", h.HandleOAuthCallback)
 	mux.Handle("POST /logout", cop.Handler(http.HandlerFunc(h.HandleLogout)))
+	mux.Handle("POST /auth/switch", cop.Handler(http.HandlerFunc(h.HandleSwitchAccount)))
 	mux.HandleFunc("GET /client-metadata.json", h.HandleClientMetadata)
 	mux.HandleFunc("GET /.well-known/oauth-client-metadata", h.HandleWellKnownOAuth)
+	mux.HandleFunc("GET "+atproto.JWKSPath, h.HandleJWKS)
 
 	// API routes for handle resolution (used by login autocomplete)
 	// These are intentionally public and don't require HTMX headers
 	mux.HandleFunc("GET /api/resolve-handle", h.HandleResolveHandle)
 	mux.HandleFunc("GET /api/search-actors", h.HandleSearchActors)
 
+	// Public community search over records published by registered users
+	mux.HandleFunc("GET /api/search", h.HandleCommunitySearchAPI)
+
 	// API route for fetching all user data (used by client-side cache via fetch())
 	// Auth-protected but accessible without HTMX header (called from JavaScript)
 	mux.HandleFunc("GET /api/data", h.HandleAPIListAll)
@@ -47,6 +57,9 @@ func SetupRouter(cfg Config) http.Handler {
 	mux.Handle("GET /api/feed", middleware.RequireHTMXMiddleware(http.HandlerFunc(h.HandleFeedPartial)))
 	mux.Handle("GET /api/brews", middleware.RequireHTMXMiddleware(http.HandlerFunc(h.HandleBrewListPartial)))
 	mux.Handle("GET /api/manage", middleware.RequireHTMXMiddleware(http.HandlerFunc(h.HandleManagePartial)))
+	mux.Handle("GET /api/grind-hints", middleware.RequireHTMXMiddleware(http.HandlerFunc(h.HandleGrindHintsPartial)))
+	mux.Handle("GET /api/search/mine", middleware.RequireHTMXMiddleware(http.HandlerFunc(h.HandleSearchPartial)))
+	mux.Handle("GET /api/search/community", middleware.RequireHTMXMiddleware(http.HandlerFunc(h.HandleCommunitySearchPartial)))
 	mux.Handle("GET /api/profile/{actor}", middleware.RequireHTMXMiddleware(http.HandlerFunc(h.HandleProfilePartial)))
 
 	// Page routes (must come before static files)
@@ -61,6 +74,27 @@ func SetupRouter(cfg Config) http.Handler {
 	mux.Handle("PUT /brews/{id}", cop.Handler(http.HandlerFunc(h.HandleBrewUpdate)))
 	mux.Handle("DELETE /brews/{id}", cop.Handler(http.HandlerFunc(h.HandleBrewDelete)))
 	mux.HandleFunc("GET /brews/export", h.HandleBrewExport)
+	mux.HandleFunc("GET /stats", h.HandleStats)
+	mux.HandleFunc("GET /search", h.HandleSearch)
+	mux.HandleFunc("GET /search/community", h.HandleCommunitySearch)
+	mux.HandleFunc("GET /settings", h.HandleSettings)
+	mux.Handle("POST /settings", cop.Handler(http.HandlerFunc(h.HandleSettingsUpdate)))
+	mux.HandleFunc("GET /settings/sessions", h.HandleSessions)
+	mux.Handle("POST /settings/sessions/revoke", cop.Handler(http.HandlerFunc(h.HandleRevokeSession)))
+	mux.Handle("POST /settings/sessions/revoke-others", cop.Handler(http.HandlerFunc(h.HandleRevokeOtherSessions)))
+	mux.HandleFunc("GET /settings/graph", h.HandleGraph)
+	mux.Handle("POST /settings/graph/mute", cop.Handler(http.HandlerFunc(h.HandleMute)))
+	mux.Handle("POST /settings/graph/unmute", cop.Handler(http.HandlerFunc(h.HandleUnmute)))
+	mux.Handle("POST /settings/graph/block", cop.Handler(http.HandlerFunc(h.HandleBlock)))
+	mux.Handle("POST /settings/graph/unblock", cop.Handler(http.HandlerFunc(h.HandleUnblock)))
+	mux.Handle("POST /settings/graph/import", cop.Handler(http.HandlerFunc(h.HandleImportBlocks)))
+
+	// Admin dashboard, limited to the DIDs in admin.dids
+	mux.HandleFunc("GET /admin", h.HandleAdmin)
+	mux.Handle("POST /admin/ban", cop.Handler(http.HandlerFunc(h.HandleAdminBan)))
+	mux.Handle("POST /admin/unban", cop.Handler(http.HandlerFunc(h.HandleAdminUnban)))
+	mux.Handle("POST /admin/hide", cop.Handler(http.HandlerFunc(h.HandleAdminHide)))
+	mux.Handle("POST /admin/unhide", cop.Handler(http.HandlerFunc(h.HandleAdminUnhide)))
 
 	// API routes for CRUD operations
 	mux.Handle("POST /api/beans", cop.Handler(http.HandlerFunc(h.HandleBeanCreate)))
@@ -83,8 +117,7 @@ func SetupRouter(cfg Config) http.Handler {
 	mux.HandleFunc("GET /profile/{actor}", h.HandleProfile)
 
 	// Static files (must come after specific routes)
-	fs := http.FileServer(http.Dir("web/static"))
-	mux.Handle("GET /static/", http.StripPrefix("/static/", fs))
+	mux.Handle("GET /static/", cfg.Assets.Handler())
 
 	// Catch-all 404 handler - must be last, catches any unmatched routes
 	mux.HandleFunc("/", h.HandleNotFound)
@@ -92,21 +125,33 @@ func SetupRouter(cfg Config) http.Handler {
 	// Apply middleware in order (outermost first, innermost last)
 	var handler http.Handler = mux
 
-	// 1. Limit request body size (innermost - runs first on request)
+	// 1. Record the matched route pattern for request metrics (must wrap the mux directly)
+	handler = middleware.RouteMiddleware(handler)
+
+	// 2. Limit request body size
 	handler = middleware.LimitBodyMiddleware(handler)
 
-	// 2. Apply OAuth middleware to add auth context
+	// 3. Compress responses and answer conditional requests with 304 Not Modified
+	handler = middleware.CompressMiddleware(handler)
+
+	// 4. Apply OAuth middleware to add auth context
 	handler = cfg.OAuthManager.AuthMiddleware(handler)
 
-	// 3. Apply rate limiting
-	rateLimitConfig := middleware.NewDefaultRateLimitConfig()
-	handler = middleware.RateLimitMiddleware(rateLimitConfig)(handler)
+	// 5. Apply rate limiting
+	handler = middleware.RateLimitMiddleware(cfg.RateLimits)(handler)
 
-	// 4. Apply security headers
+	// 6. Apply security headers
 	handler = middleware.SecurityHeadersMiddleware(handler)
 
-	// 5. Apply logging middleware (outermost - wraps everything)
+	// 7. Apply logging middleware (outermost - wraps everything)
 	handler = middleware.LoggingMiddleware(cfg.Logger)(handler)
 
-	return handler
+	// Probes bypass the middleware stack so they aren't logged, traced or rate
+	// limited, and can't trigger session lookups
+	root := http.NewServeMux()
+	root.HandleFunc("GET /healthz", cfg.Health.HandleLiveness)
+	root.HandleFunc("GET /readyz", cfg.Health.HandleReadiness)
+	root.Handle("/", handler)
+
+	return root
 }
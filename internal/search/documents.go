@@ -0,0 +1,148 @@
+package search
+
+import (
+	"strings"
+
+	"arabica/internal/models"
+)
+
+// Field weights: names rank above origins and types, which rank above free text
+const (
+	weightName  = 3.0
+	weightMeta  = 2.0
+	weightNotes = 1.0
+)
+
+// BrewDocument indexes a brew's tasting notes along with the names of the
+// bean, roaster and gear it was made with
+func BrewDocument(brew *models.Brew) *Document {
+	doc := &Document{
+		ID:        TypeBrew + ":" + brew.RKey,
+		Type:      TypeBrew,
+		Title:     "Brew",
+		URL:       "/brews/" + brew.RKey,
+		CreatedAt: brew.CreatedAt,
+	}
+
+	var subtitle []string
+	if bean := brew.Bean; bean != nil {
+		doc.Title = bean.Name
+		doc.add("Bean", bean.Name, weightName)
+		doc.add("Origin", bean.Origin, weightMeta)
+		if bean.Roaster != nil {
+			doc.add("Roaster", bean.Roaster.Name, weightMeta)
+			subtitle = append(subtitle, bean.Roaster.Name)
+		}
+	}
+	doc.add("Method", brew.Method, weightMeta)
+	if brew.BrewerObj != nil {
+		doc.add("Brewer", brew.BrewerObj.Name, weightMeta)
+		subtitle = append(subtitle, brew.BrewerObj.Name)
+	}
+	if brew.GrinderObj != nil {
+		doc.add("Grinder", brew.GrinderObj.Name, weightMeta)
+	}
+	doc.add("Tasting notes", brew.TastingNotes, weightNotes)
+
+	subtitle = append(subtitle, brew.CreatedAt.Format("Jan 2, 2006"))
+	doc.Subtitle = strings.Join(subtitle, " · ")
+	return doc
+}
+
+// BeanDocument indexes a bean's name, origin, process and description
+func BeanDocument(bean *models.Bean) *Document {
+	doc := &Document{
+		ID:        TypeBean + ":" + bean.RKey,
+		Type:      TypeBean,
+		Title:     bean.Name,
+		Subtitle:  bean.Origin,
+		URL:       "/manage",
+		CreatedAt: bean.CreatedAt,
+	}
+	doc.add("Name", bean.Name, weightName)
+	doc.add("Origin", bean.Origin, weightMeta)
+	doc.add("Roast", bean.RoastLevel, weightMeta)
+	doc.add("Process", bean.Process, weightMeta)
+	if bean.Roaster != nil {
+		doc.add("Roaster", bean.Roaster.Name, weightMeta)
+	}
+	doc.add("Description", bean.Description, weightNotes)
+	return doc
+}
+
+// RoasterDocument indexes a roaster's name and location
+func RoasterDocument(roaster *models.Roaster) *Document {
+	doc := &Document{
+		ID:        TypeRoaster + ":" + roaster.RKey,
+		Type:      TypeRoaster,
+		Title:     roaster.Name,
+		Subtitle:  roaster.Location,
+		URL:       "/manage",
+		CreatedAt: roaster.CreatedAt,
+	}
+	doc.add("Name", roaster.Name, weightName)
+	doc.add("Location", roaster.Location, weightMeta)
+	return doc
+}
+
+// GrinderDocument indexes a grinder's name, type and notes
+func GrinderDocument(grinder *models.Grinder) *Document {
+	doc := &Document{
+		ID:        TypeGrinder + ":" + grinder.RKey,
+		Type:      TypeGrinder,
+		Title:     grinder.Name,
+		Subtitle:  strings.TrimSpace(grinder.GrinderType + " " + grinder.BurrType),
+		URL:       "/manage",
+		CreatedAt: grinder.CreatedAt,
+	}
+	doc.add("Name", grinder.Name, weightName)
+	doc.add("Type", grinder.GrinderType, weightMeta)
+	doc.add("Burrs", grinder.BurrType, weightMeta)
+	doc.add("Notes", grinder.Notes, weightNotes)
+	return doc
+}
+
+// BrewerDocument indexes a brewer's name, type and description
+func BrewerDocument(brewer *models.Brewer) *Document {
+	doc := &Document{
+		ID:        TypeBrewer + ":" + brewer.RKey,
+		Type:      TypeBrewer,
+		Title:     brewer.Name,
+		Subtitle:  brewer.BrewerType,
+		URL:       "/manage",
+		CreatedAt: brewer.CreatedAt,
+	}
+	doc.add("Name", brewer.Name, weightName)
+	doc.add("Type", brewer.BrewerType, weightMeta)
+	doc.add("Description", brewer.Description, weightNotes)
+	return doc
+}
+
+// BuildIndex indexes all of a user's records
+func BuildIndex(beans []*models.Bean, roasters []*models.Roaster, grinders []*models.Grinder, brewers []*models.Brewer, brews []*models.Brew) *Index {
+	idx := NewIndex()
+	for _, b := range beans {
+		idx.Add(BeanDocument(b))
+	}
+	for _, r := range roasters {
+		idx.Add(RoasterDocument(r))
+	}
+	for _, g := range grinders {
+		idx.Add(GrinderDocument(g))
+	}
+	for _, b := range brewers {
+		idx.Add(BrewerDocument(b))
+	}
+	for _, b := range brews {
+		idx.Add(BrewDocument(b))
+	}
+	return idx
+}
+
+// add appends a field, skipping empty text
+func (d *Document) add(name, text string, weight float64) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	d.Fields = append(d.Fields, Field{Name: name, Text: text, Weight: weight})
+}
@@ -0,0 +1,93 @@
+package search
+
+import "strings"
+
+// excerptRadius is how many bytes of context to keep either side of the first match
+const excerptRadius = 80
+
+// Segment is a run of text that either matched the query or did not
+type Segment struct {
+	Text  string
+	Match bool
+}
+
+// Highlight splits text into segments, marking the words whose normalized
+// form is in terms
+func Highlight(text string, terms map[string]bool) []Segment {
+	var segments []Segment
+	pos := 0
+	for _, tok := range Tokenize(text) {
+		if !terms[tok.Term] {
+			continue
+		}
+		if tok.Start > pos {
+			segments = append(segments, Segment{Text: text[pos:tok.Start]})
+		}
+		segments = append(segments, Segment{Text: text[tok.Start:tok.End], Match: true})
+		pos = tok.End
+	}
+	if pos < len(text) {
+		segments = append(segments, Segment{Text: text[pos:]})
+	}
+	return segments
+}
+
+// Excerpt trims long text to a window around the first matched word,
+// cutting at spaces and marking cuts with an ellipsis
+func Excerpt(text string, terms map[string]bool) string {
+	if len(text) <= 2*excerptRadius {
+		return text
+	}
+
+	first := -1
+	for _, tok := range Tokenize(text) {
+		if terms[tok.Term] {
+			first = tok.Start
+			break
+		}
+	}
+	if first < 0 {
+		return text
+	}
+
+	start := max(0, first-excerptRadius)
+	end := min(len(text), first+excerptRadius)
+	if start > 0 {
+		if i := strings.IndexByte(text[start:first], ' '); i >= 0 {
+			start += i + 1
+		}
+	}
+	if end < len(text) {
+		if i := strings.LastIndexByte(text[first:end], ' '); i > 0 {
+			end = first + i
+		}
+	}
+
+	excerpt := text[start:end]
+	if start > 0 {
+		excerpt = "…" + excerpt
+	}
+	if end < len(text) {
+		excerpt += "…"
+	}
+	return excerpt
+}
+
+// PlainText joins segments back into unmarked text
+func PlainText(segments []Segment) string {
+	var b strings.Builder
+	for _, s := range segments {
+		b.WriteString(s.Text)
+	}
+	return b.String()
+}
+
+// hasMatch reports whether any segment matched
+func hasMatch(segments []Segment) bool {
+	for _, s := range segments {
+		if s.Match {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,299 @@
+// Package search provides a small in-process full-text index over coffee
+// records, with prefix and typo-tolerant matching and highlighting.
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Document types
+const (
+	TypeBrew    = "brew"
+	TypeBean    = "bean"
+	TypeRoaster = "roaster"
+	TypeGrinder = "grinder"
+	TypeBrewer  = "brewer"
+)
+
+// Relative weight of how a query term matched an indexed term
+const (
+	exactMatchScore  = 1.0
+	prefixMatchScore = 0.7
+	fuzzyMatchScore  = 0.4
+)
+
+// DefaultLimit caps the number of results when no limit is given
+const DefaultLimit = 50
+
+// Field is a piece of searchable text on a document. Weight boosts matches
+// in important fields such as names over matches in free-form notes.
+type Field struct {
+	Name   string
+	Text   string
+	Weight float64
+}
+
+// Document is a searchable record
+type Document struct {
+	ID        string // Unique within the index, e.g. "bean:3jzfcijpj2z2a"
+	Type      string
+	Title     string
+	Subtitle  string
+	URL       string
+	Fields    []Field
+	CreatedAt time.Time
+
+	// Author of the record, set for community search over other users' records
+	AuthorDID    string
+	AuthorHandle string
+}
+
+// WithAuthor scopes a document to the user who published it, so records from
+// different users with the same rkey don't collide, and links it to their profile
+func (d *Document) WithAuthor(did, handle string) *Document {
+	d.ID = did + "/" + d.ID
+	d.AuthorDID = did
+	d.AuthorHandle = handle
+	if handle != "" {
+		d.URL = "/profile/" + handle
+	} else {
+		d.URL = "/profile/" + did
+	}
+	return d
+}
+
+// posting records that a term occurs in a field of a document
+type posting struct {
+	doc   int
+	field int
+	count int
+}
+
+// Index is an inverted index over documents. It is safe for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	docs     []*Document
+	byID     map[string]int
+	postings map[string][]posting
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{
+		byID:     make(map[string]int),
+		postings: make(map[string][]posting),
+	}
+}
+
+// Add indexes a document. Adding a document whose ID is already indexed replaces it.
+func (idx *Index) Add(doc *Document) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if i, ok := idx.byID[doc.ID]; ok {
+		idx.removeLocked(i)
+	}
+
+	i := len(idx.docs)
+	idx.docs = append(idx.docs, doc)
+	idx.byID[doc.ID] = i
+
+	for f, field := range doc.Fields {
+		counts := make(map[string]int)
+		for _, term := range Terms(field.Text) {
+			counts[term]++
+		}
+		for term, count := range counts {
+			idx.postings[term] = append(idx.postings[term], posting{doc: i, field: f, count: count})
+		}
+	}
+}
+
+// Remove drops a document from the index
+func (idx *Index) Remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if i, ok := idx.byID[id]; ok {
+		idx.removeLocked(i)
+	}
+}
+
+// removeLocked tombstones document i. Its slot stays so other postings keep
+// their positions; searches skip nil documents.
+func (idx *Index) removeLocked(i int) {
+	delete(idx.byID, idx.docs[i].ID)
+	idx.docs[i] = nil
+	for term, list := range idx.postings {
+		kept := list[:0]
+		for _, p := range list {
+			if p.doc != i {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == 0 {
+			delete(idx.postings, term)
+		} else {
+			idx.postings[term] = kept
+		}
+	}
+}
+
+// Len returns the number of indexed documents
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.byID)
+}
+
+// Options narrows a search
+type Options struct {
+	Types  []string // Only return documents of these types; empty means all
+	Author string   // Only return documents by this DID or handle; empty means anyone
+	Limit  int      // Maximum number of results; 0 means DefaultLimit
+}
+
+// Result is a matching document with highlighted text
+type Result struct {
+	Doc      *Document
+	Score    float64
+	Title    []Segment    // Title split into matched and unmatched segments
+	Snippets []FieldMatch // Non-title fields that matched, trimmed around the match
+}
+
+// FieldMatch is a highlighted excerpt of a matching field
+type FieldMatch struct {
+	Name     string
+	Segments []Segment
+}
+
+// Search returns documents containing every query term, allowing prefix and
+// fuzzy matches, ordered by score and then by recency
+func (idx *Index) Search(query string, opts Options) []Result {
+	queryTerms := Terms(query)
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	author := strings.ToLower(strings.TrimPrefix(opts.Author, "@"))
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scores := make(map[int]float64)
+	matched := make(map[int]map[string]bool) // doc -> index terms that matched
+	for qi, qt := range queryTerms {
+		termScores := make(map[int]float64)
+		for term, factor := range idx.expand(qt) {
+			for _, p := range idx.postings[term] {
+				doc := idx.docs[p.doc]
+				if doc == nil || !typeAllowed(doc.Type, opts.Types) || !authorAllowed(doc, author) {
+					continue
+				}
+				s := factor * doc.Fields[p.field].Weight * float64(p.count)
+				termScores[p.doc] = max(termScores[p.doc], s)
+				if matched[p.doc] == nil {
+					matched[p.doc] = make(map[string]bool)
+				}
+				matched[p.doc][term] = true
+			}
+		}
+
+		// Every query term must match somewhere in the document
+		if qi == 0 {
+			scores = termScores
+			continue
+		}
+		for doc := range scores {
+			if s, ok := termScores[doc]; ok {
+				scores[doc] += s
+			} else {
+				delete(scores, doc)
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for i, score := range scores {
+		doc := idx.docs[i]
+		results = append(results, Result{
+			Doc:      doc,
+			Score:    score,
+			Title:    Highlight(doc.Title, matched[i]),
+			Snippets: snippets(doc, matched[i]),
+		})
+	}
+
+	sort.Slice(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return results[a].Doc.CreatedAt.After(results[b].Doc.CreatedAt)
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// expand maps a query term to the indexed terms it matches and how well
+func (idx *Index) expand(qt string) map[string]float64 {
+	out := make(map[string]float64)
+	edits := maxEdits(qt)
+	for term := range idx.postings {
+		switch {
+		case term == qt:
+			out[term] = exactMatchScore
+		case strings.HasPrefix(term, qt):
+			out[term] = prefixMatchScore
+		case edits > 0 && editDistance(qt, term, edits) <= edits:
+			out[term] = fuzzyMatchScore
+		}
+	}
+	return out
+}
+
+// typeAllowed reports whether docType passes the type filter
+func typeAllowed(docType string, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if t == docType {
+			return true
+		}
+	}
+	return false
+}
+
+// authorAllowed reports whether doc passes the author filter, given as a
+// lowercased DID or handle
+func authorAllowed(doc *Document, author string) bool {
+	if author == "" {
+		return true
+	}
+	return doc.AuthorDID == author || strings.ToLower(doc.AuthorHandle) == author
+}
+
+// snippets highlights every field that contains a matched term
+func snippets(doc *Document, terms map[string]bool) []FieldMatch {
+	var out []FieldMatch
+	for _, field := range doc.Fields {
+		if field.Text == doc.Title {
+			continue
+		}
+		segments := Highlight(Excerpt(field.Text, terms), terms)
+		if hasMatch(segments) {
+			out = append(out, FieldMatch{Name: field.Name, Segments: segments})
+		}
+	}
+	return out
+}
@@ -0,0 +1,249 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"arabica/internal/models"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"lowercases and splits", "Blueberry, Jasmine & honey", []string{"blueberry", "jasmine", "honey"}},
+		{"folds diacritics", "Café Señor Güji", []string{"cafe", "senor", "guji"}},
+		{"drops single characters", "a V60 b", []string{"v60"}},
+		{"empty", "  ", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Terms(tt.text)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Terms(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Terms(%q)[%d] = %q, want %q", tt.text, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTokenizeOffsets(t *testing.T) {
+	text := "Café notes"
+	tokens := Tokenize(text)
+	if len(tokens) != 2 {
+		t.Fatalf("got %d tokens, want 2", len(tokens))
+	}
+	if got := text[tokens[0].Start:tokens[0].End]; got != "Café" {
+		t.Errorf("first token spans %q, want %q", got, "Café")
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"ethiopia", "ethiopia", 2, 0},
+		{"ethiopa", "ethiopia", 2, 1},
+		{"yirgacheffe", "yirgachefe", 2, 1},
+		{"kenya", "tanzania", 2, 3},
+		{"abc", "abcdef", 1, 2},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.limit); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func testIndex() *Index {
+	roaster := &models.Roaster{RKey: "r1", Name: "Onyx Coffee Lab", Location: "Rogers, Arkansas"}
+	ethiopia := &models.Bean{RKey: "b1", Name: "Guji Hambela", Origin: "Ethiopia", Process: "Natural", Roaster: roaster}
+	colombia := &models.Bean{RKey: "b2", Name: "Huila Decaf", Origin: "Colombia", Process: "Washed"}
+	grinder := &models.Grinder{RKey: "g1", Name: "Comandante C40", GrinderType: "Hand", BurrType: "Conical"}
+	brewer := &models.Brewer{RKey: "w1", Name: "Hario V60", BrewerType: "Pour over"}
+
+	return BuildIndex(
+		[]*models.Bean{ethiopia, colombia},
+		[]*models.Roaster{roaster},
+		[]*models.Grinder{grinder},
+		[]*models.Brewer{brewer},
+		[]*models.Brew{
+			{RKey: "x1", Bean: ethiopia, BrewerObj: brewer, TastingNotes: "Bright blueberry and jasmine", CreatedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+			{RKey: "x2", Bean: colombia, TastingNotes: "Chocolate, a little flat", CreatedAt: time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC)},
+		},
+	)
+}
+
+func resultIDs(results []Result) []string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.Doc.ID
+	}
+	return ids
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx := testIndex()
+
+	tests := []struct {
+		name  string
+		query string
+		opts  Options
+		want  []string
+	}{
+		{"tasting notes", "blueberry", Options{}, []string{"brew:x1"}},
+		{"name ranks above gear on brews", "hario", Options{}, []string{"brewer:w1", "brew:x1"}},
+		{"ties broken by recency", "ethiopia", Options{}, []string{"brew:x1", "bean:b1"}},
+		{"prefix", "choc", Options{}, []string{"brew:x2"}},
+		{"typo", "blueberyy", Options{}, []string{"brew:x1"}},
+		{"diacritics", "commandante", Options{}, []string{"grinder:g1"}},
+		{"all terms required", "huila blueberry", Options{}, nil},
+		{"type filter", "onyx", Options{Types: []string{TypeRoaster}}, []string{"roaster:r1"}},
+		{"limit", "ethiopia", Options{Limit: 1}, []string{"brew:x1"}},
+		{"no typos in short terms", "v61", Options{}, nil},
+		{"no terms", "  ", Options{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resultIDs(idx.Search(tt.query, tt.opts))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Search(%q)[%d] = %q, want %q", tt.query, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIndexAddReplacesAndRemove(t *testing.T) {
+	idx := NewIndex()
+	idx.Add(&Document{ID: "bean:1", Type: TypeBean, Title: "Kenya", Fields: []Field{{Name: "Name", Text: "Kenya", Weight: 1}}})
+	idx.Add(&Document{ID: "bean:1", Type: TypeBean, Title: "Rwanda", Fields: []Field{{Name: "Name", Text: "Rwanda", Weight: 1}}})
+
+	if idx.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", idx.Len())
+	}
+	if got := idx.Search("kenya", Options{}); len(got) != 0 {
+		t.Errorf("replaced document still matches: %v", resultIDs(got))
+	}
+	if got := idx.Search("rwanda", Options{}); len(got) != 1 {
+		t.Errorf("replacement not found")
+	}
+
+	idx.Remove("bean:1")
+	if idx.Len() != 0 {
+		t.Errorf("Len() after Remove = %d, want 0", idx.Len())
+	}
+	if got := idx.Search("rwanda", Options{}); len(got) != 0 {
+		t.Errorf("removed document still matches")
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	segments := Highlight("Bright blueberry, jasmine", map[string]bool{"blueberry": true})
+	want := []Segment{
+		{Text: "Bright "},
+		{Text: "blueberry", Match: true},
+		{Text: ", jasmine"},
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("Highlight() = %v, want %v", segments, want)
+	}
+	for i := range want {
+		if segments[i] != want[i] {
+			t.Errorf("segment %d = %+v, want %+v", i, segments[i], want[i])
+		}
+	}
+}
+
+func TestSearchSnippets(t *testing.T) {
+	results := testIndex().Search("jasmin", Options{})
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	snippets := results[0].Snippets
+	if len(snippets) != 1 || snippets[0].Name != "Tasting notes" {
+		t.Fatalf("snippets = %+v, want one tasting notes snippet", snippets)
+	}
+	if !hasMatch(snippets[0].Segments) {
+		t.Errorf("snippet has no highlighted match")
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	long := "Start lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore " +
+		"et dolore magna aliqua blueberry ut enim ad minim veniam quis nostrud exercitation ullamco laboris " +
+		"nisi ut aliquip ex ea commodo consequat"
+
+	got := Excerpt(long, map[string]bool{"blueberry": true})
+	if len(got) >= len(long) {
+		t.Errorf("Excerpt did not trim: %q", got)
+	}
+	if got[:len("…")] != "…" {
+		t.Errorf("Excerpt should start with an ellipsis: %q", got)
+	}
+	if !hasMatch(Highlight(got, map[string]bool{"blueberry": true})) {
+		t.Errorf("Excerpt lost the match: %q", got)
+	}
+
+	short := "Bright blueberry"
+	if got := Excerpt(short, map[string]bool{"blueberry": true}); got != short {
+		t.Errorf("Excerpt(short) = %q, want unchanged", got)
+	}
+}
+
+func TestIndexSearchAuthor(t *testing.T) {
+	idx := NewIndex()
+	kenya := func(rkey string) *Document {
+		return BeanDocument(&models.Bean{RKey: rkey, Name: "Kenya Kiambu", Origin: "Kenya"})
+	}
+	idx.Add(kenya("b1").WithAuthor("did:plc:alice", "alice.example.com"))
+	idx.Add(kenya("b1").WithAuthor("did:plc:bob", "bob.example.com"))
+
+	tests := []struct {
+		name   string
+		author string
+		want   int
+	}{
+		{"anyone", "", 2},
+		{"by did", "did:plc:alice", 1},
+		{"by handle", "Bob.Example.com", 1},
+		{"by handle with at sign", "@bob.example.com", 1},
+		{"unknown", "carol.example.com", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := idx.Search("kenya", Options{Author: tt.author})
+			if len(got) != tt.want {
+				t.Errorf("Search with author %q returned %d results, want %d", tt.author, len(got), tt.want)
+			}
+		})
+	}
+
+	got := idx.Search("kenya", Options{Author: "alice.example.com"})
+	if len(got) == 1 && got[0].Doc.URL != "/profile/alice.example.com" {
+		t.Errorf("URL = %q, want profile link", got[0].Doc.URL)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	text := "Bright blueberry, jasmine"
+	if got := PlainText(Highlight(text, map[string]bool{"jasmine": true})); got != text {
+		t.Errorf("PlainText() = %q, want %q", got, text)
+	}
+}
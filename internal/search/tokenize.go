@@ -0,0 +1,123 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minTokenLength drops single characters, which match too much to be useful
+const minTokenLength = 2
+
+// Token is a normalized term and its byte range in the original text
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// foldTable maps accented Latin letters to their ASCII base so "Café" matches "cafe"
+var foldTable = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a',
+	'ç': 'c',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i',
+	'ñ': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u',
+	'ý': 'y', 'ÿ': 'y',
+}
+
+// foldRune lowercases r and strips common diacritics
+func foldRune(r rune) rune {
+	r = unicode.ToLower(r)
+	if folded, ok := foldTable[r]; ok {
+		return folded
+	}
+	return r
+}
+
+// Tokenize splits text into normalized terms, keeping byte offsets so matches
+// can be highlighted in the original text
+func Tokenize(text string) []Token {
+	var tokens []Token
+	var b strings.Builder
+	start := -1
+
+	flush := func(end int) {
+		if start >= 0 && len([]rune(b.String())) >= minTokenLength {
+			tokens = append(tokens, Token{Term: b.String(), Start: start, End: end})
+		}
+		b.Reset()
+		start = -1
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			b.WriteRune(foldRune(r))
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+
+	return tokens
+}
+
+// Terms returns just the normalized terms of text
+func Terms(text string) []string {
+	tokens := Tokenize(text)
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.Term
+	}
+	return terms
+}
+
+// maxEdits returns how many typos are tolerated for a query term of this length
+func maxEdits(term string) int {
+	switch n := len([]rune(term)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns the Levenshtein distance between a and b, stopping
+// early with limit+1 once the distance is known to exceed limit
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > limit || -diff > limit {
+		return limit + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
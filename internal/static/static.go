@@ -0,0 +1,83 @@
+// Package static serves the web/static assets. Asset URLs carry a hash of the
+// file's content, so browsers can cache them indefinitely and still pick up
+// new versions as soon as a deploy changes them.
+package static
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// hashLength is how many hex characters of the SHA-256 content hash are kept
+const hashLength = 12
+
+// Cache-Control values for versioned and unversioned requests
+const (
+	cacheImmutable  = "public, max-age=31536000, immutable"
+	cacheRevalidate = "no-cache"
+)
+
+// Assets serves static files from a filesystem
+type Assets struct {
+	hashes map[string]string // file path -> content hash
+	files  http.Handler
+}
+
+// New hashes every file in fsys. In dev mode nothing is hashed, so URLs stay
+// unversioned and edits on disk are served right away.
+func New(fsys fs.FS, dev bool) (*Assets, error) {
+	a := &Assets{
+		hashes: make(map[string]string),
+		files:  http.FileServerFS(fsys),
+	}
+	if dev {
+		return a, nil
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		a.hashes[name] = hex.EncodeToString(sum[:])[:hashLength]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// URL returns the URL for an asset path relative to web/static (e.g. "css/output.css"),
+// versioned with its content hash when the file is known
+func (a *Assets) URL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if hash, ok := a.hashes[name]; ok {
+		return "/static/" + name + "?v=" + hash
+	}
+	return "/static/" + name
+}
+
+// Handler serves assets under /static/. Requests carrying the current content
+// hash are cached for a year; anything else must be revalidated, which the
+// ETag makes cheap.
+func (a *Assets) Handler() http.Handler {
+	return http.StripPrefix("/static/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cacheControl := cacheRevalidate
+		if hash, ok := a.hashes[r.URL.Path]; ok {
+			w.Header().Set("ETag", `"`+hash+`"`)
+			if r.URL.Query().Get("v") == hash {
+				cacheControl = cacheImmutable
+			}
+		}
+		w.Header().Set("Cache-Control", cacheControl)
+		a.files.ServeHTTP(w, r)
+	}))
+}
@@ -0,0 +1,88 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"css/output.css": {Data: []byte("body{}")},
+		"js/app.js":      {Data: []byte("console.log(1)")},
+	}
+}
+
+func TestAssetsURL(t *testing.T) {
+	assets, err := New(testFS(), false)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	url := assets.URL("css/output.css")
+	if !strings.HasPrefix(url, "/static/css/output.css?v=") || len(url) != len("/static/css/output.css?v=")+hashLength {
+		t.Errorf("URL() = %q, want a hashed URL", url)
+	}
+	if got := assets.URL("/css/output.css"); got != url {
+		t.Errorf("URL() with leading slash = %q, want %q", got, url)
+	}
+	if got := assets.URL("missing.js"); got != "/static/missing.js" {
+		t.Errorf("URL() for unknown file = %q, want unversioned", got)
+	}
+
+	dev, err := New(testFS(), true)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := dev.URL("css/output.css"); got != "/static/css/output.css" {
+		t.Errorf("dev URL() = %q, want unversioned", got)
+	}
+}
+
+func TestAssetsHandler(t *testing.T) {
+	assets, err := New(testFS(), false)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	handler := assets.Handler()
+	versioned := assets.URL("js/app.js")
+	etag := `"` + versioned[strings.Index(versioned, "=")+1:] + `"`
+
+	tests := []struct {
+		name        string
+		url         string
+		ifNoneMatch string
+		wantStatus  int
+		wantCache   string
+		wantETag    string
+	}{
+		{"versioned", versioned, "", http.StatusOK, cacheImmutable, etag},
+		{"unversioned", "/static/js/app.js", "", http.StatusOK, cacheRevalidate, etag},
+		{"stale version", "/static/js/app.js?v=old", "", http.StatusOK, cacheRevalidate, etag},
+		{"revalidated", "/static/js/app.js", etag, http.StatusNotModified, cacheRevalidate, etag},
+		{"missing", "/static/nope.js", "", http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.wantCache {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantCache)
+			}
+			if got := rec.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Package tracing configures OpenTelemetry and provides helpers for starting
+// spans around handlers, cache lookups, PDS calls and template rendering.
+//
+// The exporter is chosen with the standard OTEL_TRACES_EXPORTER variable:
+//
+//   - "otlp" sends spans over OTLP/HTTP, configured by the usual
+//     OTEL_EXPORTER_OTLP_* variables (endpoint, headers, ...)
+//   - "console" or "stdout" pretty-prints spans to stdout for local debugging
+//   - unset or "none" disables tracing; spans are then no-ops
+//
+// Sampling follows OTEL_TRACES_SAMPLER and the service name OTEL_SERVICE_NAME.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies spans created by this application
+const instrumentationName = "arabica"
+
+// Setup installs the global tracer provider and propagator according to the
+// environment. The returned shutdown function flushes pending spans and must
+// be called before exit; it is a no-op when tracing is disabled.
+func Setup(ctx context.Context) (shutdown func(context.Context) error, exporter string, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	noop := func(context.Context) error { return nil }
+
+	var exp sdktrace.SpanExporter
+	exporter = os.Getenv("OTEL_TRACES_EXPORTER")
+	switch exporter {
+	case "", "none":
+		return noop, "none", nil
+	case "otlp":
+		exp, err = otlptracehttp.New(ctx)
+	case "console", "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return noop, exporter, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q", exporter)
+	}
+	if err != nil {
+		return noop, exporter, fmt.Errorf("creating %s exporter: %w", exporter, err)
+	}
+
+	// Later options win, so OTEL_SERVICE_NAME overrides the default name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName("arabica")),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return noop, exporter, fmt.Errorf("creating resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, exporter, nil
+}
+
+// Start begins a span named name as a child of any span in ctx
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// RecordError marks the span as failed when err is non-nil
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// End records err on the span, if any, and ends it. Meant for deferring with
+// a named error result.
+func End(span trace.Span, err error) {
+	RecordError(span, err)
+	span.End()
+}
@@ -1,6 +1,20 @@
 { config, lib, pkgs, ... }:
 
-let cfg = config.services.arabica;
+let
+  cfg = config.services.arabica;
+
+  # Wait for /readyz after start so units ordered after arabica only start once
+  # it can serve requests
+  waitReady = pkgs.writeShellScript "arabica-wait-ready" ''
+    for _ in $(seq 1 30); do
+      if ${pkgs.curl}/bin/curl -fsS -o /dev/null http://127.0.0.1:${toString cfg.settings.port}/readyz; then
+        exit 0
+      fi
+      sleep 1
+    done
+    echo "arabica did not become ready within 30s" >&2
+    exit 1
+  '';
 in {
   options.services.arabica = {
     enable = lib.mkEnableOption "Arabica coffee brew tracking service";
@@ -36,6 +50,15 @@ in {
         default = true;
         description = "Whether to set the Secure flag on cookies. Should be true when using HTTPS.";
       };
+
+      checkUpstream = lib.mkOption {
+        type = lib.types.bool;
+        default = false;
+        description = ''
+          Whether /readyz also reports reachability of the PLC directory and the
+          Bluesky AppView. These checks are informational and never fail readiness.
+        '';
+      };
     };
 
     oauth = {
@@ -64,6 +87,40 @@ in {
       description = "Directory where arabica stores its data (OAuth sessions, etc.).";
     };
 
+    sessionKeyFile = lib.mkOption {
+      type = lib.types.nullOr lib.types.str;
+      default = null;
+      description = ''
+        File holding the base64 key that encrypts OAuth sessions in the database,
+        as printed by `arabica admin sessions keygen`. Given as a string so the
+        key is never copied into the nix store. Sessions are stored unencrypted
+        when null.
+      '';
+      example = "/run/secrets/arabica-session-key";
+    };
+
+    signingKeyFile = lib.mkOption {
+      type = lib.types.nullOr lib.types.str;
+      default = null;
+      description = ''
+        File holding the P-256 key arabica signs OAuth client assertions with,
+        as printed by `arabica admin oauth keygen`, making it a confidential
+        client. Given as a string so the key is never copied into the nix store.
+        Requires a public URL.
+      '';
+      example = "/run/secrets/arabica-oauth-key";
+    };
+
+    configFile = lib.mkOption {
+      type = lib.types.nullOr lib.types.path;
+      default = null;
+      description = ''
+        Optional YAML config file for tunables such as cache TTLs and rate limits.
+        Settings from the options above are passed as environment variables and
+        take precedence over the same keys in this file.
+      '';
+    };
+
     user = lib.mkOption {
       type = lib.types.str;
       default = "arabica";
@@ -104,6 +161,7 @@ in {
         User = cfg.user;
         Group = cfg.group;
         ExecStart = "${cfg.package}/bin/arabica";
+        ExecStartPost = waitReady;
         Restart = "on-failure";
         RestartSec = "10s";
 
@@ -134,6 +192,13 @@ in {
         OAUTH_CLIENT_ID = cfg.oauth.clientId;
         OAUTH_REDIRECT_URI = cfg.oauth.redirectUri;
         ARABICA_DB_PATH = "${cfg.dataDir}/arabica.db";
+        ARABICA_READYZ_UPSTREAM = lib.boolToString cfg.settings.checkUpstream;
+      } // lib.optionalAttrs (cfg.configFile != null) {
+        ARABICA_CONFIG = toString cfg.configFile;
+      } // lib.optionalAttrs (cfg.sessionKeyFile != null) {
+        ARABICA_SESSION_KEY_FILE = cfg.sessionKeyFile;
+      } // lib.optionalAttrs (cfg.signingKeyFile != null) {
+        ARABICA_OAUTH_SIGNING_KEY_FILE = cfg.signingKeyFile;
       };
     };
 
@@ -10,6 +10,14 @@ function brewForm() {
     showNewBrewer: false,
     rating: 5,
     pours: [],
+    coffee: "",
+    water: "",
+    tds: "",
+    beverage: "",
+    // Conversion factors for the user's unit preferences, so the ratio is
+    // always grams of water per gram of coffee
+    gramsPerWeight: 1,
+    mlPerVolume: 1,
     newBean: {
       name: "",
       origin: "",
@@ -29,6 +37,11 @@ function brewForm() {
     dataLoaded: false,
 
     async init() {
+      this.gramsPerWeight =
+        parseFloat(this.$el.getAttribute("data-grams-per-weight")) || 1;
+      this.mlPerVolume =
+        parseFloat(this.$el.getAttribute("data-ml-per-volume")) || 1;
+
       // Load existing pours if editing
       const poursData = this.$el.getAttribute("data-pours");
       if (poursData) {
@@ -196,6 +209,32 @@ function brewForm() {
       }
     },
 
+    // Total water, falling back to the sum of pours when no total is entered
+    totalWater() {
+      const water = parseFloat(this.water);
+      if (water > 0) return water;
+      return this.pours.reduce((sum, p) => sum + (parseFloat(p.water) || 0), 0);
+    },
+
+    // Mirrors models.Brew.BrewRatio
+    ratioText() {
+      const coffee = parseFloat(this.coffee);
+      const water = this.totalWater();
+      if (!(coffee > 0) || !(water > 0)) return "N/A";
+      const ratio = (water * this.mlPerVolume) / (coffee * this.gramsPerWeight);
+      return "1:" + Math.round(ratio * 10) / 10;
+    },
+
+    // Mirrors models.Brew.ExtractionYield: TDS% * beverage weight / dose.
+    // Both weights are in the same unit, so no conversion is needed.
+    extractionText() {
+      const coffee = parseFloat(this.coffee);
+      const tds = parseFloat(this.tds);
+      const beverage = parseFloat(this.beverage);
+      if (!(coffee > 0) || !(tds > 0) || !(beverage > 0)) return "N/A";
+      return ((tds * beverage) / coffee).toFixed(1) + "%";
+    },
+
     addPour() {
       this.pours.push({ water: "", time: "" });
     },
@@ -277,6 +316,8 @@ function brewForm() {
         );
         if (grinderSelect && newGrinder.rkey) {
           grinderSelect.value = newGrinder.rkey;
+          // Refresh grind size hints for the new grinder
+          grinderSelect.dispatchEvent(new Event("change"));
         }
         // Close modal and reset form
         this.showNewGrinder = false;
@@ -4,7 +4,8 @@
  * to reduce PDS round-trips on page loads.
  */
 
-const CACHE_KEY = "arabica_data_cache";
+const CACHE_KEY_PREFIX = "arabica_data_cache";
+const CACHE_KEY = accountCacheKey();
 const CACHE_VERSION = 1;
 const CACHE_TTL_MS = 30 * 1000; // 30 seconds (shorter for multi-device sync)
 const REFRESH_INTERVAL_MS = 30 * 1000; // 30 seconds
@@ -14,6 +15,20 @@ let refreshTimer = null;
 let isRefreshing = false;
 let listeners = [];
 
+/**
+ * Get the cache key for the signed-in account. Several accounts can be
+ * signed in at once, so each gets its own cache and switching accounts
+ * never shows another account's records.
+ */
+function accountCacheKey() {
+  const meta = document.querySelector('meta[name="arabica-account"]');
+  if (!meta || !meta.content) return CACHE_KEY_PREFIX;
+
+  // Drop the cache from before it was kept per account
+  localStorage.removeItem(CACHE_KEY_PREFIX);
+  return `${CACHE_KEY_PREFIX}:${meta.content}`;
+}
+
 /**
  * Get the current cache from localStorage
  */
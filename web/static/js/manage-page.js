@@ -1,3 +1,17 @@
+/**
+ * Blank grinder form state, including the optional setting scale and calibration points
+ */
+function emptyGrinderForm() {
+  return {
+    name: "",
+    grinder_type: "",
+    burr_type: "",
+    notes: "",
+    scale: { min: "", max: "", step: "", unit: "" },
+    calibration: [],
+  };
+}
+
 /**
  * Alpine.js component for the manage page
  * Handles CRUD operations for beans, roasters, grinders, and brewers
@@ -22,7 +36,7 @@ function managePage() {
       roaster_rkey: "",
     },
     roasterForm: { name: "", location: "", website: "" },
-    grinderForm: { name: "", grinder_type: "", burr_type: "", notes: "" },
+    grinderForm: emptyGrinderForm(),
     brewerForm: { name: "", brewer_type: "", description: "" },
 
     init() {
@@ -161,12 +175,54 @@ function managePage() {
       }
     },
 
-    editGrinder(rkey, name, grinder_type, burr_type, notes) {
+    newGrinder() {
+      this.editingGrinder = null;
+      this.grinderForm = emptyGrinderForm();
+      this.showGrinderForm = true;
+    },
+
+    editGrinder(rkey, name, grinder_type, burr_type, notes, settings = {}) {
+      const form = emptyGrinderForm();
+      Object.assign(form, { name, grinder_type, burr_type, notes });
+      if (settings.scale) {
+        form.scale = { ...settings.scale };
+      }
+      if (settings.calibration) {
+        form.calibration = settings.calibration.map((p) => ({ ...p }));
+      }
+
       this.editingGrinder = rkey;
-      this.grinderForm = { name, grinder_type, burr_type, notes };
+      this.grinderForm = form;
       this.showGrinderForm = true;
     },
 
+    // Build the request body, dropping an empty scale and incomplete calibration rows
+    grinderPayload() {
+      const { scale, calibration, ...fields } = this.grinderForm;
+      const payload = { ...fields };
+
+      if (scale.unit || scale.max !== "") {
+        payload.scale = {
+          min: parseFloat(scale.min) || 0,
+          max: parseFloat(scale.max) || 0,
+          step: parseFloat(scale.step) || 0,
+          unit: scale.unit,
+        };
+      }
+
+      const points = calibration
+        .filter((p) => p.setting !== "" && p.microns !== "")
+        .map((p) => ({
+          setting: parseFloat(p.setting),
+          microns: parseInt(p.microns, 10),
+        }));
+      if (points.length > 0) {
+        payload.calibration = points;
+      }
+
+      return payload;
+    },
+
     async saveGrinder() {
       if (!this.grinderForm.name || !this.grinderForm.grinder_type) {
         alert("Name and Grinder Type are required");
@@ -183,7 +239,7 @@ function managePage() {
         headers: {
           "Content-Type": "application/json",
         },
-        body: JSON.stringify(this.grinderForm),
+        body: JSON.stringify(this.grinderPayload()),
       });
 
       if (response.ok) {